@@ -28,6 +28,9 @@ import (
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/console"
 	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/state/pruner"
 	"github.com/sero-cash/go-sero/event"
 	"github.com/sero-cash/go-sero/log"
 	"github.com/sero-cash/go-sero/sero/downloader"
@@ -119,6 +122,36 @@ The export-preimages command export hash preimages to an RLP encoded stream`,
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The first argument must be the directory containing the blockchain to download from`,
+	}
+	pruneStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(pruneState),
+		Name:      "prune-state",
+		Usage:     "Prune the state history outside a retention window",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.StateRetainFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The prune-state command deletes the trie nodes and the zstate block records only
+needed by the states of blocks older than the last --state.retain blocks. The
+records of unspent outs are kept. The node must not be running.`,
+	}
+	inspectCommand = cli.Command{
+		Action:    utils.MigrateFlags(inspect),
+		Name:      "inspect",
+		Usage:     "Inspect the storage size for each type of data in the database",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The inspect command traverses the entire chain database and reports the space
+taken by every category of data, in LevelDB and in the ancient store.`,
 	}
 	removedbCommand = cli.Command{
 		Action:    utils.MigrateFlags(removeDB),
@@ -389,6 +422,39 @@ func copyDb(ctx *cli.Context) error {
 	return nil
 }
 
+func pruneState(ctx *cli.Context) error {
+	retain := ctx.GlobalUint64(utils.StateRetainFlag.Name)
+	if retain == 0 {
+		utils.Fatalf("This command requires --%s.", utils.StateRetainFlag.Name)
+	}
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack).(*serodb.LDBDatabase)
+	defer chainDb.Close()
+
+	head := rawdb.ReadHeadBlockHash(chainDb)
+	number := rawdb.ReadHeaderNumber(chainDb, head)
+	if number == nil {
+		utils.Fatalf("No head block in the database")
+	}
+	p, err := pruner.New(chainDb, state.NewDatabase(chainDb), retain)
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	start := time.Now()
+	if err := p.Prune(*number); err != nil {
+		utils.Fatalf("Pruning failed: %v", err)
+	}
+	fmt.Printf("Pruning done in %v\n", time.Since(start))
+
+	start = time.Now()
+	fmt.Println("Compacting entire database...")
+	if err = chainDb.LDB().CompactRange(util.Range{}); err != nil {
+		utils.Fatalf("Compaction failed: %v", err)
+	}
+	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
+	return nil
+}
+
 func removeDB(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 
@@ -450,3 +516,11 @@ func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
 	return err != nil
 }
+
+func inspect(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack).(*serodb.LDBDatabase)
+	defer chainDb.Close()
+
+	return rawdb.InspectDatabase(chainDb)
+}
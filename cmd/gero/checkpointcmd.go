@@ -0,0 +1,181 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/sero-cash/go-sero/cmd/utils"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	checkpointFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block of the checkpoints (default = after the last known checkpoint)",
+	}
+	checkpointToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block of the checkpoints (default = head block)",
+	}
+	checkpointKeyFlag = cli.StringFlag{
+		Name:  "key",
+		Usage: "Private key file signing the checkpoints",
+	}
+
+	checkpointCommand = cli.Command{
+		Name:     "checkpoint",
+		Usage:    "Generate and verify state checkpoints",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The state root of every 10000th block is checked against the checkpoints built
+into gero and the ones of the files given with --checkpoints.`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(generateCheckpoints),
+				Name:      "generate",
+				Usage:     "Write a signed checkpoint file from the local chain",
+				ArgsUsage: "<filename>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					checkpointFromFlag,
+					checkpointToFlag,
+					checkpointKeyFlag,
+				},
+				Description: `
+    gero checkpoint generate --key <keyfile> [--from <block>] [--to <block>] <filename>
+
+Reads the state roots of the synced local chain and writes them as a checkpoint
+file signed with the given key. The file can be loaded by other nodes with
+--checkpoints.`,
+			},
+			{
+				Action:    utils.MigrateFlags(verifyCheckpoints),
+				Name:      "verify",
+				Usage:     "Verify the local chain against the checkpoints",
+				ArgsUsage: "[<filename> ...]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CheckPointFilesFlag,
+					utils.CheckPointSignersFlag,
+				},
+				Description: `
+    gero checkpoint verify [<filename> ...]
+
+Compares the state roots of the local chain with the known checkpoints and the
+ones of the given files, reporting every mismatch.`,
+			},
+		},
+	}
+)
+
+func generateCheckpoints(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	if !ctx.IsSet(checkpointKeyFlag.Name) {
+		utils.Fatalf("This command requires --%s.", checkpointKeyFlag.Name)
+	}
+	key, err := crypto.LoadECDSA(ctx.String(checkpointKeyFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to load the key: %v", err)
+	}
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	head := rawdb.ReadHeaderNumber(chainDb, rawdb.ReadHeadHeaderHash(chainDb))
+	if head == nil {
+		utils.Fatalf("No head block in the database")
+	}
+	from := zconfig.CheckPoints.MaxNum() + 1
+	if ctx.IsSet(checkpointFromFlag.Name) {
+		from = ctx.Uint64(checkpointFromFlag.Name)
+	}
+	to := *head
+	if ctx.IsSet(checkpointToFlag.Name) {
+		to = ctx.Uint64(checkpointToFlag.Name)
+	}
+	if to > *head {
+		utils.Fatalf("The block %d is beyond the head block %d", to, *head)
+	}
+	if from == 0 {
+		from = 1
+	}
+	file := &zconfig.CheckPointFile{}
+	for num := (from + 9999) / 10000 * 10000; num <= to; num += 10000 {
+		header := rawdb.ReadHeader(chainDb, rawdb.ReadCanonicalHash(chainDb, num), num)
+		if header == nil {
+			utils.Fatalf("Missing the header of the block %d", num)
+		}
+		cp := zconfig.CheckPoint{Num: num}
+		copy(cp.Root[:], header.Root[:])
+		file.Points = append(file.Points, cp)
+	}
+	if len(file.Points) == 0 {
+		utils.Fatalf("No checkpoint between the blocks %d and %d", from, to)
+	}
+	if err := file.Sign(key); err != nil {
+		utils.Fatalf("Failed to sign the checkpoints: %v", err)
+	}
+	if err := zconfig.WriteCheckPointFile(ctx.Args().First(), file); err != nil {
+		utils.Fatalf("Failed to write the checkpoints: %v", err)
+	}
+	fmt.Printf("Wrote %d checkpoints from %d to %d, signer %s\n", len(file.Points), file.Points[0].Num, file.Points[len(file.Points)-1].Num, crypto.CompressPubkey(&key.PublicKey))
+	return nil
+}
+
+func verifyCheckpoints(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	for _, path := range ctx.Args() {
+		if _, err := zconfig.LoadCheckPointFile(path, nil); err != nil {
+			utils.Fatalf("Failed to load %s: %v", path, err)
+		}
+	}
+	head := rawdb.ReadHeaderNumber(chainDb, rawdb.ReadHeadHeaderHash(chainDb))
+	if head == nil {
+		utils.Fatalf("No head block in the database")
+	}
+	var checked, mismatches int
+	for _, cp := range zconfig.CheckPoints.Points() {
+		if cp.Num > *head {
+			break
+		}
+		header := rawdb.ReadHeader(chainDb, rawdb.ReadCanonicalHash(chainDb, cp.Num), cp.Num)
+		if header == nil {
+			fmt.Printf("Block %d: missing header\n", cp.Num)
+			mismatches++
+			continue
+		}
+		checked++
+		if err := zconfig.CheckPoints.Check(cp.Num, header.Root[:]); err != nil {
+			fmt.Printf("Block %d: %v\n", cp.Num, err)
+			mismatches++
+		}
+	}
+	fmt.Printf("Checked %d checkpoints up to the block %d, %d mismatches\n", checked, *head, mismatches)
+	if mismatches > 0 {
+		utils.Fatalf("The local chain does not match the checkpoints")
+	}
+	return nil
+}
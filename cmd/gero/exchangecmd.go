@@ -0,0 +1,156 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/sero-cash/go-sero/cmd/utils"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/wallet/exchange"
+	"github.com/sero-cash/go-sero/zero/wallet/light"
+	"github.com/sero-cash/go-sero/zero/wallet/snapshot"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	snapshotLightFlag = cli.BoolFlag{
+		Name:  "light",
+		Usage: "Use the light node index instead of the exchange one",
+	}
+
+	exchangeCommand = cli.Command{
+		Name:     "exchange",
+		Usage:    "Export and import the exchange and light node indexes",
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The exchange and the light node index the chain from their first block on. A
+snapshot of the index of a node lets another node of the same chain skip it.`,
+		Subcommands: []cli.Command{
+			{
+				Action:    utils.MigrateFlags(exportSnapshot),
+				Name:      "export",
+				Usage:     "Write a snapshot of the index",
+				ArgsUsage: "<filename>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					snapshotLightFlag,
+				},
+				Description: `
+    gero exchange export [--light] <filename>
+
+Writes the index of the exchange, or of the light node, with a manifest of the
+last block indexed, the accounts indexed and the hash of the content. The node
+must be stopped.`,
+			},
+			{
+				Action:    utils.MigrateFlags(importSnapshot),
+				Name:      "import",
+				Usage:     "Load a snapshot of the index",
+				ArgsUsage: "<filename>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					snapshotLightFlag,
+				},
+				Description: `
+    gero exchange import [--light] <filename>
+
+Loads a snapshot into the empty index of the exchange, or of the light node,
+after checking its content and that its last block is in the local chain. The
+node indexes the following blocks once started.`,
+			},
+		},
+	}
+)
+
+// openIndex opens the index database of the exchange or of the light node.
+func openIndex(ctx *cli.Context) (*serodb.LDBDatabase, string) {
+	dir, kind := zconfig.Exchange_dir(), snapshot.KindExchange
+	if ctx.Bool(snapshotLightFlag.Name) {
+		dir, kind = zconfig.Light_dir(), snapshot.KindLight
+	}
+	db, err := serodb.NewLDBDatabase(dir, 16, 16)
+	if err != nil {
+		utils.Fatalf("Failed to open the %s index: %v", kind, err)
+	}
+	return db, kind
+}
+
+func exportSnapshot(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	db, kind := openIndex(ctx)
+	defer db.Close()
+
+	var (
+		manifest *snapshot.Manifest
+		err      error
+	)
+	if kind == snapshot.KindLight {
+		manifest, err = light.SnapshotManifest(db, chainDb)
+	} else {
+		manifest, err = exchange.SnapshotManifest(db, chainDb)
+	}
+	if err != nil {
+		utils.Fatalf("Failed to export the %s index: %v", kind, err)
+	}
+	if err := manifest.CheckChain(chainDb); err != nil {
+		utils.Fatalf("Failed to export the %s index: %v", kind, err)
+	}
+	if manifest, err = snapshot.Export(db, ctx.Args().First(), *manifest); err != nil {
+		utils.Fatalf("Failed to export the %s index: %v", kind, err)
+	}
+	fmt.Printf("Exported %d entries of the %s index at block %d [%x], %d accounts, content %x\n",
+		manifest.Entries, kind, manifest.Number, manifest.Hash, len(manifest.Pks), manifest.ContentHash)
+	return nil
+}
+
+func importSnapshot(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	path := ctx.Args().First()
+	manifest, err := snapshot.ReadManifest(path)
+	if err != nil {
+		utils.Fatalf("Failed to read the snapshot: %v", err)
+	}
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	db, kind := openIndex(ctx)
+	defer db.Close()
+
+	if manifest.Kind != kind {
+		utils.Fatalf("The snapshot is of the %s index, not of the %s one", manifest.Kind, kind)
+	}
+	if manifest, err = snapshot.Import(db, chainDb, path); err != nil {
+		utils.Fatalf("Failed to import the snapshot: %v", err)
+	}
+	fmt.Printf("Imported %d entries of the %s index at block %d [%x]\n", manifest.Entries, kind, manifest.Number, manifest.Hash)
+	for _, pk := range manifest.Pks {
+		fmt.Printf("Indexed account %s\n", base58.Encode(pk[:]))
+	}
+	return nil
+}
@@ -88,6 +88,8 @@ var (
 		utils.TxPoolLifetimeFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
+		utils.StateRetainFlag,
+		utils.AncientThresholdFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheGCFlag,
@@ -118,17 +120,27 @@ var (
 		utils.AutoMergeFlag,
 		utils.ConfirmedBlockFlag,
 		utils.LightNodeFlag,
+		utils.HistoryFlag,
+		utils.LightWalletFlag,
+		utils.LightPeersFlag,
 		utils.ResetBlockNumber,
 
 		utils.DeveloperFlag,
+		utils.DevSkipProofFlag,
 		utils.OfflineFlag,
 		utils.SnapshotFlag,
+		utils.CheckPointFilesFlag,
+		utils.CheckPointSignersFlag,
 		utils.TestStartBlockFlag,
 		utils.TestForkFlag,
 		utils.VMEnableDebugFlag,
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
+		utils.RPCAuthFileFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCRateBurstFlag,
+		utils.RPCMethodCostsFlag,
 		utils.SeroStatsURLFlag,
 		utils.MetricsEnabledFlag,
 		utils.FakePoWFlag,
@@ -189,12 +201,18 @@ func init() {
 		importPreimagesCommand,
 		exportPreimagesCommand,
 		copydbCommand,
+		pruneStateCommand,
+		inspectCommand,
+		checkpointCommand,
+		exchangeCommand,
 		removedbCommand,
 		//dumpCommand,
 		// See monitorcmd.go:
 		monitorCommand,
 		// See accountcmd.go:
 		accountCommand,
+		// See signcmd.go:
+		signOfflineCommand,
 		// See consolecmd.go:
 		consoleCommand,
 		attachCommand,
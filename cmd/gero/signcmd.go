@@ -0,0 +1,134 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/superzk"
+	"github.com/sero-cash/go-sero/accounts/keystore"
+	"github.com/sero-cash/go-sero/cmd/utils"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/console"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/zero/txtool/bundle"
+	"github.com/sero-cash/go-sero/zero/txtool/flight"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	signedBundleFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the signed bundle to (default = <bundle file>.signed)",
+	}
+	signOfflineCommand = cli.Command{
+		Action:    utils.MigrateFlags(signOffline),
+		Name:      "sign-offline",
+		Usage:     "Sign a transaction bundle with an encrypted key file",
+		ArgsUsage: "<bundle file> <key file>",
+		Flags: []cli.Flag{
+			utils.PasswordFileFlag,
+			signedBundleFlag,
+		},
+		Category: "ACCOUNT COMMANDS",
+		Description: `
+The sign-offline command signs a transaction bundle exported by
+exchange.genTxBundle on a machine that never holds the keys, so the keys
+don't need to leave an air-gapped one.
+
+The summary of the transaction is shown for confirmation before the key
+file is decrypted, without asking if a password file is given. The signed
+bundle is written next to the bundle file and can be committed with
+exchange.commitTxBundle.`,
+	}
+)
+
+// signOffline signs the transaction of an unsigned bundle.
+func signOffline(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("This command requires two arguments.")
+	}
+	bundleFile, keyFile := ctx.Args().Get(0), ctx.Args().Get(1)
+
+	blob, err := ioutil.ReadFile(bundleFile)
+	if err != nil {
+		utils.Fatalf("Failed to read the bundle: %v", err)
+	}
+	unsigned, err := bundle.DecodeUnsigned(blob)
+	if err != nil {
+		utils.Fatalf("Invalid bundle %s: %v", bundleFile, err)
+	}
+	fmt.Printf("Chain:   %v\n", unsigned.ChainId.ToInt())
+	fmt.Print(unsigned.Summary.String())
+
+	if len(utils.MakePasswordList(ctx)) == 0 {
+		confirm, err := console.Stdin.PromptConfirm("Sign this transaction?")
+		switch {
+		case err != nil:
+			utils.Fatalf("%v", err)
+		case !confirm:
+			utils.Fatalf("Signing aborted")
+		}
+	}
+
+	keyjson, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		utils.Fatalf("Failed to read the key file: %v", err)
+	}
+	passphrase := getPassPhrase("", false, 0, utils.MakePasswordList(ctx))
+	key, err := keystore.DecryptKey(keyjson, passphrase)
+	if err != nil {
+		utils.Fatalf("Failed to decrypt the key file: %v", err)
+	}
+	param := unsigned.Param
+	tk := key.Tk.ToTk()
+	for _, in := range param.Ins {
+		if !superzk.IsMyPKr(&tk, in.Out.State.OS.ToPKr()) {
+			utils.Fatalf("Input %s isn't owned by the key %s", hexutil.Encode(in.Out.Root[:]), key.Address)
+		}
+	}
+
+	var seed c_type.Uint256
+	copy(seed[:], crypto.FromECDSA(key.PrivateKey))
+	sk := superzk.Seed2Sk(&seed, key.Version)
+	tx, err := flight.SignTx(&sk, &param)
+	if err != nil {
+		utils.Fatalf("Failed to sign the transaction: %v", err)
+	}
+	for _, in := range param.Ins {
+		tx.Roots = append(tx.Roots, in.Out.Root)
+	}
+	signed, err := bundle.NewSigned(unsigned, &tx)
+	if err != nil {
+		utils.Fatalf("Failed to bundle the transaction: %v", err)
+	}
+	if blob, err = json.MarshalIndent(signed, "", "  "); err != nil {
+		utils.Fatalf("Failed to encode the signed bundle: %v", err)
+	}
+	out := ctx.String(signedBundleFlag.Name)
+	if out == "" {
+		out = bundleFile + ".signed"
+	}
+	if err := ioutil.WriteFile(out, blob, 0600); err != nil {
+		utils.Fatalf("Failed to write the signed bundle: %v", err)
+	}
+	fmt.Printf("Signed transaction %s written to %s\n", hexutil.Encode(tx.Hash[:]), out)
+	return nil
+}
@@ -73,6 +73,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NetworkIdFlag,
 			utils.AlphanetFlag,
 			utils.DeveloperFlag,
+			utils.DevSkipProofFlag,
 			utils.SyncModeFlag,
 			utils.SeroStatsURLFlag,
 			utils.IdentityFlag,
@@ -142,6 +143,10 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.RPCAuthFileFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCRateBurstFlag,
+			utils.RPCMethodCostsFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
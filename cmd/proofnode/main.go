@@ -40,7 +40,7 @@ func startNode(endpoint, rpcAddr string, config *proofservice.Config, timeout rp
 			Public:    true,
 		}}
 
-	_, _, err := rpc.StartHTTPEndpoint(endpoint, apis, []string{"proof"}, []string{}, []string{}, timeout)
+	_, _, err := rpc.StartHTTPEndpoint(endpoint, apis, []string{"proof"}, []string{}, []string{}, timeout, nil, nil)
 	if err != nil {
 		return err
 	}
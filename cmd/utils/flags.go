@@ -44,15 +44,19 @@ import (
 	"github.com/sero-cash/go-sero/accounts/keystore"
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/common/fdlimit"
+	"github.com/sero-cash/go-sero/common/hexutil"
 	"github.com/sero-cash/go-sero/consensus"
 
 	"github.com/sero-cash/go-sero/consensus/ethash"
 	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
 	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/state/pruner"
 	"github.com/sero-cash/go-sero/core/vm"
 	"github.com/sero-cash/go-sero/crypto"
 	"github.com/sero-cash/go-sero/dashboard"
 	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/lwp"
 	"github.com/sero-cash/go-sero/metrics"
 	"github.com/sero-cash/go-sero/metrics/influxdb"
 	"github.com/sero-cash/go-sero/node"
@@ -149,6 +153,10 @@ var (
 		Name:  "dev",
 		Usage: "Dev network: pre-configured proof-of-work in development network",
 	}
+	DevSkipProofFlag = cli.BoolFlag{
+		Name:  "dev.skipproof",
+		Usage: "Neither generate nor verify the zero knowledge proofs on the dev network (testing only)",
+	}
 	OfflineFlag = cli.BoolFlag{
 		Name:  "offline",
 		Usage: "Use gero as the offline mode",
@@ -158,6 +166,15 @@ var (
 		Usage: "Use for create chaindata snapshot",
 	}
 
+	CheckPointFilesFlag = cli.StringFlag{
+		Name:  "checkpoints",
+		Usage: "Comma separated checkpoint files checked in addition to the built-in ones",
+	}
+	CheckPointSignersFlag = cli.StringFlag{
+		Name:  "checkpoints.signers",
+		Usage: "Comma separated public keys trusted to sign the checkpoint files (required by --checkpoints)",
+	}
+
 	TestStartBlockFlag = cli.Uint64Flag{
 		Name:  "testStartBlock",
 		Usage: "test from startBlock",
@@ -201,6 +218,14 @@ var (
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	StateRetainFlag = cli.Uint64Flag{
+		Name:  "state.retain",
+		Usage: "Number of recent blocks to keep the state history of, older history is pruned (0 = keep all)",
+	}
+	AncientThresholdFlag = cli.Uint64Flag{
+		Name:  "ancient.threshold",
+		Usage: "Number of recent blocks kept in LevelDB, older blocks are moved to the ancient store (0 = no migration)",
+	}
 	DashboardAddrFlag = cli.StringFlag{
 		Name:  "dashboard.addr",
 		Usage: "Dashboard listening interface",
@@ -334,6 +359,21 @@ var (
 		Usage: "start light node",
 	}
 
+	HistoryFlag = cli.BoolFlag{
+		Name:  "history",
+		Usage: "start the history indexer of the wallet accounts",
+	}
+
+	LightWalletFlag = cli.BoolFlag{
+		Name:  "lightwallet",
+		Usage: "Serve the light wallet protocol to wallets syncing over devp2p",
+	}
+	LightPeersFlag = cli.IntFlag{
+		Name:  "lightpeers",
+		Usage: "Maximum number of light wallet peers",
+		Value: sero.DefaultConfig.LightPeers,
+	}
+
 	ConfirmedBlockFlag = cli.Uint64Flag{
 		Name:  "confirmedBlock",
 		Usage: "The balance will be confirmed after the current block of number,default is 12",
@@ -431,6 +471,22 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
 	}
+	RPCAuthFileFlag = cli.StringFlag{
+		Name:  "rpcauth",
+		Usage: "Token file authenticating the HTTP-RPC and WS-RPC clients and listing the methods each one may call",
+	}
+	RPCRateLimitFlag = cli.Float64Flag{
+		Name:  "rpcratelimit",
+		Usage: "Call cost units refilled per second for each HTTP-RPC and WS-RPC client (0 = unlimited)",
+	}
+	RPCRateBurstFlag = cli.Float64Flag{
+		Name:  "rpcrateburst",
+		Usage: "Call cost units each HTTP-RPC and WS-RPC client can spend at once (default = rate)",
+	}
+	RPCMethodCostsFlag = cli.StringFlag{
+		Name:  "rpccosts",
+		Usage: "Comma separated list of method=cost overrides of the call costs, methods accept '*' wildcards",
+	}
 	RPCReadTimeoutFlag = cli.Int64Flag{
 		Name: "rpcreadtimeout",
 		Usage: `ReadTimeout is the maximum duration for reading the entire request, including the body.
@@ -813,6 +869,33 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCAuthFileFlag.Name) {
+		cfg.RPCAuthFile = ctx.GlobalString(RPCAuthFileFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		cfg.RPCRateLimit.Rate = ctx.GlobalFloat64(RPCRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCRateBurstFlag.Name) {
+		cfg.RPCRateLimit.Burst = ctx.GlobalFloat64(RPCRateBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCMethodCostsFlag.Name) {
+		costs := make(map[string]float64)
+		for method, cost := range cfg.RPCRateLimit.Costs {
+			costs[method] = cost
+		}
+		for _, entry := range splitAndTrim(ctx.GlobalString(RPCMethodCostsFlag.Name)) {
+			parts := strings.Split(entry, "=")
+			if len(parts) != 2 {
+				Fatalf("Option %q: invalid entry %q, expected method=cost", RPCMethodCostsFlag.Name, entry)
+			}
+			cost, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				Fatalf("Option %q: invalid cost of %s: %v", RPCMethodCostsFlag.Name, parts[0], err)
+			}
+			costs[parts[0]] = cost
+		}
+		cfg.RPCRateLimit.Costs = costs
+	}
 	if ctx.GlobalIsSet(RPCReadTimeoutFlag.Name) {
 		cfg.HTTPTimeouts.ReadTimeout = time.Duration(ctx.GlobalInt64(RPCReadTimeoutFlag.Name)) * time.Second
 	}
@@ -1023,6 +1106,9 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.NoDiscovery = true
 		cfg.DiscoveryV5 = false
 		seroparam.Init_Dev(true)
+		if ctx.GlobalBool(DevSkipProofFlag.Name) {
+			zconfig.Init_SkipProof(true)
+		}
 	}
 	if ctx.GlobalBool(OfflineFlag.Name) {
 		seroparam.Init_Offline(true)
@@ -1204,6 +1290,32 @@ func checkExclusive(ctx *cli.Context, args ...interface{}) {
 	}
 }
 
+// setCheckPoints loads the checkpoint files given on the command line.
+func setCheckPoints(ctx *cli.Context) {
+	if !ctx.GlobalIsSet(CheckPointFilesFlag.Name) {
+		return
+	}
+	if !ctx.GlobalIsSet(CheckPointSignersFlag.Name) {
+		Fatalf("Option %q requires %q", CheckPointFilesFlag.Name, CheckPointSignersFlag.Name)
+	}
+	var signers []hexutil.Bytes
+	for _, s := range strings.Split(ctx.GlobalString(CheckPointSignersFlag.Name), ",") {
+		signer, err := hexutil.Decode(strings.TrimSpace(s))
+		if err != nil {
+			Fatalf("Option %q: invalid signer %s: %v", CheckPointSignersFlag.Name, s, err)
+		}
+		signers = append(signers, signer)
+	}
+	for _, path := range strings.Split(ctx.GlobalString(CheckPointFilesFlag.Name), ",") {
+		path = strings.TrimSpace(path)
+		signer, err := zconfig.LoadCheckPointFile(path, signers)
+		if err != nil {
+			Fatalf("Option %q: %v", CheckPointFilesFlag.Name, err)
+		}
+		log.Info("Loaded checkpoint file", "path", path, "signer", signer, "maxNum", zconfig.CheckPoints.MaxNum())
+	}
+}
+
 // SetSeroConfig applies sero-related command line flags to the config.
 func SetSeroConfig(ctx *cli.Context, stack *node.Node, cfg *sero.Config) {
 	// Avoid conflicting network flags
@@ -1213,6 +1325,7 @@ func SetSeroConfig(ctx *cli.Context, stack *node.Node, cfg *sero.Config) {
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
+	setCheckPoints(ctx)
 
 	cfg.Proof = initProof(ctx)
 	cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
@@ -1229,6 +1342,18 @@ func SetSeroConfig(ctx *cli.Context, stack *node.Node, cfg *sero.Config) {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	if ctx.GlobalIsSet(AncientThresholdFlag.Name) {
+		cfg.AncientThreshold = ctx.GlobalUint64(AncientThresholdFlag.Name)
+	}
+	if ctx.GlobalIsSet(StateRetainFlag.Name) {
+		cfg.StateRetain = ctx.GlobalUint64(StateRetainFlag.Name)
+		if cfg.StateRetain > 0 && cfg.NoPruning {
+			Fatalf("--%s can't be used with --%s=archive", StateRetainFlag.Name, GCModeFlag.Name)
+		}
+		if cfg.StateRetain > 0 && cfg.StateRetain < pruner.MinRetainBlocks {
+			Fatalf("--%s must be at least %d", StateRetainFlag.Name, pruner.MinRetainBlocks)
+		}
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
@@ -1270,6 +1395,17 @@ func SetSeroConfig(ctx *cli.Context, stack *node.Node, cfg *sero.Config) {
 		cfg.StartLight = true
 	}
 
+	if ctx.GlobalIsSet(HistoryFlag.Name) {
+		cfg.StartHistory = true
+	}
+
+	if ctx.GlobalIsSet(LightWalletFlag.Name) {
+		cfg.LightWallet = ctx.GlobalBool(LightWalletFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightPeersFlag.Name) {
+		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
+	}
+
 	// Override any default configs for hard coded networks.
 	switch {
 	case ctx.GlobalBool(AlphanetFlag.Name):
@@ -1342,6 +1478,9 @@ func SetDashboardConfig(ctx *cli.Context, cfg *dashboard.Config) {
 func RegisterEthService(stack *node.Node, cfg *sero.Config) {
 	err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 		fullNode, err := sero.New(ctx, cfg)
+		if fullNode != nil && cfg.LightWallet {
+			fullNode.AddLesServer(lwp.NewServer(fullNode, cfg))
+		}
 		return fullNode, err
 	})
 	if err != nil {
@@ -1352,7 +1491,9 @@ func RegisterEthService(stack *node.Node, cfg *sero.Config) {
 // RegisterDashboardService adds a dashboard to the stack.
 func RegisterDashboardService(stack *node.Node, cfg *dashboard.Config, commit string) {
 	stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-		return dashboard.New(cfg, commit, ctx.ResolvePath("logs")), nil
+		var seroServ *sero.Sero
+		ctx.Service(&seroServ)
+		return dashboard.New(cfg, commit, ctx.ResolvePath("logs"), seroServ), nil
 	})
 }
 
@@ -1394,6 +1535,12 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) serodb.Database {
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
+	// Serve the blocks already moved to the ancient store, without moving more
+	if db, ok := chainDb.(*serodb.LDBDatabase); ok {
+		if _, err := rawdb.OpenFreezer(db, 0); err != nil {
+			Fatalf("Could not open ancient database: %v", err)
+		}
+	}
 	return chainDb
 }
 
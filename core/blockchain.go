@@ -89,6 +89,7 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	RetainBlocks  uint64        // Number of recent blocks to keep the state history of (0 = keep all)
 }
 
 type Downloader interface {
@@ -143,6 +144,7 @@ type BlockChain struct {
 
 	quit    chan struct{} // blockchain quit channel
 	running int32         // running must be called atomically
+	pruning int32         // pruning is set while an online pruning runs, atomically
 	// procInterrupt must be atomically called
 	procInterrupt int32          // interrupt signaler for block processing
 	wg            sync.WaitGroup // chain processing wait group for shutting down
@@ -204,6 +206,14 @@ func NewBlockChain(db serodb.Database, cacheConfig *CacheConfig, chainConfig *pa
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	// Databases created before the registry events and the token transfers were
+	// recorded regenerate the ones of the blocks already imported
+	if rawdb.ReadRegistryEventsTail(db) == nil {
+		rawdb.WriteRegistryEventsTail(db, bc.CurrentBlock().NumberU64()+1)
+	}
+	if rawdb.ReadTokenTransfersTail(db) == nil {
+		rawdb.WriteTokenTransfersTail(db, bc.CurrentBlock().NumberU64()+1)
+	}
 	// Take ownership of this particular state
 	go bc.update()
 
@@ -329,6 +339,10 @@ func (bc *BlockChain) loadLastState() error {
 		currentHeader = currentBlock.Header()
 		bc.hc.SetCurrentHeader(currentHeader)
 		bc.currentFastBlock.Store(currentBlock)
+
+		// Persist the reset head, the ancient store migrates the blocks behind it
+		rawdb.WriteHeadBlockHash(bc.db, currentBlock.Hash())
+		rawdb.WriteHeadFastBlockHash(bc.db, currentBlock.Hash())
 	} else {
 		// Make sure the entire head block is available
 		currentBlock = bc.GetBlockByHash(head)
@@ -366,6 +380,10 @@ func (bc *BlockChain) loadLastState() error {
 			}
 		}
 	}
+	// Drop the frozen blocks beyond the head of a rewound chain
+	if err := rawdb.TruncateAncients(bc.db, currentHeader.Number.Uint64()+1); err != nil {
+		return err
+	}
 
 	// Issue a status log for the user
 	currentFastBlock := bc.CurrentFastBlock()
@@ -756,6 +774,11 @@ func (bc *BlockChain) TrieNode(hash common.Hash) ([]byte, error) {
 	return bc.stateCache.TrieDB().Node(hash)
 }
 
+// StateCache returns the caching database underpinning the blockchain instance.
+func (bc *BlockChain) StateCache() state.Database {
+	return bc.stateCache
+}
+
 // Stop stops the blockchain service. If any imports are currently in progress
 // it will abort them using the procInterrupt.
 func (bc *BlockChain) Stop() {
@@ -946,6 +969,14 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 	// Update the head fast sync block if better
 	bc.mu.Lock()
 	head := blockChain[len(blockChain)-1]
+	// The blocks are not executed, so their registry events and token transfers
+	// are not recorded
+	if tail := rawdb.ReadRegistryEventsTail(bc.db); tail == nil || *tail <= head.NumberU64() {
+		rawdb.WriteRegistryEventsTail(bc.db, head.NumberU64()+1)
+	}
+	if tail := rawdb.ReadTokenTransfersTail(bc.db); tail == nil || *tail <= head.NumberU64() {
+		rawdb.WriteTokenTransfersTail(bc.db, head.NumberU64()+1)
+	}
 	if td := bc.GetTd(head.Hash(), head.NumberU64()); td != nil { // Rewind may have occurred, skip in that case
 		currentFastBlock := bc.CurrentFastBlock()
 		if bc.GetTd(currentFastBlock.Hash(), currentFastBlock.NumberU64()).Cmp(td) < 0 {
@@ -1049,12 +1080,15 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 		bc.triegc.Push(root, -float32(block.NumberU64()))
 
 		if current := block.NumberU64(); current > triesInMemory {
+			// An online pruning sweeps the disk, no trie is flushed until it ends
+			flush := atomic.LoadInt32(&bc.pruning) == 0
+
 			// If we exceeded our memory allowance, flush matured singleton nodes to disk
 			var (
 				nodes, imgs = triedb.Size()
 				limit       = common.StorageSize(bc.cacheConfig.TrieNodeLimit) * 1024 * 1024
 			)
-			if nodes > limit || imgs > 4*1024*1024 {
+			if flush && (nodes > limit || imgs > 4*1024*1024) {
 				triedb.Cap(limit - serodb.IdealBatchSize)
 			}
 			// Find the next state trie we need to commit
@@ -1070,7 +1104,7 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 			}
 
 			// If we exceeded out time allowance, flush an entire trie to disk
-			if needCommit {
+			if flush && needCommit {
 				// If we're exceeding limits but haven't reached a large enough memory gap,
 				// warn the user that the system is becoming unstable.
 				if chosen < lastWrite+triesInMemory && bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit {
@@ -1090,10 +1124,19 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 				}
 				triedb.Dereference(root.(common.Hash))
 			}
+			if bc.cacheConfig.RetainBlocks > 0 {
+				bc.maybePrune(current)
+			}
 		}
 	}
 
 	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
+	if events := state.RegistryEvents(); len(events) > 0 {
+		rawdb.WriteRegistryEvents(batch, block.Hash(), block.NumberU64(), events)
+	}
+	if transfers := state.TokenTransfers(); len(transfers) > 0 {
+		rawdb.WriteBlockTokenTransfers(batch, block.Hash(), block.NumberU64(), transfers)
+	}
 
 	// If the total difficulty is higher than our known, add it to the canonical chain
 	// Second clause in the if statement reduces the vulnerability to selfish mining.
@@ -1213,10 +1256,12 @@ func (bc *BlockChain) insertChain(chain types.Blocks, local bool) (int, []interf
 		coalescedLogs []*types.Log
 	)
 
+	// The headers are not verified below the built-in checkpoints only, the
+	// ones of the checkpoint files check the state roots.
 	is_all_in_checkpoints := true
 	var all_chain types.Blocks
 	for _, block := range chain {
-		if block.Header().Number.Uint64() > uint64(zconfig.CheckPoints.MaxNum()) {
+		if block.Header().Number.Uint64() > zconfig.CheckPoints.TrustedNum() {
 			is_all_in_checkpoints = false
 			break
 		}
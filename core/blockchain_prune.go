@@ -0,0 +1,94 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/state/pruner"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/localdb"
+)
+
+// pruneInterval is the number of blocks the retention window moves between two
+// online prunings.
+const pruneInterval = 10000
+
+// maybePrune starts an online pruning in the background once the retention
+// window moved far enough since the last one. A failed pruning is retried only
+// after as many blocks, not on every import.
+func (bc *BlockChain) maybePrune(number uint64) {
+	retain := bc.cacheConfig.RetainBlocks
+	if number+1 < retain+pruneInterval || number+1-retain < localdb.GetPruned(bc.db)+pruneInterval {
+		return
+	}
+	if last := localdb.GetPruneAttempt(bc.db); last > 0 && number < last+pruneInterval {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&bc.pruning, 0, 1) {
+		return
+	}
+	bc.wg.Add(1)
+	go bc.prune(number)
+}
+
+// prune deletes the state history outside the retention window while blocks
+// keep being imported. The import doesn't flush any trie meanwhile, so the
+// nodes reaching the disk are exactly the ones marked, and the retained roots
+// held in memory are referenced until the sweep ends.
+func (bc *BlockChain) prune(number uint64) {
+	defer bc.wg.Done()
+	defer atomic.StoreInt32(&bc.pruning, 0)
+
+	db, ok := bc.db.(*serodb.LDBDatabase)
+	if !ok {
+		log.Warn("Online pruning needs a disk database")
+		return
+	}
+	p, err := pruner.New(db, bc.stateCache, bc.cacheConfig.RetainBlocks)
+	if err != nil {
+		log.Warn("Online pruning disabled", "err", err)
+		return
+	}
+	localdb.PutPruneAttempt(bc.db, number)
+
+	triedb := bc.stateCache.TrieDB()
+	var roots []common.Hash
+	bc.mu.Lock()
+	for n := number + 1 - bc.cacheConfig.RetainBlocks; n <= number; n++ {
+		if header := bc.GetHeaderByNumber(n); header != nil && triedb.Dirty(header.Root) {
+			triedb.Reference(header.Root, common.Hash{})
+			roots = append(roots, header.Root)
+		}
+	}
+	bc.mu.Unlock()
+	defer func() {
+		for _, root := range roots {
+			triedb.Dereference(root)
+		}
+	}()
+
+	if err := p.Mark(number); err != nil {
+		log.Warn("Failed to mark retained state", "err", err)
+		return
+	}
+	if err := p.Sweep(bc.quit); err != nil {
+		log.Warn("Failed to prune state history", "err", err)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/state/pruner"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/localdb"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+)
+
+// Tests that an online pruning keeps the states and the zstate records of every
+// block in the retention window, and drops the older ones.
+func TestPruneRetainedStates(t *testing.T) {
+	cpt.ZeroInit(cpt.NET_Alpha)
+	seroparam.Init_Dev(true)
+	zconfig.Init_SkipProof(true)
+	defer seroparam.Init_Dev(false)
+	defer zconfig.Init_SkipProof(false)
+
+	dir, err := ioutil.TempDir("", "prune")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := serodb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	alice := NewTestAccount("alice")
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  GenesisAlloc{alice.Address(): {Balance: big.NewInt(1000000)}},
+	}
+	gspec.MustCommit(db)
+	cacheConfig := &CacheConfig{Disabled: true, TrieNodeLimit: 256, TrieTimeLimit: 5 * time.Minute, RetainBlocks: pruner.MinRetainBlocks}
+	chain, err := NewBlockChain(db, cacheConfig, params.TestChainConfig, ethash.NewFullFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := GeneratePrivateChain(chain, 300, nil); err != nil {
+		t.Fatalf("failed to generate chain: %v", err)
+	}
+	head := chain.CurrentBlock().NumberU64()
+	atomic.StoreInt32(&chain.pruning, 1)
+	chain.wg.Add(1)
+	chain.prune(head)
+
+	tail := head + 1 - pruner.MinRetainBlocks
+	if pruned := localdb.GetPruned(db); pruned != tail {
+		t.Fatalf("pruned tail mismatch: have %d, want %d", pruned, tail)
+	}
+	if attempt := localdb.GetPruneAttempt(db); attempt != head {
+		t.Fatalf("prune attempt mismatch: have %d, want %d", attempt, head)
+	}
+	for number := tail; number <= head; number++ {
+		header := chain.GetHeaderByNumber(number)
+		statedb, err := state.New(chain.stateCache, header)
+		if err != nil {
+			t.Fatalf("block %d: retained state missing: %v", number, err)
+		}
+		zst := statedb.CurrentZState()
+		block := localdb.GetBlock(db, number, header.Hash().HashToUint256())
+		if block == nil {
+			t.Fatalf("block %d: zstate records missing", number)
+		}
+		for i := range block.Roots {
+			if zst.State.GetOut(&block.Roots[i]) == nil {
+				t.Fatalf("block %d: out %d missing", number, i)
+			}
+			if !zst.State.FindAnchorInSzk(&block.Roots[i]) {
+				t.Fatalf("block %d: out %d not in the state", number, i)
+			}
+		}
+	}
+	if _, err := state.New(chain.stateCache, chain.GetHeaderByNumber(0)); err != nil {
+		t.Fatalf("genesis state missing: %v", err)
+	}
+	for number := uint64(1); number < tail; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if localdb.GetBlock(db, number, header.Hash().HashToUint256()) != nil {
+			t.Fatalf("block %d: zstate records not pruned", number)
+		}
+		if _, err := chain.stateCache.OpenTrie(header.Root); err == nil {
+			t.Fatalf("block %d: state not pruned", number)
+		}
+	}
+}
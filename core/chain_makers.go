@@ -36,12 +36,14 @@ type BlockGen struct {
 	parent      *types.Block
 	chain       []*types.Block
 	chainReader consensus.ChainReader
+	bc          *BlockChain // Chain the blocks are inserted into, see GeneratePrivateChain
 	header      *types.Header
 	statedb     *state.StateDB
 
-	gasPool  *GasPool
-	txs      []*types.Transaction
-	receipts []*types.Receipt
+	gasPool   *GasPool
+	txs       []*types.Transaction
+	receipts  []*types.Receipt
+	gasReward *big.Int
 
 	config *params.ChainConfig
 	engine consensus.Engine
@@ -90,10 +92,11 @@ func (b *BlockGen) AddTxWithChain(bc *BlockChain, tx *types.Transaction) {
 		b.SetCoinbase(common.Address{})
 	}
 	b.statedb.Prepare(tx.Hash(), common.Hash{}, len(b.txs))
-	receipt, _, err := ApplyTransaction(b.config, bc, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, &b.header.GasUsed, vm.Config{})
+	receipt, gas, err := ApplyTransaction(b.config, bc, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, &b.header.GasUsed, vm.Config{})
 	if err != nil {
 		panic(err)
 	}
+	b.gasReward.Add(b.gasReward, new(big.Int).Mul(new(big.Int).SetUint64(gas), tx.GasPrice()))
 	b.txs = append(b.txs, tx)
 	b.receipts = append(b.receipts, receipt)
 }
@@ -159,7 +162,7 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		blockchain, _ := NewBlockChain(db, nil, config, engine, vm.Config{}, nil)
 		defer blockchain.Stop()
 
-		b := &BlockGen{i: i, parent: parent, chain: blocks, chainReader: blockchain, statedb: statedb, config: config, engine: engine}
+		b := &BlockGen{i: i, parent: parent, chain: blocks, chainReader: blockchain, statedb: statedb, gasReward: new(big.Int), config: config, engine: engine}
 		b.header = makeHeader(b.chainReader, parent, statedb, b.engine)
 
 		// Mutate the state and block according to any hard-fork specs
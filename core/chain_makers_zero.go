@@ -0,0 +1,452 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-czero-import/superzk"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/zero/localdb"
+	"github.com/sero-cash/go-sero/zero/stake"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txs/zstate"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/txtool/flight"
+	"github.com/sero-cash/go-sero/zero/txtool/prepare"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// TestAccount is a private account derived from a seed string. It follows the
+// chain it is used on to know its unspent outputs, so that the generators can
+// send zero knowledge transactions from it.
+type TestAccount struct {
+	Sk  c_type.Uint512
+	Tk  c_type.Tk
+	Pk  c_type.Uint512
+	PKr c_type.PKr
+
+	outs   map[c_type.Uint256]prepare.Utxo // Unspent outputs by root
+	synced uint64                          // Next block number to scan
+}
+
+// NewTestAccount derives the keys of a test account from the given seed, the
+// same seed always giving the same account.
+func NewTestAccount(seed string) *TestAccount {
+	var s, rnd c_type.Uint256
+	copy(s[:], crypto.Keccak256([]byte(seed)))
+	copy(rnd[:], crypto.Keccak256(s[:]))
+
+	account := &TestAccount{outs: make(map[c_type.Uint256]prepare.Utxo)}
+	account.Sk = superzk.Seed2Sk(&s, 2)
+	account.Tk, _ = superzk.Sk2Tk(&account.Sk)
+	account.Pk, _ = superzk.Tk2Pk(&account.Tk)
+	account.PKr = superzk.Pk2PKr(&account.Pk, &rnd)
+	return account
+}
+
+// Address returns the address of the account, to be used in a genesis alloc
+// or as the receiver of a transfer.
+func (a *TestAccount) Address() common.Address {
+	return common.BytesToAddress(a.PKr[:])
+}
+
+// Balance returns the unspent amount of the given currency the account had
+// when it was last synced.
+func (a *TestAccount) Balance(currency string) *big.Int {
+	balance := new(big.Int)
+	id := utils.CurrencyToUint256(currency)
+	for _, utxo := range a.outs {
+		if utxo.Asset.Tkn != nil && utxo.Asset.Tkn.Currency == id {
+			balance.Add(balance, utxo.Asset.Tkn.Value.ToIntRef())
+		}
+	}
+	return balance
+}
+
+// Sync scans the canonical blocks inserted since the last call for the
+// outputs of the account.
+func (a *TestAccount) Sync(bc *BlockChain) {
+	current := bc.CurrentBlock().NumberU64()
+	for ; a.synced <= current; a.synced++ {
+		header := bc.GetHeaderByNumber(a.synced)
+		if header == nil {
+			continue
+		}
+		block := localdb.GetBlock(bc.db, a.synced, header.Hash().HashToUint256())
+		if block == nil {
+			continue
+		}
+		for _, root := range block.Roots {
+			rs := localdb.GetRoot(bc.db, &root)
+			if rs == nil {
+				continue
+			}
+			if pkr := rs.OS.ToPKr(); pkr == nil || !superzk.IsMyPKr(&a.Tk, pkr) {
+				continue
+			}
+			douts := flight.DecOut(&a.Tk, []txtool.Out{{Root: root, State: *rs}})
+			if len(douts) > 0 && douts[0].Asset.HasAsset() {
+				a.outs[root] = prepare.Utxo{Root: root, Asset: douts[0].Asset}
+			}
+		}
+	}
+}
+
+// roots returns the roots of the unspent outputs in a stable order.
+func (a *TestAccount) roots() []c_type.Uint256 {
+	roots := make([]c_type.Uint256, 0, len(a.outs))
+	for root := range a.outs {
+		roots = append(roots, root)
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		return bytes.Compare(roots[i][:], roots[j][:]) < 0
+	})
+	return roots
+}
+
+// testTxGen implements prepare.TxParamGenerator on the outputs of a test account.
+type testTxGen struct {
+	account *TestAccount
+}
+
+func (self *testTxGen) FindRoots(pk *c_type.Uint512, currency string, amount *big.Int) (utxos prepare.Utxos, remain big.Int) {
+	remain.Set(amount)
+	id := utils.CurrencyToUint256(currency)
+	for _, root := range self.account.roots() {
+		if remain.Sign() <= 0 {
+			break
+		}
+		utxo := self.account.outs[root]
+		if utxo.Asset.Tkn != nil && utxo.Asset.Tkn.Currency == id {
+			utxos = append(utxos, utxo)
+			remain.Sub(&remain, utxo.Asset.Tkn.Value.ToIntRef())
+		}
+	}
+	return
+}
+
+func (self *testTxGen) FindRootsByTicket(pk *c_type.Uint512, tickets []assets.Ticket) (utxos prepare.Utxos, remain map[c_type.Uint256]c_type.Uint256) {
+	remain = make(map[c_type.Uint256]c_type.Uint256)
+	for _, ticket := range tickets {
+		remain[ticket.Value] = ticket.Category
+	}
+	for _, root := range self.account.roots() {
+		utxo := self.account.outs[root]
+		if utxo.Asset.Tkt == nil {
+			continue
+		}
+		if category, ok := remain[utxo.Asset.Tkt.Value]; ok && category == utxo.Asset.Tkt.Category {
+			utxos = append(utxos, utxo)
+			delete(remain, utxo.Asset.Tkt.Value)
+		}
+	}
+	return
+}
+
+func (self *testTxGen) GetRoot(root *c_type.Uint256) *prepare.Utxo {
+	if utxo, ok := self.account.outs[*root]; ok {
+		return &utxo
+	}
+	return nil
+}
+
+func (self *testTxGen) DefaultRefundTo(pk *c_type.Uint512) *c_type.PKr {
+	return &self.account.PKr
+}
+
+// testTxState implements prepare.TxParamState on the state of the parent of
+// the generated block.
+type testTxState struct {
+	bc      *BlockChain
+	statedb *state.StateDB
+	zstate  *zstate.ZState
+}
+
+func (self *testTxState) GetAnchor(roots []c_type.Uint256) (wits []txtool.Witness, e error) {
+	for _, root := range roots {
+		out := self.GetOut(&root)
+		if out == nil {
+			return nil, fmt.Errorf("can not find the out of root %v", hexutil.Encode(root[:]))
+		}
+		wit := txtool.Witness{}
+		var pos uint64
+		if out.OS.IsSzk() {
+			pos, wit.Paths, wit.Anchor = self.zstate.State.SzkTree.GetPaths(*out.OS.RootCM)
+		} else {
+			pos, wit.Paths, wit.Anchor = self.zstate.State.CzeroTree.GetPaths(*out.OS.RootCM)
+		}
+		wit.Pos = hexutil.Uint64(pos)
+		wits = append(wits, wit)
+	}
+	return
+}
+
+func (self *testTxState) GetOut(root *c_type.Uint256) *localdb.RootState {
+	return localdb.GetRoot(self.bc.db, root)
+}
+
+func (self *testTxState) GetPkgById(id *c_type.Uint256) *localdb.ZPkg {
+	return self.zstate.Pkgs.GetPkgById(id)
+}
+
+func (self *testTxState) GetSeroGasLimit(to *common.Address, tfee *assets.Token, gasPrice *big.Int) (uint64, error) {
+	return self.statedb.GetSeroGasLimit(to, tfee, gasPrice)
+}
+
+// testGasPrice and testGas are the price and the gas limit of the private
+// transactions added by BlockGen, testContractGas the gas limit of the ones
+// running a contract.
+var (
+	testGasPrice    = big.NewInt(params.Gta)
+	testGas         = uint64(25000)
+	testContractGas = uint64(1000000)
+)
+
+// AddPrivateTx creates, signs and proves a zero knowledge transaction of the
+// given account and adds it to the generated block. The inputs are taken from
+// the outputs of the account in the blocks before the generated one.
+//
+// AddPrivateTx can only be used with the generators of GeneratePrivateChain
+// and panics if the transaction can not be created or executed.
+func (b *BlockGen) AddPrivateTx(from *TestAccount, receptions []prepare.Reception, cmds prepare.Cmds) *types.Transaction {
+	return b.addPrivateTx(from, receptions, cmds, testGas)
+}
+
+func (b *BlockGen) addPrivateTx(from *TestAccount, receptions []prepare.Reception, cmds prepare.Cmds, gas uint64) *types.Transaction {
+	if b.bc == nil {
+		panic("private transactions need a chain generated by GeneratePrivateChain")
+	}
+	from.Sync(b.bc)
+
+	statedb, err := state.New(b.bc.stateCache, b.parent.Header())
+	if err != nil {
+		panic(err)
+	}
+	param := prepare.PreTxParam{
+		From:       from.Pk,
+		RefundTo:   &from.PKr,
+		Receptions: receptions,
+		Cmds:       cmds,
+		Fee: assets.Token{
+			Currency: utils.CurrencyToUint256("SERO"),
+			Value:    utils.U256(*new(big.Int).Mul(new(big.Int).SetUint64(gas), testGasPrice)),
+		},
+		GasPrice: testGasPrice,
+	}
+	txState := &testTxState{bc: b.bc, statedb: statedb, zstate: statedb.CurrentZState()}
+	txParam, err := prepare.GenTxParam(&param, &testTxGen{from}, txState)
+	if err != nil {
+		panic(err)
+	}
+	num := b.parent.NumberU64()
+	isExt := num >= seroparam.SIP7()
+	txParam.Num, txParam.IsExt = &num, &isExt
+
+	tx, signed, _, _, err := flight.SignLight(&from.Sk, txParam)
+	if err != nil {
+		panic(err)
+	}
+	gtx, err := flight.ProveTx1(&tx, &signed)
+	if err != nil {
+		panic(err)
+	}
+	stxn := types.NewTxWithGTx(uint64(gtx.Gas), gtx.GasPrice.ToInt(), &gtx.Tx)
+	b.AddTxWithChain(b.bc, stxn)
+
+	for _, in := range txParam.Ins {
+		delete(from.outs, in.Out.Root)
+	}
+	return stxn
+}
+
+// Transfer sends an amount of the given currency from one test account to
+// the given address.
+func (b *BlockGen) Transfer(from *TestAccount, to common.Address, currency string, amount *big.Int) *types.Transaction {
+	reception := prepare.Reception{
+		Addr: *to.ToPKr(),
+		Asset: assets.Asset{
+			Tkn: &assets.Token{
+				Currency: utils.CurrencyToUint256(currency),
+				Value:    utils.U256(*amount),
+			},
+		},
+	}
+	return b.AddPrivateTx(from, []prepare.Reception{reception}, prepare.Cmds{})
+}
+
+// CallContract calls the contract at the given address with an asset of the
+// account, or creates one running data as its code if to is nil. The data is
+// sent after an empty table of addresses.
+func (b *BlockGen) CallContract(from *TestAccount, to *common.Address, asset assets.Asset, data []byte) *types.Transaction {
+	cmd := &stx.ContractCmd{
+		Asset: asset,
+		Data:  append(make([]byte, 18), data...),
+	}
+	if to != nil {
+		cmd.To = to.ToPKr()
+	}
+	return b.addPrivateTx(from, nil, prepare.Cmds{Contract: cmd}, testContractGas)
+}
+
+// BuyShare buys stake shares for the given amount of SERO, voting with the
+// given account, optionally through a stake pool.
+func (b *BlockGen) BuyShare(from *TestAccount, amount *big.Int, vote *TestAccount, pool *common.Hash) *types.Transaction {
+	cmd := &stx.BuyShareCmd{
+		Value: utils.U256(*amount),
+		Vote:  vote.PKr,
+	}
+	if pool != nil {
+		cmd.Pool = pool.HashToUint256()
+	}
+	return b.AddPrivateTx(from, nil, prepare.Cmds{BuyShare: cmd})
+}
+
+// RegistPool registers a stake pool of the account, voting with the given one.
+func (b *BlockGen) RegistPool(from *TestAccount, amount *big.Int, vote *TestAccount, feeRate uint32) *types.Transaction {
+	cmd := &stx.RegistPoolCmd{
+		Value:   utils.U256(*amount),
+		Vote:    vote.PKr,
+		FeeRate: feeRate,
+	}
+	return b.AddPrivateTx(from, nil, prepare.Cmds{RegistPool: cmd})
+}
+
+// CreatePkg packs an asset of the account into a package owned by the given
+// address.
+func (b *BlockGen) CreatePkg(from *TestAccount, id c_type.Uint256, to common.Address, asset assets.Asset) *types.Transaction {
+	cmd := &prepare.PkgCreateCmd{
+		Id:    id,
+		PKr:   *to.ToPKr(),
+		Asset: asset,
+	}
+	return b.AddPrivateTx(from, nil, prepare.Cmds{PkgCreate: cmd})
+}
+
+// TransferPkg transfers a package of the account to the given address.
+func (b *BlockGen) TransferPkg(from *TestAccount, id c_type.Uint256, to common.Address) *types.Transaction {
+	cmd := &prepare.PkgTransferCmd{
+		Id:  id,
+		PKr: *to.ToPKr(),
+	}
+	return b.AddPrivateTx(from, nil, prepare.Cmds{PkgTransfer: cmd})
+}
+
+// Vote signs the votes of the given accounts for the shares selected for the
+// generated block and includes them in its header. It returns the number of
+// votes included.
+func (b *BlockGen) Vote(voters ...*TestAccount) int {
+	if b.bc == nil {
+		panic("votes need a chain generated by GeneratePrivateChain")
+	}
+	statedb, err := state.New(b.bc.stateCache, b.parent.Header())
+	if err != nil {
+		panic(err)
+	}
+	stakeState := stake.NewStakeState(statedb)
+	if err := stakeState.ProcessBeforeApply(b.bc, b.header); err != nil {
+		panic(err)
+	}
+	if stakeState.ShareSize() == 0 {
+		return 0
+	}
+	posHash, parentPos := b.header.HashPos(), b.parent.HashPos()
+	_, shares, err := stakeState.SeleteShare(posHash)
+	if err != nil {
+		panic(err)
+	}
+	sign := func(share *stake.Share, pkr *c_type.PKr, isPool bool) {
+		for _, voter := range voters {
+			if len(b.header.CurrentVotes) >= 3 || !superzk.IsMyPKr(&voter.Tk, pkr) {
+				continue
+			}
+			stakeHash := types.StakeHash(&posHash, &parentPos, isPool)
+			signature, err := superzk.SignPKr_ByHeight(b.header.Number.Uint64(), &voter.Sk, stakeHash.HashToUint256(), pkr)
+			if err != nil {
+				panic(err)
+			}
+			b.header.CurrentVotes = append(b.header.CurrentVotes, types.HeaderVote{
+				Id:     common.BytesToHash(share.Id()),
+				IsPool: isPool,
+				Sign:   signature,
+			})
+			return
+		}
+	}
+	for _, share := range shares {
+		if share.PoolId != nil {
+			if pool := stakeState.GetStakePool(*share.PoolId); pool != nil {
+				sign(share, &pool.VotePKr, true)
+				continue
+			}
+		}
+		sign(share, &share.VotePKr, false)
+	}
+	return len(b.header.CurrentVotes)
+}
+
+// GeneratePrivateChain creates n blocks on top of the current head of bc and
+// inserts them one by one, so that the generator of a block can spend the
+// outputs of the previous ones with AddPrivateTx. Like GenerateChain, it
+// requires bc to use a non-validating proof of work implementation, and on
+// networks where the proofs are checked, either real zero knowledge proofs or
+// the dev skip proof switch of zconfig.
+func GeneratePrivateChain(bc *BlockChain, n int, gen func(int, *BlockGen)) ([]*types.Block, error) {
+	blocks := make([]*types.Block, 0, n)
+	for i := 0; i < n; i++ {
+		parent := bc.CurrentBlock()
+		statedb, err := state.New(bc.stateCache, parent.Header())
+		if err != nil {
+			return blocks, err
+		}
+		b := &BlockGen{i: i, parent: parent, chain: blocks, chainReader: bc, bc: bc, statedb: statedb, gasReward: new(big.Int), config: bc.chainConfig, engine: bc.engine}
+		b.header = makeHeader(bc, parent, statedb, bc.engine)
+
+		if b.header.Number.Uint64() >= seroparam.SIP4() {
+			if err := stake.NewStakeState(statedb).ProcessBeforeApply(bc, b.header); err != nil {
+				return blocks, err
+			}
+		}
+		if gen != nil {
+			gen(i, b)
+		}
+		block, err := bc.engine.Finalize(bc, b.header, statedb, b.txs, b.receipts, b.gasReward.Uint64())
+		if err != nil {
+			return blocks, err
+		}
+		if block == nil {
+			return blocks, errors.New("engine finalized no block")
+		}
+		if _, err := bc.InsertChain(types.Blocks{block}); err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/stake"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txtool/prepare"
+	"github.com/sero-cash/go-sero/zero/utils"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+)
+
+// Tests that the private chain generator inserts its blocks into the chain, and
+// that the transactions of its helpers move the assets, shares and packages.
+func TestGeneratePrivateChain(t *testing.T) {
+	cpt.ZeroInit(cpt.NET_Alpha)
+	seroparam.Init_Dev(true)
+	zconfig.Init_SkipProof(true)
+	defer seroparam.Init_Dev(false)
+	defer zconfig.Init_SkipProof(false)
+
+	if a, b := NewTestAccount("alice"), NewTestAccount("alice"); a.PKr != b.PKr {
+		t.Fatalf("test account not deterministic: %x != %x", a.PKr, b.PKr)
+	}
+	sero := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	amount := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), sero) }
+
+	var (
+		alice = NewTestAccount("alice")
+		bob   = NewTestAccount("bob")
+		carol = NewTestAccount("carol")
+		db    = serodb.NewMemDatabase()
+		gspec = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{alice.Address(): {Balance: amount(1000000)}},
+		}
+		pkgId = c_type.Uint256{1}
+	)
+	gspec.MustCommit(db)
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFullFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var shareTx, poolTx *types.Transaction
+	blocks, err := GeneratePrivateChain(chain, 5, func(i int, b *BlockGen) {
+		switch i {
+		case 0:
+			if votes := b.Vote(alice); votes != 0 {
+				t.Errorf("block %d: votes without shares: %d", i, votes)
+			}
+			b.Transfer(alice, bob.Address(), "SERO", amount(1000))
+		case 1:
+			reception := prepare.Reception{
+				Addr:  carol.PKr,
+				Asset: assets.Asset{Tkn: &assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.U256(*amount(1))}},
+			}
+			b.AddPrivateTx(bob, []prepare.Reception{reception}, prepare.Cmds{})
+			shareTx = b.BuyShare(alice, amount(100), alice, nil)
+		case 2:
+			poolTx = b.RegistPool(alice, stake.GetPoolValueThreshold(), alice, seroparam.LOWEST_STAKING_NODE_FEE_RATE)
+		case 3:
+			b.CreatePkg(alice, pkgId, alice.Address(), assets.Asset{Tkn: &assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.U256(*amount(10))}})
+		case 4:
+			b.TransferPkg(alice, pkgId, bob.Address())
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to generate chain: %v", err)
+	}
+	if len(blocks) != 5 {
+		t.Fatalf("generated block count mismatch: have %d, want 5", len(blocks))
+	}
+	if head := chain.CurrentBlock(); head.Hash() != blocks[4].Hash() {
+		t.Fatalf("head mismatch: have %d, want %d", head.NumberU64(), blocks[4].NumberU64())
+	}
+	// The proofs are skipped on the dev network
+	proved := 0
+	for i, block := range blocks {
+		if len(block.Transactions()) == 0 {
+			t.Errorf("block %d: no transactions", i)
+		}
+		for _, tx := range block.Transactions() {
+			stx := tx.GetZZSTX()
+			proved += len(stx.Tx1.Ins_C) + len(stx.Tx1.Outs_C)
+			for _, in := range stx.Tx1.Ins_C {
+				if in.Proof != (c_type.Proof{}) {
+					t.Errorf("block %d: tx %x has an input proof", i, tx.Hash())
+				}
+			}
+			for _, out := range stx.Tx1.Outs_C {
+				if out.Proof != (c_type.Proof{}) {
+					t.Errorf("block %d: tx %x has an output proof", i, tx.Hash())
+				}
+			}
+			if create := stx.Desc_Pkg.Create; create != nil && create.Proof != (c_type.Proof{}) {
+				t.Errorf("block %d: tx %x has a package proof", i, tx.Hash())
+			}
+		}
+	}
+	if proved == 0 {
+		t.Error("no confidential ins or outs generated")
+	}
+
+	// Bob received 1000 SERO and paid 1 SERO and the fee to carol
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(testGas), testGasPrice)
+	bob.Sync(chain)
+	carol.Sync(chain)
+	if have, want := bob.Balance("SERO"), new(big.Int).Sub(amount(999), fee); have.Cmp(want) != 0 {
+		t.Errorf("bob balance mismatch: have %v, want %v", have, want)
+	}
+	if have, want := carol.Balance("SERO"), amount(1); have.Cmp(want) != 0 {
+		t.Errorf("carol balance mismatch: have %v, want %v", have, want)
+	}
+
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to open head state: %v", err)
+	}
+	stakeState := stake.NewStakeState(statedb)
+	receipt := func(tx *types.Transaction, number int) *types.Receipt {
+		for _, r := range chain.GetReceiptsByHash(blocks[number].Hash()) {
+			if r.TxHash == tx.Hash() {
+				return r
+			}
+		}
+		t.Fatalf("block %d: missing receipt of %x", number, tx.Hash())
+		return nil
+	}
+	if r := receipt(shareTx, 1); r.ShareId == nil {
+		t.Error("share not bought")
+	} else if share := stakeState.GetShare(*r.ShareId); share == nil || share.InitNum == 0 || share.VotePKr != alice.PKr {
+		t.Errorf("share mismatch: %+v", share)
+	}
+	if r := receipt(poolTx, 2); r.PoolId == nil {
+		t.Error("pool not registered")
+	} else if pool := stakeState.GetStakePool(*r.PoolId); pool == nil || pool.VotePKr != alice.PKr || pool.Amount.Cmp(stake.GetPoolValueThreshold()) != 0 {
+		t.Errorf("pool mismatch: %+v", pool)
+	}
+	if pkg := statedb.CurrentZState().Pkgs.GetPkgById(&pkgId); pkg == nil || pkg.Pack.PKr != bob.PKr {
+		t.Errorf("package not transferred to bob: %+v", pkg)
+	}
+}
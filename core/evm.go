@@ -117,6 +117,13 @@ func Transfer(db vm.StateDB, sender, recipient common.Address, asset *assets.Ass
 			if amount.Sign() > 0 {
 				currency := strings.Trim(string(asset.Tkn.Currency[:]), string([]byte{0}))
 				db.SubBalance(sender, currency, &amount)
+				db.AddTokenTransfer(&types.TokenTransfer{
+					Kind:     types.TransferSend,
+					Contract: sender,
+					Currency: currency,
+					Amount:   new(big.Int).Set(&amount),
+					Peer:     recipient,
+				})
 			}
 		}
 		if asset.Tkt != nil {
@@ -131,6 +138,13 @@ func Transfer(db vm.StateDB, sender, recipient common.Address, asset *assets.Ass
 			if amount.Sign() > 0 {
 				currency := strings.Trim(string(asset.Tkn.Currency[:]), string([]byte{0}))
 				db.AddBalance(recipient, currency, &amount)
+				db.AddTokenTransfer(&types.TokenTransfer{
+					Kind:     types.TransferReceive,
+					Contract: recipient,
+					Currency: currency,
+					Amount:   new(big.Int).Set(&amount),
+					Peer:     sender,
+				})
 			}
 		}
 		if asset.Tkt != nil {
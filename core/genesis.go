@@ -287,6 +287,7 @@ func (g *Genesis) ToBlock(db serodb.Database) *types.Block {
 	blockhash := block.Hash()
 	statedb.GetStakeCons().Record(block.Header(), db)
 	statedb.NextZState().RecordBlock(db, blockhash.HashToUint256())
+	rawdb.WriteRegistryEvents(db, blockhash, block.NumberU64(), statedb.RegistryEvents())
 
 	return block
 }
@@ -304,6 +305,8 @@ func (g *Genesis) Commit(db serodb.Database) (*types.Block, error) {
 	rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
 	rawdb.WriteHeadBlockHash(db, block.Hash())
 	rawdb.WriteHeadHeaderHash(db, block.Hash())
+	rawdb.WriteRegistryEventsTail(db, 0)
+	rawdb.WriteTokenTransfersTail(db, 0)
 
 	config := g.Config
 	if config == nil {
@@ -126,6 +126,24 @@ func WriteFastTrieProgress(db DatabaseWriter, count uint64) {
 	}
 }
 
+// ReadZeroSyncProgress retrieves the number of the next block whose zero state
+// records are to be fast synced, zero if none was synced yet.
+func ReadZeroSyncProgress(db DatabaseReader) uint64 {
+	data, _ := db.Get(zeroSyncProgressKey)
+	if len(data) == 0 {
+		return 0
+	}
+	return new(big.Int).SetBytes(data).Uint64()
+}
+
+// WriteZeroSyncProgress stores the number of the next block whose zero state
+// records are to be fast synced, to resume from it across restarts.
+func WriteZeroSyncProgress(db DatabaseWriter, number uint64) {
+	if err := db.Put(zeroSyncProgressKey, new(big.Int).SetUint64(number).Bytes()); err != nil {
+		log.Crit("Failed to store zero state sync progress", "err", err)
+	}
+}
+
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(number, hash))
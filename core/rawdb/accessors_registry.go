@@ -0,0 +1,138 @@
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+)
+
+// ReadRegistryEvents retrieves the token registry events raised in a block.
+func ReadRegistryEvents(db DatabaseReader, hash common.Hash, number uint64) []*types.RegistryEvent {
+	data, _ := db.Get(blockRegistryKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	var events []*types.RegistryEvent
+	if err := rlp.DecodeBytes(data, &events); err != nil {
+		log.Error("Invalid registry events RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return events
+}
+
+// WriteRegistryEvents stores the token registry events raised in a block.
+func WriteRegistryEvents(db DatabaseWriter, hash common.Hash, number uint64, events []*types.RegistryEvent) {
+	data, err := rlp.EncodeToBytes(events)
+	if err != nil {
+		log.Crit("Failed to encode registry events", "err", err)
+	}
+	if err := db.Put(blockRegistryKey(number, hash), data); err != nil {
+		log.Crit("Failed to store registry events", "err", err)
+	}
+}
+
+// DeleteRegistryEvents removes the token registry events of a block.
+func DeleteRegistryEvents(db DatabaseDeleter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockRegistryKey(number, hash)); err != nil {
+		log.Crit("Failed to delete registry events", "err", err)
+	}
+}
+
+// ReadRegistryEventsTail retrieves the first block whose registry events were
+// recorded on import.
+func ReadRegistryEventsTail(db DatabaseReader) *uint64 {
+	data, _ := db.Get(registryEventsTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteRegistryEventsTail stores the first block whose registry events were
+// recorded on import.
+func WriteRegistryEventsTail(db DatabaseWriter, number uint64) {
+	if err := db.Put(registryEventsTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store registry events tail", "err", err)
+	}
+}
+
+// ReadTokenRegistration retrieves the indexed registration of a currency or a
+// ticket category.
+func ReadTokenRegistration(db DatabaseReader, kind uint8, name string) *types.TokenRegistration {
+	data, _ := db.Get(tokenRegistryKey(kind, name))
+	if len(data) == 0 {
+		return nil
+	}
+	registration := new(types.TokenRegistration)
+	if err := rlp.DecodeBytes(data, registration); err != nil {
+		log.Error("Invalid token registration RLP", "name", name, "err", err)
+		return nil
+	}
+	return registration
+}
+
+// WriteTokenRegistration stores the registration of a currency or a ticket category.
+func WriteTokenRegistration(db DatabaseWriter, registration *types.TokenRegistration) {
+	data, err := rlp.EncodeToBytes(registration)
+	if err != nil {
+		log.Crit("Failed to encode token registration", "err", err)
+	}
+	if err := db.Put(tokenRegistryKey(registration.Kind, registration.Name), data); err != nil {
+		log.Crit("Failed to store token registration", "err", err)
+	}
+}
+
+// DeleteTokenRegistration removes the registration of a currency or a ticket category.
+func DeleteTokenRegistration(db DatabaseDeleter, kind uint8, name string) {
+	if err := db.Delete(tokenRegistryKey(kind, name)); err != nil {
+		log.Crit("Failed to delete token registration", "err", err)
+	}
+}
+
+// ReadTokenRegistryNames retrieves the names of all indexed registrations of a kind,
+// in registration order.
+func ReadTokenRegistryNames(db DatabaseReader, kind uint8) []string {
+	data, _ := db.Get(tokenRegistryKey(kind, ""))
+	if len(data) == 0 {
+		return nil
+	}
+	var names []string
+	if err := rlp.DecodeBytes(data, &names); err != nil {
+		log.Error("Invalid token registry names RLP", "kind", kind, "err", err)
+		return nil
+	}
+	return names
+}
+
+// WriteTokenRegistryNames stores the names of all indexed registrations of a kind.
+func WriteTokenRegistryNames(db DatabaseWriter, kind uint8, names []string) {
+	data, err := rlp.EncodeToBytes(names)
+	if err != nil {
+		log.Crit("Failed to encode token registry names", "err", err)
+	}
+	if err := db.Put(tokenRegistryKey(kind, ""), data); err != nil {
+		log.Crit("Failed to store token registry names", "err", err)
+	}
+}
+
+// ReadTokenRegistrySections retrieves the number of sections committed by the
+// token registry indexer.
+func ReadTokenRegistrySections(db DatabaseReader) uint64 {
+	data, _ := db.Get(tokenRegistrySectionsKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteTokenRegistrySections stores the number of sections committed by the
+// token registry indexer.
+func WriteTokenRegistrySections(db DatabaseWriter, sections uint64) {
+	if err := db.Put(tokenRegistrySectionsKey, encodeBlockNumber(sections)); err != nil {
+		log.Crit("Failed to store token registry sections", "err", err)
+	}
+}
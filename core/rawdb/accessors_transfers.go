@@ -0,0 +1,110 @@
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+// prefixIteratee is a database able to iterate over the keys with a prefix.
+type prefixIteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// ReadBlockTokenTransfers retrieves the token transfers done by contracts in a block.
+func ReadBlockTokenTransfers(db DatabaseReader, hash common.Hash, number uint64) []*types.TokenTransfer {
+	data, _ := db.Get(blockTransfersKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	var transfers []*types.TokenTransfer
+	if err := rlp.DecodeBytes(data, &transfers); err != nil {
+		log.Error("Invalid token transfers RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return transfers
+}
+
+// WriteBlockTokenTransfers stores the token transfers done by contracts in a block.
+func WriteBlockTokenTransfers(db DatabaseWriter, hash common.Hash, number uint64, transfers []*types.TokenTransfer) {
+	data, err := rlp.EncodeToBytes(transfers)
+	if err != nil {
+		log.Crit("Failed to encode token transfers", "err", err)
+	}
+	if err := db.Put(blockTransfersKey(number, hash), data); err != nil {
+		log.Crit("Failed to store token transfers", "err", err)
+	}
+}
+
+// DeleteBlockTokenTransfers removes the token transfers of a block.
+func DeleteBlockTokenTransfers(db DatabaseDeleter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockTransfersKey(number, hash)); err != nil {
+		log.Crit("Failed to delete token transfers", "err", err)
+	}
+}
+
+// ReadTokenTransfersTail retrieves the first block whose token transfers were
+// recorded on import.
+func ReadTokenTransfersTail(db DatabaseReader) *uint64 {
+	data, _ := db.Get(tokenTransfersTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteTokenTransfersTail stores the first block whose token transfers were
+// recorded on import.
+func WriteTokenTransfersTail(db DatabaseWriter, number uint64) {
+	if err := db.Put(tokenTransfersTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store token transfers tail", "err", err)
+	}
+}
+
+// WriteTokenTransfer stores an indexed token transfer at its position in the
+// transfers of its block.
+func WriteTokenTransfer(db DatabaseWriter, transfer *types.TokenTransfer, index uint32) {
+	data, err := rlp.EncodeToBytes(transfer)
+	if err != nil {
+		log.Crit("Failed to encode token transfer", "err", err)
+	}
+	if err := db.Put(tokenTransferKey(transfer.Contract, transfer.Currency, transfer.Number, index), data); err != nil {
+		log.Crit("Failed to store token transfer", "err", err)
+	}
+}
+
+// ReadTokenTransfers retrieves the indexed token transfers of a currency on the
+// balance of a contract, from the block begin to the block end excluded, in
+// the order of execution.
+func ReadTokenTransfers(db DatabaseReader, contract common.Address, currency string, begin, end uint64) []*types.TokenTransfer {
+	iteratee, ok := db.(prefixIteratee)
+	if !ok {
+		return nil
+	}
+	prefix := tokenTransferKey(contract, currency, 0, 0)
+	prefix = prefix[:len(prefix)-12]
+
+	it := iteratee.NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	var transfers []*types.TokenTransfer
+	for ok := it.Seek(tokenTransferKey(contract, currency, begin, 0)); ok; ok = it.Next() {
+		number := binary.BigEndian.Uint64(it.Key()[len(prefix):])
+		if number >= end {
+			break
+		}
+		transfer := new(types.TokenTransfer)
+		if err := rlp.DecodeBytes(it.Value(), transfer); err != nil {
+			log.Error("Invalid token transfer RLP", "contract", contract, "currency", currency, "number", number, "err", err)
+			continue
+		}
+		transfer.Number = number
+		transfers = append(transfers, transfer)
+	}
+	return transfers
+}
@@ -0,0 +1,67 @@
+package rawdb
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// Tests that the indexed token transfers are returned by contract and currency,
+// in block and execution order, within the block range.
+func TestTokenTransferIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transfers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := serodb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	contract := common.BytesToAddress([]byte{0x01})
+	other := common.BytesToAddress([]byte{0x02})
+	transfer := func(contract common.Address, currency string, number, amount uint64) *types.TokenTransfer {
+		return &types.TokenTransfer{
+			Kind:     types.TransferSend,
+			Contract: contract,
+			Currency: currency,
+			Amount:   new(big.Int).SetUint64(amount),
+			Number:   number,
+		}
+	}
+	WriteTokenTransfer(db, transfer(contract, "ABC", 10, 1), 0)
+	WriteTokenTransfer(db, transfer(contract, "ABC", 10, 2), 1)
+	WriteTokenTransfer(db, transfer(contract, "ABCD", 10, 3), 2)
+	WriteTokenTransfer(db, transfer(other, "ABC", 11, 4), 0)
+	WriteTokenTransfer(db, transfer(contract, "ABC", 12, 5), 0)
+	WriteTokenTransfer(db, transfer(contract, "ABC", 256, 6), 0)
+
+	check := func(begin, end uint64, want ...uint64) {
+		transfers := ReadTokenTransfers(db, contract, "ABC", begin, end)
+		if len(transfers) != len(want) {
+			t.Fatalf("range %d-%d: transfer count mismatch: have %d, want %d", begin, end, len(transfers), len(want))
+		}
+		for i, transfer := range transfers {
+			if transfer.Amount.Uint64() != want[i] {
+				t.Errorf("range %d-%d: transfer %d amount mismatch: have %v, want %d", begin, end, i, transfer.Amount, want[i])
+			}
+		}
+	}
+	check(0, 1000, 1, 2, 5, 6)
+	check(10, 12, 1, 2)
+	check(11, 256, 5)
+	check(256, 257, 6)
+	check(13, 256)
+
+	if transfers := ReadTokenTransfers(db, contract, "ABC", 12, 13); transfers[0].Number != 12 {
+		t.Errorf("block number mismatch: have %d, want 12", transfers[0].Number)
+	}
+}
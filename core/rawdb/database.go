@@ -0,0 +1,177 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/localdb"
+)
+
+// OpenFreezer opens the freezer in the "ancient" directory of the database and
+// sets it as its ancient store. The blocks older than the threshold are moved
+// to it in the background, unless the threshold is zero in which case only an
+// existing freezer is opened.
+func OpenFreezer(db *serodb.LDBDatabase, threshold uint64) (*Freezer, error) {
+	dir := filepath.Join(db.Path(), "ancient")
+	if threshold == 0 {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	freezer, err := NewFreezer(dir)
+	if err != nil {
+		return nil, err
+	}
+	db.SetAncientStore(freezer)
+	if threshold > 0 {
+		freezer.Start(db, threshold)
+	}
+	return freezer, nil
+}
+
+// TruncateAncients discards the frozen blocks from the given number, if the
+// database has an ancient store.
+func TruncateAncients(db serodb.Database, items uint64) error {
+	if db, ok := db.(*serodb.LDBDatabase); ok {
+		if freezer, ok := db.AncientStore().(*Freezer); ok {
+			return freezer.TruncateAncients(items)
+		}
+	}
+	return nil
+}
+
+// inspectStat is the count and the size of the entries of a category.
+type inspectStat struct {
+	count uint64
+	size  common.StorageSize
+}
+
+func (s *inspectStat) add(size int) {
+	s.count++
+	s.size += common.StorageSize(size)
+}
+
+// InspectDatabase traverses the entire database and prints the space taken by
+// every category of data, in LevelDB and in the freezer.
+func InspectDatabase(db *serodb.LDBDatabase) error {
+	var (
+		headers, bodies, receipts, tds, canonicals, numbers  inspectStat
+		txLookups, bloomBits, registries, transfers, zblocks inspectStat
+		tries, preimages, configs, metadata, others          inspectStat
+
+		start  = time.Now()
+		logged = time.Now()
+		total  common.StorageSize
+	)
+	it := db.NewIterator()
+	defer it.Release()
+
+	for it.Next() {
+		key, size := it.Key(), len(it.Key())+len(it.Value())
+		total += common.StorageSize(size)
+
+		switch {
+		case bytes.HasPrefix(key, headerPrefix) && len(key) == len(headerPrefix)+8+common.HashLength:
+			headers.add(size)
+		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix) && len(key) == len(headerPrefix)+8+common.HashLength+len(headerTDSuffix):
+			tds.add(size)
+		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix) && len(key) == len(headerPrefix)+8+len(headerHashSuffix):
+			canonicals.add(size)
+		case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == len(headerNumberPrefix)+common.HashLength:
+			numbers.add(size)
+		case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == len(blockBodyPrefix)+8+common.HashLength:
+			bodies.add(size)
+		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == len(blockReceiptsPrefix)+8+common.HashLength:
+			receipts.add(size)
+		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == len(txLookupPrefix)+common.HashLength:
+			txLookups.add(size)
+		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == len(bloomBitsPrefix)+10+common.HashLength:
+			bloomBits.add(size)
+		case bytes.HasPrefix(key, blockRegistryPrefix) || bytes.HasPrefix(key, tokenRegistryPrefix):
+			registries.add(size)
+		case bytes.HasPrefix(key, blockTransfersPrefix) || bytes.HasPrefix(key, tokenTransferPrefix):
+			transfers.add(size)
+		case bytes.HasPrefix(key, localdb.BlockKeyPrefix):
+			zblocks.add(size)
+		case bytes.HasPrefix(key, preimagePrefix) && len(key) == len(preimagePrefix)+common.HashLength:
+			preimages.add(size)
+		case bytes.HasPrefix(key, configPrefix) && len(key) == len(configPrefix)+common.HashLength:
+			configs.add(size)
+		case len(key) == common.HashLength:
+			tries.add(size)
+		default:
+			var known bool
+			for _, meta := range [][]byte{databaseVerisionKey, headHeaderKey, headBlockKey, headFastBlockKey, fastTrieProgressKey} {
+				if bytes.Equal(key, meta) {
+					metadata.add(size)
+					known = true
+					break
+				}
+			}
+			if !known {
+				others.add(size)
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Inspecting database", "size", total, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	rows := [][]string{
+		{"Key-Value store", "Headers", headers.size.String(), fmt.Sprint(headers.count)},
+		{"Key-Value store", "Bodies", bodies.size.String(), fmt.Sprint(bodies.count)},
+		{"Key-Value store", "Receipts", receipts.size.String(), fmt.Sprint(receipts.count)},
+		{"Key-Value store", "Difficulties", tds.size.String(), fmt.Sprint(tds.count)},
+		{"Key-Value store", "Block number->hash", canonicals.size.String(), fmt.Sprint(canonicals.count)},
+		{"Key-Value store", "Block hash->number", numbers.size.String(), fmt.Sprint(numbers.count)},
+		{"Key-Value store", "Transaction index", txLookups.size.String(), fmt.Sprint(txLookups.count)},
+		{"Key-Value store", "Bloombit index", bloomBits.size.String(), fmt.Sprint(bloomBits.count)},
+		{"Key-Value store", "Token registry", registries.size.String(), fmt.Sprint(registries.count)},
+		{"Key-Value store", "Token transfers", transfers.size.String(), fmt.Sprint(transfers.count)},
+		{"Key-Value store", "Zero state blocks", zblocks.size.String(), fmt.Sprint(zblocks.count)},
+		{"Key-Value store", "Trie nodes", tries.size.String(), fmt.Sprint(tries.count)},
+		{"Key-Value store", "Trie preimages", preimages.size.String(), fmt.Sprint(preimages.count)},
+		{"Key-Value store", "Chain configs", configs.size.String(), fmt.Sprint(configs.count)},
+		{"Key-Value store", "Metadata", metadata.size.String(), fmt.Sprint(metadata.count)},
+		{"Key-Value store", "Others", others.size.String(), fmt.Sprint(others.count)},
+	}
+	if freezer, ok := db.AncientStore().(*Freezer); ok {
+		sizes, frozen := freezer.AncientSizes(), freezer.Frozen()
+		for _, name := range freezerTables {
+			rows = append(rows, []string{"Ancient store", name, sizes[name].String(), fmt.Sprint(frozen)})
+			total += sizes[name]
+		}
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Database", "Category", "Size", "Items"})
+	table.SetFooter([]string{"", "Total", total.String(), " "})
+	table.AppendBulk(rows)
+	table.Render()
+	return nil
+}
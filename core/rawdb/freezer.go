@@ -0,0 +1,393 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/localdb"
+)
+
+// The tables of the freezer, every one holds an item per frozen block.
+const (
+	freezerHashTable     = "hashes"   // Canonical hash of the blocks
+	freezerHeaderTable   = "headers"  // Header RLP of the blocks
+	freezerBodiesTable   = "bodies"   // Body RLP of the blocks
+	freezerReceiptsTable = "receipts" // Receipts RLP of the blocks
+	freezerZBlockTable   = "zblocks"  // Zero state records of the blocks
+)
+
+var freezerTables = []string{freezerHashTable, freezerHeaderTable, freezerBodiesTable, freezerReceiptsTable, freezerZBlockTable}
+
+const (
+	// freezerBatchLimit is the maximum number of blocks moved at once.
+	freezerBatchLimit = 30000
+
+	// freezerRecheckInterval is the time between two checks for blocks to move.
+	freezerRecheckInterval = time.Minute
+)
+
+// Freezer is an append-only store of the immutable data of the canonical
+// blocks older than a threshold. It serves the data under the keys it had in
+// LevelDB, so that the accessors find it once set as the ancient store of the
+// database.
+type Freezer struct {
+	frozen uint64 // Number of blocks frozen (atomic)
+
+	tables map[string]*freezerTable
+	lock   sync.Mutex // Serializes the migration batches and the truncations
+
+	quit      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewFreezer opens the freezer of the given directory, dropping the items of
+// the blocks not completely frozen.
+func NewFreezer(dir string) (*Freezer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f := &Freezer{
+		tables: make(map[string]*freezerTable),
+		quit:   make(chan struct{}),
+	}
+	for _, name := range freezerTables {
+		table, err := newFreezerTable(dir, name)
+		if err != nil {
+			for _, table := range f.tables {
+				table.Close()
+			}
+			return nil, err
+		}
+		f.tables[name] = table
+	}
+	f.frozen = f.tables[freezerHashTable].Items()
+	for _, table := range f.tables {
+		if items := table.Items(); items < f.frozen {
+			f.frozen = items
+		}
+	}
+	if err := f.truncate(f.frozen); err != nil {
+		f.Close()
+		return nil, err
+	}
+	log.Info("Opened ancient database", "dir", dir, "frozen", f.frozen)
+	return f, nil
+}
+
+// Frozen returns the number of blocks frozen.
+func (f *Freezer) Frozen() uint64 {
+	return atomic.LoadUint64(&f.frozen)
+}
+
+// Ancient returns the item of the given table for the given block.
+func (f *Freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	table := f.tables[kind]
+	if table == nil {
+		return nil, fmt.Errorf("unknown ancient table %s", kind)
+	}
+	if number >= f.Frozen() {
+		return nil, errOutOfBounds
+	}
+	return table.Retrieve(number)
+}
+
+// Get implements serodb.AncientStore, returning the data of a frozen block of
+// the given key.
+func (f *Freezer) Get(key []byte) ([]byte, bool) {
+	kind, number, hash, ok := ancientKey(key)
+	if !ok || number >= f.Frozen() {
+		return nil, false
+	}
+	if canonical, err := f.Ancient(freezerHashTable, number); err != nil || !bytes.Equal(canonical, hash[:]) {
+		return nil, false
+	}
+	data, err := f.Ancient(kind, number)
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+	return data, true
+}
+
+// ancientKey returns the table, block number and hash of a key of the data
+// moved to the freezer.
+func ancientKey(key []byte) (kind string, number uint64, hash common.Hash, ok bool) {
+	if len(key) == 1+8+common.HashLength {
+		switch {
+		case bytes.HasPrefix(key, headerPrefix):
+			kind = freezerHeaderTable
+		case bytes.HasPrefix(key, blockBodyPrefix):
+			kind = freezerBodiesTable
+		case bytes.HasPrefix(key, blockReceiptsPrefix):
+			kind = freezerReceiptsTable
+		default:
+			return
+		}
+		return kind, binary.BigEndian.Uint64(key[1:9]), common.BytesToHash(key[9:]), true
+	}
+	// localdb.BlockKey = prefix + num (big.Int bytes) + "$" + hash
+	if bytes.HasPrefix(key, localdb.BlockKeyPrefix) && len(key) >= len(localdb.BlockKeyPrefix)+1+common.HashLength {
+		rest := key[len(localdb.BlockKeyPrefix):]
+		num := rest[:len(rest)-1-common.HashLength]
+		if len(num) > 8 || (len(num) > 0 && num[0] == 0) || rest[len(num)] != '$' {
+			return
+		}
+		return freezerZBlockTable, new(big.Int).SetBytes(num).Uint64(), common.BytesToHash(rest[len(num)+1:]), true
+	}
+	return
+}
+
+// append adds the data of a block to the tables, or none of it.
+func (f *Freezer) append(number uint64, hash common.Hash, header, body, receipts, zblock []byte) error {
+	items := map[string][]byte{
+		freezerHashTable:     hash[:],
+		freezerHeaderTable:   header,
+		freezerBodiesTable:   body,
+		freezerReceiptsTable: receipts,
+		freezerZBlockTable:   zblock,
+	}
+	for _, name := range freezerTables {
+		if err := f.tables[name].Append(number, items[name]); err != nil {
+			f.truncate(number)
+			return err
+		}
+	}
+	return nil
+}
+
+// TruncateAncients discards the frozen blocks from the given number, they are
+// not served anymore and are frozen again once the chain reaches them.
+func (f *Freezer) TruncateAncients(items uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	frozen := f.Frozen()
+	if items >= frozen {
+		return nil
+	}
+	if err := f.truncate(items); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	log.Warn("Truncated ancient blocks", "frozen", items, "dropped", frozen-items)
+	return nil
+}
+
+// truncate discards the items of the blocks from the given one.
+func (f *Freezer) truncate(items uint64) error {
+	for _, table := range f.tables {
+		table.lock.Lock()
+		err := table.truncate(items)
+		table.lock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sync flushes the tables to the disk.
+func (f *Freezer) sync() error {
+	for _, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AncientSizes returns the size of the files of every table.
+func (f *Freezer) AncientSizes() map[string]common.StorageSize {
+	sizes := make(map[string]common.StorageSize)
+	for name, table := range f.tables {
+		sizes[name] = common.StorageSize(table.Size())
+	}
+	return sizes
+}
+
+// Start moves in the background the blocks older than the threshold from the
+// database to the freezer.
+func (f *Freezer) Start(db *serodb.LDBDatabase, threshold uint64) {
+	f.wg.Add(1)
+	go f.freeze(db, threshold)
+}
+
+// Close stops the migration and closes the tables.
+func (f *Freezer) Close() error {
+	var errs []error
+	f.closeOnce.Do(func() {
+		close(f.quit)
+		f.wg.Wait()
+		for _, table := range f.tables {
+			if err := table.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// freeze is the migration loop, it moves the blocks by batches and waits for
+// new ones once caught up.
+func (f *Freezer) freeze(db *serodb.LDBDatabase, threshold uint64) {
+	defer f.wg.Done()
+
+	// Removes the leftovers of a migration interrupted before the deletion
+	if frozen := f.Frozen(); frozen > 0 {
+		first := uint64(0)
+		if frozen > freezerBatchLimit {
+			first = frozen - freezerBatchLimit
+		}
+		f.lock.Lock()
+		f.cleanup(db, first, frozen)
+		f.lock.Unlock()
+	}
+	for {
+		moved := f.freezeBatch(db, threshold)
+		if moved == freezerBatchLimit {
+			select {
+			case <-f.quit:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-f.quit:
+			return
+		case <-time.After(freezerRecheckInterval):
+		}
+	}
+}
+
+// freezeBatch moves the next batch of blocks older than the threshold and
+// returns their number.
+func (f *Freezer) freezeBatch(db *serodb.LDBDatabase, threshold uint64) uint64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	head := ReadHeaderNumber(db, ReadHeadBlockHash(db))
+	if head == nil || *head < threshold {
+		return 0
+	}
+	frozen := f.Frozen()
+	limit := *head - threshold + 1
+	if limit <= frozen {
+		return 0
+	}
+	if limit-frozen > freezerBatchLimit {
+		limit = frozen + freezerBatchLimit
+	}
+	start := time.Now()
+	number := frozen
+freeze:
+	for ; number < limit; number++ {
+		select {
+		case <-f.quit:
+			break freeze
+		default:
+		}
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			log.Error("Canonical hash missing, can't freeze", "number", number)
+			break freeze
+		}
+		header := ReadHeaderRLP(db, hash, number)
+		if len(header) == 0 {
+			log.Error("Block header missing, can't freeze", "number", number, "hash", hash)
+			break freeze
+		}
+		body := ReadBodyRLP(db, hash, number)
+		if len(body) == 0 {
+			log.Error("Block body missing, can't freeze", "number", number, "hash", hash)
+			break freeze
+		}
+		receipts, _ := db.Get(blockReceiptsKey(number, hash))
+		zblock, _ := db.Get(localdb.BlockKey(number, hash.HashToUint256()))
+		if err := f.append(number, hash, header, body, receipts, zblock); err != nil {
+			log.Error("Failed to freeze block", "number", number, "hash", hash, "err", err)
+			break freeze
+		}
+	}
+	if number == frozen {
+		return 0
+	}
+	if err := f.sync(); err != nil {
+		log.Crit("Failed to flush frozen tables", "err", err)
+	}
+	atomic.StoreUint64(&f.frozen, number)
+	f.cleanup(db, frozen, number)
+
+	log.Info("Moved ancient blocks into the freezer", "blocks", number-frozen, "frozen", number, "elapsed", common.PrettyDuration(time.Since(start)))
+	return number - frozen
+}
+
+// cleanup deletes from the database the frozen data of the given blocks and
+// the side chain blocks of the same numbers. The genesis block is left in place.
+func (f *Freezer) cleanup(db *serodb.LDBDatabase, first, last uint64) {
+	batch := db.NewBatch()
+	for number := first; number < last; number++ {
+		if number == 0 {
+			continue
+		}
+		hash, err := f.Ancient(freezerHashTable, number)
+		if err != nil {
+			log.Error("Failed to read frozen hash", "number", number, "err", err)
+			return
+		}
+		canonical := common.BytesToHash(hash)
+		batch.Delete(headerKey(number, canonical))
+		batch.Delete(blockBodyKey(number, canonical))
+		batch.Delete(blockReceiptsKey(number, canonical))
+		batch.Delete(localdb.BlockKey(number, canonical.HashToUint256()))
+
+		it := db.NewIteratorWithPrefix(append(append([]byte{}, headerPrefix...), encodeBlockNumber(number)...))
+		for it.Next() {
+			if key := it.Key(); len(key) == len(headerPrefix)+8+common.HashLength {
+				if hash := common.BytesToHash(key[len(headerPrefix)+8:]); hash != canonical {
+					DeleteBlock(batch, hash, number)
+					batch.Delete(localdb.BlockKey(number, hash.HashToUint256()))
+				}
+			}
+		}
+		it.Release()
+
+		if batch.ValueSize() > serodb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to delete frozen blocks", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete frozen blocks", "err", err)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	// errOutOfBounds is returned if the item requested is not contained within the
+	// freezer table.
+	errOutOfBounds = errors.New("out of bounds")
+
+	// errOutOrderInsertion is returned if the user attempts to inject out-of-order
+	// binary blobs into the freezer.
+	errOutOrderInsertion = errors.New("the append operation is out-order")
+)
+
+// indexEntrySize is the size of an entry of the index file, the end offset of
+// the item in the data file (uint64 big endian).
+const indexEntrySize = 8
+
+// freezerTable is an append-only flat file table of items numbered from zero.
+// The data file holds the items back to back and the index file holds the end
+// offset of every item.
+type freezerTable struct {
+	name  string
+	index *os.File // File of the end offsets of the items
+	data  *os.File // File of the items
+
+	items uint64 // Number of items stored in the table
+	size  uint64 // Size of the data file
+
+	lock sync.RWMutex
+}
+
+// newFreezerTable opens the given table, repairing the files left inconsistent
+// by an interrupted append.
+func newFreezerTable(dir, name string) (*freezerTable, error) {
+	index, err := os.OpenFile(filepath.Join(dir, name+".idx"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.OpenFile(filepath.Join(dir, name+".dat"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		index.Close()
+		return nil, err
+	}
+	t := &freezerTable{name: name, index: index, data: data}
+	if err := t.repair(); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// repair drops the trailing items whose data is incomplete.
+func (t *freezerTable) repair() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	items := uint64(stat.Size()) / indexEntrySize
+	if stat, err = t.data.Stat(); err != nil {
+		return err
+	}
+	dataSize := uint64(stat.Size())
+	for ; items > 0; items-- {
+		end, err := t.readOffset(items - 1)
+		if err != nil {
+			return err
+		}
+		if end <= dataSize {
+			break
+		}
+	}
+	return t.truncate(items)
+}
+
+// readOffset reads the end offset of the given item from the index file.
+func (t *freezerTable) readOffset(item uint64) (uint64, error) {
+	buf := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buf, int64(item*indexEntrySize)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+// truncate discards the items from the given one.
+func (t *freezerTable) truncate(items uint64) error {
+	var size uint64
+	if items > 0 {
+		end, err := t.readOffset(items - 1)
+		if err != nil {
+			return err
+		}
+		size = end
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(size)); err != nil {
+		return err
+	}
+	t.items, t.size = items, size
+	return nil
+}
+
+// Append adds the item of the given number, which must follow the last one.
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if item != t.items {
+		return fmt.Errorf("%v: table %s, have %d, want %d", errOutOrderInsertion, t.name, item, t.items)
+	}
+	if _, err := t.data.WriteAt(blob, int64(t.size)); err != nil {
+		return err
+	}
+	buf := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint64(buf, t.size+uint64(len(blob)))
+	if _, err := t.index.WriteAt(buf, int64(t.items*indexEntrySize)); err != nil {
+		return err
+	}
+	t.items++
+	t.size += uint64(len(blob))
+	return nil
+}
+
+// Retrieve returns the item of the given number.
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if item >= t.items {
+		return nil, errOutOfBounds
+	}
+	var start uint64
+	if item > 0 {
+		var err error
+		if start, err = t.readOffset(item - 1); err != nil {
+			return nil, err
+		}
+	}
+	end, err := t.readOffset(item)
+	if err != nil {
+		return nil, err
+	}
+	blob := make([]byte, end-start)
+	if _, err := t.data.ReadAt(blob, int64(start)); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// Items returns the number of items of the table.
+func (t *freezerTable) Items() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.items
+}
+
+// Size returns the size of the files of the table.
+func (t *freezerTable) Size() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.size + t.items*indexEntrySize
+}
+
+// Sync flushes the files of the table to the disk.
+func (t *freezerTable) Sync() error {
+	if err := t.data.Sync(); err != nil {
+		return err
+	}
+	return t.index.Sync()
+}
+
+// Close closes the files of the table.
+func (t *freezerTable) Close() error {
+	var errs []error
+	for _, f := range []*os.File{t.index, t.data} {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/localdb"
+)
+
+// Tests that the items of a table survive a reopen and that an interrupted
+// append is dropped.
+func TestFreezerTableRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table, err := newFreezerTable(dir, "test")
+	if err != nil {
+		t.Fatalf("failed to open table: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := table.Append(uint64(i), bytes.Repeat([]byte{byte(i)}, i)); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	if err := table.Append(20, []byte{1}); err == nil {
+		t.Fatalf("out of order append succeeded")
+	}
+	table.Close()
+
+	// Cut the data of the last item
+	if err := os.Truncate(filepath.Join(dir, "test.dat"), 40); err != nil {
+		t.Fatal(err)
+	}
+	if table, err = newFreezerTable(dir, "test"); err != nil {
+		t.Fatalf("failed to reopen table: %v", err)
+	}
+	defer table.Close()
+
+	if items := table.Items(); items != 9 {
+		t.Fatalf("items mismatch: have %d, want %d", items, 9)
+	}
+	for i := 0; i < 9; i++ {
+		blob, err := table.Retrieve(uint64(i))
+		if err != nil {
+			t.Fatalf("failed to retrieve item %d: %v", i, err)
+		}
+		if !bytes.Equal(blob, bytes.Repeat([]byte{byte(i)}, i)) {
+			t.Fatalf("item %d mismatch: have %x", i, blob)
+		}
+	}
+	if _, err := table.Retrieve(9); err != errOutOfBounds {
+		t.Fatalf("retrieved the dropped item: %v", err)
+	}
+}
+
+// Tests that the frozen blocks are removed from LevelDB and still served by
+// the accessors.
+func TestFreezerMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := serodb.NewLDBDatabase(filepath.Join(dir, "chaindata"), 16, 16)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	var blocks []*types.Block
+	for i := 0; i < 20; i++ {
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(int64(i)), Extra: []byte("freezer test")})
+		WriteBlock(db, block)
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		WriteReceipts(db, block.Hash(), block.NumberU64(), types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{}}})
+		localdb.PutBlock(db, block.NumberU64(), block.Hash().HashToUint256(), &localdb.Block{Roots: []c_type.Uint256{{byte(i)}}})
+		WriteHeadBlockHash(db, block.Hash())
+		blocks = append(blocks, block)
+	}
+	// Side chain blocks, the frozen one is deleted
+	var sides []*types.Block
+	for _, number := range []int64{3, 17} {
+		block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number), Extra: []byte("side chain")})
+		WriteBlock(db, block)
+		WriteTd(db, block.Hash(), block.NumberU64(), big.NewInt(1))
+		sides = append(sides, block)
+	}
+	freezer, err := OpenFreezer(db, 0)
+	if err != nil || freezer != nil {
+		t.Fatalf("opened a missing freezer without migration: %v", err)
+	}
+	if freezer, err = NewFreezer(filepath.Join(dir, "chaindata", "ancient")); err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	db.SetAncientStore(freezer)
+
+	if moved := freezer.freezeBatch(db, 5); moved != 15 {
+		t.Fatalf("moved blocks mismatch: have %d, want %d", moved, 15)
+	}
+	for _, block := range blocks {
+		hash, number := block.Hash(), block.NumberU64()
+		if has, _ := db.LDB().Has(headerKey(number, hash), nil); has != (number == 0 || number >= 15) {
+			t.Fatalf("block %d: header in LevelDB %v", number, has)
+		}
+		if header := ReadHeader(db, hash, number); header == nil || header.Hash() != hash {
+			t.Fatalf("block %d: header not found", number)
+		}
+		if body := ReadBody(db, hash, number); body == nil {
+			t.Fatalf("block %d: body not found", number)
+		}
+		if receipts := ReadReceipts(db, hash, number); len(receipts) != 1 {
+			t.Fatalf("block %d: receipts not found", number)
+		}
+		if zblock := localdb.GetBlock(db, number, hash.HashToUint256()); zblock == nil || zblock.Roots[0][0] != byte(number) {
+			t.Fatalf("block %d: zero state block not found", number)
+		}
+		if header := ReadHeader(db, blocks[(number+1)%20].Hash(), number); header != nil {
+			t.Fatalf("block %d: header found under another hash", number)
+		}
+	}
+	if header := ReadHeader(db, sides[0].Hash(), 3); header != nil {
+		t.Fatalf("frozen side chain header kept")
+	}
+	if td := ReadTd(db, sides[0].Hash(), 3); td != nil {
+		t.Fatalf("frozen side chain difficulty kept")
+	}
+	if header := ReadHeader(db, sides[1].Hash(), 17); header == nil {
+		t.Fatalf("recent side chain header deleted")
+	}
+	db.Close()
+
+	// Reopen and check the frozen blocks are served again
+	if db, err = serodb.NewLDBDatabase(filepath.Join(dir, "chaindata"), 16, 16); err != nil {
+		t.Fatalf("failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	if freezer, err = OpenFreezer(db, 0); err != nil || freezer == nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	if frozen := freezer.Frozen(); frozen != 15 {
+		t.Fatalf("frozen mismatch: have %d, want %d", frozen, 15)
+	}
+	if header := ReadHeader(db, blocks[10].Hash(), 10); header == nil {
+		t.Fatalf("frozen header not found after reopen")
+	}
+
+	// Truncate the frozen blocks of a rewound chain
+	if err := TruncateAncients(db, 10); err != nil {
+		t.Fatalf("failed to truncate: %v", err)
+	}
+	if frozen := freezer.Frozen(); frozen != 10 {
+		t.Fatalf("frozen mismatch after truncation: have %d, want %d", frozen, 10)
+	}
+	if header := ReadHeader(db, blocks[12].Hash(), 12); header != nil {
+		t.Fatalf("truncated header still served")
+	}
+	if header := ReadHeader(db, blocks[9].Hash(), 9); header == nil {
+		t.Fatalf("frozen header not found after truncation")
+	}
+}
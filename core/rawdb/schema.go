@@ -21,6 +21,7 @@ import (
 	"encoding/binary"
 
 	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/crypto"
 	"github.com/sero-cash/go-sero/metrics"
 )
 
@@ -41,6 +42,22 @@ var (
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
+	// zeroSyncProgressKey tracks the next block whose zero state records are to
+	// be retrieved during fast sync.
+	zeroSyncProgressKey = []byte("ZeroSync")
+
+	// registryEventsTailKey tracks the first block whose registry events were
+	// recorded on import, the ones of older blocks have to be regenerated.
+	registryEventsTailKey = []byte("RegistryEventsTail")
+
+	// tokenTransfersTailKey tracks the first block whose token transfers were
+	// recorded on import, the ones of older blocks have to be regenerated.
+	tokenTransfersTailKey = []byte("TokenTransfersTail")
+
+	// tokenRegistrySectionsKey tracks the number of sections committed by the
+	// token registry indexer.
+	tokenRegistrySectionsKey = []byte("TokenRegistrySections")
+
 	indexPrefix = []byte("indexB")
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
@@ -54,11 +71,18 @@ var (
 	txLookupPrefix  = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 
+	blockRegistryPrefix  = []byte("g") // blockRegistryPrefix + num (uint64 big endian) + hash -> block registry events
+	tokenRegistryPrefix  = []byte("T") // tokenRegistryPrefix + kind + name -> token registration, tokenRegistryPrefix + kind -> names
+	blockTransfersPrefix = []byte("x") // blockTransfersPrefix + num (uint64 big endian) + hash -> block token transfers
+	tokenTransferPrefix  = []byte("X") // tokenTransferPrefix + contract + currency hash + num (uint64 big endian) + index (uint32 big endian) -> token transfer
+
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
-	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	BloomBitsIndexPrefix     = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	TokenRegistryIndexPrefix = []byte("iT") // TokenRegistryIndexPrefix is the data table of the token registry indexer to track its progress
+	TokenTransferIndexPrefix = []byte("iX") // TokenTransferIndexPrefix is the data table of the token transfer indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -113,6 +137,30 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// blockRegistryKey = blockRegistryPrefix + num (uint64 big endian) + hash
+func blockRegistryKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockRegistryPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// tokenRegistryKey = tokenRegistryPrefix + kind + name
+func tokenRegistryKey(kind uint8, name string) []byte {
+	return append(append(tokenRegistryPrefix, kind), name...)
+}
+
+// blockTransfersKey = blockTransfersPrefix + num (uint64 big endian) + hash
+func blockTransfersKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockTransfersPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// tokenTransferKey = tokenTransferPrefix + contract + currency hash + num (uint64 big endian) + index (uint32 big endian)
+func tokenTransferKey(contract common.Address, currency string, number uint64, index uint32) []byte {
+	key := append(append(tokenTransferPrefix, contract[:]...), crypto.Keccak256([]byte(currency))...)
+	key = append(key, encodeBlockNumber(number)...)
+	enc := make([]byte, 4)
+	binary.BigEndian.PutUint32(enc, index)
+	return append(key, enc...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
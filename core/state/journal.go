@@ -114,6 +114,11 @@ type (
 		account       *common.Address
 		key, prevalue common.Hash
 	}
+	storageResetChange struct {
+		account               *common.Address
+		prevtrie              Trie
+		prevcached, prevdirty Storage
+	}
 	codeChange struct {
 		account            *common.Address
 		prevcode, prevhash []byte
@@ -134,6 +139,8 @@ type (
 		prev      bool
 		prevDirty bool
 	}
+	addRegistryEventChange struct{}
+	addTokenTransferChange struct{}
 )
 
 func (tn ticketNonceChange) revert(s *StateDB) {
@@ -208,6 +215,15 @@ func (ch storageChange) dirtied() *common.Address {
 	return ch.account
 }
 
+func (ch storageResetChange) revert(s *StateDB) {
+	obj := s.getStateObject(*ch.account)
+	obj.trie, obj.cachedStorage, obj.dirtyStorage = ch.prevtrie, ch.prevcached, ch.prevdirty
+}
+
+func (ch storageResetChange) dirtied() *common.Address {
+	return ch.account
+}
+
 func (ch refundChange) revert(s *StateDB) {
 	s.refund = ch.prev
 }
@@ -230,6 +246,22 @@ func (ch addLogChange) dirtied() *common.Address {
 	return nil
 }
 
+func (ch addRegistryEventChange) revert(s *StateDB) {
+	s.registryEvents = s.registryEvents[:len(s.registryEvents)-1]
+}
+
+func (ch addRegistryEventChange) dirtied() *common.Address {
+	return nil
+}
+
+func (ch addTokenTransferChange) revert(s *StateDB) {
+	s.tokenTransfers = s.tokenTransfers[:len(s.tokenTransfers)-1]
+}
+
+func (ch addTokenTransferChange) dirtied() *common.Address {
+	return nil
+}
+
 func (ch addPreimageChange) revert(s *StateDB) {
 	delete(s.preimages, ch.hash)
 }
@@ -0,0 +1,266 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner deletes the state history outside a retention window: the
+// trie nodes only reachable from the states of older blocks, and the zero
+// state records of those blocks.
+package pruner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/trie"
+	"github.com/sero-cash/go-sero/zero/localdb"
+)
+
+// MinRetainBlocks is the smallest retention window, the tries of that many
+// recent blocks are only held in memory and reach the disk later.
+const MinRetainBlocks = 128
+
+var (
+	errNotMarked = errors.New("state not marked")
+	errAborted   = errors.New("pruning aborted")
+
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+// Pruner marks every trie node and contract code reachable from the states
+// of the retained blocks and the genesis, then sweeps all other ones from the
+// database. The marks are exact, a node shared by several states is walked
+// only once, so their memory grows with the size of a single state.
+type Pruner struct {
+	db     *serodb.LDBDatabase
+	cache  state.Database
+	retain uint64
+
+	marked map[common.Hash]struct{}
+	head   uint64 // Last block the marks were extended to
+	tail   uint64 // First retained block
+	states int    // Number of retained states found on disk
+}
+
+// New creates a pruner keeping the state history of the last retain blocks.
+// The cache resolves tries not flushed to disk yet when pruning online.
+func New(db *serodb.LDBDatabase, cache state.Database, retain uint64) (*Pruner, error) {
+	if retain < MinRetainBlocks {
+		return nil, fmt.Errorf("retention window of %d blocks below the minimum of %d", retain, MinRetainBlocks)
+	}
+	return &Pruner{
+		db:     db,
+		cache:  cache,
+		retain: retain,
+		marked: make(map[common.Hash]struct{}),
+	}, nil
+}
+
+// Prune marks the retained states up to head and sweeps everything else.
+func (p *Pruner) Prune(head uint64) error {
+	if err := p.Mark(head); err != nil {
+		return err
+	}
+	return p.Sweep(nil)
+}
+
+// Mark marks the retained states up to head. It can be called again with a
+// later head, only the parts of the tries changed since are walked then.
+func (p *Pruner) Mark(head uint64) error {
+	start := time.Now()
+
+	from := uint64(0)
+	if head >= p.retain {
+		from = head + 1 - p.retain
+	}
+	tail := from
+	if len(p.marked) == 0 {
+		if err := p.markBlock(0); err != nil {
+			return err
+		}
+	} else if p.head >= from {
+		from = p.head + 1
+	}
+	for number := from; number <= head; number++ {
+		if err := p.markBlock(number); err != nil {
+			return err
+		}
+	}
+	p.head, p.tail = head, tail
+	log.Info("Marked retained state", "tail", tail, "head", head, "states", p.states, "nodes", len(p.marked), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func (p *Pruner) markBlock(number uint64) error {
+	hash := rawdb.ReadCanonicalHash(p.db, number)
+	header := rawdb.ReadHeader(p.db, hash, number)
+	if header == nil {
+		return fmt.Errorf("missing header #%d", number)
+	}
+	if _, ok := p.marked[header.Root]; ok {
+		return nil
+	}
+	tr, err := p.cache.OpenTrie(header.Root)
+	if err != nil {
+		// Only some states of a full node are on disk
+		if _, ok := err.(*trie.MissingNodeError); ok {
+			return nil
+		}
+		return err
+	}
+	p.states++
+	return p.markTrie(tr.NodeIterator(nil), p.markAccount)
+}
+
+func (p *Pruner) markAccount(leaf []byte) error {
+	// The zero consensus keeps raw items in the account trie too
+	var account state.Account
+	if err := rlp.DecodeBytes(leaf, &account); err != nil {
+		return nil
+	}
+	if account.Root != emptyRoot {
+		if _, ok := p.marked[account.Root]; !ok {
+			tr, err := p.cache.OpenStorageTrie(common.Hash{}, account.Root)
+			if err != nil {
+				return err
+			}
+			if err := p.markTrie(tr.NodeIterator(nil), nil); err != nil {
+				return err
+			}
+		}
+	}
+	if code := common.BytesToHash(account.CodeHash); code != emptyCode {
+		p.marked[code] = struct{}{}
+	}
+	return nil
+}
+
+// markTrie marks the nodes of a trie, skipping the subtries marked already.
+func (p *Pruner) markTrie(it trie.NodeIterator, onLeaf func([]byte) error) error {
+	for descend := true; it.Next(descend); {
+		descend = true
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if _, ok := p.marked[hash]; ok {
+				descend = false
+				continue
+			}
+			p.marked[hash] = struct{}{}
+		}
+		if it.Leaf() && onLeaf != nil {
+			if err := onLeaf(it.LeafBlob()); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Error()
+}
+
+// Sweep deletes every trie node and contract code not marked, together with
+// the zstate records of the blocks below the retention window. The records
+// of outs created there are only deleted if their roots are known spent, the
+// ones of private outs can't be told apart and are all kept. Only the keys
+// whose values hash to them are deleted, other 32 byte keys aren't nodes.
+// Closing abort stops the sweep early, which leaves the database consistent.
+func (p *Pruner) Sweep(abort <-chan struct{}) error {
+	if len(p.marked) == 0 {
+		return errNotMarked
+	}
+	start := time.Now()
+	batch := p.db.NewBatch()
+	flush := func(force bool) error {
+		if !force && batch.ValueSize() < serodb.IdealBatchSize {
+			return nil
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	}
+
+	// Drop the records of the spent outs first, the block records list them
+	headHash := rawdb.ReadCanonicalHash(p.db, p.head)
+	st, err := state.New(p.cache, rawdb.ReadHeader(p.db, headHash, p.head))
+	if err != nil {
+		return err
+	}
+	zst := st.CurrentZState()
+	roots := 0
+	for number := localdb.GetPruned(p.db); number < p.tail; number++ {
+		hash := rawdb.ReadCanonicalHash(p.db, number)
+		block := localdb.GetBlock(p.db, number, hash.HashToUint256())
+		if block == nil {
+			continue
+		}
+		for i := range block.Roots {
+			if !zst.State.HasIn(&block.Roots[i]) {
+				continue
+			}
+			if rs := localdb.GetRoot(p.db, &block.Roots[i]); rs != nil {
+				localdb.DeleteRoot(batch, &block.Roots[i], rs)
+				roots++
+			}
+		}
+		if err := flush(false); err != nil {
+			return err
+		}
+	}
+
+	// Sweep the unmarked nodes and the old block records of all branches
+	var keys, nodes, records int
+	it := p.db.NewIterator()
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		keys++
+		if len(key) == common.HashLength {
+			if _, ok := p.marked[common.BytesToHash(key)]; !ok && crypto.Keccak256Hash(it.Value()) == common.BytesToHash(key) {
+				batch.Delete(common.CopyBytes(key))
+				nodes++
+			}
+		} else if number, ok := localdb.ParseBlockKey(key); ok && number < p.tail {
+			batch.Delete(common.CopyBytes(key))
+			records++
+		}
+		if err := flush(false); err != nil {
+			return err
+		}
+		if keys%10000 == 0 {
+			select {
+			case <-abort:
+				flush(true)
+				return errAborted
+			default:
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	localdb.PutPruned(batch, p.tail)
+	if err := flush(true); err != nil {
+		return err
+	}
+	log.Info("Pruned state history", "tail", p.tail, "nodes", nodes, "records", records, "roots", roots, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
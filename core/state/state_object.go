@@ -224,6 +224,28 @@ func (self *stateObject) setState(key, value common.Hash) {
 	self.dirtyStorage[key] = value
 }
 
+// SetStorage replaces the entire storage of the object with the given one, on
+// top of an empty storage trie.
+func (self *stateObject) SetStorage(db Database, storage map[common.Hash]common.Hash) {
+	tr, err := db.OpenStorageTrie(self.addrHash, common.Hash{})
+	if err != nil {
+		self.setError(err)
+		return
+	}
+	self.db.journal.append(storageResetChange{
+		account:    &self.address,
+		prevtrie:   self.trie,
+		prevcached: self.cachedStorage,
+		prevdirty:  self.dirtyStorage,
+	})
+	self.trie = tr
+	self.cachedStorage = make(Storage)
+	self.dirtyStorage = make(Storage)
+	for key, value := range storage {
+		self.setState(key, value)
+	}
+}
+
 // updateTrie writes cached storage modifications into the object's storage trie.
 func (self *stateObject) updateTrie(db Database) Trie {
 	tr := self.getTrie(db)
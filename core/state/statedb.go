@@ -94,6 +94,9 @@ type StateDB struct {
 	logs         map[common.Hash][]*types.Log
 	logSize      uint
 
+	registryEvents []*types.RegistryEvent
+	tokenTransfers []*types.TokenTransfer
+
 	preimages map[common.Hash][]byte
 
 	// Journal of state modifications. This is the backbone of
@@ -167,6 +170,11 @@ func (self *StateDB) registerAddressByState(name string, contractAddr common.Add
 	address := self.getAddressByState(hashKey0, hashKey1, common.Hash{})
 	if address == (common.Address{}) {
 		self.setAddressByState(hashKey0, hashKey1, common.Hash{}, contractAddr)
+		kind := types.RegistryToken
+		if name == "Ticket" {
+			kind = types.RegistryTicket
+		}
+		self.addRegistryEvent(&types.RegistryEvent{Kind: kind, Name: key, Contract: contractAddr})
 		return true
 	} else {
 		return address == contractAddr
@@ -283,6 +291,13 @@ func (self *StateDB) SetTokenRate(contractAddr common.Address, coinName string,
 		stateObject.SetState(self.db, crypto.Keccak256Hash(bytes0), common.BigToHash(tokens))
 		bytes1, _ := rlp.EncodeToBytes([]interface{}{"RateTa", contractAddr, strings.ToUpper(coinName)})
 		stateObject.SetState(self.db, crypto.Keccak256Hash(bytes1), common.BigToHash(tas))
+		self.addRegistryEvent(&types.RegistryEvent{
+			Kind:     types.RegistryTokenRate,
+			Name:     strings.ToUpper(coinName),
+			Contract: contractAddr,
+			Tokens:   new(big.Int).Set(tokens),
+			Tas:      new(big.Int).Set(tas),
+		})
 		return true
 	}
 	return false
@@ -369,6 +384,8 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.txIndex = 0
 	self.logs = make(map[common.Hash][]*types.Log)
 	self.logSize = 0
+	self.registryEvents = nil
+	self.tokenTransfers = nil
 	self.preimages = make(map[common.Hash][]byte)
 	self.clearJournalAndRefund()
 	return nil
@@ -385,6 +402,31 @@ func (self *StateDB) AddLog(log *types.Log) {
 	self.logSize++
 }
 
+func (self *StateDB) addRegistryEvent(event *types.RegistryEvent) {
+	self.journal.append(addRegistryEventChange{})
+
+	event.TxHash = self.thash
+	self.registryEvents = append(self.registryEvents, event)
+}
+
+// RegistryEvents returns the token and ticket registrations done in this state.
+func (self *StateDB) RegistryEvents() []*types.RegistryEvent {
+	return self.registryEvents
+}
+
+// AddTokenTransfer records a movement of a currency on the balance of a contract.
+func (self *StateDB) AddTokenTransfer(transfer *types.TokenTransfer) {
+	self.journal.append(addTokenTransferChange{})
+
+	transfer.TxHash = self.thash
+	self.tokenTransfers = append(self.tokenTransfers, transfer)
+}
+
+// TokenTransfers returns the token transfers done by contracts in this state.
+func (self *StateDB) TokenTransfers() []*types.TokenTransfer {
+	return self.tokenTransfers
+}
+
 func (self *StateDB) GetLogs(hash common.Hash) []*types.Log {
 	return self.logs[hash]
 }
@@ -522,6 +564,32 @@ func (self *StateDB) StorageTrie(addr common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+// GetProof returns the Merkle proof of an account against the state root.
+func (self *StateDB) GetProof(addr common.Address) ([][]byte, error) {
+	var proof proofList
+	err := self.trie.Prove(crypto.Keccak256(addr.Bytes()), 0, &proof)
+	return [][]byte(proof), err
+}
+
+// GetStorageProof returns the Merkle proof of a storage slot against the
+// storage root of the account.
+func (self *StateDB) GetStorageProof(addr common.Address, key common.Hash) ([][]byte, error) {
+	var proof proofList
+	trie := self.StorageTrie(addr)
+	if trie == nil {
+		return proof, errors.New("storage trie for requested address does not exist")
+	}
+	err := trie.Prove(crypto.Keccak256(key.Bytes()), 0, &proof)
+	return [][]byte(proof), err
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -587,6 +655,14 @@ func (self *StateDB) SetState(addr common.Address, key, value common.Hash) {
 	}
 }
 
+// SetStorage replaces the entire storage of the account with the given one.
+func (self *StateDB) SetStorage(addr common.Address, storage map[common.Hash]common.Hash) {
+	stateObject := self.GetOrNewStateObject(addr)
+	if stateObject != nil {
+		stateObject.SetStorage(self.db, storage)
+	}
+}
+
 // Suicide marks the given account as suicided.
 // This clears the account balance.
 //
@@ -792,6 +868,8 @@ func (self *StateDB) Copy() *StateDB {
 		state.logs[hash] = make([]*types.Log, len(logs))
 		copy(state.logs[hash], logs)
 	}
+	state.registryEvents = append(state.registryEvents, self.registryEvents...)
+	state.tokenTransfers = append(state.tokenTransfers, self.tokenTransfers...)
 	for hash, preimage := range self.preimages {
 		state.preimages[hash] = preimage
 	}
@@ -28,7 +28,7 @@ import (
 	"testing"
 	"testing/quick"
 
-	"github.com/sero-cash/go-czero-import/c_czero"
+	"github.com/sero-cash/go-czero-import/cpt"
 	"gopkg.in/check.v1"
 
 	"github.com/sero-cash/go-sero/common"
@@ -167,7 +167,7 @@ func TestCopy(t *testing.T) {
 }
 
 func TestSnapshotRandom(t *testing.T) {
-	cpt.ZeroInit("", c_czero.NET_Alpha)
+	cpt.ZeroInit(cpt.NET_Alpha)
 	config := &quick.Config{MaxCount: 1000}
 	err := quick.Check((*snapshotTest).run, config)
 	if cerr, ok := err.(*quick.CheckError); ok {
@@ -428,3 +428,33 @@ func TestCopyOfCopy(t *testing.T) {
 		t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	}
 }
+
+// TestSetStorageRevert tests that replacing the storage of an account is
+// undone by reverting to an earlier snapshot.
+func TestSetStorageRevert(t *testing.T) {
+	sdb, _ := New(NewDatabase(serodb.NewMemDatabase()), nil)
+	addr := common.BytesToAddress([]byte{1})
+	key, other := common.BytesToHash([]byte{1}), common.BytesToHash([]byte{2})
+	sdb.SetState(addr, key, common.BytesToHash([]byte{1}))
+	root, _ := sdb.Commit(false)
+	sdb, _ = New(sdb.db, &types.Header{Root: root, Number: big.NewInt(0)})
+
+	snapshot := sdb.Snapshot()
+	sdb.SetStorage(addr, map[common.Hash]common.Hash{other: common.BytesToHash([]byte{2})})
+	if value := sdb.GetState(addr, key); value != (common.Hash{}) {
+		t.Fatalf("replaced storage still has %x", value)
+	}
+	if value := sdb.GetState(addr, other); value != common.BytesToHash([]byte{2}) {
+		t.Fatalf("storage not replaced: have %x", value)
+	}
+	sdb.RevertToSnapshot(snapshot)
+	if value := sdb.GetState(addr, key); value != common.BytesToHash([]byte{1}) {
+		t.Fatalf("storage not restored: have %x", value)
+	}
+	if value := sdb.GetState(addr, other); value != (common.Hash{}) {
+		t.Fatalf("override kept after revert: have %x", value)
+	}
+	if have, _ := sdb.Commit(false); have != root {
+		t.Fatalf("root mismatch after revert: have %x, want %x", have, root)
+	}
+}
@@ -51,6 +51,10 @@ const (
 var (
 	ErrVerifyError = errors.New("stx Verify error")
 
+	// ErrProofError is returned if the proofs of a transaction are invalid,
+	// whatever the state it is verified against.
+	ErrProofError = errors.New("stx proof verify error")
+
 	// ErrUnderpriced is returned if a transaction's gas priced is below the minimum
 	// configured for the transaction pool.
 	ErrUnderpriced = errors.New("transaction underpriced")
@@ -80,6 +84,10 @@ var (
 	// General tx metrics
 	invalidTxCounter     = metrics.NewRegisteredCounter("txpool/invalid", nil)
 	underpricedTxCounter = metrics.NewRegisteredCounter("txpool/underpriced", nil)
+
+	// Zero knowledge proof verification metrics
+	verifyTxMeter       = metrics.NewRegisteredMeter("txpool/verify", nil)
+	verifyFailedTxMeter = metrics.NewRegisteredMeter("txpool/verify/failed", nil)
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -482,10 +490,12 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) (e error) {
 	}
 
 	num := pool.chain.CurrentBlock().NumberU64()
+	verifyTxMeter.Mark(1)
 	if err := verify.VerifyWithoutState(tx.Ehash().NewRef(), tx.GetZZSTX(), num); err != nil {
 		log.Error("validateTx verify without state error", "hash", tx.Hash().Hex(), "verify stx err", err)
+		verifyFailedTxMeter.Mark(1)
 		pool.faileds[tx.Hash()] = time.Now()
-		return ErrVerifyError
+		return ErrProofError
 	}
 
 	copyState := pool.currentState.CopyWithNoZState()
@@ -498,6 +508,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) (e error) {
 	//err := verify.Verify(tx.GetZZSTX(), pool.currentState.Copy().GetZState())
 	if err != nil {
 		log.Error("validateTx error", "hash", tx.Hash().Hex(), "verify stx err", err)
+		verifyFailedTxMeter.Mark(1)
 		pool.faileds[tx.Hash()] = time.Now()
 		return ErrVerifyError
 	}
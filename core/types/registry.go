@@ -0,0 +1,45 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/sero-cash/go-sero/common"
+)
+
+// Kinds of registry events raised while executing contracts.
+const (
+	RegistryToken uint8 = iota
+	RegistryTicket
+	RegistryTokenRate
+)
+
+// RegistryEvent records a currency or ticket category registration, or a token
+// rate change, done by a contract. These events are not part of consensus, they
+// are kept beside the block for the token registry indexer.
+type RegistryEvent struct {
+	Kind     uint8
+	Name     string
+	Contract common.Address
+	TxHash   common.Hash
+	Tokens   *big.Int `rlp:"nil"`
+	Tas      *big.Int `rlp:"nil"`
+}
+
+// TokenRate is a SetTokenRate change of a registered currency.
+type TokenRate struct {
+	Contract common.Address
+	Number   uint64
+	TxHash   common.Hash
+	Tokens   *big.Int
+	Tas      *big.Int
+}
+
+// TokenRegistration is the indexed registration of a currency or a ticket category.
+type TokenRegistration struct {
+	Kind     uint8
+	Name     string
+	Contract common.Address
+	Number   uint64
+	TxHash   common.Hash
+	Rates    []TokenRate
+}
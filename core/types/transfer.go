@@ -0,0 +1,30 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/sero-cash/go-sero/common"
+)
+
+// Kinds of token transfers done by contracts.
+const (
+	TransferIssue    uint8 = iota // Currency issued into a contract
+	TransferSend                  // Contract sending to an address or another contract
+	TransferReceive               // Contract receiving the asset of a call
+	TransferPkgClose              // Package closed into a contract
+)
+
+// TokenTransfer records a movement of a currency on the balance of a contract.
+// Transfers are not part of consensus, they are kept beside the block for the
+// token transfer indexer.
+type TokenTransfer struct {
+	Kind     uint8
+	Contract common.Address // Contract whose balance changed
+	Currency string
+	Amount   *big.Int
+	Peer     common.Address // Recipient of a send, sender of a receive or a package
+	TxHash   common.Hash
+
+	// Number is the block of the transfer, it is filled by the indexer.
+	Number uint64 `rlp:"-"`
+}
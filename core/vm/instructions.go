@@ -1030,6 +1030,14 @@ func handleIssueToken(d []byte, evm *EVM, contract *Contract, mem []byte) (bool,
 
 	total := new(big.Int).SetBytes(d[32:64])
 	evm.StateDB.AddBalance(contract.Address(), coinName, total)
+	if total.Sign() > 0 {
+		evm.StateDB.AddTokenTransfer(&types.TokenTransfer{
+			Kind:     types.TransferIssue,
+			Contract: contract.Address(),
+			Currency: coinName,
+			Amount:   new(big.Int).Set(total),
+		})
+	}
 	return true, nil
 }
 
@@ -1223,6 +1231,13 @@ func makeLog(size int) executionFunc {
 					amount := pkg.O.Asset.Tkn.Value.ToIntRef()
 					if len(currency) != 0 && amount.Sign() > 0 {
 						interpreter.evm.StateDB.AddBalance(contract.Address(), currency, amount)
+						interpreter.evm.StateDB.AddTokenTransfer(&types.TokenTransfer{
+							Kind:     types.TransferPkgClose,
+							Contract: contract.Address(),
+							Currency: currency,
+							Amount:   new(big.Int).Set(amount),
+							Peer:     common.BytesToAddress(pkg.Z.From[:]),
+						})
 					}
 					memory.Set(mStart.Uint64(), 32, pkg.O.Asset.Tkn.Currency[:])
 					hash := common.BigToHash(pkg.O.Asset.Tkn.Value.ToIntRef())
@@ -0,0 +1,193 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dashboard
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/internal/ethapi"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/stake"
+)
+
+const (
+	chainHeadChanSize = 10  // Size of channel listening to ChainHeadEvent
+	voteRateWindow    = 128 // Number of recent blocks the vote rate is taken over
+)
+
+// toSero converts an amount in ta to float SERO for the charts.
+func toSero(amount *big.Int) float64 {
+	value, _ := new(big.Float).Quo(new(big.Float).SetInt(amount), big.NewFloat(1e18)).Float64()
+	return value
+}
+
+// recentVoteRate returns the percentage of the votes the node signed for the
+// blocks of the window below head that were included in the chain, the ones
+// for a block can be in its own header or in the one of its child.
+func recentVoteRate(header func(uint64) *types.Header, signed func(uint64) []types.HeaderVote, head uint64) float64 {
+	from := uint64(1)
+	if head > voteRateWindow {
+		from = head - voteRateWindow
+	}
+	var total, included int
+	for number := from; number < head; number++ {
+		votes := signed(number)
+		if len(votes) == 0 {
+			continue
+		}
+		current, child := header(number), header(number+1)
+		if current == nil || child == nil {
+			continue
+		}
+		for _, vote := range votes {
+			total++
+			for _, v := range append(append([]types.HeaderVote{}, current.CurrentVotes...), child.ParentVotes...) {
+				if v.Id == vote.Id && v.IsPool == vote.IsPool {
+					included++
+					break
+				}
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(included) / float64(total) * 100
+}
+
+// collectChainData collects the chain and staking data of every new head block.
+func (db *Dashboard) collectChainData() {
+	defer db.wg.Done()
+
+	if db.sero == nil {
+		errc := <-db.quit
+		errc <- nil
+		return
+	}
+	chain := db.sero.BlockChain()
+	api := ethapi.NewPublicBlockChainAPI(db.sero.APIBackend)
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case errc := <-db.quit:
+			errc <- nil
+			return
+		case err := <-sub.Err():
+			log.Warn("Dashboard chain subscription failed", "err", err)
+			errc := <-db.quit
+			errc <- nil
+			return
+		case head := <-heads:
+			block := head.Block
+			header := block.Header()
+			now := time.Now()
+
+			msg := &ChainMessage{
+				Head: &ChainHead{
+					Number:     block.NumberU64(),
+					Hash:       block.Hash(),
+					Time:       block.Time().Uint64(),
+					Difficulty: block.Difficulty().String(),
+					Txs:        len(block.Transactions()),
+					Votes:      len(header.CurrentVotes) + len(header.ParentVotes),
+				},
+			}
+			blockTime := &ChartEntry{
+				Time: now,
+			}
+			parent := chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+			if parent != nil {
+				blockTime.Value = float64(new(big.Int).Sub(block.Time(), parent.Time).Int64())
+			}
+			powReward := new(big.Int)
+			for _, reward := range api.GetBlockRewardByNumber(context.Background(), rpc.BlockNumber(block.NumberU64())) {
+				powReward.Add(powReward, reward.ToInt())
+			}
+			posReward := new(big.Int)
+			var shareSize, sharePrice *ChartEntry
+			if state, err := chain.StateAt(header); err != nil {
+				log.Debug("Dashboard failed to open block state", "number", block.NumberU64(), "err", err)
+			} else {
+				stakeState := stake.NewStakeState(state)
+				if msg.Head.Votes > 0 {
+					solo, total := stakeState.StakeCurrentReward(block.Number())
+					for _, votes := range [][]types.HeaderVote{header.CurrentVotes, header.ParentVotes} {
+						for _, vote := range votes {
+							if vote.IsPool {
+								posReward.Add(posReward, total)
+							} else {
+								posReward.Add(posReward, solo)
+							}
+						}
+					}
+				}
+				shareSize = &ChartEntry{
+					Time:  now,
+					Value: float64(stakeState.ShareSize()),
+				}
+				sharePrice = &ChartEntry{
+					Time:  now,
+					Value: toSero(stakeState.CurrentPrice()),
+				}
+			}
+			voteRate := &ChartEntry{
+				Time:  now,
+				Value: recentVoteRate(chain.GetHeaderByNumber, db.sero.Voter().SignedVotes, block.NumberU64()),
+			}
+			pending, _ := db.sero.TxPool().Stats()
+			home := &HomeMessage{
+				Head:     msg.Head,
+				Peers:    db.server.PeerCount(),
+				Pending:  pending,
+				Syncing:  db.sero.Downloader().Synchronising(),
+				VoteRate: voteRate.Value,
+			}
+			msg.BlockTime = ChartEntries{blockTime}
+			msg.PowReward = ChartEntries{{Time: now, Value: toSero(powReward)}}
+			msg.PosReward = ChartEntries{{Time: now, Value: toSero(posReward)}}
+			msg.VoteRate = ChartEntries{voteRate}
+			if shareSize != nil {
+				msg.SharePoolSize = ChartEntries{shareSize}
+				msg.SharePrice = ChartEntries{sharePrice}
+			}
+
+			hist := db.history.Chain
+			db.lock.Lock()
+			db.history.Home = home
+			hist.Head = msg.Head
+			hist.BlockTime = append(hist.BlockTime[1:], msg.BlockTime...)
+			hist.PowReward = append(hist.PowReward[1:], msg.PowReward...)
+			hist.PosReward = append(hist.PosReward[1:], msg.PosReward...)
+			hist.VoteRate = append(hist.VoteRate[1:], msg.VoteRate...)
+			if shareSize != nil {
+				hist.SharePoolSize = append(hist.SharePoolSize[1:], msg.SharePoolSize...)
+				hist.SharePrice = append(hist.SharePrice[1:], msg.SharePrice...)
+			}
+			db.lock.Unlock()
+
+			db.sendToAll(&Message{Home: home, Chain: msg})
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dashboard
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+)
+
+func TestToSero(t *testing.T) {
+	tests := []struct {
+		amount *big.Int
+		want   float64
+	}{
+		{big.NewInt(0), 0},
+		{new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil), 1},
+		{new(big.Int).Mul(big.NewInt(15), new(big.Int).Exp(big.NewInt(10), big.NewInt(17), nil)), 1.5},
+	}
+	for _, tt := range tests {
+		if have := toSero(tt.amount); have != tt.want {
+			t.Errorf("%v: have %v, want %v", tt.amount, have, tt.want)
+		}
+	}
+}
+
+// Tests that the vote rate is taken over the recent blocks, counting the votes
+// included in the block voted for and in its child.
+func TestRecentVoteRate(t *testing.T) {
+	var (
+		a = types.HeaderVote{Id: common.Hash{1}}
+		b = types.HeaderVote{Id: common.Hash{2}}
+		p = types.HeaderVote{Id: common.Hash{1}, IsPool: true}
+	)
+	headers := make(map[uint64]*types.Header)
+	for n := uint64(0); n <= 300; n++ {
+		headers[n] = &types.Header{Number: new(big.Int).SetUint64(n)}
+	}
+	signed := map[uint64][]types.HeaderVote{
+		10:  {a}, // Outside the window of head 300
+		200: {a, b},
+		201: {a},
+		202: {p},
+		299: {b},
+	}
+	headers[10].CurrentVotes = []types.HeaderVote{a}
+	headers[200].CurrentVotes = []types.HeaderVote{a}
+	headers[201].ParentVotes = []types.HeaderVote{b}
+	headers[202].CurrentVotes = []types.HeaderVote{a} // Solo vote doesn't count for the pool one
+	headers[300].ParentVotes = []types.HeaderVote{b}
+
+	header := func(n uint64) *types.Header { return headers[n] }
+	votes := func(n uint64) []types.HeaderVote { return signed[n] }
+
+	// 200 a, b and 299 b are included, 201 a and 202 p aren't
+	if have, want := recentVoteRate(header, votes, 300), 60.0; have != want {
+		t.Fatalf("vote rate mismatch: have %v, want %v", have, want)
+	}
+	// Only the votes of block 10 are in the window
+	if have, want := recentVoteRate(header, votes, 100), 100.0; have != want {
+		t.Fatalf("vote rate mismatch: have %v, want %v", have, want)
+	}
+	if have := recentVoteRate(header, func(uint64) []types.HeaderVote { return nil }, 300); have != 0 {
+		t.Fatalf("vote rate without votes: have %v, want 0", have)
+	}
+}
@@ -41,6 +41,7 @@ import (
 	"github.com/sero-cash/go-sero/p2p"
 	"github.com/sero-cash/go-sero/params"
 	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/sero"
 	"golang.org/x/net/websocket"
 )
 
@@ -53,6 +54,9 @@ const (
 	systemCPUSampleLimit      = 200 // Maximum number of system cpu data samples
 	diskReadSampleLimit       = 200 // Maximum number of disk read data samples
 	diskWriteSampleLimit      = 200 // Maximum number of disk write data samples
+	chainSampleLimit          = 200 // Maximum number of chain data samples
+	txPoolSampleLimit         = 200 // Maximum number of tx pool data samples
+	networkSampleLimit        = 200 // Maximum number of network data samples
 )
 
 var nextID uint32 // Next connection id
@@ -68,6 +72,9 @@ type Dashboard struct {
 
 	logdir string
 
+	sero   *sero.Sero  // Full node to collect the chain data of, nil if not running
+	server *p2p.Server // Peer-to-peer server to collect the network data of
+
 	quit chan chan error // Channel used for graceful exit
 	wg   sync.WaitGroup
 }
@@ -80,7 +87,7 @@ type client struct {
 }
 
 // New creates a new dashboard instance with the given configuration.
-func New(config *Config, commit string, logdir string) *Dashboard {
+func New(config *Config, commit string, logdir string, sero *sero.Sero) *Dashboard {
 	now := time.Now()
 	versionMeta := ""
 	if len(params.VersionMeta) > 0 {
@@ -105,8 +112,27 @@ func New(config *Config, commit string, logdir string) *Dashboard {
 				DiskRead:       emptyChartEntries(now, diskReadSampleLimit, config.Refresh),
 				DiskWrite:      emptyChartEntries(now, diskWriteSampleLimit, config.Refresh),
 			},
+			Chain: &ChainMessage{
+				BlockTime:     emptyChartEntries(now, chainSampleLimit, config.Refresh),
+				PowReward:     emptyChartEntries(now, chainSampleLimit, config.Refresh),
+				PosReward:     emptyChartEntries(now, chainSampleLimit, config.Refresh),
+				SharePoolSize: emptyChartEntries(now, chainSampleLimit, config.Refresh),
+				SharePrice:    emptyChartEntries(now, chainSampleLimit, config.Refresh),
+				VoteRate:      emptyChartEntries(now, chainSampleLimit, config.Refresh),
+			},
+			TxPool: &TxPoolMessage{
+				Pending:    emptyChartEntries(now, txPoolSampleLimit, config.Refresh),
+				Queued:     emptyChartEntries(now, txPoolSampleLimit, config.Refresh),
+				VerifyFail: emptyChartEntries(now, txPoolSampleLimit, config.Refresh),
+			},
+			Network: &NetworkMessage{
+				Peers:   emptyChartEntries(now, networkSampleLimit, config.Refresh),
+				Ingress: make(map[string]ChartEntries),
+				Egress:  make(map[string]ChartEntries),
+			},
 		},
 		logdir: logdir,
+		sero:   sero,
 	}
 }
 
@@ -132,8 +158,15 @@ func (db *Dashboard) APIs() []rpc.API { return nil }
 func (db *Dashboard) Start(server *p2p.Server) error {
 	log.Info("Starting dashboard")
 
-	db.wg.Add(2)
+	db.server = server
+	now := time.Now()
+	for _, proto := range server.Protocols {
+		db.history.Network.Ingress[proto.Name] = emptyChartEntries(now, networkSampleLimit, db.config.Refresh)
+		db.history.Network.Egress[proto.Name] = emptyChartEntries(now, networkSampleLimit, db.config.Refresh)
+	}
+	db.wg.Add(3)
 	go db.collectData()
+	go db.collectChainData()
 	go db.streamLogs()
 
 	http.HandleFunc("/", db.webHandler)
@@ -160,7 +193,7 @@ func (db *Dashboard) Stop() error {
 	}
 	// Close the collectors.
 	errc := make(chan error, 1)
-	for i := 0; i < 2; i++ {
+	for i := 0; i < 3; i++ {
 		db.quit <- errc
 		if err := <-errc; err != nil {
 			errs = append(errs, err)
@@ -293,9 +326,28 @@ func (db *Dashboard) collectData() {
 		prevDiskRead       = collectDiskRead()
 		prevDiskWrite      = collectDiskWrite()
 
+		collectVerify       = meterCollector("txpool/verify")
+		collectVerifyFailed = meterCollector("txpool/verify/failed")
+
+		prevVerify       = collectVerify()
+		prevVerifyFailed = collectVerifyFailed()
+
 		frequency = float64(db.config.Refresh / time.Second)
 		numCPU    = float64(runtime.NumCPU())
 	)
+	// The sub-protocols meter their traffic under p2p/<protocol>/.
+	var (
+		collectProtocolIngress = make(map[string]func() int64)
+		collectProtocolEgress  = make(map[string]func() int64)
+		prevProtocolIngress    = make(map[string]int64)
+		prevProtocolEgress     = make(map[string]int64)
+	)
+	for name := range db.history.Network.Ingress {
+		ingress := metrics.GetOrRegisterMeter("p2p/"+name+"/InboundTraffic", nil)
+		egress := metrics.GetOrRegisterMeter("p2p/"+name+"/OutboundTraffic", nil)
+		collectProtocolIngress[name], collectProtocolEgress[name] = ingress.Count, egress.Count
+		prevProtocolIngress[name], prevProtocolEgress[name] = ingress.Count(), egress.Count()
+	}
 
 	for {
 		select {
@@ -361,7 +413,53 @@ func (db *Dashboard) collectData() {
 				Time:  now,
 				Value: float64(deltaDiskWrite) / frequency,
 			}
+			curVerify, curVerifyFailed := collectVerify(), collectVerifyFailed()
+			verifyFail := &ChartEntry{
+				Time: now,
+			}
+			if curVerify > prevVerify {
+				verifyFail.Value = float64(curVerifyFailed-prevVerifyFailed) / float64(curVerify-prevVerify) * 100
+			}
+			prevVerify, prevVerifyFailed = curVerify, curVerifyFailed
+
+			var pending, queued *ChartEntry
+			if db.sero != nil {
+				p, q := db.sero.TxPool().Stats()
+				pending = &ChartEntry{
+					Time:  now,
+					Value: float64(p),
+				}
+				queued = &ChartEntry{
+					Time:  now,
+					Value: float64(q),
+				}
+			}
+			peers := &ChartEntry{
+				Time:  now,
+				Value: float64(db.server.PeerCount()),
+			}
+			protocolIngress := make(map[string]ChartEntries)
+			protocolEgress := make(map[string]ChartEntries)
+			for name, collect := range collectProtocolIngress {
+				cur := collect()
+				protocolIngress[name] = ChartEntries{{
+					Time:  now,
+					Value: float64(cur-prevProtocolIngress[name]) / frequency,
+				}}
+				prevProtocolIngress[name] = cur
+			}
+			for name, collect := range collectProtocolEgress {
+				cur := collect()
+				protocolEgress[name] = ChartEntries{{
+					Time:  now,
+					Value: float64(cur-prevProtocolEgress[name]) / frequency,
+				}}
+				prevProtocolEgress[name] = cur
+			}
+
 			sys := db.history.System
+			pool := db.history.TxPool
+			network := db.history.Network
 			db.lock.Lock()
 			sys.ActiveMemory = append(sys.ActiveMemory[1:], activeMemory)
 			sys.VirtualMemory = append(sys.VirtualMemory[1:], virtualMemory)
@@ -371,8 +469,28 @@ func (db *Dashboard) collectData() {
 			sys.SystemCPU = append(sys.SystemCPU[1:], systemCPU)
 			sys.DiskRead = append(sys.DiskRead[1:], diskRead)
 			sys.DiskWrite = append(sys.DiskWrite[1:], diskWrite)
+			pool.VerifyFail = append(pool.VerifyFail[1:], verifyFail)
+			if db.sero != nil {
+				pool.Pending = append(pool.Pending[1:], pending)
+				pool.Queued = append(pool.Queued[1:], queued)
+			}
+			network.Peers = append(network.Peers[1:], peers)
+			for name, entries := range protocolIngress {
+				network.Ingress[name] = append(network.Ingress[name][1:], entries...)
+			}
+			for name, entries := range protocolEgress {
+				network.Egress[name] = append(network.Egress[name][1:], entries...)
+			}
 			db.lock.Unlock()
 
+			txPool := &TxPoolMessage{
+				VerifyFail: ChartEntries{verifyFail},
+			}
+			if db.sero != nil {
+				txPool.Pending = ChartEntries{pending}
+				txPool.Queued = ChartEntries{queued}
+			}
+
 			db.sendToAll(&Message{
 				System: &SystemMessage{
 					ActiveMemory:   ChartEntries{activeMemory},
@@ -384,6 +502,12 @@ func (db *Dashboard) collectData() {
 					DiskRead:       ChartEntries{diskRead},
 					DiskWrite:      ChartEntries{diskWrite},
 				},
+				TxPool: txPool,
+				Network: &NetworkMessage{
+					Peers:   ChartEntries{peers},
+					Ingress: protocolIngress,
+					Egress:  protocolEgress,
+				},
 			})
 		}
 	}
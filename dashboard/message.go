@@ -19,6 +19,8 @@ package dashboard
 import (
 	"encoding/json"
 	"time"
+
+	"github.com/sero-cash/go-sero/common"
 )
 
 type Message struct {
@@ -43,20 +45,45 @@ type GeneralMessage struct {
 	Commit  string `json:"commit,omitempty"`
 }
 
+// HomeMessage contains the overview of the node, updated with every new head.
 type HomeMessage struct {
-	/* TODO (kurkomisi) */
+	Head     *ChainHead `json:"head,omitempty"`
+	Peers    int        `json:"peers"`
+	Pending  int        `json:"pending"`  // Number of pending transactions
+	Syncing  bool       `json:"syncing"`  // Whether the node is synchronising
+	VoteRate float64    `json:"voteRate"` // Percentage of the node's recent votes included in the chain
 }
 
 type ChainMessage struct {
-	/* TODO (kurkomisi) */
+	Head          *ChainHead   `json:"head,omitempty"`
+	BlockTime     ChartEntries `json:"blockTime,omitempty"`     // Seconds since the parent block
+	PowReward     ChartEntries `json:"powReward,omitempty"`     // Mining reward of the block in SERO
+	PosReward     ChartEntries `json:"posReward,omitempty"`     // Staking reward of the block in SERO
+	SharePoolSize ChartEntries `json:"sharePoolSize,omitempty"` // Number of shares in the pool
+	SharePrice    ChartEntries `json:"sharePrice,omitempty"`    // Price of the next share in SERO
+	VoteRate      ChartEntries `json:"voteRate,omitempty"`      // Percentage of the node's recent votes included in the chain
+}
+
+// ChainHead contains the attributes of the current head block.
+type ChainHead struct {
+	Number     uint64      `json:"number"`
+	Hash       common.Hash `json:"hash"`
+	Time       uint64      `json:"time"`
+	Difficulty string      `json:"difficulty"`
+	Txs        int         `json:"txs"`
+	Votes      int         `json:"votes"`
 }
 
 type TxPoolMessage struct {
-	/* TODO (kurkomisi) */
+	Pending    ChartEntries `json:"pending,omitempty"`
+	Queued     ChartEntries `json:"queued,omitempty"`
+	VerifyFail ChartEntries `json:"verifyFail,omitempty"` // Percentage of the failed proof verifications
 }
 
 type NetworkMessage struct {
-	/* TODO (kurkomisi) */
+	Peers   ChartEntries            `json:"peers,omitempty"`
+	Ingress map[string]ChartEntries `json:"ingress,omitempty"` // Inbound traffic by protocol
+	Egress  map[string]ChartEntries `json:"egress,omitempty"`  // Outbound traffic by protocol
 }
 
 type SystemMessage struct {
@@ -69,6 +69,7 @@ import (
 	"github.com/sero-cash/go-sero/params"
 	"github.com/sero-cash/go-sero/rlp"
 	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/account"
 	"github.com/sero-cash/go-sero/zero/txs/stx"
 	ztx "github.com/sero-cash/go-sero/zero/txs/tx"
 	"github.com/syndtr/goleveldb/leveldb"
@@ -1127,19 +1128,62 @@ type CallArgs struct {
 	Tkt         *common.Hash              `json:"tkt"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+// OverrideAccount is the state of a contract replaced for a simulated call.
+// State replaces the whole storage of the contract while StateDiff replaces
+// the given slots only. Tickets are added to the ones of the contract.
+type OverrideAccount struct {
+	Code      *hexutil.Bytes                `json:"code"`
+	Balance   map[Smbol]*hexutil.Big        `json:"balance"`
+	Tickets   map[Smbol][]common.Hash       `json:"tickets"`
+	State     *map[common.Hash]common.Hash `json:"state"`
+	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
+}
 
-	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+// StateOverride is the set of contracts whose state is replaced for a
+// simulated call.
+type StateOverride map[ContractAddress]OverrideAccount
 
-	if state == nil || err != nil {
-		return nil, 0, false, err
+// Apply overrides the given state with the accounts of the set.
+func (diff *StateOverride) Apply(state *state.StateDB) error {
+	if diff == nil {
+		return nil
+	}
+	for contractAddr, account := range *diff {
+		addr := common.BytesToAddress(contractAddr[:])
+		if account.Code != nil {
+			state.SetCode(addr, *account.Code)
+		}
+		for currency, balance := range account.Balance {
+			state.SetBalance(addr, string(currency), balance.ToInt())
+		}
+		for category, values := range account.Tickets {
+			for _, value := range values {
+				state.AddTicket(addr, string(category), value)
+			}
+		}
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", base58.Encode(contractAddr[:]))
+		}
+		if account.State != nil {
+			state.SetStorage(addr, *account.State)
+		}
+		if account.StateDiff != nil {
+			for key, value := range *account.StateDiff {
+				state.SetState(addr, key, value)
+			}
+		}
 	}
+	return nil
+}
+
+// ToMessage converts the arguments of a call into a message executed on top
+// of the given state.
+func (args *CallArgs) ToMessage(b Backend, state *state.StateDB) (types.Message, error) {
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if args.From == nil {
 		addr = &address.MixBase58Adrress{}
-		if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
+		if wallets := b.AccountManager().Wallets(); len(wallets) > 0 {
 			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
 				fromAddr := accounts[0].Address
 				*addr = fromAddr[:]
@@ -1202,7 +1246,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if args.To != nil && state.IsContract(common.BytesToAddress(args.To[:])) && args.GasCurrency.IsNotSero() {
 		m, d := state.GetTokenRate(common.BytesToAddress(args.To[:]), string(args.GasCurrency))
 		if m.Sign() == 0 || d.Sign() == 0 {
-			return nil, 0, false, errors.New("gasCurrency must be SERO or nil")
+			return types.Message{}, errors.New("gasCurrency must be SERO or nil")
 		}
 		state.AddBalance(common.BytesToAddress(args.To[:]), "SERO", fee)
 		fee = new(big.Int).Div(fee.Mul(fee, m), d)
@@ -1221,7 +1265,24 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 		fromPkr = superzk.Pk2PKr(&fromPk, rand.ToUint256().NewRef())
 	}
 
-	msg := types.NewMessage(common.BytesToAddress(fromPkr[:]), to, 0, asset, feeToken, gasPrice, args.Data)
+	return types.NewMessage(common.BytesToAddress(fromPkr[:]), to, 0, asset, feeToken, gasPrice, args.Data), nil
+}
+
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+
+	if state == nil || err != nil {
+		return nil, 0, false, err
+	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, 0, false, err
+	}
+	msg, err := args.ToMessage(s.b, state)
+	if err != nil {
+		return nil, 0, false, err
+	}
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -1259,16 +1320,18 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 
 }
 
-// Call executes the given transaction on the state for the given block number.
+// Call executes the given transaction on the state for the given block number,
+// with the state of some contracts optionally overridden.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride) (hexutil.Bytes, error) {
+	result, _, _, err := s.doCall(ctx, args, blockNr, overrides, vm.Config{}, 5*time.Second)
 	return (hexutil.Bytes)(result), err
 }
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the
-// given transaction against the current pending block.
-func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
+// given transaction against the current pending block, with the state of some
+// contracts optionally overridden.
+func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs, overrides *StateOverride) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
 		lo  uint64 = params.TxGas - 1
@@ -1291,7 +1354,7 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	executable := func(gas uint64) bool {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.LatestBlockNumber, vm.Config{}, 0)
+		_, _, failed, err := s.doCall(ctx, args, rpc.LatestBlockNumber, overrides, vm.Config{}, 0)
 		if err != nil || failed {
 			return false
 		}
@@ -1347,7 +1410,7 @@ func (s *PublicBlockChainAPI) GetDecimal(ctx context.Context, tokenName string)
 			continue
 		}
 		callArgs.Data = data
-		res, _, failed, err := s.doCall(ctx, callArgs, rpc.LatestBlockNumber, vm.Config{}, 0)
+		res, _, failed, err := s.doCall(ctx, callArgs, rpc.LatestBlockNumber, nil, vm.Config{}, 0)
 		if err != nil || failed {
 			log.Info("SRC20Decimal", "docall", err)
 			continue
@@ -1746,10 +1809,16 @@ type SendTxArgs struct {
 	Category    Smbol                    `json:"catg"`
 	Tkt         *common.Hash             `json:"tkt"`
 	Memo        string                   `json:"Memo"`
+	URI         string                   `json:"uri"` // sero: payment URI filling the fields above
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
 func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
+	if args.URI != "" {
+		if err := args.applyPaymentURI(ctx, b); err != nil {
+			return err
+		}
+	}
 	if args.Gas == nil {
 		args.Gas = new(hexutil.Uint64)
 		*(*uint64)(args.Gas) = 90000
@@ -1760,9 +1829,8 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	}
 
 	if strings.Trim(args.Memo, "") != "" {
-		b := []byte(args.Memo)
-		if len(b) > 64 {
-			return errors.New("args memo is too long,it's limited 64 bytes")
+		if _, err := account.EncodeMemo(args.Memo); err != nil {
+			return err
 		}
 	}
 
@@ -1905,6 +1973,10 @@ func (args *SendTxArgs) toTxParam(state *state.StateDB, fromAccount accounts.Acc
 	} else {
 		refundPkr = args.From.ToPkr()
 		receptions := []prepare.Reception{{Addr: args.To.ToPkr(false), Asset: asset}}
+		// Only the payments requested by an uri carry the memo to the output
+		if args.URI != "" {
+			receptions[0].Memo = stringToUint512(args.Memo)
+		}
 		txParam.Receptions = receptions
 	}
 	feeAsset := assets.Token{
@@ -1921,13 +1993,9 @@ func defaultFee(gasPrice *hexutil.Big, gas *hexutil.Uint64) *big.Int {
 	return new(big.Int).Mul(((*big.Int)(gasPrice)), new(big.Int).SetUint64(uint64(*gas)))
 }
 
+// stringToUint512 encodes a memo checked by the args, typed when it fits.
 func stringToUint512(str string) c_type.Uint512 {
-	var ret c_type.Uint512
-	b := []byte(str)
-	if len(b) > len(ret) {
-		b = b[len(b)-len(ret):]
-	}
-	copy(ret[len(ret)-len(b):], b)
+	ret, _ := account.EncodeMemo(str)
 	return ret
 }
 
@@ -1975,6 +2043,9 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 }
 
 func (s *PublicTransactionPoolAPI) GenTx(ctx context.Context, param GenTxArgs) (*txtool.GTxParam, error) {
+	if err := param.applyPaymentURIs(ctx, s.b); err != nil {
+		return nil, err
+	}
 	if err := param.check(); err != nil {
 		return nil, err
 	}
@@ -10,6 +10,7 @@ import (
 	"github.com/sero-cash/go-sero/zero/txtool/flight"
 
 	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/txtool/bundle"
 	"github.com/sero-cash/go-sero/zero/utils"
 
 	"github.com/sero-cash/go-sero/core/types"
@@ -22,11 +23,14 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/p2p/discover"
 
+	"github.com/sero-cash/go-czero-import/c_superzk"
 	"github.com/sero-cash/go-czero-import/c_type"
 	"github.com/sero-cash/go-czero-import/seroparam"
 	"github.com/sero-cash/go-czero-import/superzk"
 
+	"github.com/sero-cash/go-sero/zero/account"
 	"github.com/sero-cash/go-sero/zero/wallet/exchange"
 
 	"github.com/sero-cash/go-sero/common"
@@ -58,16 +62,34 @@ func (s *PublicExchangeAPI) GetPkSynced(ctx context.Context, pk *address.PKAddre
 	numbers := exchangeInstance.GetUtxoNum(pk.ToUint512())
 
 	// Otherwise gather the block sync stats
-	return map[string]interface{}{
+	ret := map[string]interface{}{
 		"currentPKBlock": currentPKBlock,
 		"confirmedBlock": seroparam.DefaultConfirmedBlock(),
 		"currentBlock":   progress.CurrentBlock,
 		"highestBlock":   progress.HighestBlock,
 		"utxoCount":      numbers,
-	}, nil
+	}
+	if status, ok := exchangeInstance.GetRescan(pk.ToUint512()); ok {
+		ret["rescanFrom"] = status.From
+		ret["rescanTarget"] = status.Target
+	}
+	return ret, nil
 
 }
 
+// Rescan removes the index of the account from the given block on and fetches
+// the blocks again, the progress is reported by GetPkSynced.
+func (s *PublicExchangeAPI) Rescan(ctx context.Context, pk address.PKAddress, fromBlock hexutil.Uint64) (bool, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return false, errors.New("exchange mode no start")
+	}
+	if err := exchangeInstance.Rescan(pk.ToUint512(), uint64(fromBlock)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (s *PublicExchangeAPI) GetPkr(ctx context.Context, pk address.PKAddress, index *c_type.Uint256) (pkrAdd PKrAddress, e error) {
 
 	pkr, err := s.b.GetPkr(pk.ToUint512().NewRef(), index)
@@ -102,13 +124,59 @@ func (s *PublicExchangeAPI) GetBalances(ctx context.Context, pk address.PKAddres
 	}
 	ret["tkn"] = result
 	ret["tkt"] = tickets
+	// The unconfirmed amounts are reported apart, the balances are left as is
+	if pending, _, err := s.b.GetPending(pk.ToUint512()); err == nil {
+		ret["pending"] = pendingBalances(pending)
+	}
 	return ret
 }
 
+type PendingBalance struct {
+	In            *Big
+	Out           *Big
+	Confirmations uint64
+}
+
+type PendingRecord struct {
+	Record
+	Spent         bool
+	InPool        bool
+	Confirmations uint64
+}
+
+func pendingBalances(balances map[string]*exchange.PendingBalance) map[string]PendingBalance {
+	result := make(map[string]PendingBalance)
+	for currency, balance := range balances {
+		result[currency] = PendingBalance{In: (*Big)(balance.In), Out: (*Big)(balance.Out), Confirmations: balance.Confirmations}
+	}
+	return result
+}
+
+// GetPending returns the unconfirmed incoming and outgoing amounts of the
+// account by currency, with the outputs they come from.
+func (s *PublicExchangeAPI) GetPending(ctx context.Context, pk address.PKAddress) (map[string]interface{}, error) {
+	balances, utxos, err := s.b.GetPending(pk.ToUint512())
+	if err != nil {
+		return nil, err
+	}
+	records := []PendingRecord{}
+	for _, utxo := range utxos {
+		if utxo.Asset.Tkn != nil {
+			record := Record{Pkr: pkrToPKrAddress(utxo.Pkr), Root: utxo.Root, TxHash: utxo.TxHash, Nil: utxo.Nil, Num: utxo.Num, Currency: common.BytesToString(utxo.Asset.Tkn.Currency[:]), Value: (*Big)(utxo.Asset.Tkn.Value.ToIntRef())}
+			records = append(records, PendingRecord{Record: record, Spent: utxo.Spent, InPool: utxo.InPool, Confirmations: utxo.Confirmations})
+		}
+	}
+	return map[string]interface{}{
+		"balances": pendingBalances(balances),
+		"records":  records,
+	}, nil
+}
+
 type ReceptionArgs struct {
 	Addr     MixAdrress
 	Currency Smbol
 	Value    *Big
+	Memo     string
 }
 
 func MixAdrressToPkr(addr MixAdrress) c_type.PKr {
@@ -124,6 +192,9 @@ func MixAdrressToPkr(addr MixAdrress) c_type.PKr {
 }
 
 func (s *PublicExchangeAPI) GenTx(ctx context.Context, param GenTxArgs) (*txtool.GTxParam, error) {
+	if err := param.applyPaymentURIs(ctx, s.b); err != nil {
+		return nil, err
+	}
 	if err := param.check(); err != nil {
 		return nil, err
 	}
@@ -131,7 +202,25 @@ func (s *PublicExchangeAPI) GenTx(ctx context.Context, param GenTxArgs) (*txtool
 	return s.b.GenTx(param.toTxParam())
 }
 
+// GenTxBundle generates a transaction like GenTx, bundled with a summary for
+// an air-gapped signer.
+func (s *PublicExchangeAPI) GenTxBundle(ctx context.Context, param GenTxArgs) (*bundle.Unsigned, error) {
+	txParam, err := s.GenTx(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+	unsigned, err := bundle.NewUnsigned(s.b.ChainConfig().ChainID, txParam)
+	if err != nil {
+		exchange.CurrentExchange().ClearTxParam(txParam)
+		return nil, err
+	}
+	return unsigned, nil
+}
+
 func (s *PublicExchangeAPI) GenTxWithSign(ctx context.Context, param GenTxArgs) (*txtool.GTx, error) {
+	if err := param.applyPaymentURIs(ctx, s.b); err != nil {
+		return nil, err
+	}
 	if err := param.check(); err != nil {
 		return nil, err
 	}
@@ -158,6 +247,21 @@ type Record struct {
 	Num      uint64
 	Currency string
 	Value    *Big
+	Memo     string `json:",omitempty"`
+}
+
+// newRecord returns the record of a received token output.
+func newRecord(utxo *exchange.Utxo) Record {
+	return Record{
+		Pkr:      pkrToPKrAddress(utxo.Pkr),
+		Root:     utxo.Root,
+		TxHash:   utxo.TxHash,
+		Nil:      utxo.Nil,
+		Num:      utxo.Num,
+		Currency: common.BytesToString(utxo.Asset.Tkn.Currency[:]),
+		Value:    (*Big)(utxo.Asset.Tkn.Value.ToIntRef()),
+		Memo:     utxo.Memo.String(),
+	}
 }
 
 func (s *PublicExchangeAPI) GetTx(ctx context.Context, txHash c_type.Uint256) (map[string]interface{}, error) {
@@ -199,7 +303,7 @@ func (s *PublicExchangeAPI) GetTx(ctx context.Context, txHash c_type.Uint256) (m
 	records := []Record{}
 	for _, utxo := range utxos {
 		if utxo.Asset.Tkn != nil {
-			records = append(records, Record{Pkr: pkrToPKrAddress(utxo.Pkr), Root: utxo.Root, TxHash: utxo.TxHash, Nil: utxo.Nil, Num: utxo.Num, Currency: common.BytesToString(utxo.Asset.Tkn.Currency[:]), Value: (*Big)(utxo.Asset.Tkn.Value.ToIntRef())})
+			records = append(records, newRecord(&utxo))
 		}
 	}
 	outs := []map[string]interface{}{}
@@ -267,13 +371,34 @@ func (s *PublicExchangeAPI) GetRecords(ctx context.Context, begin, end uint64, a
 
 	for _, utxo := range utxos {
 		if utxo.Asset.Tkn != nil {
-			records = append(records, Record{Pkr: pkrToPKrAddress(utxo.Pkr), Root: utxo.Root, TxHash: utxo.TxHash, Nil: utxo.Nil, Num: utxo.Num, Currency: common.BytesToString(utxo.Asset.Tkn.Currency[:]), Value: (*Big)(utxo.Asset.Tkn.Value.ToIntRef())})
+			records = append(records, newRecord(&utxo))
 		}
 	}
 
 	return
 }
 
+// GetRecordsByMemo returns the token outputs received from begin to end with
+// the given memo: a text, "inv:<hex>" for an invoice id or "ref:<number>" for a
+// reference. The memos written as plain text match the typed text ones.
+func (s *PublicExchangeAPI) GetRecordsByMemo(ctx context.Context, memo string, begin, end uint64) (records []Record, err error) {
+	m, err := account.ParseMemo(memo)
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := s.b.GetRecordsByMemo(m, begin, end)
+	if err != nil {
+		return nil, err
+	}
+	records = []Record{}
+	for _, utxo := range utxos {
+		if utxo.Asset.Tkn != nil {
+			records = append(records, newRecord(&utxo))
+		}
+	}
+	return
+}
+
 type MergeArgs struct {
 	From     address.PKAddress
 	To       *PKrAddress
@@ -399,6 +524,14 @@ func (s *PublicExchangeAPI) CommitTx(ctx context.Context, args *txtool.GTx) erro
 	return s.b.CommitTx(args)
 }
 
+// CommitTxBundle commits the transaction of a bundle signed offline.
+func (s *PublicExchangeAPI) CommitTxBundle(ctx context.Context, signed *bundle.Signed) (c_type.Uint256, error) {
+	if err := signed.Verify(s.b.ChainConfig().ChainID); err != nil {
+		return c_type.Uint256{}, err
+	}
+	return signed.Tx.Hash, s.CommitTx(ctx, &signed.Tx)
+}
+
 func (s *PublicExchangeAPI) ClearUsedFlag(ctx context.Context, pk address.PKAddress) (count int, e error) {
 	exchangeInstance := exchange.CurrentExchange()
 	if exchangeInstance == nil {
@@ -591,3 +724,146 @@ func (s *PublicExchangeAPI) SetBalancePkr(ctx context.Context, pkr PKrAddress) e
 func (s *PublicExchangeAPI) IgnorePkrUtxos(ctx context.Context, pkr PKrAddress, ignore bool) (utxos []exchange.Utxo, e error) {
 	return exchange.CurrentExchange().IgnorePkrUtxos(*pkr.ToPKr(), ignore)
 }
+
+type WatchAccount struct {
+	PK    address.PKAddress
+	TK    address.TKAddress
+	Label string
+	At    hexutil.Uint64
+}
+
+type AuditBundle struct {
+	Version   uint
+	Accounts  []WatchAccount
+	Checksum  common.Hash
+	Signature hexutil.Bytes
+	Signer    discover.NodeID
+}
+
+func toWatchAccount(watch *exchange.WatchAccount) (ret WatchAccount) {
+	copy(ret.PK[:], watch.Pk[:])
+	copy(ret.TK[:], watch.Tk[:])
+	ret.Label = watch.Label
+	ret.At = hexutil.Uint64(watch.At)
+	return
+}
+
+// AddWatchAccount makes the exchange index the outputs of the account of the
+// TK from the given block, without importing it in the keystore.
+func (s *PublicExchangeAPI) AddWatchAccount(ctx context.Context, tk address.TKAddress, label string, at *hexutil.Uint64) (pkAddr address.PKAddress, e error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		e = errors.New("exchange mode no start")
+		return
+	}
+	var start uint64
+	if at != nil {
+		start = uint64(*at)
+	}
+	if c_superzk.IsFlagSet(tk[:]) && start < seroparam.SIP5() {
+		start = seroparam.SIP5()
+	}
+	pk, err := exchangeInstance.AddWatchAccount(tk.ToTk(), label, start)
+	if err != nil {
+		e = err
+		return
+	}
+	copy(pkAddr[:], pk[:])
+	return
+}
+
+func (s *PublicExchangeAPI) RemoveWatchAccount(ctx context.Context, pk address.PKAddress) (bool, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return false, errors.New("exchange mode no start")
+	}
+	if err := exchangeInstance.RemoveWatchAccount(pk.ToUint512()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *PublicExchangeAPI) SetWatchLabel(ctx context.Context, pk address.PKAddress, label string) (bool, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return false, errors.New("exchange mode no start")
+	}
+	if err := exchangeInstance.SetWatchLabel(pk.ToUint512(), label); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *PublicExchangeAPI) GetWatchAccounts(ctx context.Context) ([]WatchAccount, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return nil, errors.New("exchange mode no start")
+	}
+	watches, err := exchangeInstance.GetWatchAccounts()
+	if err != nil {
+		return nil, err
+	}
+	result := []WatchAccount{}
+	for i := range watches {
+		result = append(result, toWatchAccount(&watches[i]))
+	}
+	return result, nil
+}
+
+// ExportAuditBundle returns the TKs and the metadata of the given watch-only
+// accounts, or of all of them, for an audit node to index them. The bundle is
+// signed with the node key.
+func (s *PublicExchangeAPI) ExportAuditBundle(ctx context.Context, pks []address.PKAddress) (*AuditBundle, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return nil, errors.New("exchange mode no start")
+	}
+	keys := []c_type.Uint512{}
+	for _, pk := range pks {
+		keys = append(keys, pk.ToUint512())
+	}
+	bundle, err := exchangeInstance.ExportAuditBundle(keys)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := bundle.Signer()
+	if err != nil {
+		return nil, err
+	}
+	result := &AuditBundle{Version: bundle.Version, Accounts: []WatchAccount{}, Checksum: bundle.Checksum, Signature: bundle.Signature, Signer: signer}
+	for i := range bundle.Accounts {
+		result.Accounts = append(result.Accounts, toWatchAccount(&bundle.Accounts[i]))
+	}
+	return result, nil
+}
+
+// ImportAuditBundle adds the accounts of an audit bundle signed by the given
+// node as watch-only ones and returns the ones which were not tracked yet. The
+// signer is the node ID of the exporting exchange, it is not taken from the
+// bundle.
+func (s *PublicExchangeAPI) ImportAuditBundle(ctx context.Context, bundle AuditBundle, signer discover.NodeID) ([]address.PKAddress, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return nil, errors.New("exchange mode no start")
+	}
+	ebundle := exchange.AuditBundle{Version: bundle.Version, Checksum: bundle.Checksum, Signature: bundle.Signature}
+	for _, watch := range bundle.Accounts {
+		ebundle.Accounts = append(ebundle.Accounts, exchange.WatchAccount{
+			Pk:    watch.PK.ToUint512(),
+			Tk:    watch.TK.ToTk(),
+			Label: watch.Label,
+			At:    uint64(watch.At),
+		})
+	}
+	pks, err := exchangeInstance.ImportAuditBundle(&ebundle, signer)
+	if err != nil {
+		return nil, err
+	}
+	result := []address.PKAddress{}
+	for _, pk := range pks {
+		var pkAddr address.PKAddress
+		copy(pkAddr[:], pk[:])
+		result = append(result, pkAddr)
+	}
+	return result, nil
+}
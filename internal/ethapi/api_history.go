@@ -0,0 +1,122 @@
+package ethapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/address"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/zero/wallet/history"
+)
+
+// maxHistoryRecords is the number of records of a history page, unless a lower
+// limit is given.
+const maxHistoryRecords = 1000
+
+var historyKinds = map[uint8]string{
+	history.RecordIn:       "in",
+	history.RecordOut:      "out",
+	history.RecordFee:      "fee",
+	history.RecordContract: "contract",
+}
+
+type HistoryRecord struct {
+	Kind     string          `json:"kind"`
+	Num      hexutil.Uint64  `json:"blockNumber"`
+	TxHash   c_type.Uint256  `json:"txHash"`
+	Address  interface{}     `json:"address,omitempty"` // PKr of the out, or contract called
+	Root     *c_type.Uint256 `json:"root,omitempty"`
+	Currency string          `json:"currency,omitempty"`
+	Value    *Big            `json:"value,omitempty"`
+	Category string          `json:"category,omitempty"`
+	Ticket   *common.Hash    `json:"ticket,omitempty"`
+}
+
+// HistoryCursor is the position of the first record of a history page.
+type HistoryCursor struct {
+	Num   hexutil.Uint64 `json:"blockNumber"`
+	Index hexutil.Uint   `json:"index"`
+}
+
+// HistoryPage is a page of the history of an account, with the cursor of the
+// next page if any.
+type HistoryPage struct {
+	Records []HistoryRecord `json:"records"`
+	Next    *HistoryCursor  `json:"next"`
+}
+
+func newHistoryRecord(record *history.Record) HistoryRecord {
+	result := HistoryRecord{
+		Kind:   historyKinds[record.Kind],
+		Num:    hexutil.Uint64(record.Num),
+		TxHash: record.TxHash,
+	}
+	var addr common.Address
+	copy(addr[:], record.Pkr[:])
+	result.Address = toPeerAddress(addr)
+	if record.Root != (c_type.Uint256{}) {
+		root := record.Root
+		result.Root = &root
+	}
+	if tkn := record.Asset.Tkn; tkn != nil {
+		result.Currency = common.BytesToString(tkn.Currency[:])
+		result.Value = (*Big)(tkn.Value.ToIntRef())
+	}
+	if tkt := record.Asset.Tkt; tkt != nil {
+		ticket := common.BytesToHash(tkt.Value[:])
+		result.Category = common.BytesToString(tkt.Category[:])
+		result.Ticket = &ticket
+	}
+	return result
+}
+
+// accountHistory returns a page of the history of a wallet account from the
+// block begin, or the cursor if given, to the block end excluded.
+func accountHistory(b Backend, pk address.PKAddress, begin, end uint64, cursor *HistoryCursor, limit *hexutil.Uint) (*HistoryPage, error) {
+	if end < begin {
+		return nil, errors.New("end can not be lower than begin")
+	}
+	from := history.Cursor{Num: begin}
+	if cursor != nil {
+		if uint64(cursor.Num) < begin || uint64(cursor.Num) >= end {
+			return nil, errors.New("cursor out of the block range")
+		}
+		from = history.Cursor{Num: uint64(cursor.Num), Index: uint32(cursor.Index)}
+	}
+	count := maxHistoryRecords
+	if limit != nil && int(*limit) < count {
+		count = int(*limit)
+	}
+	if count == 0 {
+		return nil, errors.New("limit can not be zero")
+	}
+	records, next, err := b.GetAccountHistory(pk.ToUint512(), from, end, count)
+	if err != nil {
+		return nil, err
+	}
+	page := &HistoryPage{Records: []HistoryRecord{}}
+	for i := range records {
+		page.Records = append(page.Records, newHistoryRecord(&records[i]))
+	}
+	if next != nil {
+		page.Next = &HistoryCursor{Num: hexutil.Uint64(next.Num), Index: hexutil.Uint(next.Index)}
+	}
+	return page, nil
+}
+
+// GetAccountHistory returns the outs received and spent, the fees and the
+// contract calls of a wallet account from the block begin to the block end
+// excluded, by pages of at most limit records. The next page starts at the
+// cursor returned with the previous one. The history indexer must be started
+// with --history.
+func (s *PublicBlockChainAPI) GetAccountHistory(ctx context.Context, pk address.PKAddress, begin, end uint64, cursor *HistoryCursor, limit *hexutil.Uint) (*HistoryPage, error) {
+	return accountHistory(s.b, pk, begin, end, cursor, limit)
+}
+
+// GetHistory returns the history of a wallet account, see
+// sero_getAccountHistory.
+func (s *PrivateAccountAPI) GetHistory(ctx context.Context, pk address.PKAddress, begin, end uint64, cursor *HistoryCursor, limit *hexutil.Uint) (*HistoryPage, error) {
+	return accountHistory(s.b, pk, begin, end, cursor, limit)
+}
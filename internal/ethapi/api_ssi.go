@@ -44,6 +44,23 @@ func (s *PublicSSIAPI) CommitTx(ctx context.Context, txhash c_type.Uint256) (e e
 		e = err
 		return
 	} else {
-		return s.b.CommitTx(tx)
+		if e = s.b.CommitTx(tx); e != nil {
+			return
+		}
+		return ssi.SSI_Inst.Committed(txhash)
 	}
 }
+
+func (s *PublicSSIAPI) GetTxStatus(ctx context.Context, txhash c_type.Uint256) (*ssi.TxStatus, error) {
+	return ssi.SSI_Inst.GetTxStatus(txhash)
+}
+
+// ListTxs returns the status of the generated transactions, only of the ones
+// in the given state (generated, committed, mined or dropped) if it's set.
+func (s *PublicSSIAPI) ListTxs(ctx context.Context, state *string) ([]ssi.TxStatus, error) {
+	filter := ssi.TxState("")
+	if state != nil {
+		filter = ssi.TxState(*state)
+	}
+	return ssi.SSI_Inst.ListTxs(filter)
+}
@@ -828,3 +828,167 @@ func (s *PublicStakeApI) GetShareAtNumber(ctx context.Context, shareId common.Ha
 	share = stake.GetShareByBlockNumber(s.b.ChainDb(), shareId, header.Hash(), header.Number.Uint64())
 	return
 }
+
+type PoolOperatorArgs struct {
+	From        address.MixBase58Adrress  `json:"from"`
+	SweepTo     *address.MixBase58Adrress `json:"sweepTo"`
+	SweepMin    *hexutil.Big              `json:"sweepMin"`
+	SweepPeriod *hexutil.Uint64           `json:"sweepPeriod"`
+	AlertRate   *hexutil.Uint             `json:"alertRate"`
+}
+
+type RPCPoolSchedule struct {
+	At   hexutil.Uint64 `json:"at"`
+	Fee  *hexutil.Uint  `json:"fee,omitempty"`
+	Vote *string        `json:"vote,omitempty"`
+}
+
+type RPCPoolOperator struct {
+	PoolId      common.Hash       `json:"poolId"`
+	Own         string            `json:"own"`
+	SweepTo     *string           `json:"sweepTo,omitempty"`
+	SweepMin    hexutil.Big       `json:"sweepMin"`
+	SweepPeriod hexutil.Uint64    `json:"sweepPeriod"`
+	Swept       hexutil.Big       `json:"swept"`
+	LastSweep   hexutil.Uint64    `json:"lastSweep"`
+	SweepTx     *common.Hash      `json:"sweepTx,omitempty"`
+	Sweeping    *hexutil.Big      `json:"sweeping,omitempty"`
+	AlertRate   hexutil.Uint      `json:"alertRate"`
+	Schedules   []RPCPoolSchedule `json:"schedules"`
+}
+
+func newRPCPoolOperator(config *stakeservice.PoolOperator) RPCPoolOperator {
+	ret := RPCPoolOperator{
+		PoolId:      config.PoolId,
+		Own:         base58.Encode(config.PKr[:]),
+		SweepMin:    hexutil.Big(*config.SweepMin),
+		SweepPeriod: hexutil.Uint64(config.SweepPeriod),
+		Swept:       hexutil.Big(*config.Swept),
+		LastSweep:   hexutil.Uint64(config.LastSweep),
+		AlertRate:   hexutil.Uint(config.AlertRate),
+		Schedules:   []RPCPoolSchedule{},
+	}
+	if config.SweepTo != (c_type.PKr{}) {
+		sweepTo := base58.Encode(config.SweepTo[:])
+		ret.SweepTo = &sweepTo
+	}
+	if config.SweepTx != (common.Hash{}) {
+		sweepTx := config.SweepTx
+		ret.SweepTx = &sweepTx
+		ret.Sweeping = (*hexutil.Big)(config.Sweeping)
+	}
+	for _, schedule := range config.Schedules {
+		rpcSchedule := RPCPoolSchedule{At: hexutil.Uint64(schedule.At)}
+		if schedule.Fee != 0 {
+			fee := hexutil.Uint(schedule.Fee)
+			rpcSchedule.Fee = &fee
+		}
+		if schedule.Vote != (c_type.PKr{}) {
+			vote := base58.Encode(schedule.Vote[:])
+			rpcSchedule.Vote = &vote
+		}
+		ret.Schedules = append(ret.Schedules, rpcSchedule)
+	}
+	return ret
+}
+
+// StartPoolOperator starts to watch the vote health of the pool of the account,
+// to sweep its income and to send its scheduled changes.
+func (s *PublicStakeApI) StartPoolOperator(ctx context.Context, args PoolOperatorArgs) (common.Hash, error) {
+	var fromPkr c_type.PKr
+	fromAccount, err := s.b.AccountManager().FindAccountByPkr(args.From.ToPkr())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if args.From.IsPkr() {
+		fromPkr = args.From.ToPkr()
+	} else {
+		fromPkr = getStakePoolPkr(fromAccount)
+	}
+	config := stakeservice.PoolOperator{
+		Pk:          fromAccount.Address.ToUint512(),
+		PKr:         fromPkr,
+		SweepPeriod: stake.GetStatisticsMissWindow(),
+	}
+	if args.SweepTo != nil {
+		config.SweepTo = args.SweepTo.ToPkr()
+	}
+	if args.SweepMin != nil {
+		config.SweepMin = args.SweepMin.ToInt()
+	}
+	if args.SweepPeriod != nil {
+		config.SweepPeriod = uint64(*args.SweepPeriod)
+	}
+	if args.AlertRate != nil {
+		config.AlertRate = uint32(*args.AlertRate)
+	}
+	if err := stakeservice.CurrentStakeService().AddPoolOperator(config); err != nil {
+		return common.Hash{}, err
+	}
+	return getStakePoolId(fromPkr), nil
+}
+
+func (s *PublicStakeApI) StopPoolOperator(ctx context.Context, poolId common.Hash) error {
+	return stakeservice.CurrentStakeService().RemovePoolOperator(poolId)
+}
+
+func (s *PublicStakeApI) PoolOperators(ctx context.Context) []RPCPoolOperator {
+	ret := []RPCPoolOperator{}
+	for _, config := range stakeservice.CurrentStakeService().PoolOperators() {
+		ret = append(ret, newRPCPoolOperator(&config))
+	}
+	return ret
+}
+
+func (s *PublicStakeApI) PoolHealth(ctx context.Context, poolId common.Hash) (stakeservice.PoolHealth, error) {
+	return stakeservice.CurrentStakeService().PoolHealth(poolId)
+}
+
+// SchedulePoolFee changes the fee rate of an operated pool once the chain
+// reaches the given height.
+func (s *PublicStakeApI) SchedulePoolFee(ctx context.Context, poolId common.Hash, fee hexutil.Uint64, at hexutil.Uint64) error {
+	if uint32(fee) < seroparam.LOWEST_STAKING_NODE_FEE_RATE {
+		return errors.New(fmt.Sprintf("fee rate can not less then %v", seroparam.LOWEST_STAKING_NODE_FEE_RATE))
+	}
+	if uint32(fee) > seroparam.HIGHEST_STAKING_NODE_FEE_RATE {
+		return errors.New(fmt.Sprintf("fee rate can not large then  %v", seroparam.HIGHEST_STAKING_NODE_FEE_RATE))
+	}
+	return stakeservice.CurrentStakeService().SchedulePoolChange(poolId, stakeservice.PoolSchedule{At: uint64(at), Fee: uint32(fee)})
+}
+
+// SchedulePoolVote rotates the vote PKr of an operated pool once the chain
+// reaches the given height.
+func (s *PublicStakeApI) SchedulePoolVote(ctx context.Context, poolId common.Hash, vote address.MixBase58Adrress, at hexutil.Uint64) error {
+	return stakeservice.CurrentStakeService().SchedulePoolChange(poolId, stakeservice.PoolSchedule{At: uint64(at), Vote: vote.ToPkr()})
+}
+
+// PoolAlerts creates a subscription that is triggered when the vote health of
+// an operated pool degrades or when an operator acts on its pool.
+func (s *PublicStakeApI) PoolAlerts(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		alerts := make(chan stakeservice.PoolAlert, 16)
+		alertsSub := stakeservice.CurrentStakeService().SubscribePoolAlerts(alerts)
+
+		for {
+			select {
+			case alert := <-alerts:
+				notifier.Notify(rpcSub.ID, alert)
+			case <-rpcSub.Err():
+				alertsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				alertsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
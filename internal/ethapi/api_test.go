@@ -0,0 +1,113 @@
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/address"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// callTestBackend serves a single state to the simulated calls.
+type callTestBackend struct {
+	Backend
+	db     state.Database
+	header *types.Header
+}
+
+func (b *callTestBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	statedb, err := state.New(b.db, b.header)
+	return statedb, b.header, err
+}
+
+func (b *callTestBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	context := core.NewEVMContext(msg, header, nil, &common.Address{})
+	return vm.NewEVM(context, state, params.TestChainConfig, vmCfg), func() error { return nil }, nil
+}
+
+// newCallTestBackend returns a backend whose state holds a contract returning
+// the first slot of its storage, set to 1.
+func newCallTestBackend(t *testing.T) (*callTestBackend, AllMixedAddress) {
+	var contract AllMixedAddress
+	contract[0] = 1
+	addr := common.BytesToAddress(contract[:])
+
+	db := state.NewDatabase(serodb.NewMemDatabase())
+	statedb, _ := state.New(db, nil)
+	// PUSH1 0 SLOAD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	statedb.SetCode(addr, common.FromHex("0x60005460005260206000f3"))
+	statedb.SetState(addr, common.Hash{}, common.BigToHash(big.NewInt(1)))
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit the state: %v", err)
+	}
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit the trie: %v", err)
+	}
+	header := &types.Header{
+		Root:       root,
+		Number:     big.NewInt(1),
+		Time:       big.NewInt(1),
+		Difficulty: big.NewInt(1),
+		GasLimit:   params.GenesisGasLimit,
+	}
+	return &callTestBackend{db: db, header: header}, contract
+}
+
+func TestCallOverrides(t *testing.T) {
+	b, contract := newCallTestBackend(t)
+	api := NewPublicBlockChainAPI(b)
+
+	from := make(address.MixBase58Adrress, 96)
+	from[0] = 2
+	args := CallArgs{
+		From: &from,
+		To:   &contract,
+		Gas:  hexutil.Uint64(100000),
+		// The call uses no address of the table
+		Data: make(hexutil.Bytes, 18),
+	}
+	slot := func(value int64) *map[common.Hash]common.Hash {
+		return &map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(value))}
+	}
+	tests := []struct {
+		overrides *StateOverride
+		want      int64
+	}{
+		{nil, 1},
+		{&StateOverride{ContractAddress(contract): {State: &map[common.Hash]common.Hash{}}}, 0},
+		{&StateOverride{ContractAddress(contract): {State: &map[common.Hash]common.Hash{{1}: {1}}}}, 0},
+		{&StateOverride{ContractAddress(contract): {StateDiff: &map[common.Hash]common.Hash{{1}: {1}}}}, 1},
+		{&StateOverride{ContractAddress(contract): {State: slot(2)}}, 2},
+		{&StateOverride{ContractAddress(contract): {StateDiff: slot(3)}}, 3},
+	}
+	for i, test := range tests {
+		res, err := api.Call(context.Background(), args, rpc.LatestBlockNumber, test.overrides)
+		if err != nil {
+			t.Fatalf("test %d: failed to call: %v", i, err)
+		}
+		if have := new(big.Int).SetBytes(res); have.Int64() != test.want {
+			t.Errorf("test %d: result mismatch: have %v, want %d", i, have, test.want)
+		}
+	}
+
+	// The overrides are never written to the state of the chain
+	statedb, _, _ := b.StateAndHeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	if value := statedb.GetState(common.BytesToAddress(contract[:]), common.Hash{}); value != common.BigToHash(big.NewInt(1)) {
+		t.Fatalf("state overridden: have %x", value)
+	}
+
+	both := &StateOverride{ContractAddress(contract): {State: &map[common.Hash]common.Hash{}, StateDiff: &map[common.Hash]common.Hash{}}}
+	if _, err := api.Call(context.Background(), args, rpc.LatestBlockNumber, both); err == nil {
+		t.Fatal("state and stateDiff both accepted")
+	}
+}
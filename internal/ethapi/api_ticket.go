@@ -0,0 +1,218 @@
+package ethapi
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/superzk"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/address"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/utils"
+	"github.com/sero-cash/go-sero/zero/wallet/exchange"
+)
+
+const defaultTicketPageSize = uint64(100)
+
+type TicketCategory struct {
+	Category string
+	Count    uint64
+	Contract *ContractAddress
+}
+
+type TicketRecord struct {
+	Category string
+	Value    common.Hash
+	Pkr      PKrAddress
+	Root     c_type.Uint256
+	TxHash   c_type.Uint256
+	Num      uint64
+	Locked   bool
+	Contract *ContractAddress
+}
+
+type TicketEvent struct {
+	PK     address.PKAddress
+	Pkr    PKrAddress
+	Root   c_type.Uint256
+	TxHash c_type.Uint256
+	Num    uint64
+	Spent  bool
+}
+
+func ticketContract(st *state.StateDB, category string) *ContractAddress {
+	if st == nil {
+		return nil
+	}
+	contractAddress := st.GetContrctAddressByTicket(category)
+	if contractAddress == (common.Address{}) {
+		return nil
+	}
+	result := &ContractAddress{}
+	result.SetBytes(contractAddress[:64])
+	return result
+}
+
+func (s *PublicExchangeAPI) GetTicketCategories(ctx context.Context, pk address.PKAddress) ([]TicketCategory, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return nil, errors.New("exchange mode no start")
+	}
+	st, _, _ := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+
+	result := []TicketCategory{}
+	for category, count := range exchangeInstance.GetTicketCategories(pk.ToUint512()) {
+		name := utils.Uint256ToCurrency(&category)
+		result = append(result, TicketCategory{Category: name, Count: count, Contract: ticketContract(st, name)})
+	}
+	return result, nil
+}
+
+func (s *PublicExchangeAPI) GetTickets(ctx context.Context, pk address.PKAddress, category *Smbol, offset *uint64, limit *uint64) (map[string]interface{}, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return nil, errors.New("exchange mode no start")
+	}
+	var cy *c_type.Uint256
+	if category != nil && category.IsNotEmpty() {
+		cy = utils.CurrencyToUint256(category.String()).NewRef()
+	}
+	start := uint64(0)
+	if offset != nil {
+		start = *offset
+	}
+	count := defaultTicketPageSize
+	if limit != nil && *limit > 0 {
+		count = *limit
+	}
+
+	records, total, err := exchangeInstance.GetTickets(pk.ToUint512(), cy, start, count)
+	if err != nil {
+		return nil, err
+	}
+	st, _, _ := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+
+	contracts := map[string]*ContractAddress{}
+	tickets := []TicketRecord{}
+	for _, record := range records {
+		name := utils.Uint256ToCurrency(&record.Category)
+		contract, ok := contracts[name]
+		if !ok {
+			contract = ticketContract(st, name)
+			contracts[name] = contract
+		}
+		tickets = append(tickets, TicketRecord{
+			Category: name,
+			Value:    common.BytesToHash(record.Value[:]),
+			Pkr:      pkrToPKrAddress(record.Pkr),
+			Root:     record.Root,
+			TxHash:   record.TxHash,
+			Num:      record.Num,
+			Locked:   record.Locked,
+			Contract: contract,
+		})
+	}
+	return map[string]interface{}{
+		"total":   total,
+		"offset":  start,
+		"tickets": tickets,
+	}, nil
+}
+
+func (s *PublicExchangeAPI) GetTicketHistory(ctx context.Context, value common.Hash) ([]TicketEvent, error) {
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return nil, errors.New("exchange mode no start")
+	}
+	events, err := exchangeInstance.GetTicketHistory(*value.HashToUint256())
+	if err != nil {
+		return nil, err
+	}
+	result := []TicketEvent{}
+	for _, event := range events {
+		e := TicketEvent{
+			Pkr:    pkrToPKrAddress(event.Pkr),
+			Root:   event.Root,
+			TxHash: event.TxHash,
+			Num:    event.Num,
+			Spent:  event.Spent,
+		}
+		copy(e.PK[:], event.Pk[:])
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+type TicketArgs struct {
+	Category Smbol
+	Value    common.Hash
+}
+
+type TransferTicketsArgs struct {
+	From     address.PKAddress
+	To       PKrAddress
+	RefundTo *PKrAddress
+	Tickets  []TicketArgs
+	GasPrice *Big
+}
+
+func (args TransferTicketsArgs) check() error {
+	if len(args.Tickets) == 0 {
+		return errors.New("have no tickets")
+	}
+	if !superzk.IsPKrValid(args.To.ToPKr()) {
+		return errors.New("To is not a valid pkr")
+	}
+	if args.RefundTo != nil {
+		if !superzk.IsPKrValid(args.RefundTo.ToPKr()) {
+			return errors.New("RefundTo is not a valid pkr")
+		}
+	}
+	for _, ticket := range args.Tickets {
+		if ticket.Category.IsEmpty() {
+			return errors.Errorf("ticket %v category is nil", ticket.Value.Hex())
+		}
+		if ticket.Value == (common.Hash{}) {
+			return errors.Errorf("ticket %v value is nil", ticket.Category)
+		}
+	}
+	return nil
+}
+
+func (args TransferTicketsArgs) toParam() *exchange.TransferTicketsParam {
+	param := exchange.TransferTicketsParam{
+		From: args.From.ToUint512(),
+		To:   *args.To.ToPKr(),
+	}
+	if args.RefundTo != nil {
+		param.RefundTo = args.RefundTo.ToPKr()
+	}
+	if args.GasPrice != nil {
+		param.GasPrice = args.GasPrice.ToInt()
+	}
+	for _, ticket := range args.Tickets {
+		param.Tickets = append(param.Tickets, assets.Ticket{
+			Category: utils.CurrencyToUint256(ticket.Category.String()),
+			Value:    *ticket.Value.HashToUint256(),
+		})
+	}
+	return &param
+}
+
+func (s *PublicExchangeAPI) TransferTickets(ctx context.Context, args TransferTicketsArgs) (common.Hash, error) {
+	if err := args.check(); err != nil {
+		return common.Hash{}, err
+	}
+	exchangeInstance := exchange.CurrentExchange()
+	if exchangeInstance == nil {
+		return common.Hash{}, errors.New("exchange mode no start")
+	}
+	hash, err := exchangeInstance.TransferTickets(args.toParam())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(hash[:]), nil
+}
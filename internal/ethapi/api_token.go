@@ -0,0 +1,185 @@
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/rpc"
+)
+
+const seroDecimals = 18
+
+type TokenRateInfo struct {
+	Contract    *ContractAddress `json:"contract"`
+	BlockNumber hexutil.Uint64   `json:"blockNumber"`
+	TxHash      common.Hash      `json:"txHash"`
+	Tokens      *hexutil.Big     `json:"tokens"`
+	Tas         *hexutil.Big     `json:"tas"`
+}
+
+type TokenInfo struct {
+	Name        string           `json:"name"`
+	Kind        string           `json:"kind"`
+	Contract    *ContractAddress `json:"contract"`
+	BlockNumber *hexutil.Uint64  `json:"blockNumber"`
+	TxHash      *common.Hash     `json:"txHash"`
+	Decimals    *hexutil.Uint    `json:"decimals"`
+	Rates       []TokenRateInfo  `json:"rates"`
+}
+
+func toContractAddress(addr common.Address) *ContractAddress {
+	if addr == state.EmptyAddress {
+		return nil
+	}
+	result := &ContractAddress{}
+	result.SetBytes(addr[:64])
+	return result
+}
+
+func (s *PublicBlockChainAPI) tokenInfo(ctx context.Context, registration *types.TokenRegistration) TokenInfo {
+	number := hexutil.Uint64(registration.Number)
+	txHash := registration.TxHash
+	info := TokenInfo{
+		Name:        registration.Name,
+		Kind:        "token",
+		Contract:    toContractAddress(registration.Contract),
+		BlockNumber: &number,
+		TxHash:      &txHash,
+		Rates:       []TokenRateInfo{},
+	}
+	if registration.Kind == types.RegistryTicket {
+		info.Kind = "ticket"
+		return info
+	}
+	if registration.Name == params.DefaultCurrency {
+		decimals := hexutil.Uint(seroDecimals)
+		info.Decimals = &decimals
+	} else if decimals, err := s.GetDecimal(ctx, registration.Name); err == nil {
+		info.Decimals = decimals
+	}
+	for _, rate := range registration.Rates {
+		info.Rates = append(info.Rates, TokenRateInfo{
+			Contract:    toContractAddress(rate.Contract),
+			BlockNumber: hexutil.Uint64(rate.Number),
+			TxHash:      rate.TxHash,
+			Tokens:      (*hexutil.Big)(rate.Tokens),
+			Tas:         (*hexutil.Big)(rate.Tas),
+		})
+	}
+	return info
+}
+
+// ListTokens returns all the currencies and ticket categories indexed by the
+// token registry, in registration order.
+func (s *PublicBlockChainAPI) ListTokens(ctx context.Context) ([]TokenInfo, error) {
+	db := s.b.ChainDb()
+	result := []TokenInfo{}
+	for _, kind := range []uint8{types.RegistryToken, types.RegistryTicket} {
+		for _, name := range rawdb.ReadTokenRegistryNames(db, kind) {
+			if registration := rawdb.ReadTokenRegistration(db, kind, name); registration != nil {
+				result = append(result, s.tokenInfo(ctx, registration))
+			}
+		}
+	}
+	return result, nil
+}
+
+// GetTokenInfo returns the registration of a currency or a ticket category. Names
+// registered in blocks not yet indexed are resolved from the latest state.
+func (s *PublicBlockChainAPI) GetTokenInfo(ctx context.Context, name Smbol) (*TokenInfo, error) {
+	if name.IsEmpty() {
+		return nil, errors.New("name can not be empty!")
+	}
+	db := s.b.ChainDb()
+	for _, kind := range []uint8{types.RegistryToken, types.RegistryTicket} {
+		if registration := rawdb.ReadTokenRegistration(db, kind, name.String()); registration != nil {
+			info := s.tokenInfo(ctx, registration)
+			return &info, nil
+		}
+	}
+
+	st, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if contract := st.GetContrctAddressByToken(name.String()); contract != (common.Address{}) {
+		info := TokenInfo{Name: name.String(), Kind: "token", Contract: toContractAddress(contract), Rates: []TokenRateInfo{}}
+		if decimals, err := s.GetDecimal(ctx, name.String()); err == nil {
+			info.Decimals = decimals
+		}
+		return &info, nil
+	}
+	if contract := st.GetContrctAddressByTicket(name.String()); contract != (common.Address{}) {
+		return &TokenInfo{Name: name.String(), Kind: "ticket", Contract: toContractAddress(contract), Rates: []TokenRateInfo{}}, nil
+	}
+	return nil, errors.New(name.String() + " not exists!")
+}
+
+// maxTokenTransferBlocks is the widest block range of a token transfers query.
+const maxTokenTransferBlocks = 100000
+
+var tokenTransferKinds = map[uint8]string{
+	types.TransferIssue:    "issue",
+	types.TransferSend:     "send",
+	types.TransferReceive:  "receive",
+	types.TransferPkgClose: "pkgClose",
+}
+
+type TokenTransferInfo struct {
+	Kind        string           `json:"kind"`
+	Contract    *ContractAddress `json:"contract"`
+	Currency    string           `json:"currency"`
+	Amount      *hexutil.Big     `json:"amount"`
+	Peer        interface{}      `json:"peer"`
+	BlockNumber hexutil.Uint64   `json:"blockNumber"`
+	TxHash      common.Hash      `json:"txHash"`
+}
+
+// toPeerAddress returns the contract or the PKr of the other side of a transfer.
+func toPeerAddress(addr common.Address) interface{} {
+	if addr == state.EmptyAddress {
+		return nil
+	}
+	if AllMixedAddress(addr).IsContract() {
+		return toContractAddress(addr)
+	}
+	return PKrAddress(addr)
+}
+
+// GetTokenTransfers returns the indexed movements of a currency on the balance
+// of a contract from the block begin to the block end excluded, in execution
+// order. The last blocks of the chain are not indexed yet.
+func (s *PublicBlockChainAPI) GetTokenTransfers(ctx context.Context, contract ContractAddress, currency Smbol, begin, end uint64) ([]TokenTransferInfo, error) {
+	if currency.IsEmpty() {
+		return nil, errors.New("currency can not be empty!")
+	}
+	if end < begin {
+		return nil, errors.New("end can not be lower than begin")
+	}
+	if end-begin > maxTokenTransferBlocks {
+		return nil, fmt.Errorf("block range can not exceed %d blocks", maxTokenTransferBlocks)
+	}
+	var addr common.Address
+	copy(addr[:], contract[:])
+
+	result := []TokenTransferInfo{}
+	for _, transfer := range rawdb.ReadTokenTransfers(s.b.ChainDb(), addr, currency.String(), begin, end) {
+		result = append(result, TokenTransferInfo{
+			Kind:        tokenTransferKinds[transfer.Kind],
+			Contract:    toContractAddress(transfer.Contract),
+			Currency:    transfer.Currency,
+			Amount:      (*hexutil.Big)(transfer.Amount),
+			Peer:        toPeerAddress(transfer.Peer),
+			BlockNumber: hexutil.Uint64(transfer.Number),
+			TxHash:      transfer.TxHash,
+		})
+	}
+	return result, nil
+}
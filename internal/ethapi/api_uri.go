@@ -0,0 +1,183 @@
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/sero-cash/go-sero/common/address"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/zero/account"
+)
+
+// PaymentURIArgs are the fields of a payment URI to encode, the value is in
+// the smallest unit of the currency.
+type PaymentURIArgs struct {
+	To       MixAdrress     `json:"to"`
+	Currency Smbol          `json:"cy"`
+	Value    *hexutil.Big   `json:"value"`
+	Memo     string         `json:"memo"`
+	Expiry   hexutil.Uint64 `json:"expiry"`
+	Label    string         `json:"label"`
+}
+
+// RPCPaymentURI is a decoded payment URI.
+type RPCPaymentURI struct {
+	To       MixAdrress     `json:"to"`
+	Currency Smbol          `json:"cy"`
+	Amount   string         `json:"amount"`
+	Value    *hexutil.Big   `json:"value"`
+	Decimals hexutil.Uint   `json:"decimals"`
+	Memo     string         `json:"memo"`
+	Expiry   hexutil.Uint64 `json:"expiry"`
+	Expired  bool           `json:"expired"`
+	Label    string         `json:"label"`
+}
+
+// currencyDecimals returns the decimals of a currency, SERO or a token.
+func currencyDecimals(ctx context.Context, b Backend, currency string) (uint8, error) {
+	cy := Smbol(currency)
+	if cy.IsEmpty() || cy.IsSero() {
+		return seroDecimals, nil
+	}
+	decimals, err := NewPublicBlockChainAPI(b).GetDecimal(ctx, currency)
+	if err != nil {
+		return 0, err
+	}
+	return uint8(*decimals), nil
+}
+
+// decodePaymentURI parses a payment URI and converts its amount with the
+// decimals of its currency.
+func decodePaymentURI(ctx context.Context, b Backend, uri string) (*RPCPaymentURI, error) {
+	payment, err := account.ParsePaymentURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if len(payment.Address.Bytes) == 96 {
+		err = address.ValidPkr(payment.Address.Bytes)
+	} else {
+		err = address.ValidPk(payment.Address.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	currency := payment.Currency
+	if currency == "" {
+		currency = params.DefaultCurrency
+	}
+	decimals, err := currencyDecimals(ctx, b, currency)
+	if err != nil {
+		return nil, err
+	}
+	value, err := payment.Value(decimals)
+	if err != nil {
+		return nil, err
+	}
+	return &RPCPaymentURI{
+		To:       MixAdrress(payment.Address.Bytes),
+		Currency: Smbol(currency),
+		Amount:   payment.Amount,
+		Value:    (*hexutil.Big)(value),
+		Decimals: hexutil.Uint(decimals),
+		Memo:     payment.Memo,
+		Expiry:   hexutil.Uint64(payment.Expiry),
+		Expired:  payment.Expired(time.Now()),
+		Label:    payment.Label,
+	}, nil
+}
+
+// EncodePaymentURI returns the sero: URI requesting the given payment.
+func (s *PublicBlockChainAPI) EncodePaymentURI(ctx context.Context, args PaymentURIArgs) (string, error) {
+	if len(args.To) == 0 {
+		return "", errors.New("the payment address is missing")
+	}
+	if len(args.Memo) > account.MaxMemoLength {
+		return "", fmt.Errorf("the memo is longer than %d bytes", account.MaxMemoLength)
+	}
+	payment := account.PaymentURI{
+		Address:  account.NewAddressByBytes(args.To),
+		Currency: args.Currency.String(),
+		Memo:     args.Memo,
+		Expiry:   uint64(args.Expiry),
+		Label:    args.Label,
+	}
+	if args.Value != nil {
+		decimals, err := currencyDecimals(ctx, s.b, payment.Currency)
+		if err != nil {
+			return "", err
+		}
+		payment.SetValue(args.Value.ToInt(), decimals)
+	}
+	return payment.String(), nil
+}
+
+// DecodePaymentURI returns the payment requested by a sero: URI.
+func (s *PublicBlockChainAPI) DecodePaymentURI(ctx context.Context, uri string) (*RPCPaymentURI, error) {
+	return decodePaymentURI(ctx, s.b, uri)
+}
+
+// applyPaymentURI fills the transaction with the payment requested by its URI.
+// The fields also given directly must match the ones of the URI.
+func (args *SendTxArgs) applyPaymentURI(ctx context.Context, b Backend) error {
+	payment, err := decodePaymentURI(ctx, b, args.URI)
+	if err != nil {
+		return err
+	}
+	if payment.Expired {
+		return errors.New("the payment request has expired")
+	}
+	to := AllBase58Adrress(payment.To)
+	if args.To == nil {
+		args.To = &to
+	} else if !bytes.Equal(*args.To, to) {
+		return errors.New("to conflicts with the payment uri")
+	}
+	if args.Currency.IsEmpty() {
+		args.Currency = payment.Currency
+	} else if !strings.EqualFold(args.Currency.String(), payment.Currency.String()) {
+		return errors.New("cy conflicts with the payment uri")
+	}
+	if payment.Value != nil {
+		if args.Value == nil {
+			args.Value = payment.Value
+		} else if args.Value.ToInt().Cmp(payment.Value.ToInt()) != 0 {
+			return errors.New("value conflicts with the payment uri")
+		}
+	}
+	if args.Memo == "" {
+		args.Memo = payment.Memo
+	} else if payment.Memo != "" && args.Memo != payment.Memo {
+		return errors.New("memo conflicts with the payment uri")
+	}
+	return nil
+}
+
+// applyPaymentURIs adds a reception for the payment requested by every URI.
+func (args *GenTxArgs) applyPaymentURIs(ctx context.Context, b Backend) error {
+	for _, uri := range args.URIs {
+		payment, err := decodePaymentURI(ctx, b, uri)
+		if err != nil {
+			return err
+		}
+		if payment.Expired {
+			return fmt.Errorf("the payment request %s has expired", uri)
+		}
+		if payment.Value == nil {
+			return fmt.Errorf("the payment request %s has no amount", uri)
+		}
+		args.Receptions = append(args.Receptions, ReceptionArgs{
+			Addr:     payment.To,
+			Currency: payment.Currency,
+			Value:    (*Big)(new(big.Int).Set(payment.Value.ToInt())),
+			Memo:     payment.Memo,
+		})
+	}
+	args.URIs = nil
+	return nil
+}
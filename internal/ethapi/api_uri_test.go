@@ -0,0 +1,161 @@
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common/address"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/account"
+)
+
+const testPaymentPk = "3fCJhSjsGJPPB3tSqbycBbwyTahv1WAz8RJY7fpVBqr44A7foQAZjWssGXHjc7uVofYCx5cNkmV3k2kEJWU97nKY"
+
+// encodeTestPaymentURI returns a payment uri of 1.5 SERO to the test account.
+func encodeTestPaymentURI(t *testing.T, memo string, expiry uint64) string {
+	pk := address.StringToPk(testPaymentPk)
+	uri, err := NewPublicBlockChainAPI(nil).EncodePaymentURI(context.Background(), PaymentURIArgs{
+		To:       pk[:],
+		Currency: "SERO",
+		Value:    (*hexutil.Big)(big.NewInt(1500000000000000000)),
+		Memo:     memo,
+		Expiry:   hexutil.Uint64(expiry),
+		Label:    "Shop",
+	})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	return uri
+}
+
+func TestEncodePaymentURI(t *testing.T) {
+	uri := encodeTestPaymentURI(t, "order 42", 0)
+	payment, err := account.ParsePaymentURI(uri)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", uri, err)
+	}
+	pk := address.StringToPk(testPaymentPk)
+	if !bytes.Equal(payment.Address.Bytes, pk[:]) || payment.Currency != "SERO" || payment.Amount != "1.5" ||
+		payment.Memo != "order 42" || payment.Label != "Shop" {
+		t.Fatalf("payment mismatch: %+v", payment)
+	}
+
+	api := NewPublicBlockChainAPI(nil)
+	if _, err := api.EncodePaymentURI(context.Background(), PaymentURIArgs{Currency: "SERO"}); err == nil {
+		t.Fatal("encoded a payment without address")
+	}
+	long := PaymentURIArgs{To: pk[:], Memo: strings.Repeat("m", account.MaxMemoLength+1)}
+	if _, err := api.EncodePaymentURI(context.Background(), long); err == nil {
+		t.Fatal("encoded a payment with a memo too long")
+	}
+}
+
+func TestDecodePaymentURI(t *testing.T) {
+	api := NewPublicBlockChainAPI(nil)
+	payment, err := api.DecodePaymentURI(context.Background(), encodeTestPaymentURI(t, "order 42", 0))
+	if err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	pk := address.StringToPk(testPaymentPk)
+	if !bytes.Equal(payment.To, pk[:]) || payment.Currency != "SERO" || payment.Amount != "1.5" || payment.Decimals != 18 ||
+		payment.Value.ToInt().Cmp(big.NewInt(1500000000000000000)) != 0 || payment.Memo != "order 42" || payment.Expired {
+		t.Fatalf("payment mismatch: %+v", payment)
+	}
+	if payment, err = api.DecodePaymentURI(context.Background(), encodeTestPaymentURI(t, "", 1)); err != nil || !payment.Expired {
+		t.Fatalf("expired payment mismatch: %+v, %v", payment, err)
+	}
+}
+
+func TestPaymentURITransactions(t *testing.T) {
+	uri := encodeTestPaymentURI(t, "invoice 7", 0)
+	pk := address.StringToPk(testPaymentPk)
+
+	args := SendTxArgs{URI: uri}
+	if err := args.applyPaymentURI(context.Background(), nil); err != nil {
+		t.Fatalf("failed to apply: %v", err)
+	}
+	if !bytes.Equal(*args.To, pk[:]) || args.Currency != "SERO" || args.Value.ToInt().Cmp(big.NewInt(1500000000000000000)) != 0 || args.Memo != "invoice 7" {
+		t.Fatalf("transaction mismatch: %+v", args)
+	}
+	conflict := SendTxArgs{URI: uri, Memo: "invoice 8"}
+	if err := conflict.applyPaymentURI(context.Background(), nil); err == nil {
+		t.Fatal("applied an uri conflicting with the memo")
+	}
+	expired := SendTxArgs{URI: encodeTestPaymentURI(t, "", 1)}
+	if err := expired.applyPaymentURI(context.Background(), nil); err == nil {
+		t.Fatal("applied an expired uri")
+	}
+
+	gen := GenTxArgs{URIs: []string{uri}}
+	if err := gen.applyPaymentURIs(context.Background(), nil); err != nil {
+		t.Fatalf("failed to apply: %v", err)
+	}
+	if len(gen.URIs) != 0 || len(gen.Receptions) != 1 || gen.Receptions[0].Memo != "invoice 7" {
+		t.Fatalf("receptions mismatch: %+v", gen.Receptions)
+	}
+}
+
+func TestGenTxMemo(t *testing.T) {
+	memo := stringToUint512("order 42")
+	if memo[0] != account.MemoVersion || account.DecodeMemo(&memo).String() != "order 42" {
+		t.Fatalf("memo encoding mismatch: %x", memo)
+	}
+	ref := stringToUint512("ref:42")
+	if decoded := account.DecodeMemo(&ref); decoded.Type != account.MemoReference || decoded.String() != "ref:42" {
+		t.Fatalf("reference memo mismatch: %x", ref)
+	}
+	// The texts too long for a typed memo are written as plain text
+	text := strings.Repeat("x", account.MaxMemoLength)
+	plain := stringToUint512(text)
+	if !bytes.Equal(plain[:], []byte(text)) || account.DecodeMemo(&plain).String() != text {
+		t.Fatalf("plain memo mismatch: %x", plain)
+	}
+
+	gen := GenTxArgs{
+		Receptions: []ReceptionArgs{
+			{Addr: make(MixAdrress, 96), Currency: "SERO", Value: (*Big)(big.NewInt(1)), Memo: "order 42"},
+			{Addr: make(MixAdrress, 96), Currency: "SERO", Value: (*Big)(big.NewInt(2))},
+		},
+		GasPrice: (*Big)(big.NewInt(1)),
+	}
+	param := gen.toTxParam()
+	if param.Receptions[0].Memo != memo {
+		t.Fatalf("reception memo mismatch: %x", param.Receptions[0].Memo)
+	}
+	if param.Receptions[1].Memo != stringToUint512("") {
+		t.Fatalf("empty memo mismatch: %x", param.Receptions[1].Memo)
+	}
+}
+
+func TestSendTxMemo(t *testing.T) {
+	statedb, _ := state.New(state.NewDatabase(serodb.NewMemDatabase()), nil)
+	to := make(AllBase58Adrress, 96)
+	to[0] = 1
+	gas := hexutil.Uint64(25000)
+	args := SendTxArgs{
+		From:        make(address.MixBase58Adrress, 96),
+		To:          &to,
+		Gas:         &gas,
+		GasCurrency: "SERO",
+		GasPrice:    (*hexutil.Big)(big.NewInt(1)),
+		Value:       (*hexutil.Big)(big.NewInt(1)),
+		Currency:    "SERO",
+		Memo:        "order 42",
+	}
+	// The memo of an ordinary send is not carried to the output
+	param := args.toTxParam(statedb, accounts.Account{})
+	if param.Receptions[0].Memo != stringToUint512("") {
+		t.Fatalf("ordinary send memo mismatch: %x", param.Receptions[0].Memo)
+	}
+	args.URI = "sero:pay"
+	param = args.toTxParam(statedb, accounts.Account{})
+	if param.Receptions[0].Memo != stringToUint512("order 42") {
+		t.Fatalf("payment memo mismatch: %x", param.Receptions[0].Memo)
+	}
+}
@@ -25,7 +25,9 @@ import (
 
 	"github.com/sero-cash/go-sero/zero/txtool"
 
+	"github.com/sero-cash/go-sero/zero/account"
 	"github.com/sero-cash/go-sero/zero/wallet/exchange"
+	"github.com/sero-cash/go-sero/zero/wallet/history"
 
 	"github.com/sero-cash/go-czero-import/c_type"
 
@@ -92,13 +94,18 @@ type Backend interface {
 	GetPkNumber(pk c_type.Uint512) (number uint64, e error)
 	GetPkr(pk *c_type.Uint512, index *c_type.Uint256) (c_type.PKr, error)
 	GetBalances(pk c_type.Uint512) (balances map[string]*big.Int, tickets map[string][]*common.Hash)
+	GetPending(pk c_type.Uint512) (balances map[string]*exchange.PendingBalance, records []exchange.PendingRecord, e error)
 	GenTx(param prepare.PreTxParam) (*txtool.GTxParam, error)
 	GetRecordsByPk(pk *c_type.Uint512, begin, end uint64) (records []exchange.Utxo, err error)
 	GetRecordsByPkr(pkr c_type.PKr, begin, end uint64) (records []exchange.Utxo, err error)
+	GetRecordsByMemo(memo account.Memo, begin, end uint64) (records []exchange.Utxo, err error)
 	GetLockedBalances(pk c_type.Uint512) (balances map[string]*big.Int)
 	GetMaxAvailable(pk c_type.Uint512, currency string) (amount *big.Int)
 	GetRecordsByTxHash(txHash c_type.Uint256) (records []exchange.Utxo, err error)
 
+	// History api
+	GetAccountHistory(pk c_type.Uint512, from history.Cursor, end uint64, limit int) (records []history.Record, next *history.Cursor, err error)
+
 	//Light node api
 	GetOutByPKr(pkrs []c_type.PKr, start, end uint64) (br light.BlockOutResp, e error)
 	CheckNil(Nils []c_type.Uint256) (nilResps []light.NilValue, e error)
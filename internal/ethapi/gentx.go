@@ -13,6 +13,7 @@ import (
 	"github.com/sero-cash/go-czero-import/superzk"
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/zero/account"
 	"github.com/sero-cash/go-sero/zero/txs/assets"
 	"github.com/sero-cash/go-sero/zero/txtool/prepare"
 	"github.com/sero-cash/go-sero/zero/utils"
@@ -184,6 +185,7 @@ type GenTxArgs struct {
 	Gas        uint64
 	GasPrice   *Big
 	Roots      []c_type.Uint256
+	URIs       []string // sero: payment URIs added to the receptions
 }
 
 func (args GenTxArgs) check() error {
@@ -219,6 +221,9 @@ func (args GenTxArgs) check() error {
 		if rec.Value == nil {
 			return errors.Errorf("%v reception value is nil", hexutil.Encode(rec.Addr[:]))
 		}
+		if _, err := account.EncodeMemo(rec.Memo); err != nil {
+			return errors.Errorf("%v reception memo: %v", hexutil.Encode(rec.Addr[:]), err)
+		}
 	}
 	return nil
 
@@ -237,11 +242,12 @@ func (args GenTxArgs) toTxParam() prepare.PreTxParam {
 		bytes := common.LeftPadBytes([]byte(string(rec.Currency)), 32)
 		copy(currency[:], bytes)
 		receptions = append(receptions, prepare.Reception{
-			pkr,
-			assets.Asset{Tkn: &assets.Token{
+			Addr: pkr,
+			Asset: assets.Asset{Tkn: &assets.Token{
 				Currency: currency,
 				Value:    utils.U256(*rec.Value.ToInt())},
 			},
+			Memo: stringToUint512(rec.Memo),
 		})
 	}
 	var refundPkr *c_type.PKr
@@ -124,6 +124,22 @@ web3._extend({
 			call: 'admin_addPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'banPeer',
+			call: 'admin_banPeer',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'unbanPeer',
+			call: 'admin_unbanPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'listBans',
+			call: 'admin_listBans',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'removePeer',
 			call: 'admin_removePeer',
@@ -368,6 +384,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceCall',
+			call: 'debug_traceCall',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -415,6 +437,26 @@ web3._extend({
 			call: 'sero_genOldIndexPKr',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'encodePaymentURI',
+			call: 'sero_encodePaymentURI',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'decodePaymentURI',
+			call: 'sero_decodePaymentURI',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getTokenTransfers',
+			call: 'sero_getTokenTransfers',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'getAccountHistory',
+			call: 'sero_getAccountHistory',
+			params: 5
+		}),
 		new web3._extend.Method({
 			name: 'sign',
 			call: 'sero_sign',
@@ -599,6 +641,11 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'getHistory',
+			call: 'personal_getHistory',
+			params: 5
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -731,6 +778,17 @@ web3._extend({
 			name: 'committx',
 			call: 'ssi_commitTx',
 			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'gettxstatus',
+			call: 'ssi_getTxStatus',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'listtxs',
+			call: 'ssi_listTxs',
+			params: 1,
+			inputFormatter: [null]
 		})
 	]
 });
@@ -750,6 +808,73 @@ web3._extend({
 			call: 'exchange_getBalances',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getRecordsByMemo',
+			call: 'exchange_getRecordsByMemo',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getTicketCategories',
+			call: 'exchange_getTicketCategories',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getTickets',
+			call: 'exchange_getTickets',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'getTicketHistory',
+			call: 'exchange_getTicketHistory',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'transferTickets',
+			call: 'exchange_transferTickets',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'addWatchAccount',
+			call: 'exchange_addWatchAccount',
+			params: 3,
+			inputFormatter: [null, null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'removeWatchAccount',
+			call: 'exchange_removeWatchAccount',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setWatchLabel',
+			call: 'exchange_setWatchLabel',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getWatchAccounts',
+			call: 'exchange_getWatchAccounts',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'exportAuditBundle',
+			call: 'exchange_exportAuditBundle',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'importAuditBundle',
+			call: 'exchange_importAuditBundle',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'rescan',
+			call: 'exchange_rescan',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getPending',
+			call: 'exchange_getPending',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getLockedBalances',
 			call: 'exchange_getLockedBalances',
@@ -829,6 +954,16 @@ web3._extend({
 			name: 'ignorePkrUtxos',
 			call: 'exchange_ignorePkrUtxos',
 			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'genTxBundle',
+			call: 'exchange_genTxBundle',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'commitTxBundle',
+			call: 'exchange_commitTxBundle',
+			params: 1
 		})
 	]
 });
@@ -907,6 +1042,38 @@ web3._extend({
 			params:3,
             inputFormatter: [null,web3._extend.utils.toHex,web3._extend.utils.toHex],
             outputFormatter: web3._extend.formatters.outputStakeInfoFormatter
+		}),
+        new web3._extend.Method({
+			name: 'startPoolOperator',
+			call: 'stake_startPoolOperator',
+			params:1
+		}),
+        new web3._extend.Method({
+			name: 'stopPoolOperator',
+			call: 'stake_stopPoolOperator',
+			params:1
+		}),
+        new web3._extend.Method({
+			name: 'poolOperators',
+			call: 'stake_poolOperators',
+			params:0
+		}),
+        new web3._extend.Method({
+			name: 'poolHealth',
+			call: 'stake_poolHealth',
+			params:1
+		}),
+        new web3._extend.Method({
+			name: 'schedulePoolFee',
+			call: 'stake_schedulePoolFee',
+			params:3,
+			inputFormatter: [null, web3._extend.utils.toHex, web3._extend.utils.toHex]
+		}),
+        new web3._extend.Method({
+			name: 'schedulePoolVote',
+			call: 'stake_schedulePoolVote',
+			params:3,
+			inputFormatter: [null, null, web3._extend.utils.toHex]
 		})
 
 	],
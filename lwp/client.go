@@ -0,0 +1,314 @@
+package lwp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/p2p/discover"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+var (
+	errNoPeers         = errors.New("no light wallet peer")
+	errPeerDropped     = errors.New("light wallet peer dropped")
+	errRequestTimeout  = errors.New("light wallet request timed out")
+	errUnknownHeader   = errors.New("witnesses taken at an unknown header")
+	errUnrequestedRoot = errors.New("witness of an unrequested root")
+	errInvalidNils     = errors.New("spent flags do not match the requested nils")
+)
+
+// requestTimeout is the time a client waits for the response of a peer.
+const requestTimeout = 10 * time.Second
+
+// HeaderReader retrieves the headers the witnesses are verified against, a
+// light wallet backs it with its header chain or a node it trusts.
+type HeaderReader interface {
+	GetHeaderByHash(hash common.Hash) *types.Header
+}
+
+// request is a request waiting for the response of a peer.
+type request struct {
+	peer *peer
+	code uint64
+	resp chan interface{} // Receives the decoded response, nil if the peer dropped
+}
+
+// Client syncs a light wallet from the full nodes serving the light wallet
+// protocol. The witnesses served are verified against the headers of its
+// HeaderReader before they are returned.
+type Client struct {
+	networkId uint64
+	genesis   common.Hash
+	headers   HeaderReader
+
+	peers     *peerSet
+	protocols []p2p.Protocol
+
+	reqID   uint64
+	pending map[uint64]*request
+	lock    sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewClient creates a light wallet protocol client of the given network.
+func NewClient(networkId uint64, genesis common.Hash, headers HeaderReader) *Client {
+	client := &Client{
+		networkId: networkId,
+		genesis:   genesis,
+		headers:   headers,
+		peers:     newPeerSet(),
+		pending:   make(map[uint64]*request),
+		quit:      make(chan struct{}),
+	}
+	for i, version := range ProtocolVersions {
+		version := version // Closure for the run
+		client.protocols = append(client.protocols, p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				select {
+				case <-client.quit:
+					return p2p.DiscQuitting
+				default:
+				}
+				client.wg.Add(1)
+				defer client.wg.Done()
+				return client.handle(newPeer(int(version), p, rw))
+			},
+			PeerInfo: func(id discover.NodeID) interface{} {
+				return nil
+			},
+		})
+	}
+	return client
+}
+
+// Protocols returns the protocols to run on the p2p server of the wallet.
+func (c *Client) Protocols() []p2p.Protocol {
+	return c.protocols
+}
+
+// Stop disconnects all the peers of the client.
+func (c *Client) Stop() {
+	close(c.quit)
+	c.peers.Close()
+	c.wg.Wait()
+}
+
+func (c *Client) handle(p *peer) error {
+	p.Log().Debug("Light wallet server connected", "name", p.Name())
+
+	if err := p.Handshake(c.networkId, c.genesis, 0, c.genesis); err != nil {
+		p.Log().Debug("Light wallet handshake failed", "err", err)
+		return err
+	}
+	if err := c.peers.Register(p); err != nil {
+		p.Log().Error("Light wallet peer registration failed", "err", err)
+		return err
+	}
+	defer c.drop(p)
+
+	for {
+		if err := c.handleMsg(p); err != nil {
+			p.Log().Debug("Light wallet message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// drop unregisters a peer and fails its pending requests.
+func (c *Client) drop(p *peer) {
+	c.peers.Unregister(p.id)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for id, req := range c.pending {
+		if req.peer == p {
+			delete(c.pending, id)
+			req.resp <- nil
+		}
+	}
+}
+
+// handleMsg delivers the responses of a peer to the requests waiting for them.
+func (c *Client) handleMsg(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	var (
+		reqID uint64
+		data  interface{}
+	)
+	switch msg.Code {
+	case BlocksMsg:
+		var resp blocksData
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		reqID, data = resp.ReqID, &resp
+
+	case NilsMsg:
+		var resp nilsData
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		reqID, data = resp.ReqID, &resp
+
+	case WitnessesMsg:
+		var resp witnessesData
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		reqID, data = resp.ReqID, &resp
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+
+	c.lock.Lock()
+	req, ok := c.pending[reqID]
+	if ok && req.peer == p && req.code == msg.Code {
+		delete(c.pending, reqID)
+	}
+	c.lock.Unlock()
+
+	if !ok || req.peer != p {
+		// Late response of a timed out request
+		return nil
+	}
+	if req.code != msg.Code {
+		return errResp(ErrUnexpectedResponse, "code %v for request %v", msg.Code, reqID)
+	}
+	req.resp <- data
+	return nil
+}
+
+// request sends a request to the best peer and waits for its response.
+func (c *Client) request(ctx context.Context, code uint64, send func(p *peer, reqID uint64) error) (*peer, interface{}, error) {
+	p := c.peers.BestPeer()
+	if p == nil {
+		return nil, nil, errNoPeers
+	}
+	req := &request{peer: p, code: code, resp: make(chan interface{}, 1)}
+	reqID := atomic.AddUint64(&c.reqID, 1)
+
+	c.lock.Lock()
+	c.pending[reqID] = req
+	c.lock.Unlock()
+	defer func() {
+		c.lock.Lock()
+		delete(c.pending, reqID)
+		c.lock.Unlock()
+	}()
+
+	if err := send(p, reqID); err != nil {
+		return nil, nil, err
+	}
+	timeout := time.NewTimer(requestTimeout)
+	defer timeout.Stop()
+
+	select {
+	case data := <-req.resp:
+		if data == nil {
+			return nil, nil, errPeerDropped
+		}
+		return p, data, nil
+	case <-timeout.C:
+		return nil, nil, errRequestTimeout
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-c.quit:
+		return nil, nil, errClosed
+	}
+}
+
+// GetBlocks fetches the outs, nils and packages of a range of confirmed blocks.
+func (c *Client) GetBlocks(ctx context.Context, start uint64, count uint64) ([]txtool.Block, error) {
+	_, data, err := c.request(ctx, BlocksMsg, func(p *peer, reqID uint64) error {
+		return p.RequestBlocks(reqID, start, count)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data.(*blocksData).Blocks, nil
+}
+
+// CheckNils checks whether nils were spent, as of the returned block number.
+func (c *Client) CheckNils(ctx context.Context, nils []c_type.Uint256) (uint64, []bool, error) {
+	if len(nils) > MaxNilFetch {
+		return 0, nil, fmt.Errorf("too many nils: %d > %d", len(nils), MaxNilFetch)
+	}
+	p, data, err := c.request(ctx, NilsMsg, func(p *peer, reqID uint64) error {
+		return p.RequestNils(reqID, nils)
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	resp := data.(*nilsData)
+	if len(resp.Spent) != len(nils) {
+		p.Disconnect(p2p.DiscUselessPeer)
+		return 0, nil, errInvalidNils
+	}
+	return resp.Number, resp.Spent, nil
+}
+
+// GetWitnesses fetches the witnesses of outs and verifies them against the
+// header they were taken at. The outs the peer has no witness of are missing
+// from the result. A peer serving an invalid witness is disconnected.
+func (c *Client) GetWitnesses(ctx context.Context, outs []txtool.Out) (*types.Header, []WitnessProof, error) {
+	if len(outs) > MaxWitnessFetch {
+		return nil, nil, fmt.Errorf("too many outs: %d > %d", len(outs), MaxWitnessFetch)
+	}
+	roots := make([]c_type.Uint256, 0, len(outs))
+	requested := make(map[c_type.Uint256]*txtool.Out, len(outs))
+	for i := range outs {
+		roots = append(roots, outs[i].Root)
+		requested[outs[i].Root] = &outs[i]
+	}
+	p, data, err := c.request(ctx, WitnessesMsg, func(p *peer, reqID uint64) error {
+		return p.RequestWitnesses(reqID, roots)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	resp := data.(*witnessesData)
+	header := c.headers.GetHeaderByHash(resp.BlockHash)
+	if header == nil || header.Number.Uint64() != resp.Number {
+		return nil, nil, errUnknownHeader
+	}
+	for i := range resp.Proofs {
+		proof := &resp.Proofs[i]
+		out, ok := requested[proof.Root]
+		if !ok {
+			err = errUnrequestedRoot
+		} else if out.State.OS.RootCM == nil || *out.State.OS.RootCM != proof.RootCM || out.State.OS.IsSzk() != proof.Szk {
+			err = fmt.Errorf("witness of root %x is not the one of its out", proof.Root[:8])
+		} else {
+			err = VerifyWitness(header, proof)
+		}
+		if err != nil {
+			log.Debug("Invalid light wallet witness", "peer", p.id, "err", err)
+			p.Disconnect(p2p.DiscUselessPeer)
+			return nil, nil, err
+		}
+	}
+	return header, resp.Proofs, nil
+}
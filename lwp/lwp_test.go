@@ -0,0 +1,133 @@
+package lwp
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/p2p/discover"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+// newTestPair creates a chain with an out in its genesis and connects a client
+// to the server of the chain over a message pipe.
+func newTestPair(t *testing.T) (*core.BlockChain, *Client, func()) {
+	cpt.ZeroInit(cpt.NET_Alpha)
+
+	alice := core.NewTestAccount("alice")
+	db := serodb.NewMemDatabase()
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{alice.Address(): {Balance: big.NewInt(1000000)}},
+	}
+	genesis := gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	txtool.Ref_inst.SetBC(&core.State1BlockChain{Bc: chain})
+	if _, err := core.GeneratePrivateChain(chain, 2, nil); err != nil {
+		t.Fatalf("failed to generate chain: %v", err)
+	}
+
+	server := newServer(chain, 1, 10)
+	client := NewClient(1, genesis.Hash(), chain)
+
+	app, net := p2p.MsgPipe()
+	go server.handle(newPeer(lwp1, p2p.NewPeer(discover.NodeID{1}, "client", nil), app))
+	go client.handle(newPeer(lwp1, p2p.NewPeer(discover.NodeID{2}, "server", nil), net))
+
+	for i := 0; client.peers.Len() == 0; i++ {
+		if i == 100 {
+			t.Fatal("client not connected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return chain, client, func() {
+		app.Close()
+		net.Close()
+		chain.Stop()
+	}
+}
+
+func TestClientSync(t *testing.T) {
+	chain, client, done := newTestPair(t)
+	defer done()
+
+	ctx := context.Background()
+	blocks, err := client.GetBlocks(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("failed to fetch blocks: %v", err)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("block count mismatch: have %d, want 3", len(blocks))
+	}
+	if len(blocks[0].Outs) != 1 {
+		t.Fatalf("genesis out count mismatch: have %d, want 1", len(blocks[0].Outs))
+	}
+	out := blocks[0].Outs[0]
+
+	number, spent, err := client.CheckNils(ctx, []c_type.Uint256{out.Root, {1}})
+	if err != nil {
+		t.Fatalf("failed to check nils: %v", err)
+	}
+	if number != chain.CurrentBlock().NumberU64() || len(spent) != 2 || spent[0] || spent[1] {
+		t.Fatalf("nils mismatch: number %d, spent %v", number, spent)
+	}
+
+	// The witness of the genesis out is verified against the head of the chain,
+	// the one of an unknown out is left out
+	unknown := txtool.Out{Root: c_type.Uint256{1}}
+	header, proofs, err := client.GetWitnesses(ctx, []txtool.Out{out, unknown})
+	if err != nil {
+		t.Fatalf("failed to fetch witnesses: %v", err)
+	}
+	if header.Hash() != chain.CurrentHeader().Hash() {
+		t.Fatalf("witness header mismatch: have %d", header.Number)
+	}
+	if len(proofs) != 1 || proofs[0].Root != out.Root {
+		t.Fatalf("proofs mismatch: %+v", proofs)
+	}
+
+	// A root missing from the tree is reported rather than panicking
+	st, err := chain.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := witness(&st.CurrentZState().State.CzeroTree, c_type.Uint256{1}); err != errUnknownRoot {
+		t.Fatalf("error mismatch: have %v, want %v", err, errUnknownRoot)
+	}
+
+	// A tampered witness is rejected
+	proof := proofs[0]
+	proof.Witness.Anchor[0] ^= 0xff
+	if err := VerifyWitness(header, &proof); err == nil {
+		t.Fatal("tampered witness verified")
+	}
+}
+
+func TestClientRejectsForeignOut(t *testing.T) {
+	_, client, done := newTestPair(t)
+	defer done()
+
+	ctx := context.Background()
+	blocks, err := client.GetBlocks(ctx, 0, 1)
+	if err != nil || len(blocks) != 1 || len(blocks[0].Outs) != 1 {
+		t.Fatalf("failed to fetch the genesis out: %v", err)
+	}
+	// The witness served is not the one of the out the client knows
+	out := blocks[0].Outs[0]
+	out.State.OS.RootCM = &c_type.Uint256{1}
+	if _, _, err := client.GetWitnesses(ctx, []txtool.Out{out}); err == nil {
+		t.Fatal("witness of another out accepted")
+	}
+}
@@ -0,0 +1,227 @@
+package lwp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+var (
+	errClosed            = errors.New("peer set is closed")
+	errAlreadyRegistered = errors.New("peer is already registered")
+	errNotRegistered     = errors.New("peer is not registered")
+)
+
+const handshakeTimeout = 5 * time.Second
+
+type peer struct {
+	*p2p.Peer
+
+	rw      p2p.MsgReadWriter
+	version int
+	id      string
+
+	head   common.Hash
+	number uint64
+	lock   sync.RWMutex
+}
+
+func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
+	id := p.ID()
+	return &peer{
+		Peer:    p,
+		rw:      rw,
+		version: version,
+		id:      fmt.Sprintf("%x", id[:8]),
+	}
+}
+
+// Head retrieves a copy of the current head hash and number of the peer.
+func (p *peer) Head() (hash common.Hash, number uint64) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.head, p.number
+}
+
+// RequestBlocks fetches the outs, nils and packages of a range of blocks.
+func (p *peer) RequestBlocks(reqID uint64, start uint64, count uint64) error {
+	p.Log().Debug("Fetching light wallet blocks", "start", start, "count", count)
+	return p2p.Send(p.rw, GetBlocksMsg, &getBlocksData{ReqID: reqID, Start: start, Count: count})
+}
+
+// RequestNils checks whether a batch of nils were spent.
+func (p *peer) RequestNils(reqID uint64, nils []c_type.Uint256) error {
+	p.Log().Debug("Checking light wallet nils", "count", len(nils))
+	return p2p.Send(p.rw, GetNilsMsg, &getNilsData{ReqID: reqID, Nils: nils})
+}
+
+// RequestWitnesses fetches the witnesses of a batch of out roots.
+func (p *peer) RequestWitnesses(reqID uint64, roots []c_type.Uint256) error {
+	p.Log().Debug("Fetching light wallet witnesses", "count", len(roots))
+	return p2p.Send(p.rw, GetWitnessesMsg, &getWitnessesData{ReqID: reqID, Roots: roots})
+}
+
+// SendBlocks sends a batch of blocks to the remote peer.
+func (p *peer) SendBlocks(reqID uint64, blocks []txtool.Block) error {
+	return p2p.Send(p.rw, BlocksMsg, &blocksData{ReqID: reqID, Blocks: blocks})
+}
+
+// SendNils sends the spent flags of a batch of nils to the remote peer.
+func (p *peer) SendNils(reqID uint64, number uint64, spent []bool) error {
+	return p2p.Send(p.rw, NilsMsg, &nilsData{ReqID: reqID, Number: number, Spent: spent})
+}
+
+// SendWitnesses sends a batch of witness proofs to the remote peer.
+func (p *peer) SendWitnesses(reqID uint64, hash common.Hash, number uint64, proofs []WitnessProof) error {
+	return p2p.Send(p.rw, WitnessesMsg, &witnessesData{ReqID: reqID, BlockHash: hash, Number: number, Proofs: proofs})
+}
+
+// Handshake executes the lwp protocol handshake, negotiating version number,
+// network IDs, head and genesis blocks.
+func (p *peer) Handshake(network uint64, head common.Hash, number uint64, genesis common.Hash) error {
+	errc := make(chan error, 2)
+	var status statusData // safe to read after two values have been received from errc
+
+	go func() {
+		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
+			ProtocolVersion: uint32(p.version),
+			NetworkId:       network,
+			CurrentBlock:    head,
+			CurrentNumber:   number,
+			GenesisBlock:    genesis,
+		})
+	}()
+	go func() {
+		errc <- p.readStatus(network, &status, genesis)
+	}()
+	timeout := time.NewTimer(handshakeTimeout)
+	defer timeout.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				return err
+			}
+		case <-timeout.C:
+			return p2p.DiscReadTimeout
+		}
+	}
+	p.head, p.number = status.CurrentBlock, status.CurrentNumber
+	return nil
+}
+
+func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Code != StatusMsg {
+		return errResp(ErrNoStatusMsg, "first msg has code %x (!= %x)", msg.Code, StatusMsg)
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	if err := msg.Decode(&status); err != nil {
+		return errResp(ErrDecode, "msg %v: %v", msg, err)
+	}
+	if status.GenesisBlock != genesis {
+		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", status.GenesisBlock[:8], genesis[:8])
+	}
+	if status.NetworkId != network {
+		return errResp(ErrNetworkIdMismatch, "%d (!= %d)", status.NetworkId, network)
+	}
+	if int(status.ProtocolVersion) != p.version {
+		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", status.ProtocolVersion, p.version)
+	}
+	return nil
+}
+
+// String implements fmt.Stringer.
+func (p *peer) String() string {
+	return fmt.Sprintf("Peer %s [%s]", p.id,
+		fmt.Sprintf("lwp/%2d", p.version),
+	)
+}
+
+// peerSet represents the collection of active light wallet peers.
+type peerSet struct {
+	peers  map[string]*peer
+	lock   sync.RWMutex
+	closed bool
+}
+
+func newPeerSet() *peerSet {
+	return &peerSet{
+		peers: make(map[string]*peer),
+	}
+}
+
+// Register injects a new peer into the working set, or returns an error if the
+// peer is already known.
+func (ps *peerSet) Register(p *peer) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if ps.closed {
+		return errClosed
+	}
+	if _, ok := ps.peers[p.id]; ok {
+		return errAlreadyRegistered
+	}
+	ps.peers[p.id] = p
+	return nil
+}
+
+// Unregister removes a remote peer from the active set.
+func (ps *peerSet) Unregister(id string) error {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.peers[id]; !ok {
+		return errNotRegistered
+	}
+	delete(ps.peers, id)
+	return nil
+}
+
+// Len returns if the current number of peers in the set.
+func (ps *peerSet) Len() int {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	return len(ps.peers)
+}
+
+// BestPeer retrieves the known peer with the highest head.
+func (ps *peerSet) BestPeer() *peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	var (
+		bestPeer   *peer
+		bestNumber uint64
+	)
+	for _, p := range ps.peers {
+		if _, number := p.Head(); bestPeer == nil || number > bestNumber {
+			bestPeer, bestNumber = p, number
+		}
+	}
+	return bestPeer
+}
+
+// Close disconnects all peers.
+func (ps *peerSet) Close() {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	for _, p := range ps.peers {
+		p.Disconnect(p2p.DiscQuitting)
+	}
+	ps.closed = true
+}
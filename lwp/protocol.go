@@ -0,0 +1,142 @@
+// Package lwp implements the light wallet protocol, a devp2p sub-protocol
+// serving the outs, nils and witnesses a SERO light wallet needs to sync its
+// UTXOs from any full node.
+package lwp
+
+import (
+	"fmt"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	lwp1 = 1
+)
+
+// ProtocolName is the official short name of the protocol used during capability negotiation.
+var ProtocolName = "lwp"
+
+// ProtocolVersions are the supported versions of the lwp protocol (first is primary).
+var ProtocolVersions = []uint{lwp1}
+
+// ProtocolLengths are the number of implemented message corresponding to different protocol versions.
+var ProtocolLengths = []uint64{7}
+
+const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
+
+// lwp protocol message codes
+const (
+	StatusMsg       = 0x00
+	GetBlocksMsg    = 0x01
+	BlocksMsg       = 0x02
+	GetNilsMsg      = 0x03
+	NilsMsg         = 0x04
+	GetWitnessesMsg = 0x05
+	WitnessesMsg    = 0x06
+)
+
+const (
+	MaxBlockFetch   = 128  // Amount of blocks to be served per request
+	MaxNilFetch     = 1024 // Amount of nils to be checked per request
+	MaxWitnessFetch = 64   // Amount of witnesses to be served per request
+)
+
+type errCode int
+
+const (
+	ErrMsgTooLarge = iota
+	ErrDecode
+	ErrInvalidMsgCode
+	ErrProtocolVersionMismatch
+	ErrNetworkIdMismatch
+	ErrGenesisBlockMismatch
+	ErrNoStatusMsg
+	ErrExtraStatusMsg
+	ErrUnexpectedResponse
+)
+
+func (e errCode) String() string {
+	return errorToString[int(e)]
+}
+
+var errorToString = map[int]string{
+	ErrMsgTooLarge:             "Message too long",
+	ErrDecode:                  "Invalid message",
+	ErrInvalidMsgCode:          "Invalid message code",
+	ErrProtocolVersionMismatch: "Protocol version mismatch",
+	ErrNetworkIdMismatch:       "NetworkId mismatch",
+	ErrGenesisBlockMismatch:    "Genesis block mismatch",
+	ErrNoStatusMsg:             "No status message",
+	ErrExtraStatusMsg:          "Extra status message",
+	ErrUnexpectedResponse:      "Unexpected response",
+}
+
+func errResp(code errCode, format string, v ...interface{}) error {
+	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+}
+
+// statusData is the network packet for the status message.
+type statusData struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	CurrentBlock    common.Hash
+	CurrentNumber   uint64
+	GenesisBlock    common.Hash
+}
+
+// getBlocksData represents a request for the outs, nils and packages of a range
+// of confirmed blocks.
+type getBlocksData struct {
+	ReqID uint64
+	Start uint64
+	Count uint64
+}
+
+// blocksData is the network response to getBlocksData.
+type blocksData struct {
+	ReqID  uint64
+	Blocks []txtool.Block
+}
+
+// getNilsData represents a request to check whether nils were spent.
+type getNilsData struct {
+	ReqID uint64
+	Nils  []c_type.Uint256
+}
+
+// nilsData is the network response to getNilsData, Spent follows the order of
+// the requested nils.
+type nilsData struct {
+	ReqID  uint64
+	Number uint64
+	Spent  []bool
+}
+
+// getWitnessesData represents a request for the merkle witnesses of out roots.
+type getWitnessesData struct {
+	ReqID uint64
+	Roots []c_type.Uint256
+}
+
+// WitnessProof is the witness of an out together with the proof of its anchor
+// against the state root of the header it was taken at.
+type WitnessProof struct {
+	Root         c_type.Uint256
+	RootCM       c_type.Uint256
+	Szk          bool
+	TreeIndex    uint64
+	Witness      txtool.Witness
+	AccountProof [][]byte
+	StorageProof [][]byte
+}
+
+// witnessesData is the network response to getWitnessesData.
+type witnessesData struct {
+	ReqID     uint64
+	BlockHash common.Hash
+	Number    uint64
+	Proofs    []WitnessProof
+}
@@ -0,0 +1,282 @@
+package lwp
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/p2p/discover"
+	"github.com/sero-cash/go-sero/sero"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/merkle"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/txtool/flight"
+)
+
+// Server serves the light wallet protocol from a full node, it is plugged into
+// the Sero service as its LesServer.
+type Server struct {
+	blockchain *core.BlockChain
+	networkId  uint64
+
+	maxPeers  int
+	peers     *peerSet
+	protocols []p2p.Protocol
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewServer creates a light wallet protocol server on top of a full node.
+func NewServer(s *sero.Sero, config *sero.Config) *Server {
+	return newServer(s.BlockChain(), s.NetVersion(), config.LightPeers)
+}
+
+func newServer(blockchain *core.BlockChain, networkId uint64, maxPeers int) *Server {
+	server := &Server{
+		blockchain: blockchain,
+		networkId:  networkId,
+		maxPeers:   maxPeers,
+		peers:      newPeerSet(),
+		quit:       make(chan struct{}),
+	}
+	for i, version := range ProtocolVersions {
+		version := version // Closure for the run
+		server.protocols = append(server.protocols, p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				select {
+				case <-server.quit:
+					return p2p.DiscQuitting
+				default:
+				}
+				server.wg.Add(1)
+				defer server.wg.Done()
+				return server.handle(newPeer(int(version), p, rw))
+			},
+			NodeInfo: func() interface{} {
+				return server.NodeInfo()
+			},
+			PeerInfo: func(id discover.NodeID) interface{} {
+				return nil
+			},
+		})
+	}
+	return server
+}
+
+// Protocols implements sero.LesServer.
+func (s *Server) Protocols() []p2p.Protocol {
+	return s.protocols
+}
+
+// Start implements sero.LesServer.
+func (s *Server) Start(srvr *p2p.Server) {
+	log.Info("Light wallet protocol started", "network", s.networkId, "peers", s.maxPeers)
+}
+
+// Stop implements sero.LesServer, disconnecting all light wallet peers.
+func (s *Server) Stop() {
+	close(s.quit)
+	s.peers.Close()
+	s.wg.Wait()
+	log.Info("Light wallet protocol stopped")
+}
+
+// SetBloomBitsIndexer implements sero.LesServer, the light wallet protocol
+// doesn't serve bloom bits.
+func (s *Server) SetBloomBitsIndexer(bbIndexer *core.ChainIndexer) {
+}
+
+// NodeInfo represents a short summary of the light wallet protocol metadata
+// known about the host peer.
+type NodeInfo struct {
+	Network uint64      `json:"network"`
+	Genesis common.Hash `json:"genesis"`
+	Head    common.Hash `json:"head"`
+	Number  uint64      `json:"number"`
+}
+
+// NodeInfo retrieves some protocol metadata about the running host node.
+func (s *Server) NodeInfo() *NodeInfo {
+	head := s.blockchain.CurrentHeader()
+	return &NodeInfo{
+		Network: s.networkId,
+		Genesis: s.blockchain.Genesis().Hash(),
+		Head:    head.Hash(),
+		Number:  head.Number.Uint64(),
+	}
+}
+
+func (s *Server) handle(p *peer) error {
+	if s.peers.Len() >= s.maxPeers && !p.Peer.Info().Network.Trusted {
+		return p2p.DiscTooManyPeers
+	}
+	p.Log().Debug("Light wallet peer connected", "name", p.Name())
+
+	head := s.blockchain.CurrentHeader()
+	if err := p.Handshake(s.networkId, head.Hash(), head.Number.Uint64(), s.blockchain.Genesis().Hash()); err != nil {
+		p.Log().Debug("Light wallet handshake failed", "err", err)
+		return err
+	}
+	if err := s.peers.Register(p); err != nil {
+		p.Log().Error("Light wallet peer registration failed", "err", err)
+		return err
+	}
+	defer s.peers.Unregister(p.id)
+
+	for {
+		if err := s.handleMsg(p); err != nil {
+			p.Log().Debug("Light wallet message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMsg is invoked whenever an inbound message is received from a remote
+// peer. The remote connection is torn down upon returning any error.
+func (s *Server) handleMsg(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case StatusMsg:
+		return errResp(ErrExtraStatusMsg, "uncontrolled status message")
+
+	case GetBlocksMsg:
+		var req getBlocksData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if req.Count > MaxBlockFetch {
+			req.Count = MaxBlockFetch
+		}
+		blocks, err := flight.SRI_Inst.GetBlocksInfo(req.Start, req.Count)
+		if err != nil {
+			p.Log().Debug("Light wallet blocks unavailable", "start", req.Start, "err", err)
+		}
+		return p.SendBlocks(req.ReqID, blocks)
+
+	case GetNilsMsg:
+		var req getNilsData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if len(req.Nils) > MaxNilFetch {
+			req.Nils = req.Nils[:MaxNilFetch]
+		}
+		number, spent, err := s.checkNils(req.Nils)
+		if err != nil {
+			return err
+		}
+		return p.SendNils(req.ReqID, number, spent)
+
+	case GetWitnessesMsg:
+		var req getWitnessesData
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if len(req.Roots) > MaxWitnessFetch {
+			req.Roots = req.Roots[:MaxWitnessFetch]
+		}
+		hash, number, proofs, err := s.witnesses(p, req.Roots)
+		if err != nil {
+			return err
+		}
+		return p.SendWitnesses(req.ReqID, hash, number, proofs)
+
+	case BlocksMsg, NilsMsg, WitnessesMsg:
+		// A server never requests anything, drop unsolicited responses
+		return nil
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+}
+
+func (s *Server) checkNils(nils []c_type.Uint256) (number uint64, spent []bool, e error) {
+	header := s.blockchain.CurrentHeader()
+	st, err := s.blockchain.StateAt(header)
+	if err != nil {
+		e = err
+		return
+	}
+	zst := st.CurrentZState()
+	number = header.Number.Uint64()
+	for i := range nils {
+		spent = append(spent, zst.State.HasIn(&nils[i]))
+	}
+	return
+}
+
+func witness(tree *merkle.MerkleTree, rootCM c_type.Uint256) (wit txtool.Witness, e error) {
+	if !tree.HasLeaf(rootCM) {
+		e = errUnknownRoot
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			e = fmt.Errorf("invalid merkle tree: %v", r)
+		}
+	}()
+	pos, paths, anchor := tree.GetPaths(rootCM)
+	wit = txtool.Witness{Pos: hexutil.Uint64(pos), Paths: paths, Anchor: anchor}
+	return
+}
+
+func (s *Server) witnesses(p *peer, roots []c_type.Uint256) (hash common.Hash, number uint64, proofs []WitnessProof, e error) {
+	header := s.blockchain.CurrentHeader()
+	st, err := s.blockchain.StateAt(header)
+	if err != nil {
+		e = err
+		return
+	}
+	zst := st.CurrentZState()
+	hash, number = header.Hash(), header.Number.Uint64()
+
+	for _, root := range roots {
+		out := flight.GetOut(&root, 0)
+		if out == nil || out.OS.RootCM == nil {
+			continue
+		}
+		proof := WitnessProof{Root: root, RootCM: *out.OS.RootCM, Szk: out.OS.IsSzk()}
+
+		tree, param := &zst.State.CzeroTree, &txstate.CzeroMerkleParam
+		if proof.Szk {
+			tree, param = &zst.State.SzkTree, &txstate.SzkMerkleParam
+		}
+		// The roots are chosen by the peer, skip the ones not in the tree of
+		// this state rather than letting the tree panic
+		if proof.Witness, err = witness(tree, proof.RootCM); err != nil {
+			p.Log().Debug("Light wallet witness unavailable", "root", root, "err", err)
+			continue
+		}
+		proof.TreeIndex = tree.GetTreeIndex(proof.RootCM)
+
+		obj, key := param.AnchorKey(proof.TreeIndex)
+		addr := anchorAddress(&obj)
+		if proof.AccountProof, err = st.GetProof(addr); err != nil {
+			e = fmt.Errorf("account proof of root %v: %v", root, err)
+			return
+		}
+		if proof.StorageProof, err = st.GetStorageProof(addr, common.Hash(key)); err != nil {
+			e = fmt.Errorf("storage proof of root %v: %v", root, err)
+			return
+		}
+		proofs = append(proofs, proof)
+	}
+	return
+}
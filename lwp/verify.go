@@ -0,0 +1,81 @@
+package lwp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/trie"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
+)
+
+var (
+	errWitnessPaths  = errors.New("witness paths do not lead to the anchor")
+	errAnchorMissing = errors.New("anchor is not stored in the state")
+	errAnchorInvalid = errors.New("anchor does not match the state")
+	errUnknownRoot   = errors.New("root is not in the merkle tree")
+)
+
+// anchorAddress maps the state object of a merkle tree to its account address,
+// the same way the zero state trie does.
+func anchorAddress(obj *c_type.PKr) (addr common.Address) {
+	copy(addr[:], obj[:])
+	return
+}
+
+func proofDb(proof [][]byte) *serodb.MemDatabase {
+	db := serodb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// VerifyWitness checks a witness served by a peer: the merkle path of the out
+// must lead to the anchor, and the anchor must be proven by the account and
+// storage proofs against the state root of the header the witness was taken at.
+func VerifyWitness(header *types.Header, proof *WitnessProof) error {
+	param := &txstate.CzeroMerkleParam
+	if proof.Szk {
+		param = &txstate.SzkMerkleParam
+	}
+	if param.CalcRoot(&proof.RootCM, uint64(proof.Witness.Pos), &proof.Witness.Paths) != proof.Witness.Anchor {
+		return errWitnessPaths
+	}
+
+	obj, key := param.AnchorKey(proof.TreeIndex)
+	addr := anchorAddress(&obj)
+	enc, _, err := trie.VerifyProof(header.Root, crypto.Keccak256(addr.Bytes()), proofDb(proof.AccountProof))
+	if err != nil {
+		return fmt.Errorf("invalid account proof: %v", err)
+	}
+	if len(enc) == 0 {
+		return errAnchorMissing
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		return fmt.Errorf("invalid account: %v", err)
+	}
+
+	enc, _, err = trie.VerifyProof(account.Root, crypto.Keccak256(key[:]), proofDb(proof.StorageProof))
+	if err != nil {
+		return fmt.Errorf("invalid storage proof: %v", err)
+	}
+	if len(enc) == 0 {
+		return errAnchorMissing
+	}
+	_, content, _, err := rlp.Split(enc)
+	if err != nil {
+		return fmt.Errorf("invalid anchor: %v", err)
+	}
+	if common.BytesToHash(content) != common.Hash(proof.Witness.Anchor) {
+		return errAnchorInvalid
+	}
+	return nil
+}
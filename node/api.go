@@ -19,6 +19,7 @@ package node
 import (
 	"context"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -106,6 +107,67 @@ func (api *PrivateAdminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	return true, nil
 }
 
+// parseBanTarget parses an snode URL or a node ID, banned by node ID, or an IP.
+func parseBanTarget(target string) (*discover.NodeID, net.IP, error) {
+	if ip := net.ParseIP(target); ip != nil {
+		return nil, ip, nil
+	}
+	if id, err := discover.HexID(target); err == nil {
+		return &id, nil, nil
+	}
+	node, err := discover.ParseNode(target)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid snode, node ID or IP: %v", err)
+	}
+	return &node.ID, nil, nil
+}
+
+// BanPeer bans a node, given by snode URL or node ID, or an IP for the given
+// number of seconds, for a duration doubling with every ban if zero. The
+// matching peers are disconnected.
+func (api *PrivateAdminAPI) BanPeer(target string, seconds *uint64) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, ip, err := parseBanTarget(target)
+	if err != nil {
+		return false, err
+	}
+	var duration time.Duration
+	if seconds != nil {
+		duration = time.Duration(*seconds) * time.Second
+	}
+	server.BanPeer(id, ip, duration, "banned by admin")
+	return true, nil
+}
+
+// UnbanPeer lifts the ban of a node, given by snode URL or node ID, or of an IP
+// and forgets its penalties.
+func (api *PrivateAdminAPI) UnbanPeer(target string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, ip, err := parseBanTarget(target)
+	if err != nil {
+		return false, err
+	}
+	return server.Reputation().Unban(id, ip), nil
+}
+
+// ListBans returns the nodes and the IPs banned.
+func (api *PrivateAdminAPI) ListBans() ([]p2p.BanInfo, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.Reputation().Bans(), nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
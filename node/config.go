@@ -42,6 +42,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirBanList         = "bans.json"          // Path within the datadir to the peer bans
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -124,6 +125,16 @@ type Config struct {
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
 
+	// RPCAuthFile is the token file authenticating the clients of the HTTP and
+	// websocket RPC interfaces and listing the methods each one may call. If
+	// this field is empty, the interfaces are open to everyone reaching them.
+	RPCAuthFile string `toml:",omitempty"`
+
+	// RPCRateLimit is the token bucket each client of the HTTP and websocket RPC
+	// interfaces is held to, charging every call its method cost. A zero rate
+	// disables the limits.
+	RPCRateLimit rpc.RateLimitConfig `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -187,6 +198,14 @@ func (c *Config) NodeDB() string {
 	return c.ResolvePath(datadirNodeDatabase)
 }
 
+// BanList returns the path to the file of the peer bans.
+func (c *Config) BanList() string {
+	if c.DataDir == "" {
+		return "" // ephemeral
+	}
+	return c.ResolvePath(datadirBanList)
+}
+
 // DefaultIPCEndpoint returns the IPC path used by default.
 func DefaultIPCEndpoint(clientIdentifier string) string {
 	if clientIdentifier == "" {
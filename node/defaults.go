@@ -41,6 +41,7 @@ var DefaultConfig = Config{
 	HTTPModules:      []string{"net", "web3"},
 	HTTPVirtualHosts: []string{"localhost"},
 	HTTPTimeouts:     rpc.DefaultHTTPTimeouts,
+	RPCRateLimit:     rpc.RateLimitConfig{Costs: rpc.DefaultMethodCosts},
 	WSPort:           DefaultWSPort,
 	WSModules:        []string{"net", "web3"},
 	P2P: p2p.Config{
@@ -67,6 +67,8 @@ type Node struct {
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
 
+	rpcLimiter *rpc.RateLimiter // Token buckets of the clients shared by the HTTP and websocket endpoints
+
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 
@@ -162,6 +164,9 @@ func (n *Node) Start() error {
 	if n.serverConfig.NodeDatabase == "" {
 		n.serverConfig.NodeDatabase = n.config.NodeDB()
 	}
+	if n.serverConfig.BanList == "" {
+		n.serverConfig.BanList = n.config.BanList()
+	}
 	running := &p2p.Server{Config: n.serverConfig}
 	n.log.Info("Starting peer-to-peer node", "instance", n.serverConfig.Name)
 
@@ -336,11 +341,19 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts)
+	auth, err := n.rpcAuth()
+	if err != nil {
+		return err
+	}
+	limiter, err := n.rateLimiter()
+	if err != nil {
+		return err
+	}
+	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, timeouts, auth, limiter)
 	if err != nil {
 		return err
 	}
-	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","), "auth", auth != nil)
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
 	n.httpListener = listener
@@ -369,11 +382,19 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	auth, err := n.rpcAuth()
+	if err != nil {
+		return err
+	}
+	limiter, err := n.rateLimiter()
 	if err != nil {
 		return err
 	}
-	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
+	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll, auth, limiter)
+	if err != nil {
+		return err
+	}
+	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()), "auth", auth != nil)
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
 	n.wsListener = listener
@@ -382,6 +403,28 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	return nil
 }
 
+// rpcAuth loads the token file of the HTTP and websocket RPC endpoints, it's
+// read again on every start to pick up the changed tokens.
+func (n *Node) rpcAuth() (*rpc.Authenticator, error) {
+	if n.config.RPCAuthFile == "" {
+		return nil, nil
+	}
+	return rpc.LoadAuthConfig(n.config.RPCAuthFile)
+}
+
+// rateLimiter returns the limiter of the HTTP and websocket RPC clients, so a
+// client is held to the same limit on both.
+func (n *Node) rateLimiter() (*rpc.RateLimiter, error) {
+	if n.rpcLimiter == nil {
+		limiter, err := rpc.NewRateLimiter(n.config.RPCRateLimit)
+		if err != nil {
+			return nil, err
+		}
+		n.rpcLimiter = limiter
+	}
+	return n.rpcLimiter, nil
+}
+
 // stopWS terminates the websocket RPC endpoint.
 func (n *Node) stopWS() {
 	if n.wsListener != nil {
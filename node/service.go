@@ -17,6 +17,7 @@
 package node
 
 import (
+	"crypto/ecdsa"
 	"reflect"
 
 	"github.com/sero-cash/go-sero/accounts"
@@ -57,6 +58,11 @@ func (ctx *ServiceContext) ResolvePath(path string) string {
 	return ctx.config.ResolvePath(path)
 }
 
+// NodeKey returns the private key of the node.
+func (ctx *ServiceContext) NodeKey() *ecdsa.PrivateKey {
+	return ctx.config.NodeKey()
+}
+
 // Service retrieves a currently running service registered of a specific type.
 func (ctx *ServiceContext) Service(service interface{}) error {
 	element := reflect.ValueOf(service).Elem()
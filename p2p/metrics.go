@@ -31,6 +31,14 @@ var (
 	egressTrafficMeter  = metrics.NewRegisteredMeter("p2p/OutboundTraffic", nil)
 )
 
+// protocolTrafficMeters returns the meters of the inbound and outbound traffic
+// of a sub-protocol, named p2p/<protocol>/InboundTraffic and OutboundTraffic.
+func protocolTrafficMeters(name string) (ingress, egress metrics.Meter) {
+	ingress = metrics.GetOrRegisterMeter("p2p/"+name+"/InboundTraffic", nil)
+	egress = metrics.GetOrRegisterMeter("p2p/"+name+"/OutboundTraffic", nil)
+	return ingress, egress
+}
+
 // meteredConn is a wrapper around a net.Conn that meters both the
 // inbound and outbound network traffic.
 type meteredConn struct {
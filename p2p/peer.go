@@ -28,6 +28,7 @@ import (
 	"github.com/sero-cash/go-sero/common/mclock"
 	"github.com/sero-cash/go-sero/event"
 	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/metrics"
 	"github.com/sero-cash/go-sero/p2p/discover"
 	"github.com/sero-cash/go-sero/rlp"
 )
@@ -168,6 +169,12 @@ func (p *Peer) Inbound() bool {
 	return p.rw.is(inboundConn)
 }
 
+// Trusted returns true if the peer is a trusted or a static one, which are
+// never banned.
+func (p *Peer) Trusted() bool {
+	return p.rw.is(trustedConn | staticDialedConn)
+}
+
 func newPeer(conn *conn, protocols []Protocol) *Peer {
 	protomap := matchProtocols(protocols, conn.caps, conn)
 	p := &Peer{
@@ -327,7 +334,8 @@ outer:
 					offset -= old.Length
 				}
 				// Assign the new match
-				result[cap.Name] = &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw}
+				ingress, egress := protocolTrafficMeters(proto.Name)
+				result[cap.Name] = &protoRW{Protocol: proto, offset: offset, in: make(chan Msg), w: rw, ingress: ingress, egress: egress}
 				offset += proto.Length
 
 				continue outer
@@ -382,6 +390,9 @@ type protoRW struct {
 	werr   chan<- error    // for write results
 	offset uint64
 	w      MsgWriter
+
+	ingress metrics.Meter // meters the traffic of the protocol
+	egress  metrics.Meter
 }
 
 func (rw *protoRW) WriteMsg(msg Msg) (err error) {
@@ -392,6 +403,9 @@ func (rw *protoRW) WriteMsg(msg Msg) (err error) {
 	select {
 	case <-rw.wstart:
 		err = rw.w.WriteMsg(msg)
+		if err == nil {
+			rw.egress.Mark(int64(msg.Size))
+		}
 		// Report write status back to Peer.run. It will initiate
 		// shutdown if the error is non-nil and unblock the next write
 		// otherwise. The calling protocol code should exit for errors
@@ -407,6 +421,7 @@ func (rw *protoRW) ReadMsg() (Msg, error) {
 	select {
 	case msg := <-rw.in:
 		msg.Code -= rw.offset
+		rw.ingress.Mark(int64(msg.Size))
 		return msg, nil
 	case <-rw.closed:
 		return Msg{}, io.EOF
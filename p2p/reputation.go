@@ -0,0 +1,299 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/p2p/discover"
+)
+
+const (
+	reputationHalfLife = time.Hour          // Time for the score of a peer to halve
+	banThreshold       = 100                // Score banning a node ID
+	ipBanThreshold     = 300                // Score banning an IP, shared by the nodes behind it
+	minBanDuration     = time.Hour          // Duration of the first ban of a peer
+	maxBanDuration     = 7 * 24 * time.Hour // Cap of the duration of the repeated bans
+)
+
+// The penalties of the misbehaviours of the peers, a node ID reaching a score
+// of 100 is banned.
+const (
+	PenaltyInvalidMsg  = 25 // Undecodable message
+	PenaltyInvalidVote = 50 // Undecodable vote or vote with an invalid signature
+	PenaltyBadTx       = 20 // Transaction failing the verification of its proofs
+	PenaltyBadBlock    = 50 // Propagated block failing the verification
+)
+
+// BanInfo is the reputation of a node ID or of an IP.
+type BanInfo struct {
+	ID      *discover.NodeID `json:"id,omitempty"`
+	IP      net.IP           `json:"ip,omitempty"`
+	Score   float64          `json:"score"`   // Score of the penalties, decaying over time
+	Bans    int              `json:"bans"`    // Number of the bans, doubling their duration
+	Until   time.Time        `json:"until"`   // End of the current ban
+	Reason  string           `json:"reason"`  // Reason of the last penalty or ban
+	Updated time.Time        `json:"updated"` // Time the score was last updated
+}
+
+// Banned tells whether the node ID or the IP is banned at the given time.
+func (self *BanInfo) Banned(now time.Time) bool {
+	return self.Until.After(now)
+}
+
+// decay lowers the score for the time elapsed since its last update.
+func (self *BanInfo) decay(now time.Time) {
+	if elapsed := now.Sub(self.Updated); elapsed > 0 {
+		self.Score *= math.Pow(0.5, float64(elapsed)/float64(reputationHalfLife))
+	}
+	self.Updated = now
+}
+
+// ban bans for a duration doubling with every ban unless given.
+func (self *BanInfo) ban(now time.Time, duration time.Duration, reason string) {
+	if duration == 0 {
+		duration = maxBanDuration
+		if self.Bans < 8 {
+			if d := minBanDuration << uint(self.Bans); d < maxBanDuration {
+				duration = d
+			}
+		}
+	}
+	self.Bans++
+	self.Score = 0
+	self.Until = now.Add(duration)
+	self.Reason = reason
+}
+
+// Reputation keeps the penalty scores and the bans of the peers, by node ID
+// and by IP, in the file given if any. The methods of a nil reputation do
+// nothing.
+type Reputation struct {
+	path    string
+	records map[string]*BanInfo
+	lock    sync.Mutex
+}
+
+// NewReputation loads the reputations saved in the given file.
+func NewReputation(path string) *Reputation {
+	r := &Reputation{path: path, records: make(map[string]*BanInfo)}
+	if path == "" {
+		return r
+	}
+	var list []*BanInfo
+	if err := common.LoadJSON(path, &list); err != nil {
+		if !os.IsNotExist(err) {
+			log.Error("Can't load the peer bans", "file", path, "err", err)
+		}
+		return r
+	}
+	for _, info := range list {
+		if key := reputationKey(info.ID, info.IP); key != "" {
+			r.records[key] = info
+		}
+	}
+	return r
+}
+
+func reputationKey(id *discover.NodeID, ip net.IP) string {
+	switch {
+	case id != nil:
+		return "id:" + id.String()
+	case ip != nil:
+		return "ip:" + ip.String()
+	default:
+		return ""
+	}
+}
+
+// keys returns the keys of the records of a node ID and an IP, each optional.
+func (r *Reputation) keys(id *discover.NodeID, ip net.IP) (keys []string) {
+	if id != nil && *id != (discover.NodeID{}) {
+		keys = append(keys, reputationKey(id, nil))
+	}
+	if len(ip) > 0 && !ip.IsUnspecified() {
+		keys = append(keys, reputationKey(nil, ip))
+	}
+	return
+}
+
+func (r *Reputation) record(key string, id *discover.NodeID, ip net.IP) *BanInfo {
+	info, ok := r.records[key]
+	if !ok {
+		info = &BanInfo{Updated: time.Now()}
+		if id != nil {
+			nid := *id
+			info.ID = &nid
+		} else {
+			info.IP = ip
+		}
+		r.records[key] = info
+	}
+	return info
+}
+
+// Penalize adds a penalty to the node ID and to the IP of a peer, if given,
+// and bans each of them once its score reaches its threshold. It returns
+// whether the peer got banned.
+func (r *Reputation) Penalize(id discover.NodeID, ip net.IP, points float64, reason string) (banned bool) {
+	if r == nil {
+		return false
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	for _, key := range r.keys(&id, ip) {
+		var info *BanInfo
+		threshold := float64(banThreshold)
+		if key[:3] == "id:" {
+			info = r.record(key, &id, nil)
+		} else {
+			info = r.record(key, nil, ip)
+			threshold = ipBanThreshold
+		}
+		if info.Banned(now) {
+			banned = true
+			continue
+		}
+		info.decay(now)
+		info.Score += points
+		info.Reason = reason
+		if info.Score >= threshold {
+			info.ban(now, 0, reason)
+			log.Warn("Banned peer", "id", id, "ip", ip, "until", info.Until, "reason", reason)
+			banned = true
+		}
+	}
+	if banned {
+		r.save()
+	}
+	return banned
+}
+
+// Ban bans a node ID, an IP or both for the given duration, for a duration
+// doubling with every ban if zero.
+func (r *Reputation) Ban(id *discover.NodeID, ip net.IP, duration time.Duration, reason string) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	if id != nil {
+		r.record(reputationKey(id, nil), id, nil).ban(now, duration, reason)
+	}
+	if ip != nil {
+		r.record(reputationKey(nil, ip), nil, ip).ban(now, duration, reason)
+	}
+	r.save()
+}
+
+// Unban lifts the bans and forgets the penalties of a node ID, an IP or both.
+func (r *Reputation) Unban(id *discover.NodeID, ip net.IP) (found bool) {
+	if r == nil {
+		return false
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, key := range r.keys(id, ip) {
+		if _, ok := r.records[key]; ok {
+			delete(r.records, key)
+			found = true
+		}
+	}
+	if found {
+		r.save()
+	}
+	return found
+}
+
+// Banned tells whether the node ID or the IP of a peer is banned.
+func (r *Reputation) Banned(id discover.NodeID, ip net.IP) bool {
+	if r == nil {
+		return false
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	for _, key := range r.keys(&id, ip) {
+		if info, ok := r.records[key]; ok && info.Banned(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// Bans returns the node IDs and the IPs banned, by end of ban.
+func (r *Reputation) Bans() []BanInfo {
+	if r == nil {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	bans := []BanInfo{}
+	for _, info := range r.records {
+		if info.Banned(now) {
+			bans = append(bans, *info)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Until.Before(bans[j].Until) })
+	return bans
+}
+
+// save writes the bans and the scores not decayed yet, dropping the others
+// once the bans are old enough to be forgotten.
+func (r *Reputation) save() {
+	now := time.Now()
+	list := []*BanInfo{}
+	for key, info := range r.records {
+		if !info.Banned(now) {
+			info.decay(now)
+			if info.Score < 1 && (info.Bans == 0 || now.Sub(info.Until) > maxBanDuration) {
+				delete(r.records, key)
+				continue
+			}
+		}
+		list = append(list, info)
+	}
+	if r.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err == nil {
+		if err = ioutil.WriteFile(r.path+".tmp", data, 0644); err == nil {
+			err = os.Rename(r.path+".tmp", r.path)
+		}
+	}
+	if err != nil {
+		log.Error("Can't save the peer bans", "file", r.path, "err", err)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-sero/p2p/discover"
+)
+
+func TestReputationDecay(t *testing.T) {
+	info := &BanInfo{Score: 80, Updated: time.Now().Add(-reputationHalfLife)}
+	info.decay(time.Now())
+	if math.Abs(info.Score-40) > 0.1 {
+		t.Fatalf("score mismatch after a half life: have %v, want 40", info.Score)
+	}
+
+	// A penalty decayed for a half life doesn't add up to a ban
+	r := NewReputation("")
+	id := discover.NodeID{1}
+	r.Penalize(id, nil, PenaltyInvalidVote, "vote")
+	r.records[reputationKey(&id, nil)].Updated = time.Now().Add(-reputationHalfLife)
+	if r.Penalize(id, nil, PenaltyInvalidVote, "vote") {
+		t.Fatal("peer banned for decayed penalties")
+	}
+	if !r.Penalize(id, nil, PenaltyInvalidVote, "vote") {
+		t.Fatal("peer not banned once over the threshold")
+	}
+}
+
+func TestReputationBanExpiry(t *testing.T) {
+	r := NewReputation("")
+	id := discover.NodeID{1}
+	if !r.Penalize(id, nil, banThreshold, "bad") {
+		t.Fatal("peer not banned")
+	}
+	info := r.records[reputationKey(&id, nil)]
+	if d := time.Until(info.Until); d > minBanDuration || d < minBanDuration-time.Minute {
+		t.Fatalf("first ban duration mismatch: have %v, want %v", d, minBanDuration)
+	}
+	if !r.Banned(id, nil) || len(r.Bans()) != 1 {
+		t.Fatal("ban not reported")
+	}
+
+	// The ban ends at its expiry and the next one lasts twice as long
+	info.Until = time.Now().Add(-time.Second)
+	if r.Banned(id, nil) || len(r.Bans()) != 0 {
+		t.Fatal("expired ban still reported")
+	}
+	r.Penalize(id, nil, banThreshold, "bad")
+	if d := time.Until(info.Until); d > 2*minBanDuration || d < 2*minBanDuration-time.Minute {
+		t.Fatalf("second ban duration mismatch: have %v, want %v", d, 2*minBanDuration)
+	}
+
+	if !r.Unban(&id, nil) || r.Banned(id, nil) {
+		t.Fatal("ban not lifted")
+	}
+}
+
+func TestReputationIPThreshold(t *testing.T) {
+	r := NewReputation("")
+	ip := net.IP{10, 0, 0, 1}
+
+	// A misbehaving node doesn't ban the other nodes behind its IP
+	if !r.Penalize(discover.NodeID{1}, ip, banThreshold, "bad") {
+		t.Fatal("peer not banned")
+	}
+	if r.Banned(discover.NodeID{2}, ip) {
+		t.Fatal("ip banned below its threshold")
+	}
+	for i := byte(2); i <= ipBanThreshold/banThreshold+1; i++ {
+		r.Penalize(discover.NodeID{i}, ip, banThreshold, "bad")
+	}
+	if !r.Banned(discover.NodeID{0xff}, ip) {
+		t.Fatal("ip not banned once over its threshold")
+	}
+
+	// The penalties of the trusted peers leave their IP out
+	r.Penalize(discover.NodeID{0xfe}, nil, banThreshold, "bad")
+	if r.Banned(discover.NodeID{0xff}, net.IP{10, 0, 0, 2}) {
+		t.Fatal("unrelated ip banned")
+	}
+}
+
+func TestReputationPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reputation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bans.json")
+
+	r := NewReputation(path)
+	banned, penalized, ip := discover.NodeID{1}, discover.NodeID{2}, net.IP{10, 0, 0, 1}
+	r.Ban(&banned, ip, 0, "manual")
+	r.Penalize(penalized, nil, banThreshold-10, "bad")
+	r.Ban(&discover.NodeID{3}, nil, time.Minute, "save") // saves the penalty as well
+
+	loaded := NewReputation(path)
+	if !loaded.Banned(banned, nil) || !loaded.Banned(discover.NodeID{}, ip) {
+		t.Fatal("bans not reloaded")
+	}
+	if loaded.Banned(penalized, nil) {
+		t.Fatal("penalized peer banned")
+	}
+	if !loaded.Penalize(penalized, nil, 20, "bad") {
+		t.Fatal("score not reloaded")
+	}
+	if len(loaded.Bans()) != len(r.Bans())+1 {
+		t.Fatalf("bans mismatch: have %d, want %d", len(loaded.Bans()), len(r.Bans())+1)
+	}
+}
@@ -111,6 +111,10 @@ type Config struct {
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
 
+	// BanList is the path to the file keeping the reputation and the bans of
+	// the peers across restarts, they are kept in memory only if empty.
+	BanList string `toml:",omitempty"`
+
 	// Protocols should contain the protocols supported
 	// by the server. Matching protocols are launched for
 	// each peer.
@@ -158,6 +162,7 @@ type Server struct {
 	running bool
 
 	ntab         discoverTable
+	reputation   *Reputation
 	listener     net.Listener
 	ourHandshake *protoHandshake
 	lastLookup   time.Time
@@ -336,6 +341,41 @@ func (srv *Server) RemoveTrustedPeer(node *discover.Node) {
 	}
 }
 
+// Reputation returns the penalty scores and the bans of the peers.
+func (srv *Server) Reputation() *Reputation {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	return srv.reputation
+}
+
+// BanPeer bans a node ID, an IP or both and disconnects the matching peers.
+func (srv *Server) BanPeer(id *discover.NodeID, ip net.IP, duration time.Duration, reason string) {
+	reputation := srv.Reputation()
+	if reputation == nil {
+		return
+	}
+	reputation.Ban(id, ip, duration, reason)
+	select {
+	case srv.peerOp <- func(peers map[discover.NodeID]*Peer) {
+		for _, p := range peers {
+			if (id != nil && p.ID() == *id) || (ip != nil && ip.Equal(remoteIP(p.RemoteAddr()))) {
+				p.Disconnect(DiscUselessPeer)
+			}
+		}
+	}:
+		<-srv.peerOpDone
+	case <-srv.quit:
+	}
+}
+
+// remoteIP returns the IP of a remote address, nil if it is not a TCP one.
+func remoteIP(addr net.Addr) net.IP {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP
+	}
+	return nil
+}
+
 // SubscribePeers subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -521,8 +561,11 @@ func (srv *Server) Start() (err error) {
 		srv.DiscV5 = ntab
 	}
 
+	srv.reputation = NewReputation(srv.BanList)
+
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	dialer.reputation = srv.reputation
 
 	// handshake
 	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: discover.PubkeyID(&srv.PrivateKey.PublicKey)}
@@ -770,6 +813,8 @@ func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, inboundCo
 		return DiscAlreadyConnected
 	case c.id == srv.Self().ID:
 		return DiscSelf
+	case !c.is(trustedConn|staticDialedConn) && srv.reputation.Banned(c.id, remoteIP(c.fd.RemoteAddr())):
+		return DiscUselessPeer
 	default:
 		return nil
 	}
@@ -0,0 +1,263 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/sero-cash/go-sero/log"
+)
+
+// jwtClockSkew is how far the issue time of a token may lie from the local
+// time, a token is only valid that long after it was issued.
+const jwtClockSkew = time.Minute
+
+var (
+	errMissingToken = errors.New("missing bearer token")
+	errUnknownToken = errors.New("unknown bearer token")
+	errExpiredToken = errors.New("token expired")
+
+	auditLog = log.New("module", "rpcaudit")
+)
+
+// AuthConfig is the content of an RPC token file. It lists the clients that
+// may use the HTTP and websocket endpoints and the methods each one may call.
+//
+//	{
+//	  "tokens": [
+//	    {"name": "wallet", "token": "7f3c...", "allow": ["exchange_*", "sero_blockNumber"]},
+//	    {"name": "signer", "jwtSecret": "0x5e1d...", "allow": ["*"]}
+//	  ]
+//	}
+type AuthConfig struct {
+	Tokens []AuthToken `json:"tokens"`
+}
+
+// AuthToken describes a single client. It authenticates either with a static
+// bearer token or with HS256 signed JWTs, and may call the methods matching
+// one of its allow patterns, e.g. "sero_getBalance", "exchange_*" or "*".
+type AuthToken struct {
+	Name      string   `json:"name"`
+	Token     string   `json:"token,omitempty"`
+	JWTSecret string   `json:"jwtSecret,omitempty"`
+	Allow     []string `json:"allow"`
+}
+
+type authClient struct {
+	name   string
+	token  []byte
+	secret []byte
+	allow  []string
+}
+
+// allowed reports whether the client may call the given method.
+func (c *authClient) allowed(method string) bool {
+	for _, pattern := range c.allow {
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator verifies the bearer tokens of HTTP and websocket requests.
+type Authenticator struct {
+	clients []*authClient
+}
+
+// LoadAuthConfig reads a token file and creates an authenticator from it.
+func LoadAuthConfig(file string) (*Authenticator, error) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config AuthConfig
+	if err := json.Unmarshal(blob, &config); err != nil {
+		return nil, fmt.Errorf("invalid token file %s: %v", file, err)
+	}
+	return NewAuthenticator(&config)
+}
+
+// NewAuthenticator creates an authenticator for the clients of a config.
+func NewAuthenticator(config *AuthConfig) (*Authenticator, error) {
+	if len(config.Tokens) == 0 {
+		return nil, errors.New("no tokens configured")
+	}
+	auth := new(Authenticator)
+	names := make(map[string]bool)
+	for i, token := range config.Tokens {
+		if token.Name == "" {
+			return nil, fmt.Errorf("token %d: missing name", i)
+		}
+		if names[token.Name] {
+			return nil, fmt.Errorf("token %s: duplicate name", token.Name)
+		}
+		names[token.Name] = true
+
+		client := &authClient{name: token.Name, allow: token.Allow}
+		switch {
+		case token.Token != "" && token.JWTSecret != "":
+			return nil, fmt.Errorf("token %s: both a token and a JWT secret set", token.Name)
+		case token.Token != "":
+			client.token = []byte(token.Token)
+		case token.JWTSecret != "":
+			secret, err := hex.DecodeString(strings.TrimPrefix(token.JWTSecret, "0x"))
+			if err != nil {
+				return nil, fmt.Errorf("token %s: invalid JWT secret: %v", token.Name, err)
+			}
+			if len(secret) < 32 {
+				return nil, fmt.Errorf("token %s: JWT secret shorter than 32 bytes", token.Name)
+			}
+			client.secret = secret
+		default:
+			return nil, fmt.Errorf("token %s: neither a token nor a JWT secret set", token.Name)
+		}
+		for _, pattern := range token.Allow {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("token %s: invalid pattern %q", token.Name, pattern)
+			}
+		}
+		auth.clients = append(auth.clients, client)
+	}
+	return auth, nil
+}
+
+// authenticate returns the client owning the bearer token of a request.
+func (a *Authenticator) authenticate(r *http.Request) (*authClient, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, errMissingToken
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+
+	if strings.Count(token, ".") == 2 {
+		for _, client := range a.clients {
+			if client.secret == nil {
+				continue
+			}
+			if err := verifyJWT(token, client.secret, time.Now()); err == nil {
+				return client, nil
+			} else if err != errUnknownToken {
+				return nil, err
+			}
+		}
+		return nil, errUnknownToken
+	}
+	for _, client := range a.clients {
+		if client.token != nil && subtle.ConstantTimeCompare(client.token, []byte(token)) == 1 {
+			return client, nil
+		}
+	}
+	return nil, errUnknownToken
+}
+
+// verifyJWT checks the HS256 signature and the time claims of a token, the
+// issue time is required. Tokens signed with another secret are reported as
+// unknown.
+func verifyJWT(token string, secret []byte, now time.Time) error {
+	parts := strings.Split(token, ".")
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return errUnknownToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if blob, err := base64.RawURLEncoding.DecodeString(parts[0]); err != nil || json.Unmarshal(blob, &header) != nil {
+		return errors.New("invalid token header")
+	}
+	if header.Alg != "HS256" {
+		return fmt.Errorf("unsupported token algorithm %q", header.Alg)
+	}
+	var claims struct {
+		Exp *int64 `json:"exp"`
+		Nbf *int64 `json:"nbf"`
+		Iat *int64 `json:"iat"`
+	}
+	if blob, err := base64.RawURLEncoding.DecodeString(parts[1]); err != nil || json.Unmarshal(blob, &claims) != nil {
+		return errors.New("invalid token claims")
+	}
+	if claims.Exp != nil && now.Unix() >= *claims.Exp {
+		return errExpiredToken
+	}
+	if claims.Nbf != nil && now.Unix() < *claims.Nbf {
+		return errors.New("token not valid yet")
+	}
+	if claims.Iat == nil {
+		return errors.New("missing token issue time")
+	}
+	if issued := time.Unix(*claims.Iat, 0); issued.After(now.Add(jwtClockSkew)) {
+		return errors.New("token issued in the future")
+	} else if issued.Before(now.Add(-jwtClockSkew)) {
+		return errExpiredToken
+	}
+	return nil
+}
+
+type authClientKey struct{}
+
+// newAuthHandler rejects the requests without a valid bearer token and passes
+// the authenticated client on to the method calls. A nil authenticator lets
+// all requests through.
+func newAuthHandler(auth *Authenticator, next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Permit dumb empty requests for remote health-checks (AWS)
+		if r.Method == http.MethodGet && r.ContentLength == 0 && r.URL.RawQuery == "" && r.Header.Get("Upgrade") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		client, err := auth.authenticate(r)
+		if err != nil {
+			auditLog.Warn("Denied RPC request", "remote", r.RemoteAddr, "err", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gero"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authClientKey{}, client)))
+	})
+}
+
+// authorized reports whether the client of a request may call a method. Calls
+// over connections without authentication, like IPC and in-process ones, are
+// always allowed.
+func authorized(ctx context.Context, method string) bool {
+	client, ok := ctx.Value(authClientKey{}).(*authClient)
+	if !ok || client.allowed(method) {
+		return true
+	}
+	auditLog.Warn("Denied RPC call", "client", client.name, "remote", ctx.Value("remote"), "method", method)
+	return false
+}
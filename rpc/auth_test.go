@@ -0,0 +1,120 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type AuthTestService struct{}
+
+func (s *AuthTestService) Echo(str string) string   { return str }
+func (s *AuthTestService) Secret(str string) string { return "secret" }
+
+var authTestSecret = bytes.Repeat([]byte{0x42}, 32)
+
+// signJWT creates an HS256 token over the given claims.
+func signJWT(secret []byte, claims map[string]interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	blob, _ := json.Marshal(claims)
+	payload := base64.RawURLEncoding.EncodeToString(blob)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newAuthTestHandler(t *testing.T) http.Handler {
+	auth, err := NewAuthenticator(&AuthConfig{Tokens: []AuthToken{
+		{Name: "static", Token: "s3cr3t", Allow: []string{"test_echo"}},
+		{Name: "jwt", JWTSecret: "0x" + hex.EncodeToString(authTestSecret), Allow: []string{"test_*"}},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	server := NewServer()
+	if err := server.RegisterName("test", new(AuthTestService)); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	return newAuthHandler(auth, server)
+}
+
+// call posts a request with the given bearer token, it returns the HTTP status
+// and the JSON-RPC error code of the response.
+func call(handler http.Handler, token, method string) (int, int) {
+	body := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":%q,"params":["0x1"]}`, method)
+	req := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+	req.Header.Set("content-type", contentType)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var resp struct {
+		Error *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil || resp.Error == nil {
+		return rec.Code, 0
+	}
+	return rec.Code, resp.Error.Code
+}
+
+func TestAuthTokens(t *testing.T) {
+	handler := newAuthTestHandler(t)
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		status int
+		code   int
+	}{
+		{"static", "s3cr3t", "test_echo", http.StatusOK, 0},
+		{"jwt", signJWT(authTestSecret, map[string]interface{}{"iat": now}), "test_secret", http.StatusOK, 0},
+		{"jwt with expiry", signJWT(authTestSecret, map[string]interface{}{"iat": now, "exp": now + 10}), "test_echo", http.StatusOK, 0},
+		{"no token", "", "test_echo", http.StatusUnauthorized, 0},
+		{"unknown token", "guess", "test_echo", http.StatusUnauthorized, 0},
+		{"expired", signJWT(authTestSecret, map[string]interface{}{"iat": now, "exp": now - 1}), "test_echo", http.StatusUnauthorized, 0},
+		{"stale issue time", signJWT(authTestSecret, map[string]interface{}{"iat": now - 3600}), "test_echo", http.StatusUnauthorized, 0},
+		{"future issue time", signJWT(authTestSecret, map[string]interface{}{"iat": now + 3600}), "test_echo", http.StatusUnauthorized, 0},
+		{"missing issue time", signJWT(authTestSecret, map[string]interface{}{"exp": now + 10}), "test_echo", http.StatusUnauthorized, 0},
+		{"bad signature", signJWT(bytes.Repeat([]byte{0x24}, 32), map[string]interface{}{"iat": now}), "test_echo", http.StatusUnauthorized, 0},
+		{"wrong method", "s3cr3t", "test_secret", http.StatusOK, -32001},
+		{"wrong unsubscribe", "s3cr3t", "test_unsubscribe", http.StatusOK, -32001},
+	}
+	for _, tt := range tests {
+		status, code := call(handler, tt.token, tt.method)
+		if status != tt.status || code != tt.code {
+			t.Errorf("%s: response mismatch: have status %d code %d, want status %d code %d", tt.name, status, code, tt.status, tt.code)
+		}
+	}
+}
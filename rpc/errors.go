@@ -16,7 +16,10 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // request is for an unknown service
 type methodNotFoundError struct {
@@ -64,3 +67,24 @@ type shutdownError struct{}
 func (e *shutdownError) ErrorCode() int { return -32000 }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// issued when the authenticated client isn't allowed to call a method
+type unauthorizedError struct{ method string }
+
+func (e *unauthorizedError) ErrorCode() int { return -32001 }
+
+func (e *unauthorizedError) Error() string {
+	return fmt.Sprintf("The method %s is not allowed for this client", e.method)
+}
+
+// issued when a client exceeds its rate limit
+type limitExceededError struct {
+	method string
+	retry  time.Duration
+}
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string {
+	return fmt.Sprintf("Rate limit exceeded calling %s, retry in %v", e.method, e.retry.Round(time.Millisecond))
+}
@@ -200,8 +200,14 @@ func (t *httpReadWriteNopCloser) Close() error {
 //
 // Deprecated: Server implements http.Handler
 func NewHTTPServer(cors []string, vhosts []string, timeouts HTTPTimeouts, srv *Server) *http.Server {
-	// Wrap the CORS-handler within a host-handler
-	handler := newCorsHandler(srv, cors)
+	return newHTTPServer(cors, vhosts, timeouts, nil, srv)
+}
+
+// newHTTPServer creates an HTTP server like NewHTTPServer, requiring the
+// bearer tokens of auth if it's not nil.
+func newHTTPServer(cors []string, vhosts []string, timeouts HTTPTimeouts, auth *Authenticator, srv *Server) *http.Server {
+	// Wrap the auth-handler within a CORS-handler within a host-handler
+	handler := newCorsHandler(newAuthHandler(auth, srv), cors)
 	handler = newVHostHandler(vhosts, handler)
 
 	// Make sure timeout values are meaningful
@@ -244,8 +250,11 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx = context.WithValue(ctx, "scheme", r.Proto)
 	ctx = context.WithValue(ctx, "local", r.Host)
 
+	throttle := new(throttleState)
+	ctx = context.WithValue(ctx, throttleStateKey{}, throttle)
+
 	body := io.LimitReader(r.Body, maxRequestContentLength)
-	codec := NewJSONCodec(&httpReadWriteNopCloser{body, w})
+	codec := NewJSONCodec(&httpReadWriteNopCloser{body, &throttleWriter{w: w, state: throttle}})
 	defer codec.Close()
 
 	w.Header().Set("content-type", contentType)
@@ -270,7 +279,7 @@ func validateRequest(r *http.Request) (int, error) {
 	return 0, nil
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
 		return srv
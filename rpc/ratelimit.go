@@ -0,0 +1,255 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-sero/metrics"
+)
+
+// bucketExpiry is how often the buckets of idle clients are dropped.
+const bucketExpiry = time.Minute
+
+var (
+	rpcCostMeter      = metrics.NewRegisteredMeter("rpc/cost", nil)
+	rpcThrottledMeter = metrics.NewRegisteredMeter("rpc/throttled", nil)
+	rpcClientsGauge   = metrics.NewRegisteredGauge("rpc/clients", nil)
+)
+
+// DefaultMethodCosts are the costs of the calls doing much more work than an
+// ordinary one, which costs 1.
+var DefaultMethodCosts = map[string]float64{
+	"flight_getBlocksInfo":  50,
+	"flight_getOut":         5,
+	"light_getOutsByPKr":    50,
+	"exchange_getRecords":   20,
+	"exchange_getPkSynced":  2,
+	"sero_getBlockByNumber": 5,
+	"sero_getBlockByHash":   5,
+	"sero_getLogs":          20,
+	"sero_call":             5,
+	"sero_estimateGas":      5,
+	"debug_*":               50,
+}
+
+// RateLimitConfig is the token bucket every client of an endpoint is held to.
+// A client is an authenticated token or else a remote address.
+type RateLimitConfig struct {
+	Rate  float64            // Cost units refilled per second
+	Burst float64            // Cost units that can be spent at once
+	Costs map[string]float64 // Costs of the methods matching a pattern
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter accounts the cost of the calls of each client and rejects the
+// ones exceeding its token bucket.
+type RateLimiter struct {
+	rate  float64
+	burst float64
+
+	patterns []string           // Cost patterns, most specific first
+	costs    map[string]float64 // Cost of each pattern
+	resolved map[string]float64 // Cost of each method seen
+
+	buckets map[string]*bucket
+	expired time.Time
+	lock    sync.Mutex
+}
+
+// NewRateLimiter creates a limiter from its config. A zero rate disables the
+// limits and returns nil.
+func NewRateLimiter(config RateLimitConfig) (*RateLimiter, error) {
+	if config.Rate == 0 {
+		return nil, nil
+	}
+	if config.Rate < 0 || config.Burst < 0 {
+		return nil, fmt.Errorf("invalid rate limit %v/s with burst %v", config.Rate, config.Burst)
+	}
+	if config.Burst < config.Rate {
+		config.Burst = config.Rate
+	}
+	l := &RateLimiter{
+		rate:     config.Rate,
+		burst:    config.Burst,
+		costs:    make(map[string]float64),
+		resolved: make(map[string]float64),
+		buckets:  make(map[string]*bucket),
+		expired:  time.Now(),
+	}
+	for pattern, cost := range config.Costs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid cost pattern %q", pattern)
+		}
+		if cost < 0 {
+			return nil, fmt.Errorf("negative cost %v of %s", cost, pattern)
+		}
+		l.patterns = append(l.patterns, pattern)
+		l.costs[pattern] = cost
+	}
+	sort.Slice(l.patterns, func(i, j int) bool {
+		if len(l.patterns[i]) != len(l.patterns[j]) {
+			return len(l.patterns[i]) > len(l.patterns[j])
+		}
+		return l.patterns[i] < l.patterns[j]
+	})
+	return l, nil
+}
+
+// cost returns the cost of a method, the lock must be held.
+func (l *RateLimiter) cost(method string) float64 {
+	if cost, ok := l.resolved[method]; ok {
+		return cost
+	}
+	cost, ok := l.costs[method]
+	if !ok {
+		cost = 1
+		for _, pattern := range l.patterns {
+			if matched, _ := path.Match(pattern, method); matched {
+				cost = l.costs[pattern]
+				break
+			}
+		}
+	}
+	l.resolved[method] = cost
+	return cost
+}
+
+// take charges a client for a call. If its bucket doesn't hold enough tokens
+// nothing is charged and the time until it does is returned. Calls costing
+// more than the burst only need a full bucket.
+func (l *RateLimiter) take(client, method string, now time.Time) (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if now.Sub(l.expired) > bucketExpiry {
+		for key, b := range l.buckets {
+			if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+				delete(l.buckets, key)
+			}
+		}
+		l.expired = now
+	}
+	b := l.buckets[client]
+	if b == nil {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	rpcClientsGauge.Update(int64(len(l.buckets)))
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	cost := l.cost(method)
+	if cost > b.tokens && b.tokens < l.burst {
+		need := math.Min(cost, l.burst) - b.tokens
+		return false, time.Duration(need / l.rate * float64(time.Second))
+	}
+	b.tokens -= cost
+	rpcCostMeter.Mark(int64(cost))
+	return true, 0
+}
+
+// limited reports whether a call of a method exceeds the limit of its client,
+// together with the time to wait before retrying. Calls of connections not
+// tied to a remote client, like IPC and in-process ones, are never limited.
+func (l *RateLimiter) limited(ctx context.Context, method string) (bool, time.Duration) {
+	if l == nil {
+		return false, 0
+	}
+	var client string
+	if c, ok := ctx.Value(authClientKey{}).(*authClient); ok {
+		client = "token:" + c.name
+	} else if remote, ok := ctx.Value("remote").(string); ok {
+		if host, _, err := net.SplitHostPort(remote); err == nil {
+			remote = host
+		}
+		client = "addr:" + remote
+	} else {
+		return false, 0
+	}
+	ok, retry := l.take(client, method, time.Now())
+	if !ok {
+		rpcThrottledMeter.Mark(1)
+	}
+	if state, ok := ctx.Value(throttleStateKey{}).(*throttleState); ok {
+		state.record(retry)
+	}
+	return !ok, retry
+}
+
+type throttleStateKey struct{}
+
+// throttleState collects the throttled calls of an HTTP request, to answer
+// with the proper status once the response is written.
+type throttleState struct {
+	calls int
+	limit int
+	retry time.Duration
+	lock  sync.Mutex
+}
+
+// record counts a call, throttled if it has to be retried later.
+func (s *throttleState) record(retry time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.calls++
+	if retry > 0 {
+		s.limit++
+		if retry > s.retry {
+			s.retry = retry
+		}
+	}
+}
+
+// throttleWriter answers an HTTP request with 429 if all of its calls were
+// throttled, and hints when to retry if any was.
+type throttleWriter struct {
+	w     http.ResponseWriter
+	state *throttleState
+	wrote bool
+}
+
+func (t *throttleWriter) Write(p []byte) (int, error) {
+	if !t.wrote {
+		t.wrote = true
+
+		t.state.lock.Lock()
+		if t.state.limit > 0 {
+			t.w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(t.state.retry.Seconds()))))
+			if t.state.limit == t.state.calls {
+				t.w.WriteHeader(http.StatusTooManyRequests)
+			}
+		}
+		t.state.lock.Unlock()
+	}
+	return t.w.Write(p)
+}
@@ -0,0 +1,140 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(t *testing.T) *RateLimiter {
+	l, err := NewRateLimiter(RateLimitConfig{
+		Rate:  10,
+		Burst: 20,
+		Costs: map[string]float64{"sero_getLogs": 20, "debug_*": 50, "debug_cheap": 2},
+	})
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	return l
+}
+
+// Tests that a bucket refills at the configured rate up to the burst.
+func TestRateLimitRefill(t *testing.T) {
+	l := newTestLimiter(t)
+	now := time.Now()
+
+	for i := 0; i < 20; i++ {
+		if ok, _ := l.take("c", "sero_blockNumber", now); !ok {
+			t.Fatalf("call %d within the burst throttled", i)
+		}
+	}
+	ok, retry := l.take("c", "sero_blockNumber", now)
+	if ok {
+		t.Fatal("call beyond the burst not throttled")
+	}
+	if retry != 100*time.Millisecond {
+		t.Fatalf("retry time mismatch: have %v, want %v", retry, 100*time.Millisecond)
+	}
+	// Half a second refills 5 calls
+	now = now.Add(500 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		if ok, _ := l.take("c", "sero_blockNumber", now); !ok {
+			t.Fatalf("refilled call %d throttled", i)
+		}
+	}
+	if ok, _ := l.take("c", "sero_blockNumber", now); ok {
+		t.Fatal("call beyond the refill not throttled")
+	}
+	// The bucket never holds more than the burst
+	now = now.Add(time.Hour)
+	for i := 0; i < 20; i++ {
+		if ok, _ := l.take("c", "sero_blockNumber", now); !ok {
+			t.Fatalf("call %d within the burst throttled", i)
+		}
+	}
+	if ok, _ := l.take("c", "sero_blockNumber", now); ok {
+		t.Fatal("bucket filled beyond the burst")
+	}
+}
+
+// Tests that the methods are charged the cost of their most specific pattern,
+// and that calls costing more than the burst need a full bucket and leave it
+// in debt.
+func TestRateLimitMethodCosts(t *testing.T) {
+	l := newTestLimiter(t)
+
+	tests := map[string]float64{
+		"sero_blockNumber": 1,
+		"sero_getLogs":     20,
+		"debug_traceBlock": 50,
+		"debug_cheap":      2,
+	}
+	for method, want := range tests {
+		if have := l.cost(method); have != want {
+			t.Errorf("%s: cost mismatch: have %v, want %v", method, have, want)
+		}
+	}
+	now := time.Now()
+	if ok, _ := l.take("c", "debug_traceBlock", now); !ok {
+		t.Fatal("call over the burst throttled with a full bucket")
+	}
+	if ok, retry := l.take("c", "sero_getLogs", now); ok || retry != 5*time.Second {
+		t.Fatalf("call with a bucket in debt mismatch: have %v %v, want false %v", ok, retry, 5*time.Second)
+	}
+	now = now.Add(3500 * time.Millisecond)
+	if ok, _ := l.take("c", "sero_getLogs", now); ok {
+		t.Fatal("expensive call with a partial bucket not throttled")
+	}
+	if ok, _ := l.take("c", "debug_cheap", now); !ok {
+		t.Fatal("cheap call with a partial bucket throttled")
+	}
+}
+
+// Tests that every remote address and token has its own bucket, and that calls
+// of local connections are never limited.
+func TestRateLimitClients(t *testing.T) {
+	l := newTestLimiter(t)
+
+	remote := func(addr string) context.Context {
+		return context.WithValue(context.Background(), "remote", addr)
+	}
+	if limited, _ := l.limited(remote("10.0.0.1:1000"), "sero_getLogs"); limited {
+		t.Fatal("first call throttled")
+	}
+	// Another port of the same host shares the bucket
+	if limited, _ := l.limited(remote("10.0.0.1:2000"), "sero_getLogs"); !limited {
+		t.Fatal("second call of the same host not throttled")
+	}
+	if limited, _ := l.limited(remote("10.0.0.2:1000"), "sero_getLogs"); limited {
+		t.Fatal("call of another host throttled")
+	}
+	// An authenticated client is limited by its token, not its address
+	token := context.WithValue(remote("10.0.0.1:1000"), authClientKey{}, &authClient{name: "wallet"})
+	if limited, _ := l.limited(token, "sero_getLogs"); limited {
+		t.Fatal("call of a token throttled by its address")
+	}
+	if limited, _ := l.limited(token, "sero_getLogs"); !limited {
+		t.Fatal("second call of the token not throttled")
+	}
+	for i := 0; i < 100; i++ {
+		if limited, _ := l.limited(context.Background(), "debug_traceBlock"); limited {
+			t.Fatal("local call throttled")
+		}
+	}
+}
@@ -58,6 +58,12 @@ func NewServer() *Server {
 	return server
 }
 
+// SetRateLimiter holds the remote clients of the server to the limits of l,
+// it must be called before serving any of them.
+func (s *Server) SetRateLimiter(l *RateLimiter) {
+	s.limiter = l
+}
+
 // RPCService gives meta information about the server.
 // e.g. gives information about the loaded modules.
 type RPCService struct {
@@ -256,6 +262,9 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.err != nil {
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
+	if !authorized(ctx, req.method) {
+		return codec.CreateErrorResponse(&req.id, &unauthorizedError{req.method}), nil
+	}
 
 	if req.isUnsubscribe { // cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
@@ -274,6 +283,10 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, &invalidParamsError{"Expected subscription id as first argument"}), nil
 	}
 
+	if limited, retry := s.limiter.limited(ctx, req.method); limited {
+		return codec.CreateErrorResponse(&req.id, &limitExceededError{req.method, retry}), nil
+	}
+
 	if req.callb.isSubscribe {
 		subid, err := s.createSubscription(ctx, codec, req)
 		if err != nil {
@@ -390,7 +403,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		}
 
 		if r.isPubSub && strings.HasSuffix(r.method, unsubscribeMethodSuffix) {
-			requests[i] = &serverRequest{id: r.id, isUnsubscribe: true}
+			requests[i] = &serverRequest{id: r.id, method: r.method, isUnsubscribe: true}
 			argTypes := []reflect.Type{reflect.TypeOf("")} // expect subscription id as first arg
 			if args, err := codec.ParseRequestArguments(argTypes, r.params); err == nil {
 				requests[i].args = args
@@ -407,7 +420,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 
 		if r.isPubSub { // sero_subscribe, r.method contains the subscription method name
 			if callb, ok := svc.subscriptions[r.method]; ok {
-				requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+				requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: r.service + subscribeMethodSuffix, callb: callb}
 				if r.params != nil && len(callb.argTypes) > 0 {
 					argTypes := []reflect.Type{reflect.TypeOf("")}
 					argTypes = append(argTypes, callb.argTypes...)
@@ -424,7 +437,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		}
 
 		if callb, ok := svc.callbacks[r.method]; ok { // lookup RPC method
-			requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+			requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: r.service + serviceMethodSeparator + r.method, callb: callb}
 			if r.params != nil && len(callb.argTypes) > 0 {
 				if args, err := codec.ParseRequestArguments(callb.argTypes, r.params); err == nil {
 					requests[i].args = args
@@ -57,6 +57,7 @@ type service struct {
 type serverRequest struct {
 	id            interface{}
 	svcname       string
+	method        string // Requested method as namespace_method
 	callb         *callback
 	args          []reflect.Value
 	isUnsubscribe bool
@@ -70,6 +71,7 @@ type subscriptions map[string]*callback  // collection of subscription callbacks
 // Server represents a RPC server
 type Server struct {
 	services serviceRegistry
+	limiter  *RateLimiter
 
 	run      int32
 	codecsMu sync.Mutex
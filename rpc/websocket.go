@@ -68,7 +68,15 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			decoder := func(v interface{}) error {
 				return websocketJSONCodec.Receive(conn, v)
 			}
-			srv.ServeCodec(NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
+			codec := NewCodec(conn, encoder, decoder)
+			defer codec.Close()
+
+			// Carry the authenticated client over to the calls of the connection
+			ctx := context.WithValue(context.Background(), "remote", conn.Request().RemoteAddr)
+			if client := conn.Request().Context().Value(authClientKey{}); client != nil {
+				ctx = context.WithValue(ctx, authClientKey{}, client)
+			}
+			srv.serveRequest(ctx, codec, false, OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
@@ -26,7 +26,9 @@ import (
 	"github.com/sero-cash/go-sero/zero/txtool"
 	"github.com/sero-cash/go-sero/zero/txtool/prepare"
 
+	"github.com/sero-cash/go-sero/zero/account"
 	"github.com/sero-cash/go-sero/zero/wallet/exchange"
+	"github.com/sero-cash/go-sero/zero/wallet/history"
 
 	"github.com/sero-cash/go-sero/log"
 
@@ -301,6 +303,14 @@ func (b *SeroAPIBackend) GetMaxAvailable(pk c_type.Uint512, currency string) (am
 	return b.sero.exchange.GetMaxAvailable(pk, currency)
 }
 
+func (b *SeroAPIBackend) GetPending(pk c_type.Uint512) (balances map[string]*exchange.PendingBalance, records []exchange.PendingRecord, e error) {
+	if b.sero.exchange == nil {
+		e = errors.New("not start exchange")
+		return
+	}
+	return b.sero.exchange.GetPending(pk)
+}
+
 func (b *SeroAPIBackend) GetBalances(pk c_type.Uint512) (balances map[string]*big.Int, tickets map[string][]*common.Hash) {
 	if b.sero.exchange == nil {
 		return
@@ -324,6 +334,14 @@ func (b *SeroAPIBackend) GetRecordsByPkr(pkr c_type.PKr, begin, end uint64) (rec
 	return b.sero.exchange.GetRecordsByPkr(pkr, begin, end)
 }
 
+func (b *SeroAPIBackend) GetRecordsByMemo(memo account.Memo, begin, end uint64) (records []exchange.Utxo, err error) {
+	if b.sero.exchange == nil {
+		err = errors.New("not start exchange")
+		return
+	}
+	return b.sero.exchange.GetRecordsByMemo(memo, begin, end)
+}
+
 func (b *SeroAPIBackend) GetRecordsByPk(pk *c_type.Uint512, begin, end uint64) (records []exchange.Utxo, err error) {
 	if b.sero.exchange == nil {
 		err = errors.New("not start exchange")
@@ -340,6 +358,14 @@ func (b *SeroAPIBackend) GetRecordsByTxHash(txHash c_type.Uint256) (records []ex
 	return b.sero.exchange.GetRecordsByTxHash(txHash)
 }
 
+func (b *SeroAPIBackend) GetAccountHistory(pk c_type.Uint512, from history.Cursor, end uint64, limit int) (records []history.Record, next *history.Cursor, err error) {
+	if b.sero.history == nil {
+		err = errors.New("not start history")
+		return
+	}
+	return b.sero.history.GetRecords(pk, from, end, limit)
+}
+
 func (b *SeroAPIBackend) GetOutByPKr(pkrs []c_type.PKr, start, end uint64) (br light.BlockOutResp, e error) {
 	if b.sero.lightNode == nil {
 		e = errors.New("not start light")
@@ -62,6 +62,13 @@ type TraceConfig struct {
 	Reexec  *uint64
 }
 
+// TraceCallConfig is the config for traceCall API. It holds one more
+// field to override the state of some contracts for tracing.
+type TraceCallConfig struct {
+	TraceConfig
+	StateOverrides *ethapi.StateOverride
+}
+
 // txTraceResult is the result of a single transaction trace.
 type txTraceResult struct {
 	Result interface{} `json:"result,omitempty"` // Trace results produced by the tracer
@@ -556,6 +563,48 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	return api.traceTx(ctx, msg, vmctx, statedb, config)
 }
 
+// TraceCall lets you trace a given sero_call. It collects the structured logs
+// created during the execution of EVM if the given transaction was added on
+// top of the provided block and returns them as a JSON object.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNr rpc.BlockNumber, config *TraceCallConfig) (interface{}, error) {
+	// Fetch the block that we want to trace on top of
+	var block *types.Block
+
+	switch blockNr {
+	case rpc.PendingBlockNumber:
+		return nil, errors.New("tracing on top of pending is not supported")
+	case rpc.LatestBlockNumber:
+		block = api.eth.blockchain.CurrentBlock()
+	default:
+		block = api.eth.blockchain.GetBlockByNumber(uint64(blockNr))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	statedb, err := api.computeStateDB(block, reexec)
+	if err != nil {
+		return nil, err
+	}
+	var traceConfig *TraceConfig
+	if config != nil {
+		if err := config.StateOverrides.Apply(statedb); err != nil {
+			return nil, err
+		}
+		traceConfig = &config.TraceConfig
+	}
+	// Execute the trace
+	msg, err := args.ToMessage(api.eth.APIBackend, statedb)
+	if err != nil {
+		return nil, err
+	}
+	vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+	return api.traceTx(ctx, msg, vmctx, statedb, traceConfig)
+}
+
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
@@ -574,15 +623,18 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 				return nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
+		// Constuct the native or JavaScript tracer to execute with
+		txTracer, err := tracers.NewTxTracer(*config.Tracer)
+		if err != nil {
 			return nil, err
 		}
+		tracer = txTracer
+
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			txTracer.Stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 
@@ -609,7 +661,7 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
-	case *tracers.Tracer:
+	case tracers.TxTracer:
 		return tracer.GetResult()
 
 	default:
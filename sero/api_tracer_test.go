@@ -0,0 +1,84 @@
+package sero
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/address"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/internal/ethapi"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// Tests that the state overrides of a traced call are applied to the traced
+// state only.
+func TestTraceCallOverrides(t *testing.T) {
+	cpt.ZeroInit(cpt.NET_Alpha)
+
+	var contract ethapi.AllMixedAddress
+	contract[0] = 1
+	addr := common.BytesToAddress(contract[:])
+
+	db := serodb.NewMemDatabase()
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			// PUSH1 0 SLOAD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+			addr: {Code: common.FromHex("0x60005460005260206000f3")},
+		},
+	}
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	sero := &Sero{chainConfig: params.TestChainConfig, blockchain: chain, chainDb: db}
+	sero.APIBackend = &SeroAPIBackend{sero: sero}
+	api := NewPrivateDebugAPI(params.TestChainConfig, sero)
+
+	from := make(address.MixBase58Adrress, 96)
+	from[0] = 2
+	args := ethapi.CallArgs{
+		From: &from,
+		To:   &contract,
+		Gas:  hexutil.Uint64(100000),
+		// The call uses no address of the table
+		Data: make(hexutil.Bytes, 18),
+	}
+	slot := func(value int64) *map[common.Hash]common.Hash {
+		return &map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(value))}
+	}
+	tests := []struct {
+		config *TraceCallConfig
+		want   int64
+	}{
+		{nil, 0},
+		{&TraceCallConfig{StateOverrides: &ethapi.StateOverride{ethapi.ContractAddress(contract): {State: slot(2)}}}, 2},
+		{&TraceCallConfig{StateOverrides: &ethapi.StateOverride{ethapi.ContractAddress(contract): {StateDiff: slot(3)}}}, 3},
+		{&TraceCallConfig{}, 0},
+	}
+	for i, test := range tests {
+		res, err := api.TraceCall(context.Background(), args, rpc.LatestBlockNumber, test.config)
+		if err != nil {
+			t.Fatalf("test %d: failed to trace: %v", i, err)
+		}
+		result := res.(*ethapi.ExecutionResult)
+		if result.Failed {
+			t.Fatalf("test %d: call failed", i)
+		}
+		have, _ := new(big.Int).SetString(result.ReturnValue, 16)
+		if have.Int64() != test.want {
+			t.Errorf("test %d: result mismatch: have %v, want %d", i, have, test.want)
+		}
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/sero-cash/go-sero/common/address"
 
 	"github.com/sero-cash/go-sero/voter"
+	"github.com/sero-cash/go-sero/zero/wallet/ssi"
 	"github.com/sero-cash/go-sero/zero/wallet/stakeservice"
 
 	"github.com/sero-cash/go-sero/zero/txtool"
@@ -40,6 +41,7 @@ import (
 
 	"github.com/sero-cash/go-sero/internal/ethapi"
 	"github.com/sero-cash/go-sero/zero/wallet/exchange"
+	"github.com/sero-cash/go-sero/zero/wallet/history"
 
 	"github.com/sero-cash/go-sero/accounts"
 	"github.com/sero-cash/go-sero/common"
@@ -87,6 +89,7 @@ type Sero struct {
 	blockchain      *core.BlockChain
 	exchange        *exchange.Exchange
 	lightNode       *light.LightNode
+	history         *history.History
 	protocolManager *ProtocolManager
 	lesServer       LesServer
 
@@ -97,8 +100,10 @@ type Sero struct {
 	engine         consensus.Engine
 	accountManager *accounts.Manager
 
-	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
-	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
+	bloomRequests   chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
+	bloomIndexer    *core.ChainIndexer             // Bloom indexer operating during block imports
+	tokenIndexer    *core.ChainIndexer             // Token registry indexer operating during block imports
+	transferIndexer *core.ChainIndexer             // Token transfer indexer operating during block imports
 
 	APIBackend *SeroAPIBackend
 
@@ -163,7 +168,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Sero, error) {
 	}
 	var (
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, RetainBlocks: config.StateRetain}
 	)
 	sero.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, sero.chainConfig, sero.engine, vmConfig, sero.accountManager)
 
@@ -175,7 +180,11 @@ func New(ctx *node.ServiceContext, config *Config) (*Sero, error) {
 		sero.blockchain.SetHead(compat.RewindTo, core.DelFn)
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
+	sero.tokenIndexer = NewTokenRegistryIndexer(chainDb, sero.blockchain)
+	sero.transferIndexer = NewTokenTransferIndexer(chainDb, sero.blockchain)
 	sero.bloomIndexer.Start(sero.blockchain)
+	sero.tokenIndexer.Start(sero.blockchain)
+	sero.transferIndexer.Start(sero.blockchain)
 
 	// if config.TxPool.Journal != "" {
 	//	config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
@@ -201,16 +210,25 @@ func New(ctx *node.ServiceContext, config *Config) (*Sero, error) {
 
 	// init exchange
 	if config.StartExchange {
-		sero.exchange = exchange.NewExchange(zconfig.Exchange_dir(), sero.txPool, sero.accountManager, config.AutoMerge)
+		sero.exchange = exchange.NewExchange(zconfig.Exchange_dir(), sero.txPool, sero.accountManager, ctx.NodeKey(), config.AutoMerge)
 	}
 
-	stakeservice.NewStakeService(zconfig.Stake_dir(), sero.blockchain, sero.accountManager)
+	stakeservice.NewStakeService(zconfig.Stake_dir(), sero.blockchain, sero.txPool, sero.accountManager)
+
+	if err := ssi.SSI_Inst.Start(zconfig.SSI_dir(), chainDb, sero.txPool); err != nil {
+		return nil, err
+	}
 
 	// init light
 	if config.StartLight {
 		sero.lightNode = light.NewLightNode(zconfig.Light_dir(), sero.txPool, sero.blockchain.GetDB())
 	}
 
+	// init history
+	if config.StartHistory {
+		sero.history = history.NewHistory(zconfig.History_dir(), sero.blockchain, sero.accountManager)
+	}
+
 	// if config.Proof != nil {
 	// 	if config.Proof.PKr == (c_type.PKr{}) {
 	// 		wallets := sero.accountManager.Wallets()
@@ -253,6 +271,10 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (serodb.Dat
 	}
 	if db, ok := db.(*serodb.LDBDatabase); ok {
 		db.Meter("sero/db/chaindata/")
+		if _, err := rawdb.OpenFreezer(db, config.AncientThreshold); err != nil {
+			db.Close()
+			return nil, err
+		}
 	}
 	return db, nil
 }
@@ -445,13 +467,14 @@ func (s *Sero) Start(srvr *p2p.Server) error {
 
 	// Figure out a max peers count based on the server limits
 	maxPeers := srvr.MaxPeers
-	if s.config.LightServ > 0 {
+	if s.config.LightServ > 0 || s.config.LightWallet {
 		if s.config.LightPeers >= srvr.MaxPeers {
 			return fmt.Errorf("invalid peer config: light peer count (%d) >= total peer count (%d)", s.config.LightPeers, srvr.MaxPeers)
 		}
 		maxPeers -= s.config.LightPeers
 	}
 	// Start the networking layer and the light server if requested
+	s.protocolManager.reputation = srvr.Reputation()
 	s.protocolManager.Start(maxPeers)
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
@@ -462,13 +485,19 @@ func (s *Sero) Start(srvr *p2p.Server) error {
 // Stop implements node.Service, terminating all internal goroutines used by the
 // Sero protocol.
 func (s *Sero) Stop() error {
+	if s.history != nil {
+		s.history.Stop()
+	}
 	s.bloomIndexer.Close()
+	s.tokenIndexer.Close()
+	s.transferIndexer.Close()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
 		s.lesServer.Stop()
 	}
 	s.txPool.Stop()
+	ssi.SSI_Inst.Stop()
 	s.miner.Stop()
 	s.eventMux.Stop()
 
@@ -84,16 +84,29 @@ type Config struct {
 	SyncMode  downloader.SyncMode
 	NoPruning bool
 
+	// Number of recent blocks to keep the state history of, older history
+	// is pruned online (0 = keep all)
+	StateRetain uint64
+
+	// Number of recent blocks kept in LevelDB, the data of older blocks is
+	// moved to the ancient store (0 = no migration)
+	AncientThreshold uint64
+
 	MineMode      bool
 	StartExchange bool
 	AutoMerge     bool
 
 	StartLight bool
 
+	StartHistory bool
+
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	// Light wallet options
+	LightWallet bool `toml:",omitempty"` // Whether the light wallet protocol is served
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
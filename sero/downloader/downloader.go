@@ -43,6 +43,9 @@ var (
 	MaxReceiptFetch = 256 // Amount of transaction receipts to allow fetching per request
 	MaxStateFetch   = 384 // Amount of node state values to allow fetching per request
 
+	MaxStorageRangeFetch = 128 // Amount of storage tries to allow fetching ranges of per request
+	MaxZeroBlockFetch    = 64  // Amount of blocks to allow fetching zero state records of per request
+
 	MaxForkAncestry  = 3 * params.EpochDuration // Maximum chain reorganisation
 	rttMinEstimate   = 2 * time.Second          // Minimum round-trip time to target for download requests
 	rttMaxEstimate   = 20 * time.Second         // Maximum round-trip time to target for download requests
@@ -130,6 +133,8 @@ type Downloader struct {
 	stateSyncStart chan *stateSync
 	trackStateReq  chan *stateReq
 	stateCh        chan dataPack // [sero/63] Channel receiving inbound node state data
+	snapCh         chan dataPack // [sero/64] Channel receiving inbound account and storage ranges
+	zeroCh         chan dataPack // [sero/64] Channel receiving inbound zero state records of blocks
 
 	// Cancellation and termination
 	cancelPeer string         // Identifier of the peer currently being used as the master (cancel on drop)
@@ -219,6 +224,8 @@ func New(mode SyncMode, stateDb serodb.Database, mux *event.TypeMux, chain Block
 		headerProcCh:   make(chan []*types.Header, 1),
 		quitCh:         make(chan struct{}),
 		stateCh:        make(chan dataPack),
+		snapCh:         make(chan dataPack),
+		zeroCh:         make(chan dataPack),
 		stateSyncStart: make(chan *stateSync),
 		syncStatsState: stateSyncStats{
 			processed: rawdb.ReadFastTrieProgress(stateDb),
@@ -1374,9 +1381,21 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 // processFastSyncContent takes fetch results from the queue and writes them to the
 // database. It also controls the synchronisation of state nodes of the pivot block.
 func (d *Downloader) processFastSyncContent(latest *types.Header) error {
+	// Fill the state of the reported head block from flat ranges if any peer
+	// serves them, the trie sync afterwards only heals what the ranges missed.
+	var codes []common.Hash
+	if len(d.snapPeers()) > 0 {
+		var err error
+		if codes, err = d.syncSnapshot(latest.Root); err != nil {
+			if err == errCancelStateFetch {
+				return err
+			}
+			log.Warn("State range sync failed, healing", "err", err)
+		}
+	}
 	// Start syncing state of the reported head block. This should get us most of
 	// the state of the pivot block.
-	stateSync := d.syncState(latest.Root)
+	stateSync := d.syncState(latest.Root, codes...)
 	defer stateSync.Cancel()
 	go func() {
 		if err := stateSync.Wait(); err != nil && err != errCancelStateFetch {
@@ -1434,7 +1453,7 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 			if oldPivot != P {
 				stateSync.Cancel()
 
-				stateSync = d.syncState(P.Header.Root)
+				stateSync = d.syncState(P.Header.Root, codes...)
 				defer stateSync.Cancel()
 				go func() {
 					if err := stateSync.Wait(); err != nil && err != errCancelStateFetch {
@@ -1449,6 +1468,11 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 				if stateSync.err != nil {
 					return stateSync.err
 				}
+				// The zero state records of the blocks are checked against the
+				// state of the pivot, they can only be retrieved once it is complete
+				if err := d.syncZeroBlocks(types.NewBlockWithHeader(P.Header).WithBody(P.Transactions)); err != nil {
+					return err
+				}
 				if err := d.commitPivotBlock(P); err != nil {
 					return err
 				}
@@ -1548,6 +1572,22 @@ func (d *Downloader) DeliverNodeData(id string, data [][]byte) (err error) {
 	return d.deliver(id, d.stateCh, &statePack{id, data}, stateInMeter, stateDropMeter)
 }
 
+// DeliverAccountRange injects a range of flat accounts received from a remote node.
+func (d *Downloader) DeliverAccountRange(id string, reqID uint64, hashes []common.Hash, accounts [][]byte, proof [][]byte) (err error) {
+	return d.deliver(id, d.snapCh, &accountRangePack{id, reqID, hashes, accounts, proof}, stateInMeter, stateDropMeter)
+}
+
+// DeliverStorageRanges injects a batch of flat storage ranges received from a remote node.
+func (d *Downloader) DeliverStorageRanges(id string, reqID uint64, hashes [][]common.Hash, slots [][][]byte, proof [][]byte) (err error) {
+	return d.deliver(id, d.snapCh, &storageRangesPack{id, reqID, hashes, slots, proof}, stateInMeter, stateDropMeter)
+}
+
+// DeliverZeroBlocks injects a batch of zero state records of blocks received
+// from a remote node.
+func (d *Downloader) DeliverZeroBlocks(id string, reqID uint64, blocks []*ZeroBlock) (err error) {
+	return d.deliver(id, d.zeroCh, &zeroBlocksPack{id, reqID, blocks}, stateInMeter, stateDropMeter)
+}
+
 // deliver injects a new batch of data received from a remote node.
 func (d *Downloader) deliver(id string, destCh chan dataPack, packet dataPack, inMeter, dropMeter metrics.Meter) (err error) {
 	// Update the delivery metrics for both good and failed deliveries
@@ -90,6 +90,19 @@ type Peer interface {
 	RequestNodeData([]common.Hash) error
 }
 
+// SnapPeer encapsulates the methods required to fill the state from flat
+// account and storage ranges of a remote [sero/64] peer.
+type SnapPeer interface {
+	RequestAccountRange(uint64, common.Hash, common.Hash, common.Hash, uint64) error
+	RequestStorageRanges(uint64, []common.Hash, common.Hash, uint64) error
+}
+
+// ZeroPeer encapsulates the methods required to retrieve the zero state records
+// of fast synced blocks from a remote [sero/64] peer.
+type ZeroPeer interface {
+	RequestZeroBlocks(uint64, []common.Hash) error
+}
+
 // lightPeerWrapper wraps a LightPeer struct, stubbing out the Peer-only methods.
 type lightPeerWrapper struct {
 	peer LightPeer
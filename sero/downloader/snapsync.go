@@ -0,0 +1,493 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/trie"
+)
+
+const (
+	snapRangeBytes    = 512 * 1024 // Soft size of the ranges requested from a peer
+	snapAccountChunks = 16         // Number of account ranges to fill concurrently
+)
+
+var (
+	errNoSnapPeers = errors.New("no peers left to fill the state from ranges")
+
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+// accountTask is a slice of the account hash space still to be filled.
+type accountTask struct {
+	origin common.Hash
+	limit  common.Hash
+}
+
+// storageTask is a storage trie still to be filled, the trie holds the slots
+// of the partial ranges retrieved so far.
+type storageTask struct {
+	root   common.Hash
+	origin common.Hash
+	trie   *trie.Trie
+}
+
+// snapReq is a range request in flight to a peer.
+type snapReq struct {
+	id      uint64
+	peer    *peerConnection
+	account *accountTask
+	storage []*storageTask
+	timer   *time.Timer
+}
+
+// snapSync fills the state of a root from the flat account and storage ranges
+// served by [sero/64] peers. Every range is proven against the root it belongs
+// to as it arrives, so a bad range is pinned on the peer that served it.
+// Whatever is left out is healed afterwards by the regular trie node sync.
+type snapSync struct {
+	d    *Downloader
+	root common.Hash
+
+	triedb   *trie.Database
+	accounts *trie.Trie
+
+	accountTasks []*accountTask
+	storageTasks []*storageTask
+	storageSeen  map[common.Hash]struct{}
+	codes        []common.Hash
+	codeSeen     map[common.Hash]struct{}
+
+	active  map[string]*snapReq
+	failed  map[string]struct{} // Peers not serving the root, skipped for this sync
+	reqID   uint64
+	timeout chan *snapReq
+	done    chan struct{}
+
+	leaves uint64
+	slots  uint64
+	logged time.Time
+}
+
+// snapPeers retrieves the peers able to serve flat state ranges.
+func (d *Downloader) snapPeers() []*peerConnection {
+	var peers []*peerConnection
+	for _, p := range d.peers.AllPeers() {
+		if _, ok := p.peer.(SnapPeer); ok && p.version >= 64 {
+			peers = append(peers, p)
+		}
+	}
+	return peers
+}
+
+// syncSnapshot fills the state of the given root from flat ranges. It returns
+// the contract codes found missing, which the trie walk of the heal does not
+// reach for the accounts filled here, even if the fill itself failed.
+func (d *Downloader) syncSnapshot(root common.Hash) ([]common.Hash, error) {
+	// Ranges still in flight once done are discarded until the sync ends
+	cancel := d.cancelCh
+	defer func() {
+		go func() {
+			for {
+				select {
+				case <-d.snapCh:
+				case <-cancel:
+					return
+				}
+			}
+		}()
+	}()
+
+	s := newSnapSync(d, root)
+	start := time.Now()
+	if err := s.loop(); err != nil {
+		return s.codes, err
+	}
+	log.Info("Filled state from ranges", "root", root, "accounts", s.leaves, "slots", s.slots, "codes", len(s.codes), "elapsed", common.PrettyDuration(time.Since(start)))
+	return s.codes, nil
+}
+
+func newSnapSync(d *Downloader, root common.Hash) *snapSync {
+	triedb := trie.NewDatabase(d.stateDB)
+	accounts, _ := trie.New(common.Hash{}, triedb)
+
+	s := &snapSync{
+		d:           d,
+		root:        root,
+		triedb:      triedb,
+		accounts:    accounts,
+		storageSeen: make(map[common.Hash]struct{}),
+		codeSeen:    make(map[common.Hash]struct{}),
+		active:      make(map[string]*snapReq),
+		failed:      make(map[string]struct{}),
+		timeout:     make(chan *snapReq),
+		done:        make(chan struct{}),
+		logged:      time.Now(),
+	}
+	step := 256 / snapAccountChunks
+	for i := 0; i < snapAccountChunks; i++ {
+		task := &accountTask{}
+		task.origin[0] = byte(i * step)
+		for j := range task.limit {
+			task.limit[j] = 0xff
+		}
+		task.limit[0] = byte((i+1)*step - 1)
+		s.accountTasks = append(s.accountTasks, task)
+	}
+	return s
+}
+
+// loop assigns range requests to idle peers and processes their responses
+// until the whole state is filled.
+func (s *snapSync) loop() error {
+	defer close(s.done)
+
+	peerDrop := make(chan *peerConnection, 1024)
+	peerSub := s.d.peers.SubscribePeerDrops(peerDrop)
+	defer peerSub.Unsubscribe()
+	defer func() {
+		for _, req := range s.active {
+			req.timer.Stop()
+		}
+	}()
+
+	for len(s.accountTasks) > 0 || len(s.storageTasks) > 0 || len(s.active) > 0 {
+		if err := s.assign(); err != nil {
+			return err
+		}
+		select {
+		case pack := <-s.d.snapCh:
+			// Discard any ranges not requested (or previously timed out)
+			req := s.active[pack.PeerId()]
+			if req == nil {
+				log.Debug("Unrequested state range", "peer", pack.PeerId(), "len", pack.Items())
+				continue
+			}
+			var err error
+			switch pack := pack.(type) {
+			case *accountRangePack:
+				if pack.id != req.id || req.account == nil {
+					continue
+				}
+				req.timer.Stop()
+				delete(s.active, req.peer.id)
+				err = s.processAccounts(req, pack)
+
+			case *storageRangesPack:
+				if pack.id != req.id || req.storage == nil {
+					continue
+				}
+				req.timer.Stop()
+				delete(s.active, req.peer.id)
+				err = s.processStorage(req, pack)
+			}
+			if err != nil {
+				return err
+			}
+
+		case p := <-peerDrop:
+			if req := s.active[p.id]; req != nil {
+				req.timer.Stop()
+				delete(s.active, p.id)
+				s.revert(req)
+			}
+
+		case req := <-s.timeout:
+			// Ignore the stale timeout of a request delivered meanwhile
+			if s.active[req.peer.id] != req {
+				continue
+			}
+			delete(s.active, req.peer.id)
+			s.revert(req)
+			s.failed[req.peer.id] = struct{}{}
+
+		case <-s.d.cancelCh:
+			return errCancelStateFetch
+
+		case <-s.d.quitCh:
+			return errCancelStateFetch
+		}
+		s.report()
+	}
+	return s.commit()
+}
+
+// assign sends the pending tasks to the idle peers, storage first to keep the
+// partially filled tries few.
+func (s *snapSync) assign() error {
+	for _, p := range s.d.snapPeers() {
+		if len(s.accountTasks) == 0 && len(s.storageTasks) == 0 {
+			break
+		}
+		if _, ok := s.active[p.id]; ok {
+			continue
+		}
+		if _, ok := s.failed[p.id]; ok {
+			continue
+		}
+		s.reqID++
+		req := &snapReq{id: s.reqID, peer: p}
+
+		var err error
+		if len(s.storageTasks) > 0 {
+			// Only the first trie of a request may continue a partial range
+			n := 1
+			for n < len(s.storageTasks) && n < MaxStorageRangeFetch && s.storageTasks[n].origin == (common.Hash{}) {
+				n++
+			}
+			req.storage = append([]*storageTask{}, s.storageTasks[:n]...)
+			s.storageTasks = s.storageTasks[n:]
+
+			roots := make([]common.Hash, len(req.storage))
+			for i, task := range req.storage {
+				roots[i] = task.root
+			}
+			err = p.peer.(SnapPeer).RequestStorageRanges(req.id, roots, req.storage[0].origin, snapRangeBytes)
+		} else {
+			req.account = s.accountTasks[0]
+			s.accountTasks = s.accountTasks[1:]
+
+			err = p.peer.(SnapPeer).RequestAccountRange(req.id, s.root, req.account.origin, req.account.limit, snapRangeBytes)
+		}
+		if err != nil {
+			s.revert(req)
+			s.failed[p.id] = struct{}{}
+			continue
+		}
+		req.timer = time.AfterFunc(s.d.requestTTL(), func() {
+			select {
+			case s.timeout <- req:
+			case <-s.done:
+			}
+		})
+		s.active[p.id] = req
+	}
+	if len(s.active) == 0 && (len(s.accountTasks) > 0 || len(s.storageTasks) > 0) {
+		return errNoSnapPeers
+	}
+	return nil
+}
+
+// revert puts the tasks of an unanswered request back in front of the queues.
+func (s *snapSync) revert(req *snapReq) {
+	if req.account != nil {
+		s.accountTasks = append([]*accountTask{req.account}, s.accountTasks...)
+	}
+	if len(req.storage) > 0 {
+		s.storageTasks = append(append([]*storageTask{}, req.storage...), s.storageTasks...)
+	}
+}
+
+// unavailable marks the peer of a request as not serving the root and reverts it.
+func (s *snapSync) unavailable(req *snapReq, reason string, ctx ...interface{}) {
+	req.peer.log.Debug(reason, ctx...)
+	s.failed[req.peer.id] = struct{}{}
+	s.revert(req)
+}
+
+// invalid reverts a request whose response failed its proof and drops the peer
+// that served it.
+func (s *snapSync) invalid(req *snapReq, reason string, ctx ...interface{}) {
+	req.peer.log.Warn(reason, ctx...)
+	s.failed[req.peer.id] = struct{}{}
+	s.revert(req)
+	if s.d.dropPeer != nil {
+		s.d.dropPeer(req.peer.id)
+	}
+}
+
+// processAccounts inserts a proven range of accounts, scheduling the storage
+// tries and codes they reference.
+func (s *snapSync) processAccounts(req *snapReq, pack *accountRangePack) error {
+	task := req.account
+	if len(pack.hashes) == 0 && len(pack.proof) == 0 {
+		s.unavailable(req, "Peer lacks state root", "root", s.root)
+		return nil
+	}
+	more, err := trie.VerifyRangeProof(s.root, task.origin, pack.hashes, pack.accounts, pack.proof)
+	if err != nil {
+		s.invalid(req, "Invalid account range", "origin", task.origin, "err", err)
+		return nil
+	}
+	// The account after the limit only closes the proof, it belongs to the
+	// next task
+	hashes, encs := pack.hashes, pack.accounts
+	if n := len(hashes); n > 0 && bytes.Compare(hashes[n-1][:], task.limit[:]) >= 0 {
+		if hashes[n-1] != task.limit {
+			hashes, encs = hashes[:n-1], encs[:n-1]
+		}
+		more = false
+	}
+	for i, hash := range hashes {
+		if err := s.accounts.TryUpdate(hash[:], encs[i]); err != nil {
+			return err
+		}
+		// The zero consensus keeps raw items in the account trie too, they
+		// reference no storage or code
+		var account state.Account
+		if err := rlp.DecodeBytes(encs[i], &account); err != nil {
+			continue
+		}
+		if account.Root != emptyRoot {
+			s.addStorage(account.Root)
+		}
+		s.addCode(common.BytesToHash(account.CodeHash))
+	}
+	s.leaves += uint64(len(hashes))
+
+	if more {
+		if next, overflow := incHash(hashes[len(hashes)-1]); !overflow {
+			task.origin = next
+			s.accountTasks = append(s.accountTasks, task)
+		}
+	}
+	return nil
+}
+
+// processStorage inserts a batch of storage ranges, each proven against its
+// root before any of its slots is committed. Only the last range may be
+// partial, it is continued from its last slot.
+func (s *snapSync) processStorage(req *snapReq, pack *storageRangesPack) error {
+	if len(pack.hashes) != len(pack.slots) || len(pack.hashes) > len(req.storage) {
+		s.invalid(req, "Malformed storage ranges", "roots", len(req.storage), "hashes", len(pack.hashes), "slots", len(pack.slots))
+		return nil
+	}
+	if len(pack.hashes) == 0 {
+		s.unavailable(req, "Peer lacks storage roots", "count", len(req.storage))
+		return nil
+	}
+	var requeue []*storageTask
+	for i := range pack.hashes {
+		task := req.storage[i]
+
+		var proof [][]byte
+		if i == len(pack.hashes)-1 {
+			proof = pack.proof
+		}
+		more, err := trie.VerifyRangeProof(task.root, task.origin, pack.hashes[i], pack.slots[i], proof)
+		if err != nil {
+			// The ranges before were proven already, only revert from this one
+			req.storage = req.storage[i:]
+			s.storageTasks = append(requeue, s.storageTasks...)
+			s.invalid(req, "Invalid storage range", "root", task.root, "origin", task.origin, "err", err)
+			return nil
+		}
+		if task.trie == nil {
+			task.trie, _ = trie.New(common.Hash{}, s.triedb)
+		}
+		for j, hash := range pack.hashes[i] {
+			if err := task.trie.TryUpdate(hash[:], pack.slots[i][j]); err != nil {
+				return err
+			}
+		}
+		s.slots += uint64(len(pack.hashes[i]))
+
+		// Flush the trie either way, only its right edge is left incomplete
+		root, err := task.trie.Commit(nil)
+		if err != nil {
+			return err
+		}
+		if err := s.triedb.Commit(root, false); err != nil {
+			return err
+		}
+		if more {
+			task.origin, _ = incHash(pack.hashes[i][len(pack.hashes[i])-1])
+			requeue = append(requeue, task)
+			continue
+		}
+		// Every range of the trie was proven, it can only differ locally
+		if root != task.root {
+			return fmt.Errorf("storage ranges root mismatch: have %x, want %x", root, task.root)
+		}
+		task.trie = nil
+	}
+	if len(pack.hashes) < len(req.storage) {
+		requeue = append(requeue, req.storage[len(pack.hashes):]...)
+	}
+	s.storageTasks = append(requeue, s.storageTasks...)
+	return nil
+}
+
+// addStorage schedules a storage trie unless it is known already.
+func (s *snapSync) addStorage(root common.Hash) {
+	if _, ok := s.storageSeen[root]; ok {
+		return
+	}
+	s.storageSeen[root] = struct{}{}
+	if ok, _ := s.d.stateDB.Has(root[:]); ok {
+		return
+	}
+	s.storageTasks = append(s.storageTasks, &storageTask{root: root})
+}
+
+// addCode records a contract code to be retrieved by the heal.
+func (s *snapSync) addCode(hash common.Hash) {
+	if hash == emptyCode || hash == (common.Hash{}) {
+		return
+	}
+	if _, ok := s.codeSeen[hash]; ok {
+		return
+	}
+	s.codeSeen[hash] = struct{}{}
+	if ok, _ := s.d.stateDB.Has(hash[:]); ok {
+		return
+	}
+	s.codes = append(s.codes, hash)
+}
+
+// commit persists the account trie once it matches the requested root. Until
+// then none of its nodes touch the disk, as the heal skips any subtrie whose
+// root is present, along with the storage of the accounts below it.
+func (s *snapSync) commit() error {
+	root, err := s.accounts.Commit(nil)
+	if err != nil {
+		return err
+	}
+	if root != s.root {
+		return fmt.Errorf("account ranges root mismatch: have %x, want %x", root, s.root)
+	}
+	return s.triedb.Commit(root, false)
+}
+
+func (s *snapSync) report() {
+	if time.Since(s.logged) < 8*time.Second {
+		return
+	}
+	s.logged = time.Now()
+	log.Info("Filling state from ranges", "accounts", s.leaves, "slots", s.slots, "storage", len(s.storageTasks), "codes", len(s.codes))
+}
+
+// incHash returns the hash following h, reporting whether it wrapped around.
+func incHash(h common.Hash) (common.Hash, bool) {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			return h, false
+		}
+	}
+	return h, true
+}
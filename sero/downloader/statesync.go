@@ -57,9 +57,10 @@ type stateSyncStats struct {
 	pending    uint64 // Number of still pending state entries
 }
 
-// syncState starts downloading state with the given root hash.
-func (d *Downloader) syncState(root common.Hash) *stateSync {
-	s := newStateSync(d, root)
+// syncState starts downloading state with the given root hash, along with any
+// contract codes known to be missing that the trie walk would not reach.
+func (d *Downloader) syncState(root common.Hash, codes ...common.Hash) *stateSync {
+	s := newStateSync(d, root, codes)
 	select {
 	case d.stateSyncStart <- s:
 	case <-d.quitCh:
@@ -236,10 +237,14 @@ type stateTask struct {
 
 // newStateSync creates a new state trie download scheduler. This method does not
 // yet start the sync. The user needs to call run to initiate.
-func newStateSync(d *Downloader, root common.Hash) *stateSync {
+func newStateSync(d *Downloader, root common.Hash, codes []common.Hash) *stateSync {
+	sched := state.NewStateSync(root, d.stateDB)
+	for _, code := range codes {
+		sched.AddRawEntry(code, 64, common.Hash{})
+	}
 	return &stateSync{
 		d:       d,
-		sched:   state.NewStateSync(root, d.stateDB),
+		sched:   sched,
 		keccak:  sha3.NewKeccak256(),
 		tasks:   make(map[common.Hash]*stateTask),
 		deliver: make(chan *stateReq),
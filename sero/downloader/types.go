@@ -19,6 +19,7 @@ package downloader
 import (
 	"fmt"
 
+	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/core/types"
 )
 
@@ -73,3 +74,40 @@ type statePack struct {
 func (p *statePack) PeerId() string { return p.peerID }
 func (p *statePack) Items() int     { return len(p.states) }
 func (p *statePack) Stats() string  { return fmt.Sprintf("%d", len(p.states)) }
+
+// accountRangePack is a range of flat accounts returned by a peer.
+type accountRangePack struct {
+	peerID   string
+	id       uint64
+	hashes   []common.Hash
+	accounts [][]byte
+	proof    [][]byte
+}
+
+func (p *accountRangePack) PeerId() string { return p.peerID }
+func (p *accountRangePack) Items() int     { return len(p.hashes) }
+func (p *accountRangePack) Stats() string  { return fmt.Sprintf("%d", len(p.hashes)) }
+
+// storageRangesPack is a batch of flat storage ranges returned by a peer.
+type storageRangesPack struct {
+	peerID string
+	id     uint64
+	hashes [][]common.Hash
+	slots  [][][]byte
+	proof  [][]byte
+}
+
+func (p *storageRangesPack) PeerId() string { return p.peerID }
+func (p *storageRangesPack) Items() int     { return len(p.hashes) }
+func (p *storageRangesPack) Stats() string  { return fmt.Sprintf("%d", len(p.hashes)) }
+
+// zeroBlocksPack is a batch of zero state records of blocks returned by a peer.
+type zeroBlocksPack struct {
+	peerID string
+	id     uint64
+	blocks []*ZeroBlock
+}
+
+func (p *zeroBlocksPack) PeerId() string { return p.peerID }
+func (p *zeroBlocksPack) Items() int     { return len(p.blocks) }
+func (p *zeroBlocksPack) Stats() string  { return fmt.Sprintf("%d", len(p.blocks)) }
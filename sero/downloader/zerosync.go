@@ -0,0 +1,443 @@
+// copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/consensus"
+	"github.com/sero-cash/go-sero/zero/localdb"
+	"github.com/sero-cash/go-sero/zero/stake"
+	"github.com/sero-cash/go-sero/zero/txs/zstate/txstate"
+)
+
+var errNoZeroPeers = errors.New("no peers left to serve the zero state records of blocks")
+
+// ZeroBlock holds the records a block leaves outside of the state when it is
+// imported: the outs it created, the packages it changed and the stake objects
+// it updated. A fast synced block has none of them, they are retrieved from
+// peers instead.
+type ZeroBlock struct {
+	Hash    common.Hash
+	Block   localdb.Block
+	Outs    []localdb.RootState // Out of each root of the block, in order
+	Pkgs    []localdb.ZPkg      // Package of each package hash of the block, in order
+	Records []*consensus.Record // Stake objects changed by the block
+	Shares  []stake.Share       // Shares referenced by the records
+	Pools   []stake.StakePool   // Stake pools referenced by the records
+}
+
+// zeroReq is a request for the records of a batch of blocks in flight to a peer.
+type zeroReq struct {
+	id     uint64
+	peer   *peerConnection
+	from   uint64
+	hashes []common.Hash
+	timer  *time.Timer
+}
+
+// zeroSync retrieves the zero state records of the blocks up to the pivot of a
+// fast sync. Every record is checked against the state of the pivot before it
+// is written, so a bad batch is pinned on the peer that served it.
+type zeroSync struct {
+	d       *Downloader
+	pivot   *types.Block
+	statedb *state.StateDB
+	stake   *stake.StakeState
+
+	shares map[common.Hash]struct{} // Stake shares changed by the written records
+	pools  map[common.Hash]struct{} // Stake pools changed by the written records
+
+	tasks    []uint64          // First block of each batch still to be retrieved
+	done     map[uint64]uint64 // Batches written ahead of the progress, to their end
+	progress uint64            // First block whose records are not all written
+
+	active  map[string]*zeroReq
+	failed  map[string]struct{}
+	reqID   uint64
+	timeout chan *zeroReq
+	quit    chan struct{}
+}
+
+// zeroPeers retrieves the peers able to serve the zero state records of blocks.
+func (d *Downloader) zeroPeers() []*peerConnection {
+	var peers []*peerConnection
+	for _, p := range d.peers.AllPeers() {
+		if _, ok := p.peer.(ZeroPeer); ok && p.version >= 64 {
+			peers = append(peers, p)
+		}
+	}
+	return peers
+}
+
+// syncZeroBlocks retrieves the zero state records of the canonical blocks up to
+// and including the pivot, resuming from the progress of a previous sync. The
+// state of the pivot must be complete.
+func (d *Downloader) syncZeroBlocks(pivot *types.Block) error {
+	// Records still in flight once done are discarded until the sync ends
+	cancel := d.cancelCh
+	defer func() {
+		go func() {
+			for {
+				select {
+				case <-d.zeroCh:
+				case <-cancel:
+					return
+				}
+			}
+		}()
+	}()
+
+	statedb, err := state.New(state.NewDatabase(d.stateDB), pivot.Header())
+	if err != nil {
+		return err
+	}
+	s := &zeroSync{
+		d:        d,
+		pivot:    pivot,
+		statedb:  statedb,
+		stake:    stake.NewStakeState(statedb),
+		shares:   make(map[common.Hash]struct{}),
+		pools:    make(map[common.Hash]struct{}),
+		done:     make(map[uint64]uint64),
+		progress: rawdb.ReadZeroSyncProgress(d.stateDB),
+		active:   make(map[string]*zeroReq),
+		failed:   make(map[string]struct{}),
+		timeout:  make(chan *zeroReq),
+		quit:     make(chan struct{}),
+	}
+	// The records of the genesis are written along with its state
+	if s.progress == 0 {
+		s.progress = 1
+	}
+	for from := s.progress; from <= pivot.NumberU64(); from += uint64(MaxZeroBlockFetch) {
+		s.tasks = append(s.tasks, from)
+	}
+	start := time.Now()
+	if err := s.loop(); err != nil {
+		return err
+	}
+	if err := s.checkStakeStates(); err != nil {
+		// The records can't be told apart, they are all retrieved again
+		rawdb.WriteZeroSyncProgress(d.stateDB, 0)
+		return err
+	}
+	if pivot.NumberU64() >= seroparam.SIP4() {
+		// The shares selected by the pivot are checked by the votes of the block
+		// after it, they are derived from its state
+		batch := d.stateDB.NewBatch()
+		if err := s.stake.RecordVotes(batch, pivot); err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	log.Info("Retrieved zero state records", "pivot", pivot.NumberU64(), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// loop assigns batches to idle peers and writes their records until every block
+// up to the pivot is done.
+func (s *zeroSync) loop() error {
+	defer close(s.quit)
+
+	peerDrop := make(chan *peerConnection, 1024)
+	peerSub := s.d.peers.SubscribePeerDrops(peerDrop)
+	defer peerSub.Unsubscribe()
+	defer func() {
+		for _, req := range s.active {
+			req.timer.Stop()
+		}
+	}()
+
+	for len(s.tasks) > 0 || len(s.active) > 0 {
+		if err := s.assign(); err != nil {
+			return err
+		}
+		select {
+		case packet := <-s.d.zeroCh:
+			req := s.active[packet.PeerId()]
+			if req == nil {
+				log.Debug("Unrequested zero state records", "peer", packet.PeerId(), "len", packet.Items())
+				continue
+			}
+			pack := packet.(*zeroBlocksPack)
+			if pack.id != req.id {
+				continue
+			}
+			req.timer.Stop()
+			delete(s.active, req.peer.id)
+			if err := s.process(req, pack); err != nil {
+				return err
+			}
+
+		case p := <-peerDrop:
+			if req := s.active[p.id]; req != nil {
+				req.timer.Stop()
+				delete(s.active, p.id)
+				s.tasks = append([]uint64{req.from}, s.tasks...)
+			}
+
+		case req := <-s.timeout:
+			if s.active[req.peer.id] != req {
+				continue
+			}
+			delete(s.active, req.peer.id)
+			s.tasks = append([]uint64{req.from}, s.tasks...)
+			s.failed[req.peer.id] = struct{}{}
+
+		case <-s.d.cancelCh:
+			return errCancelStateFetch
+
+		case <-s.d.quitCh:
+			return errCancelStateFetch
+		}
+	}
+	return nil
+}
+
+// assign sends the pending batches to the idle peers.
+func (s *zeroSync) assign() error {
+	for _, p := range s.d.zeroPeers() {
+		if len(s.tasks) == 0 {
+			break
+		}
+		if _, ok := s.active[p.id]; ok {
+			continue
+		}
+		if _, ok := s.failed[p.id]; ok {
+			continue
+		}
+		from := s.tasks[0]
+		hashes, err := s.hashes(from)
+		if err != nil {
+			return err
+		}
+		s.tasks = s.tasks[1:]
+
+		s.reqID++
+		req := &zeroReq{id: s.reqID, peer: p, from: from, hashes: hashes}
+		if err := p.peer.(ZeroPeer).RequestZeroBlocks(req.id, hashes); err != nil {
+			s.tasks = append([]uint64{from}, s.tasks...)
+			s.failed[p.id] = struct{}{}
+			continue
+		}
+		req.timer = time.AfterFunc(s.d.requestTTL(), func() {
+			select {
+			case s.timeout <- req:
+			case <-s.quit:
+			}
+		})
+		s.active[p.id] = req
+	}
+	if len(s.active) == 0 && len(s.tasks) > 0 {
+		return errNoZeroPeers
+	}
+	return nil
+}
+
+// hashes returns the canonical hashes of the batch starting at from.
+func (s *zeroSync) hashes(from uint64) ([]common.Hash, error) {
+	var hashes []common.Hash
+	for n := from; n < from+uint64(MaxZeroBlockFetch) && n <= s.pivot.NumberU64(); n++ {
+		hash := rawdb.ReadCanonicalHash(s.d.stateDB, n)
+		if hash == (common.Hash{}) {
+			return nil, fmt.Errorf("missing canonical hash of block %d", n)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// process checks and writes the records of a batch. A peer lacking some of the
+// blocks is skipped for the rest of the sync, one serving a bad record is dropped.
+func (s *zeroSync) process(req *zeroReq, pack *zeroBlocksPack) error {
+	if len(pack.blocks) < len(req.hashes) {
+		req.peer.log.Debug("Peer lacks zero state records", "from", req.from, "have", len(pack.blocks), "want", len(req.hashes))
+		s.failed[req.peer.id] = struct{}{}
+		s.tasks = append([]uint64{req.from}, s.tasks...)
+		return nil
+	}
+	batch := s.d.stateDB.NewBatch()
+	for i, hash := range req.hashes {
+		if err := s.write(batch, req.from+uint64(i), hash, pack.blocks[i]); err != nil {
+			req.peer.log.Warn("Invalid zero state records", "number", req.from+uint64(i), "hash", hash, "err", err)
+			s.failed[req.peer.id] = struct{}{}
+			s.tasks = append([]uint64{req.from}, s.tasks...)
+			if s.d.dropPeer != nil {
+				s.d.dropPeer(req.peer.id)
+			}
+			return nil
+		}
+	}
+	s.done[req.from] = req.from + uint64(len(req.hashes))
+	for next, ok := s.done[s.progress]; ok; next, ok = s.done[s.progress] {
+		delete(s.done, s.progress)
+		s.progress = next
+	}
+	rawdb.WriteZeroSyncProgress(batch, s.progress)
+	return batch.Write()
+}
+
+// write checks the records of a block against the state of the pivot and adds
+// them to the batch.
+func (s *zeroSync) write(batch serodb.Batch, number uint64, hash common.Hash, zb *ZeroBlock) error {
+	if zb.Hash != hash {
+		return fmt.Errorf("block hash mismatch: have %x", zb.Hash)
+	}
+	st := &s.statedb.CurrentZState().State
+
+	// Outs are only recorded since shortly before SIP2, the older ones are read
+	// from the state
+	if len(zb.Outs) != len(zb.Block.Roots) && (len(zb.Outs) != 0 || int64(number) > int64(seroparam.SIP2())-13000) {
+		return fmt.Errorf("out count mismatch: have %d, want %d", len(zb.Outs), len(zb.Block.Roots))
+	}
+	for i := range zb.Outs {
+		if err := checkOut(st, number, &zb.Block.Roots[i], &zb.Outs[i]); err != nil {
+			return err
+		}
+	}
+	for i := range zb.Block.Dels {
+		if !st.FindAnchorInSzk(&zb.Block.Dels[i]) {
+			return fmt.Errorf("unknown spent root %x", zb.Block.Dels[i])
+		}
+	}
+	if len(zb.Pkgs) != len(zb.Block.Pkgs) {
+		return fmt.Errorf("package count mismatch: have %d, want %d", len(zb.Pkgs), len(zb.Block.Pkgs))
+	}
+	for i := range zb.Pkgs {
+		if zb.Pkgs[i].ToHash() != zb.Block.Pkgs[i] {
+			return fmt.Errorf("package hash mismatch: %x", zb.Block.Pkgs[i])
+		}
+	}
+	if err := s.checkStake(zb); err != nil {
+		return err
+	}
+
+	key := hash.HashToUint256()
+	localdb.PutBlock(batch, number, key, &zb.Block)
+	for i := range zb.Outs {
+		localdb.PutRoot(batch, &zb.Block.Roots[i], &zb.Outs[i])
+	}
+	for i := range zb.Pkgs {
+		localdb.PutPkg(batch, &zb.Block.Pkgs[i], &zb.Pkgs[i])
+	}
+	if len(zb.Records) > 0 {
+		state.StakeDB.PutBlockRecords(batch, number, &hash, zb.Records)
+	}
+	for i := range zb.Shares {
+		if err := stake.ShareDB.PutObject(batch, zb.Shares[i].State(), &zb.Shares[i]); err != nil {
+			return err
+		}
+	}
+	for i := range zb.Pools {
+		if err := stake.StakePoolDB.PutObject(batch, zb.Pools[i].State(), &zb.Pools[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkOut checks that an out was created by the given block and that root is
+// the anchor its commitment produced when it was appended to the tree.
+func checkOut(st *txstate.State, number uint64, root *c_type.Uint256, rs *localdb.RootState) error {
+	if rs.Num != number {
+		return fmt.Errorf("out %x of block %d", *root, rs.Num)
+	}
+	if !rs.OS.CheckRootCM() {
+		return fmt.Errorf("out %x commitment mismatch", *root)
+	}
+	tree := &st.SzkTree
+	if rs.OS.Out_O != nil || rs.OS.Out_Z != nil {
+		tree = &st.CzeroTree
+	}
+	if anchor, ok := tree.AnchorOf(*rs.OS.RootCM); !ok || anchor != *root {
+		return fmt.Errorf("out %x not in the tree", *root)
+	}
+	if !st.FindAnchorInSzk(root) {
+		return fmt.Errorf("unknown out root %x", *root)
+	}
+	return nil
+}
+
+// checkStake checks that the stake objects are exactly the ones the records of
+// the block reference, and that they are shares and pools of the state of the
+// pivot. Their state at the pivot is checked once all the records are written.
+func (s *zeroSync) checkStake(zb *ZeroBlock) error {
+	shares := make(map[string][]byte)
+	for i := range zb.Shares {
+		shares[string(zb.Shares[i].State())] = zb.Shares[i].Id()
+	}
+	pools := make(map[string][]byte)
+	for i := range zb.Pools {
+		pools[string(zb.Pools[i].State())] = zb.Pools[i].Id()
+	}
+	for _, record := range zb.Records {
+		objs, states, ids := shares, s.stake.ShareState, s.shares
+		switch record.Name {
+		case "share":
+		case "pool":
+			objs, states, ids = pools, s.stake.StakePoolState, s.pools
+		default:
+			return fmt.Errorf("unknown stake record %q", record.Name)
+		}
+		for _, pair := range record.Pairs {
+			id, ok := objs[string(pair.Hash)]
+			if !ok || !bytes.Equal(id, pair.Ref) {
+				return fmt.Errorf("stake %s %x not served", record.Name, pair.Ref)
+			}
+			if states(id) == nil {
+				return fmt.Errorf("stake %s %x not in the state", record.Name, id)
+			}
+			delete(objs, string(pair.Hash))
+			ids[common.BytesToHash(id)] = struct{}{}
+		}
+	}
+	if len(shares) > 0 || len(pools) > 0 {
+		return fmt.Errorf("%d stake objects not recorded", len(shares)+len(pools))
+	}
+	return nil
+}
+
+// checkStakeStates checks that the state of every stake share and pool changed
+// by the records is the one the pivot holds. Only the last record of an object
+// can be checked against the pivot, it must have been written.
+func (s *zeroSync) checkStakeStates() error {
+	for id := range s.shares {
+		if stake.ShareDB.GetObject(s.d.stateDB, s.stake.ShareState(id[:]), &stake.Share{}) == nil {
+			return fmt.Errorf("stake share %x mismatch", id)
+		}
+	}
+	for id := range s.pools {
+		if stake.StakePoolDB.GetObject(s.d.stateDB, s.stake.StakePoolState(id[:]), &stake.StakePool{}) == nil {
+			return fmt.Errorf("stake pool %x mismatch", id)
+		}
+	}
+	return nil
+}
@@ -23,12 +23,16 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
 		NoPruning               bool
+		StateRetain             uint64
+		AncientThreshold        uint64
 		MineMode                bool
 		StartExchange           bool
 		AutoMerge               bool
 		StartLight              bool
+		StartHistory            bool
 		LightServ               int  `toml:",omitempty"`
 		LightPeers              int  `toml:",omitempty"`
+		LightWallet             bool `toml:",omitempty"`
 		SkipBcVersionCheck      bool `toml:"-"`
 		DatabaseHandles         int  `toml:"-"`
 		DatabaseCache           int
@@ -49,12 +53,16 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
 	enc.NoPruning = c.NoPruning
+	enc.StateRetain = c.StateRetain
+	enc.AncientThreshold = c.AncientThreshold
 	enc.MineMode = c.MineMode
 	enc.StartExchange = c.StartExchange
 	enc.AutoMerge = c.AutoMerge
 	enc.StartLight = c.StartLight
+	enc.StartHistory = c.StartHistory
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
+	enc.LightWallet = c.LightWallet
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
@@ -79,12 +87,16 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
 		NoPruning               *bool
+		StateRetain             *uint64
+		AncientThreshold        *uint64
 		MineMode                *bool
 		StartExchange           *bool
 		AutoMerge               *bool
 		StartLight              *bool
+		StartHistory            *bool
 		LightServ               *int  `toml:",omitempty"`
 		LightPeers              *int  `toml:",omitempty"`
+		LightWallet             *bool `toml:",omitempty"`
 		SkipBcVersionCheck      *bool `toml:"-"`
 		DatabaseHandles         *int  `toml:"-"`
 		DatabaseCache           *int
@@ -116,6 +128,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPruning != nil {
 		c.NoPruning = *dec.NoPruning
 	}
+	if dec.StateRetain != nil {
+		c.StateRetain = *dec.StateRetain
+	}
+	if dec.AncientThreshold != nil {
+		c.AncientThreshold = *dec.AncientThreshold
+	}
 	if dec.MineMode != nil {
 		c.MineMode = *dec.MineMode
 	}
@@ -128,12 +146,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.StartLight != nil {
 		c.StartLight = *dec.StartLight
 	}
+	if dec.StartHistory != nil {
+		c.StartHistory = *dec.StartHistory
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.LightWallet != nil {
+		c.LightWallet = *dec.LightWallet
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
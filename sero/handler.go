@@ -22,15 +22,19 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"net"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/sero-cash/go-sero/zero/localdb"
+	"github.com/sero-cash/go-sero/zero/stake"
 	"github.com/sero-cash/go-sero/zero/zconfig"
 
 	"github.com/sero-cash/go-sero/common"
 	"github.com/sero-cash/go-sero/consensus"
 	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/state"
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/event"
 	"github.com/sero-cash/go-sero/log"
@@ -41,6 +45,7 @@ import (
 	"github.com/sero-cash/go-sero/sero/downloader"
 	"github.com/sero-cash/go-sero/sero/fetcher"
 	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/trie"
 )
 
 const (
@@ -58,8 +63,18 @@ const (
 // not compatible (low protocol version restrictions and high requirements).
 var errIncompatibleConfig = errors.New("incompatible configuration")
 
+// protoError is an error of the protocol caused by a remote peer.
+type protoError struct {
+	code errCode
+	msg  string
+}
+
+func (e *protoError) Error() string {
+	return fmt.Sprintf("%v - %v", e.code, e.msg)
+}
+
 func errResp(code errCode, format string, v ...interface{}) error {
-	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+	return &protoError{code, fmt.Sprintf(format, v...)}
 }
 
 type ProtocolManager struct {
@@ -77,6 +92,7 @@ type ProtocolManager struct {
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
+	reputation *p2p.Reputation
 
 	SubProtocols []p2p.Protocol
 
@@ -185,7 +201,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		atomic.StoreUint32(&manager.acceptTxs, 1) // Mark initial sync done on any fetcher import
 		return manager.blockchain.InsertChain(blocks)
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.dropBadPeer)
 
 	return manager, nil
 }
@@ -209,6 +225,24 @@ func (pm *ProtocolManager) removePeer(id string) {
 	}
 }
 
+// dropBadPeer penalizes and removes a peer which propagated a bad block.
+func (pm *ProtocolManager) dropBadPeer(id string) {
+	if peer := pm.peers.Peer(id); peer != nil {
+		pm.penalize(peer, p2p.PenaltyBadBlock, "bad propagated block")
+	}
+	pm.removePeer(id)
+}
+
+// penalize adds a penalty to the reputation of a peer and returns whether it
+// got banned. The IPs of the trusted and static peers are left out.
+func (pm *ProtocolManager) penalize(p *peer, points float64, reason string) bool {
+	var ip net.IP
+	if addr, ok := p.RemoteAddr().(*net.TCPAddr); ok && !p.Trusted() {
+		ip = addr.IP
+	}
+	return pm.reputation.Penalize(p.ID(), ip, points, reason)
+}
+
 func (pm *ProtocolManager) Start(maxPeers int) {
 	pm.maxPeers = maxPeers
 
@@ -305,6 +339,14 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	for {
 		if err := pm.handleMsg(p); err != nil {
 			p.Log().Debug("Sero message handling failed", "err", err)
+			if perr, ok := err.(*protoError); ok {
+				switch perr.code {
+				case ErrDecode:
+					pm.penalize(p, p2p.PenaltyInvalidMsg, perr.Error())
+				case ErrInvalidVote:
+					pm.penalize(p, p2p.PenaltyInvalidVote, perr.Error())
+				}
+			}
 			return err
 		}
 	}
@@ -525,6 +567,124 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			log.Debug("Failed to deliver node state data", "err", err)
 		}
 
+	case p.version >= sero64 && msg.Code == GetAccountRangeMsg:
+		// Decode the account range query
+		var query getAccountRangeData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if query.Bytes > softResponseLimit {
+			query.Bytes = softResponseLimit
+		}
+		response := &accountRangeData{ID: query.ID}
+		// Serve nothing for unknown roots, the requester moves to another peer
+		if tr, err := pm.blockchain.StateCache().OpenTrie(query.Root); err == nil {
+			response.Hashes, response.Accounts, response.Proof, err = trie.CollectRange(tr, query.Origin, query.Limit, query.Bytes)
+			if err != nil {
+				p.Log().Debug("Failed to collect account range", "root", query.Root, "err", err)
+				response = &accountRangeData{ID: query.ID}
+			}
+		}
+		return p.SendAccountRange(response)
+
+	case p.version >= sero64 && msg.Code == AccountRangeMsg:
+		// A range of accounts arrived to one of our previous requests
+		var data accountRangeData
+		if err := msg.Decode(&data); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverAccountRange(p.id, data.ID, data.Hashes, data.Accounts, data.Proof); err != nil {
+			log.Debug("Failed to deliver account range", "err", err)
+		}
+
+	case p.version >= sero64 && msg.Code == GetStorageRangesMsg:
+		// Decode the storage ranges query
+		var query getStorageRangesData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if query.Bytes > softResponseLimit {
+			query.Bytes = softResponseLimit
+		}
+		if len(query.Roots) > downloader.MaxStorageRangeFetch {
+			query.Roots = query.Roots[:downloader.MaxStorageRangeFetch]
+		}
+		var (
+			response = &storageRangesData{ID: query.ID}
+			bytes    uint64
+			limit    = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+		)
+		for i, root := range query.Roots {
+			if bytes >= query.Bytes {
+				break
+			}
+			tr, err := pm.blockchain.StateCache().OpenStorageTrie(common.Hash{}, root)
+			if err != nil {
+				break
+			}
+			origin := common.Hash{}
+			if i == 0 {
+				origin = query.Origin
+			}
+			hashes, slots, proof, err := trie.CollectRange(tr, origin, limit, query.Bytes-bytes)
+			if err != nil {
+				p.Log().Debug("Failed to collect storage range", "root", root, "err", err)
+				break
+			}
+			response.Hashes = append(response.Hashes, hashes)
+			response.Slots = append(response.Slots, slots)
+			for j := range hashes {
+				bytes += uint64(common.HashLength + len(slots[j]))
+			}
+			// A range cut short or not starting at the first slot is proven,
+			// ending the response
+			if origin != (common.Hash{}) || bytes >= query.Bytes {
+				response.Proof = proof
+				break
+			}
+		}
+		return p.SendStorageRanges(response)
+
+	case p.version >= sero64 && msg.Code == StorageRangesMsg:
+		// A batch of storage ranges arrived to one of our previous requests
+		var data storageRangesData
+		if err := msg.Decode(&data); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverStorageRanges(p.id, data.ID, data.Hashes, data.Slots, data.Proof); err != nil {
+			log.Debug("Failed to deliver storage ranges", "err", err)
+		}
+
+	case p.version >= sero64 && msg.Code == GetZeroBlocksMsg:
+		// Decode the zero state records query
+		var query getZeroBlocksData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if len(query.Hashes) > downloader.MaxZeroBlockFetch {
+			query.Hashes = query.Hashes[:downloader.MaxZeroBlockFetch]
+		}
+		response := &zeroBlocksData{ID: query.ID}
+		for _, hash := range query.Hashes {
+			block := pm.zeroBlock(hash)
+			if block == nil {
+				break
+			}
+			response.Blocks = append(response.Blocks, block)
+		}
+		return p.SendZeroBlocks(response)
+
+	case p.version >= sero64 && msg.Code == ZeroBlocksMsg:
+		// The zero state records of a batch of blocks arrived to one of our
+		// previous requests
+		var data zeroBlocksData
+		if err := msg.Decode(&data); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverZeroBlocks(p.id, data.ID, data.Blocks); err != nil {
+			log.Debug("Failed to deliver zero state records", "err", err)
+		}
+
 	case p.version >= sero63 && msg.Code == GetReceiptsMsg:
 		// Decode the retrieval message
 		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
@@ -641,16 +801,24 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			}
 			p.MarkTransaction(tx.Hash())
 		}
-		pm.txpool.AddRemotes(txs)
+		// Only the invalid proofs are the sender's fault, the state checks
+		// may fail on a transaction racing with the chain head
+		for _, err := range pm.txpool.AddRemotes(txs) {
+			if err == core.ErrProofError && pm.penalize(p, p2p.PenaltyBadTx, err.Error()) {
+				return errResp(ErrTxVerify, "%v", err)
+			}
+		}
 
 	case msg.Code == NewVoteMsg:
 		var vote types.Vote
 
 		if err := msg.Decode(&vote); err != nil {
-			return errResp(ErrDecode, "msg %v: %v", msg, err)
+			return errResp(ErrInvalidVote, "msg %v: %v", msg, err)
 		}
 		p.MarkVote(vote.Hash())
-		pm.voter.AddVote(&vote)
+		if err := pm.voter.AddVote(&vote); err != nil {
+			return errResp(ErrInvalidVote, "vote %x: %v", vote.Hash(), err)
+		}
 
 	case msg.Code == NewLotteryMsg:
 
@@ -668,6 +836,59 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	return nil
 }
 
+// zeroBlock collects the zero state records of a block, nil if they were
+// pruned or the block is unknown.
+func (pm *ProtocolManager) zeroBlock(hash common.Hash) *downloader.ZeroBlock {
+	header := pm.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil
+	}
+	db, number := pm.blockchain.GetDB(), header.Number.Uint64()
+	block := localdb.GetBlock(db, number, hash.HashToUint256())
+	if block == nil {
+		return nil
+	}
+	zb := &downloader.ZeroBlock{Hash: hash, Block: *block}
+	for i := range block.Roots {
+		rs := localdb.GetRoot(db, &block.Roots[i])
+		if rs == nil {
+			// Outs are only recorded since shortly before SIP2
+			if i > 0 {
+				return nil
+			}
+			break
+		}
+		zb.Outs = append(zb.Outs, *rs)
+	}
+	for i := range block.Pkgs {
+		pkg := localdb.GetPkg(db, &block.Pkgs[i])
+		if pkg == nil {
+			return nil
+		}
+		zb.Pkgs = append(zb.Pkgs, *pkg)
+	}
+	zb.Records = state.StakeDB.GetBlockRecords(db, number, &hash)
+	for _, record := range zb.Records {
+		for _, pair := range record.Pairs {
+			switch record.Name {
+			case "share":
+				share := stake.ShareDB.GetObject(db, pair.Hash, &stake.Share{})
+				if share == nil {
+					return nil
+				}
+				zb.Shares = append(zb.Shares, *share.(*stake.Share))
+			case "pool":
+				pool := stake.StakePoolDB.GetObject(db, pair.Hash, &stake.StakePool{})
+				if pool == nil {
+					return nil
+				}
+				zb.Pools = append(zb.Pools, *pool.(*stake.StakePool))
+			}
+		}
+	}
+	return zb
+}
+
 // BroadcastBlock will either propagate a block to a subset of it's peers, or
 // will only announce it's availability (depending what's requested).
 func (pm *ProtocolManager) BroadcastBlock(block *types.Block, propagate bool) {
@@ -348,6 +348,21 @@ func (p *peer) SendNodeData(data [][]byte) error {
 	return p2p.Send(p.rw, NodeDataMsg, data)
 }
 
+// SendAccountRange sends a range of flat accounts to the remote peer.
+func (p *peer) SendAccountRange(data *accountRangeData) error {
+	return p2p.Send(p.rw, AccountRangeMsg, data)
+}
+
+// SendStorageRanges sends a batch of flat storage ranges to the remote peer.
+func (p *peer) SendStorageRanges(data *storageRangesData) error {
+	return p2p.Send(p.rw, StorageRangesMsg, data)
+}
+
+// SendZeroBlocks sends the zero state records of a batch of blocks to the remote peer.
+func (p *peer) SendZeroBlocks(data *zeroBlocksData) error {
+	return p2p.Send(p.rw, ZeroBlocksMsg, data)
+}
+
 // SendReceiptsRLP sends a batch of transaction receipts, corresponding to the
 // ones requested from an already RLP encoded format.
 func (p *peer) SendReceiptsRLP(receipts []rlp.RawValue) error {
@@ -389,6 +404,24 @@ func (p *peer) RequestNodeData(hashes []common.Hash) error {
 	return p2p.Send(p.rw, GetNodeDataMsg, hashes)
 }
 
+// RequestAccountRange fetches a range of flat accounts of a state root.
+func (p *peer) RequestAccountRange(id uint64, root common.Hash, origin common.Hash, limit common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching range of accounts", "root", root, "origin", origin, "limit", limit)
+	return p2p.Send(p.rw, GetAccountRangeMsg, &getAccountRangeData{ID: id, Root: root, Origin: origin, Limit: limit, Bytes: bytes})
+}
+
+// RequestStorageRanges fetches the flat slots of a batch of storage tries.
+func (p *peer) RequestStorageRanges(id uint64, roots []common.Hash, origin common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching ranges of storage slots", "count", len(roots), "origin", origin)
+	return p2p.Send(p.rw, GetStorageRangesMsg, &getStorageRangesData{ID: id, Roots: roots, Origin: origin, Bytes: bytes})
+}
+
+// RequestZeroBlocks fetches the zero state records of a batch of blocks.
+func (p *peer) RequestZeroBlocks(id uint64, hashes []common.Hash) error {
+	p.Log().Debug("Fetching zero state records of blocks", "count", len(hashes))
+	return p2p.Send(p.rw, GetZeroBlocksMsg, &getZeroBlocksData{ID: id, Hashes: hashes})
+}
+
 // RequestReceipts fetches a batch of transaction receipts from a remote node.
 func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of receipts", "count", len(hashes))
@@ -26,22 +26,24 @@ import (
 	"github.com/sero-cash/go-sero/core/types"
 	"github.com/sero-cash/go-sero/event"
 	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/sero/downloader"
 )
 
 // Constants to match up protocol versions and messages
 const (
 	sero62 = 62
 	sero63 = 63
+	sero64 = 64
 )
 
 // ProtocolName is the official short name of the protocol used during capability negotiation.
 var ProtocolName = "sero"
 
 // ProtocolVersions are the upported versions of the sero protocol (first is primary).
-var ProtocolVersions = []uint{sero63, sero62}
+var ProtocolVersions = []uint{sero64, sero63, sero62}
 
 // ProtocolLengths are the number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{24, 8}
+var ProtocolLengths = []uint64{30, 24, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -65,6 +67,14 @@ const (
 
 	NewVoteMsg    = 0x16
 	NewLotteryMsg = 0x17
+
+	// Protocol messages belonging to sero/64
+	GetAccountRangeMsg  = 0x18
+	AccountRangeMsg     = 0x19
+	GetStorageRangesMsg = 0x1a
+	StorageRangesMsg    = 0x1b
+	GetZeroBlocksMsg    = 0x1c
+	ZeroBlocksMsg       = 0x1d
 )
 
 type errCode int
@@ -79,6 +89,8 @@ const (
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
+	ErrInvalidVote
+	ErrTxVerify
 )
 
 func (e errCode) String() string {
@@ -96,6 +108,8 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrInvalidVote:             "Invalid vote",
+	ErrTxVerify:                "Transaction verification failed",
 }
 
 type txPool interface {
@@ -115,7 +129,7 @@ type shareVoter interface {
 	SubscribeNewVoteEvent(chan<- core.NewVoteEvent) event.Subscription
 	SubscribeNewLotteryEvent(chan<- core.NewLotteryEvent) event.Subscription
 	AddLottery(lottery *types.Lottery)
-	AddVote(vote *types.Vote)
+	AddVote(vote *types.Vote) error
 }
 
 // statusData is the network packet for the status message.
@@ -190,3 +204,54 @@ type blockBody struct {
 
 // blockBodiesData is the network packet for block content distribution.
 type blockBodiesData []*blockBody
+
+// getAccountRangeData represents a query for the flat accounts of the state trie
+// of Root, from Origin up to and including Limit. The first account after Limit
+// is returned as well, closing the proof of the range.
+type getAccountRangeData struct {
+	ID     uint64      // Request id, echoed in the response
+	Root   common.Hash // State root of the accounts
+	Origin common.Hash // Hash of the first account to retrieve
+	Limit  common.Hash // Hash of the last account to retrieve
+	Bytes  uint64      // Soft limit on the response size
+}
+
+// accountRangeData is the network packet for flat account ranges.
+type accountRangeData struct {
+	ID       uint64
+	Hashes   []common.Hash // Hashes of the returned accounts
+	Accounts [][]byte      // Trie encoding of the returned accounts
+	Proof    [][]byte      // Merkle proofs of the origin and of the last account
+}
+
+// getStorageRangesData represents a query for the flat slots of a batch of
+// storage tries. Origin only applies to the first trie.
+type getStorageRangesData struct {
+	ID     uint64
+	Roots  []common.Hash // Storage roots of the tries to retrieve
+	Origin common.Hash   // Hash of the first slot to retrieve from the first trie
+	Bytes  uint64        // Soft limit on the response size
+}
+
+// storageRangesData is the network packet for flat storage ranges. Only the last
+// range may be partial, in which case Proof holds its merkle proofs.
+type storageRangesData struct {
+	ID     uint64
+	Hashes [][]common.Hash
+	Slots  [][][]byte
+	Proof  [][]byte
+}
+
+// getZeroBlocksData represents a query for the zero state records of a batch of
+// blocks.
+type getZeroBlocksData struct {
+	ID     uint64
+	Hashes []common.Hash
+}
+
+// zeroBlocksData is the network packet for the zero state records of blocks,
+// stopping at the first requested block the peer has none for.
+type zeroBlocksData struct {
+	ID     uint64
+	Blocks []*downloader.ZeroBlock
+}
@@ -0,0 +1,138 @@
+package sero
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/zero/stake"
+)
+
+// replayReexec is the number of blocks the replayer is willing to go back and
+// execute again to find the state of the block to replay.
+const replayReexec = uint64(128)
+
+var errReplayStateUnavailable = errors.New("required historical state unavailable")
+
+// blockReplayer executes again the blocks imported before their non-consensus
+// events were recorded, to regenerate these events. The blocks are replayed in
+// order on the state left by the previous one, so only the first block of a
+// run has to look for a state.
+type blockReplayer struct {
+	chain    *core.BlockChain
+	database state.Database // state database of the replayed blocks, apart from the chain one
+
+	statedb *state.StateDB // state after the last replayed block
+	root    common.Hash    // root of statedb, referenced in database
+	hash    common.Hash    // hash of the last replayed block
+	failed  bool           // whether no state was found for the last block
+}
+
+func newBlockReplayer(chain *core.BlockChain) *blockReplayer {
+	return &blockReplayer{
+		chain:    chain,
+		database: state.NewDatabase(chain.GetDB()),
+	}
+}
+
+// replay executes a block again and calls fn with its state before it is
+// committed, while its events are still available.
+func (r *blockReplayer) replay(block *types.Block, fn func(*state.StateDB)) error {
+	if block.NumberU64() == 0 {
+		return nil
+	}
+	if r.statedb == nil || r.hash != block.ParentHash() {
+		if err := r.load(block); err != nil {
+			return err
+		}
+	}
+	if err := r.process(block); err != nil {
+		r.release()
+		return err
+	}
+	fn(r.statedb)
+	return r.commit(block)
+}
+
+// load finds the state of the parent of a block. Once no state was found, only
+// the parent itself is tried, not to walk back again for every following block
+// of a chain without states.
+func (r *blockReplayer) load(block *types.Block) error {
+	r.release()
+
+	limit := replayReexec
+	if r.failed {
+		limit = 1
+	}
+	var blocks []*types.Block
+	parent := r.chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	for i := uint64(0); parent != nil && i < limit; i++ {
+		if statedb, err := state.New(r.database, parent.Header()); err == nil {
+			r.statedb = statedb
+			r.root = parent.Root()
+			r.hash = parent.Hash()
+			r.database.TrieDB().Reference(r.root, common.Hash{})
+			break
+		}
+		blocks = append(blocks, parent)
+		parent = r.chain.GetBlock(parent.ParentHash(), parent.NumberU64()-1)
+	}
+	if r.statedb == nil {
+		r.failed = true
+		return errReplayStateUnavailable
+	}
+	r.failed = false
+
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if err := r.process(blocks[i]); err != nil {
+			r.release()
+			return err
+		}
+		if err := r.commit(blocks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// process executes a block on statedb the way the chain imports it.
+func (r *blockReplayer) process(block *types.Block) error {
+	if seroparam.SIP4() <= block.NumberU64() {
+		if err := stake.NewStakeState(r.statedb).ProcessBeforeApply(r.chain, block.Header()); err != nil {
+			return err
+		}
+	}
+	_, _, _, err := r.chain.Processor().Process(block, r.statedb, vm.Config{})
+	return err
+}
+
+func (r *blockReplayer) commit(block *types.Block) error {
+	root, err := r.statedb.Commit(true)
+	if err == nil && root != block.Root() {
+		err = fmt.Errorf("replayed block #%d root mismatch: have %x, want %x", block.NumberU64(), root, block.Root())
+	}
+	if err == nil {
+		err = r.statedb.Reset(root)
+	}
+	if err != nil {
+		r.release()
+		return err
+	}
+	r.database.TrieDB().Reference(root, common.Hash{})
+	r.database.TrieDB().Dereference(r.root)
+	r.root, r.hash = root, block.Hash()
+	return nil
+}
+
+// release drops the state of the last replayed block.
+func (r *blockReplayer) release() {
+	if r.root != (common.Hash{}) {
+		r.database.TrieDB().Dereference(r.root)
+	}
+	r.statedb, r.root, r.hash = nil, common.Hash{}, common.Hash{}
+}
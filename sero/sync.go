@@ -185,8 +185,8 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		// The only scenario where this can happen is if the user manually (or via a
 		// bad block) rolled back a fast sync node below the sync point. In this case
 		// however it's safe to reenable fast sync.
-		//atomic.StoreUint32(&pm.fastSync, 1)
-		//mode = downloader.FastSync
+		atomic.StoreUint32(&pm.fastSync, 1)
+		mode = downloader.FastSync
 	}
 
 	if mode == downloader.FastSync {
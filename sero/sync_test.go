@@ -0,0 +1,163 @@
+package sero
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/event"
+	"github.com/sero-cash/go-sero/node"
+	"github.com/sero-cash/go-sero/p2p"
+	"github.com/sero-cash/go-sero/p2p/simulations"
+	"github.com/sero-cash/go-sero/p2p/simulations/adapters"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/rpc"
+	"github.com/sero-cash/go-sero/sero/downloader"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/localdb"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+)
+
+// testTxPool and testVoter stub out the transaction and vote broadcasts.
+type testTxPool struct{ feed event.Feed }
+
+func (p *testTxPool) AddRemotes([]*types.Transaction) []error { return nil }
+func (p *testTxPool) Pending() (types.Transactions, error)    { return nil, nil }
+func (p *testTxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return p.feed.Subscribe(ch)
+}
+
+type testVoter struct{ votes, lotteries event.Feed }
+
+func (v *testVoter) SubscribeNewVoteEvent(ch chan<- core.NewVoteEvent) event.Subscription {
+	return v.votes.Subscribe(ch)
+}
+func (v *testVoter) SubscribeNewLotteryEvent(ch chan<- core.NewLotteryEvent) event.Subscription {
+	return v.lotteries.Subscribe(ch)
+}
+func (v *testVoter) AddLottery(*types.Lottery) {}
+func (v *testVoter) AddVote(*types.Vote) error { return nil }
+
+// testService runs a protocol manager as a node service.
+type testService struct{ pm *ProtocolManager }
+
+func (s *testService) Protocols() []p2p.Protocol { return s.pm.SubProtocols }
+func (s *testService) APIs() []rpc.API           { return nil }
+func (s *testService) Start(*p2p.Server) error   { s.pm.Start(10); return nil }
+func (s *testService) Stop() error               { s.pm.Stop(); return nil }
+
+// newTestManager creates a protocol manager over a chain sharing the genesis of
+// gspec.
+func newTestManager(t *testing.T, gspec *core.Genesis, mode downloader.SyncMode) (*ProtocolManager, serodb.Database) {
+	db := serodb.NewMemDatabase()
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFullFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	pm, err := NewProtocolManager(gspec.Config, mode, 1, new(event.TypeMux), new(testVoter), new(testTxPool), ethash.NewFullFaker(), chain, db)
+	if err != nil {
+		t.Fatalf("failed to create protocol manager: %v", err)
+	}
+	return pm, db
+}
+
+// Tests that a node fast syncs from a peer over the simulated network, the zero
+// state records of the blocks up to the pivot included.
+func TestFastSyncZeroBlocks(t *testing.T) {
+	cpt.ZeroInit(cpt.NET_Alpha)
+	seroparam.Init_Dev(true)
+	zconfig.Init_SkipProof(true)
+	defer seroparam.Init_Dev(false)
+	defer zconfig.Init_SkipProof(false)
+
+	alice := core.NewTestAccount("alice")
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{alice.Address(): {Balance: big.NewInt(1000000)}},
+	}
+	server, _ := newTestManager(t, gspec, downloader.FullSync)
+	defer server.blockchain.Stop()
+
+	txtool.Ref_inst.SetBC(&core.State1BlockChain{Bc: server.blockchain})
+	blocks, err := core.GeneratePrivateChain(server.blockchain, 80, nil)
+	if err != nil {
+		t.Fatalf("failed to generate chain: %v", err)
+	}
+	client, clientDb := newTestManager(t, gspec, downloader.FastSync)
+	defer client.blockchain.Stop()
+
+	services := map[string]adapters.ServiceFunc{
+		"server": func(*adapters.ServiceContext) (node.Service, error) { return &testService{server}, nil },
+		"client": func(*adapters.ServiceContext) (node.Service, error) { return &testService{client}, nil },
+	}
+	network := simulations.NewNetwork(adapters.NewSimAdapter(services), &simulations.NetworkConfig{})
+	defer network.Shutdown()
+
+	var ids []*simulations.Node
+	for _, name := range []string{"server", "client"} {
+		config := adapters.RandomNodeConfig()
+		config.Services = []string{name}
+		n, err := network.NewNodeWithConfig(config)
+		if err != nil {
+			t.Fatalf("failed to create %s node: %v", name, err)
+		}
+		if err := network.Start(n.ID()); err != nil {
+			t.Fatalf("failed to start %s node: %v", name, err)
+		}
+		ids = append(ids, n)
+	}
+	if err := network.Connect(ids[1].ID(), ids[0].ID()); err != nil {
+		t.Fatalf("failed to connect nodes: %v", err)
+	}
+	for i := 0; client.peers.Len() == 0; i++ {
+		if i == 100 {
+			t.Fatal("client not connected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	client.synchronise(client.peers.BestPeer())
+
+	head := blocks[len(blocks)-1]
+	if have := client.blockchain.CurrentBlock(); have.Hash() != head.Hash() {
+		t.Fatalf("head mismatch: have %d, want %d", have.NumberU64(), head.NumberU64())
+	}
+	pivot := head.NumberU64() - 64
+	if progress := rawdb.ReadZeroSyncProgress(clientDb); progress != pivot+1 {
+		t.Fatalf("zero sync progress mismatch: have %d, want %d", progress, pivot+1)
+	}
+	// Every record up to the pivot was retrieved, the ones after it were
+	// written by the import
+	var outs int
+	for _, block := range blocks {
+		hash := block.Hash()
+		want := server.zeroBlock(hash)
+		have := client.zeroBlock(hash)
+		if want == nil || have == nil {
+			t.Fatalf("block %d: zero records missing: have %v, want %v", block.NumberU64(), have != nil, want != nil)
+		}
+		if len(have.Block.Roots) != len(want.Block.Roots) || len(have.Outs) != len(want.Outs) || len(have.Records) != len(want.Records) {
+			t.Fatalf("block %d: zero records mismatch", block.NumberU64())
+		}
+		for i := range want.Block.Roots {
+			if have.Block.Roots[i] != want.Block.Roots[i] {
+				t.Fatalf("block %d: root %d mismatch", block.NumberU64(), i)
+			}
+			if localdb.GetRoot(clientDb, &want.Block.Roots[i]) == nil {
+				t.Fatalf("block %d: out %d missing", block.NumberU64(), i)
+			}
+		}
+		outs += len(have.Outs)
+	}
+	if outs == 0 {
+		t.Fatal("no outs synced")
+	}
+}
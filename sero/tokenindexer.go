@@ -0,0 +1,224 @@
+package sero
+
+import (
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+const (
+	// tokenRegistrySectionSize is the number of blocks indexed together by the
+	// token registry indexer.
+	tokenRegistrySectionSize = 64
+
+	// tokenRegistryConfirms is the number of confirmation blocks before a section
+	// of registrations is indexed.
+	tokenRegistryConfirms = 256
+
+	// tokenRegistryThrottling is the time to wait between processing two
+	// consecutive index sections.
+	tokenRegistryThrottling = 100 * time.Millisecond
+)
+
+// TokenRegistryIndexer implements a core.ChainIndexer, collecting the currency
+// and ticket category registrations and the token rate changes of the canonical
+// chain, so they can be listed without knowing the names in advance. The events
+// of the blocks imported before they were recorded are regenerated by executing
+// these blocks again.
+type TokenRegistryIndexer struct {
+	db       serodb.Database // database instance to write index data into
+	chain    *core.BlockChain
+	replayer *blockReplayer // regenerates the events of the blocks imported before they were recorded
+
+	section       uint64                              // section being processed
+	tail          *uint64                             // first block whose events were recorded on import
+	names         map[uint8][]string                  // names of each kind, including the ones of the current section
+	registrations map[string]*types.TokenRegistration // registrations touched by the current section
+	deleted       map[string]bool                     // registrations of reorged sections to remove
+	missing       bool                                // whether the events of a block could not be regenerated
+	head          common.Hash                         // head is the hash of the last header processed
+}
+
+// NewTokenRegistryIndexer returns a chain indexer that builds the token registry
+// of the canonical chain.
+func NewTokenRegistryIndexer(db serodb.Database, chain *core.BlockChain) *core.ChainIndexer {
+	backend := &TokenRegistryIndexer{
+		db:       db,
+		chain:    chain,
+		replayer: newBlockReplayer(chain),
+	}
+	table := serodb.NewTable(db, string(rawdb.TokenRegistryIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, tokenRegistrySectionSize, tokenRegistryConfirms, tokenRegistryThrottling, "tokenregistry")
+}
+
+func registryKey(kind uint8, name string) string {
+	return string([]byte{kind}) + name
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new registry section.
+// A section indexed before is processed again after a reorg, so the
+// registrations and rates of the blocks from its start are rolled back.
+func (t *TokenRegistryIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	t.section = section
+	t.tail = rawdb.ReadRegistryEventsTail(t.db)
+	t.names = map[uint8][]string{}
+	t.registrations = map[string]*types.TokenRegistration{}
+	t.deleted = map[string]bool{}
+	t.head = common.Hash{}
+
+	if section < rawdb.ReadTokenRegistrySections(t.db) {
+		t.rollback(section * tokenRegistrySectionSize)
+	}
+	return nil
+}
+
+// rollback drops the registrations and rates of the blocks from start.
+func (t *TokenRegistryIndexer) rollback(start uint64) {
+	for _, kind := range []uint8{types.RegistryToken, types.RegistryTicket} {
+		names := []string{}
+		for _, name := range rawdb.ReadTokenRegistryNames(t.db, kind) {
+			key := registryKey(kind, name)
+			registration := rawdb.ReadTokenRegistration(t.db, kind, name)
+			if registration == nil || registration.Number >= start {
+				t.deleted[key] = true
+				continue
+			}
+			rates := []types.TokenRate{}
+			for _, rate := range registration.Rates {
+				if rate.Number < start {
+					rates = append(rates, rate)
+				}
+			}
+			if len(rates) != len(registration.Rates) {
+				registration.Rates = rates
+				t.registrations[key] = registration
+			}
+			names = append(names, name)
+		}
+		t.names[kind] = names
+	}
+	log.Info("Rolled back token registry", "number", start, "removed", len(t.deleted))
+}
+
+func (t *TokenRegistryIndexer) registration(kind uint8, name string) *types.TokenRegistration {
+	key := registryKey(kind, name)
+	if registration, ok := t.registrations[key]; ok {
+		return registration
+	}
+	if t.deleted[key] {
+		return nil
+	}
+	registration := rawdb.ReadTokenRegistration(t.db, kind, name)
+	if registration != nil {
+		t.registrations[key] = registration
+	}
+	return registration
+}
+
+func (t *TokenRegistryIndexer) addName(kind uint8, name string) {
+	if _, ok := t.names[kind]; !ok {
+		t.names[kind] = rawdb.ReadTokenRegistryNames(t.db, kind)
+	}
+	t.names[kind] = append(t.names[kind], name)
+}
+
+// events returns the registry events of a block, regenerating them if the block
+// was imported before they were recorded.
+func (t *TokenRegistryIndexer) events(header *types.Header) []*types.RegistryEvent {
+	number := header.Number.Uint64()
+	events := rawdb.ReadRegistryEvents(t.db, t.head, number)
+	if events != nil || t.tail == nil || number >= *t.tail || t.replayer == nil {
+		return events
+	}
+	block := t.chain.GetBlock(t.head, number)
+	if block == nil {
+		return nil
+	}
+	err := t.replayer.replay(block, func(statedb *state.StateDB) {
+		events = statedb.RegistryEvents()
+	})
+	if err != nil {
+		if !t.missing {
+			log.Warn("Token registry misses the registrations of blocks without state", "number", number, "err", err)
+		}
+		t.missing = true
+		return nil
+	}
+	if len(events) > 0 {
+		rawdb.WriteRegistryEvents(t.db, t.head, number, events)
+	}
+	return events
+}
+
+// Process implements core.ChainIndexerBackend, adding the registry events of a
+// new header into the index.
+func (t *TokenRegistryIndexer) Process(header *types.Header) {
+	number := header.Number.Uint64()
+	t.head = header.Hash()
+
+	for _, event := range t.events(header) {
+		switch event.Kind {
+		case types.RegistryToken, types.RegistryTicket:
+			if t.registration(event.Kind, event.Name) != nil {
+				continue
+			}
+			key := registryKey(event.Kind, event.Name)
+			delete(t.deleted, key)
+			t.registrations[key] = &types.TokenRegistration{
+				Kind:     event.Kind,
+				Name:     event.Name,
+				Contract: event.Contract,
+				Number:   number,
+				TxHash:   event.TxHash,
+			}
+			t.addName(event.Kind, event.Name)
+		case types.RegistryTokenRate:
+			registration := t.registration(types.RegistryToken, event.Name)
+			if registration == nil {
+				log.Warn("Token rate of an unindexed currency", "name", event.Name, "number", number)
+				continue
+			}
+			processed := false
+			for _, rate := range registration.Rates {
+				if rate.Number == number && rate.TxHash == event.TxHash && rate.Contract == event.Contract {
+					processed = true
+					break
+				}
+			}
+			if !processed {
+				registration.Rates = append(registration.Rates, types.TokenRate{
+					Contract: event.Contract,
+					Number:   number,
+					TxHash:   event.TxHash,
+					Tokens:   event.Tokens,
+					Tas:      event.Tas,
+				})
+			}
+		}
+	}
+}
+
+// Commit implements core.ChainIndexerBackend, writing the registrations touched
+// by the section into the database.
+func (t *TokenRegistryIndexer) Commit() error {
+	batch := t.db.NewBatch()
+
+	for key := range t.deleted {
+		rawdb.DeleteTokenRegistration(batch, key[0], key[1:])
+	}
+	for _, registration := range t.registrations {
+		rawdb.WriteTokenRegistration(batch, registration)
+	}
+	for kind, names := range t.names {
+		rawdb.WriteTokenRegistryNames(batch, kind, names)
+	}
+	rawdb.WriteTokenRegistrySections(batch, t.section+1)
+	return batch.Write()
+}
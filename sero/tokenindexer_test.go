@@ -0,0 +1,156 @@
+package sero
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// processSection runs a section of headers through the registry indexer.
+func processSection(t *testing.T, indexer *TokenRegistryIndexer, section uint64, headers []*types.Header) {
+	if err := indexer.Reset(section, common.Hash{}); err != nil {
+		t.Fatalf("section %d: reset failed: %v", section, err)
+	}
+	for _, header := range headers {
+		indexer.Process(header)
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("section %d: commit failed: %v", section, err)
+	}
+}
+
+// sectionHeaders creates the headers of a section, writing the given registry
+// events of some of them.
+func sectionHeaders(db serodb.Database, section uint64, extra byte, events map[uint64][]*types.RegistryEvent) []*types.Header {
+	headers := []*types.Header{}
+	for i := uint64(0); i < tokenRegistrySectionSize; i++ {
+		number := section*tokenRegistrySectionSize + i
+		header := &types.Header{Number: new(big.Int).SetUint64(number), Extra: []byte{extra}}
+		if list, ok := events[number]; ok {
+			rawdb.WriteRegistryEvents(db, header.Hash(), number, list)
+		}
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+func TestTokenRegistryReorg(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	rawdb.WriteRegistryEventsTail(db, 0)
+	indexer := &TokenRegistryIndexer{db: db}
+
+	processSection(t, indexer, 0, sectionHeaders(db, 0, 0, map[uint64][]*types.RegistryEvent{
+		10: {{Kind: types.RegistryToken, Name: "AAA", Contract: common.Address{1}}},
+		20: {{Kind: types.RegistryTicket, Name: "TKT", Contract: common.Address{2}}},
+	}))
+	processSection(t, indexer, 1, sectionHeaders(db, 1, 0, map[uint64][]*types.RegistryEvent{
+		70: {{Kind: types.RegistryToken, Name: "BBB", Contract: common.Address{3}}},
+		80: {{Kind: types.RegistryTokenRate, Name: "AAA", Contract: common.Address{1}, Tokens: big.NewInt(1), Tas: big.NewInt(2)}},
+	}))
+	if names := rawdb.ReadTokenRegistryNames(db, types.RegistryToken); len(names) != 2 || names[1] != "BBB" {
+		t.Fatalf("token names mismatch: %v", names)
+	}
+	if registration := rawdb.ReadTokenRegistration(db, types.RegistryToken, "AAA"); len(registration.Rates) != 1 {
+		t.Fatalf("rate not indexed: %+v", registration)
+	}
+
+	// Reorg the second section: BBB and the rate of AAA are replaced by CCC
+	processSection(t, indexer, 1, sectionHeaders(db, 1, 1, map[uint64][]*types.RegistryEvent{
+		75: {{Kind: types.RegistryToken, Name: "CCC", Contract: common.Address{4}}},
+	}))
+	if names := rawdb.ReadTokenRegistryNames(db, types.RegistryToken); len(names) != 2 || names[0] != "AAA" || names[1] != "CCC" {
+		t.Fatalf("token names mismatch after reorg: %v", names)
+	}
+	if registration := rawdb.ReadTokenRegistration(db, types.RegistryToken, "BBB"); registration != nil {
+		t.Fatalf("reorged registration kept: %+v", registration)
+	}
+	if registration := rawdb.ReadTokenRegistration(db, types.RegistryToken, "AAA"); registration == nil || len(registration.Rates) != 0 {
+		t.Fatalf("reorged rate kept: %+v", registration)
+	}
+	if registration := rawdb.ReadTokenRegistration(db, types.RegistryToken, "CCC"); registration == nil || registration.Number != 75 {
+		t.Fatalf("registration of the new chain mismatch: %+v", registration)
+	}
+	if names := rawdb.ReadTokenRegistryNames(db, types.RegistryTicket); len(names) != 1 || names[0] != "TKT" {
+		t.Fatalf("ticket names mismatch after reorg: %v", names)
+	}
+	if sections := rawdb.ReadTokenRegistrySections(db); sections != 2 {
+		t.Fatalf("committed sections mismatch: have %d, want 2", sections)
+	}
+}
+
+func newReplayChain(t *testing.T, cacheConfig *core.CacheConfig, n int) (*core.BlockChain, []*types.Block) {
+	cpt.ZeroInit(cpt.NET_Alpha)
+
+	db := serodb.NewMemDatabase()
+	gspec := &core.Genesis{Config: params.TestChainConfig}
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, cacheConfig, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	blocks, err := core.GeneratePrivateChain(chain, n, nil)
+	if err != nil {
+		t.Fatalf("failed to generate chain: %v", err)
+	}
+	return chain, blocks
+}
+
+// Tests that the blocks imported before the registry events were recorded are
+// executed again by the indexer.
+func TestTokenRegistryReplay(t *testing.T) {
+	chain, blocks := newReplayChain(t, &core.CacheConfig{Disabled: true}, 4)
+	defer chain.Stop()
+
+	// Pretend the blocks were imported before the events were recorded
+	db := chain.GetDB()
+	rawdb.WriteRegistryEventsTail(db, uint64(len(blocks))+1)
+	indexer := &TokenRegistryIndexer{db: db, chain: chain, replayer: newBlockReplayer(chain)}
+
+	if err := indexer.Reset(0, common.Hash{}); err != nil {
+		t.Fatal(err)
+	}
+	indexer.Process(chain.Genesis().Header())
+	for _, block := range blocks {
+		indexer.Process(block.Header())
+		if indexer.replayer.hash != block.Hash() {
+			t.Fatalf("block %d not replayed", block.NumberU64())
+		}
+	}
+	if indexer.missing {
+		t.Fatal("replay failed with the states available")
+	}
+}
+
+// Tests that a block whose parent state is not on disk is replayed from the
+// last state available.
+func TestBlockReplayerReexec(t *testing.T) {
+	chain, blocks := newReplayChain(t, nil, 4)
+	defer chain.Stop()
+
+	replayer := newBlockReplayer(chain)
+	replayed := false
+	if err := replayer.replay(blocks[3], func(*state.StateDB) { replayed = true }); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if !replayed || replayer.hash != blocks[3].Hash() {
+		t.Fatalf("block not replayed")
+	}
+	// The next block is replayed on the state left by the previous one
+	more, err := core.GeneratePrivateChain(chain, 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := replayer.replay(more[0], func(*state.StateDB) {}); err != nil {
+		t.Fatalf("replay of the next block failed: %v", err)
+	}
+}
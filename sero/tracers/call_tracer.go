@@ -0,0 +1,260 @@
+// Copyright 2018 The go-sero Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+)
+
+// callFrame is a call reported by the call tracer. The fields are in the order
+// of the JSON of the JavaScript call tracer.
+type callFrame struct {
+	Type    string       `json:"type,omitempty"`
+	From    string       `json:"from,omitempty"`
+	To      string       `json:"to,omitempty"`
+	Value   string       `json:"value,omitempty"`
+	Gas     string       `json:"gas,omitempty"`
+	GasUsed string       `json:"gasUsed,omitempty"`
+	Input   string       `json:"input,omitempty"`
+	Output  string       `json:"output,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Time    string       `json:"time,omitempty"`
+	Calls   []*callFrame `json:"calls,omitempty"`
+
+	gasIn   uint64
+	gasCost uint64
+	gas     *uint64 // Gas given to the call, known once it starts executing
+	outOff  *big.Int
+	outLen  *big.Int
+}
+
+// callTracer is the native version of call_tracer.js, it reports all the
+// internal calls made by a transaction.
+type callTracer struct {
+	callstack []*callFrame
+	descended bool
+
+	typ     string
+	from    common.Address
+	to      common.Address
+	value   *big.Int
+	input   []byte
+	gas     uint64
+	gasUsed uint64
+	output  []byte
+	time    time.Duration
+	err     error
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+func newCallTracer() TxTracer {
+	return &callTracer{callstack: []*callFrame{{}}}
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *callTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, asset *assets.Asset) error {
+	t.typ = "CALL"
+	if create {
+		t.typ = "CREATE"
+	}
+	t.from, t.to = from, to
+	t.input = common.CopyBytes(input)
+	t.gas = gas
+	_, t.value = assetValue(asset)
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		env.Cancel()
+		return nil
+	}
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		call := &callFrame{
+			Type:    op.String(),
+			From:    shortAddress(contract.Address()),
+			Input:   hexutil.Encode(memorySlice(memory, stack.Back(1), stack.Back(2))),
+			Value:   hexutil.EncodeBig(stack.Back(0)),
+			gasIn:   gas,
+			gasCost: cost,
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// There are no pre-compiled contracts to skip in SERO
+		to := callAddress(env, contract, stack)
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		call := &callFrame{
+			Type:    op.String(),
+			From:    shortAddress(contract.Address()),
+			To:      shortAddress(to),
+			Input:   hexutil.Encode(memorySlice(memory, stack.Back(2+off), stack.Back(3+off))),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  new(big.Int).Set(stack.Back(4 + off)),
+			outLen:  new(big.Int).Set(stack.Back(5 + off)),
+		}
+		if off == 1 {
+			call.Value = hexutil.EncodeBig(stack.Back(2))
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance. The
+	// gas given to calls of plain accounts is unknown and left out.
+	if t.descended {
+		if depth >= len(t.callstack) {
+			allowance := gas
+			t.callstack[len(t.callstack)-1].gas = &allowance
+		}
+		t.descended = false
+	}
+	// If an existing call is returning, pop off the call stack
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+		return nil
+	}
+	if depth == len(t.callstack)-1 {
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := stack.Back(0)
+		if call.Type == vm.CREATE.String() || call.Type == vm.CREATE2.String() {
+			call.GasUsed = hexutil.EncodeUint64(call.gasIn - call.gasCost - gas)
+			if ret.Sign() != 0 {
+				addr := contract.GetNonceAddress(env.StateDB, common.BigToContractAddress(ret))
+				call.To = shortAddress(addr)
+				call.Output = hexutil.Encode(env.StateDB.GetCode(addr))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else if call.gas != nil {
+			call.GasUsed = hexutil.EncodeUint64(call.gasIn - call.gasCost + *call.gas - gas)
+			if ret.Sign() != 0 {
+				call.Output = hexutil.Encode(memorySlice(memory, call.outOff, call.outLen))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		if call.gas != nil {
+			call.Gas = hexutil.EncodeUint64(*call.gas)
+		}
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	t.fault(err)
+	return nil
+}
+
+// fault flattens the failed call into its parent.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	call.Error = err.Error()
+
+	// Consume all available gas and clean any leftovers
+	if call.gas != nil {
+		call.Gas = hexutil.EncodeUint64(*call.gas)
+		call.GasUsed = call.Gas
+	}
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	// Last call failed too, leave it in the stack
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	t.output = common.CopyBytes(output)
+	t.gasUsed = gasUsed
+	t.time = d
+	t.err = err
+	return nil
+}
+
+// GetResult returns the top level call with its internal calls.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	if t.reason != nil && atomic.LoadUint32(&t.interrupt) > 0 {
+		return nil, t.reason
+	}
+	value := t.value
+	if value == nil {
+		value = new(big.Int)
+	}
+	result := &callFrame{
+		Type:    t.typ,
+		From:    shortAddress(t.from),
+		To:      shortAddress(t.to),
+		Value:   hexutil.EncodeBig(value),
+		Gas:     hexutil.EncodeUint64(t.gas),
+		GasUsed: hexutil.EncodeUint64(t.gasUsed),
+		Input:   hexutil.Encode(t.input),
+		Output:  hexutil.Encode(t.output),
+		Time:    t.time.String(),
+		Calls:   t.callstack[0].Calls,
+	}
+	if t.callstack[0].Error != "" {
+		result.Error = t.callstack[0].Error
+	} else if t.err != nil {
+		result.Error = t.err.Error()
+	}
+	if result.Error != "" {
+		result.Output = ""
+	}
+	return json.Marshal(result)
+}
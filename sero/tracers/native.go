@@ -0,0 +1,91 @@
+// Copyright 2018 The go-sero Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+)
+
+// TxTracer is a transaction tracer returning its result as JSON, either one of
+// the native tracers or a JavaScript one.
+type TxTracer interface {
+	vm.Tracer
+
+	// GetResult returns the result of the trace.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates the trace at the first opportune moment.
+	Stop(err error)
+}
+
+// natives contains the tracers implemented in Go by name, they are used in
+// place of the JavaScript tracers of the same name.
+var natives = map[string]func() TxTracer{
+	"callTracer":     newCallTracer,
+	"prestateTracer": newPrestateTracer,
+}
+
+// NewTxTracer returns the native tracer of the given name if there is one, or
+// the JavaScript tracer of the given name or code otherwise.
+func NewTxTracer(code string) (TxTracer, error) {
+	if ctor, ok := natives[code]; ok {
+		return ctor(), nil
+	}
+	return New(code)
+}
+
+// assetValue returns the currency and the value of the token of an asset.
+func assetValue(asset *assets.Asset) (currency string, value *big.Int) {
+	value = new(big.Int)
+	if asset != nil && asset.Tkn != nil {
+		currency = strings.Trim(string(asset.Tkn.Currency[:]), string([]byte{0}))
+		value.Set(asset.Tkn.Value.ToIntRef())
+	}
+	return
+}
+
+// shortAddress encodes an address in its short form, the way the JavaScript
+// tracers report it.
+func shortAddress(addr common.Address) string {
+	return hexutil.Encode(addr[:len(common.ContractAddress{})])
+}
+
+// callAddress resolves the address called by a CALL like opcode from its short
+// form on the stack.
+func callAddress(env *vm.EVM, contract *vm.Contract, stack *vm.Stack) common.Address {
+	return contract.GetNonceAddress(env.StateDB, common.BigToContractAddress(stack.Back(1)))
+}
+
+// memorySlice returns a copy of a slice of the memory, or an empty slice if it
+// is out of bounds like the JavaScript tracers do.
+func memorySlice(memory *vm.Memory, offset, size *big.Int) []byte {
+	if !offset.IsUint64() || !size.IsUint64() {
+		return []byte{}
+	}
+	start, end := offset.Uint64(), offset.Uint64()+size.Uint64()
+	if end < start || end > uint64(memory.Len()) {
+		return []byte{}
+	}
+	return common.CopyBytes(memory.Data()[start:end])
+}
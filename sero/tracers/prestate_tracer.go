@@ -0,0 +1,175 @@
+// Copyright 2018 The go-sero Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+)
+
+// prestateAccount is the state of an account before the transaction, with
+// its balance of every currency.
+type prestateAccount struct {
+	Balance map[string]*hexutil.Big     `json:"balance"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+
+	loaded map[common.Hash]bool // Storage keys looked up, zero ones included
+}
+
+// prestateTracer is the native version of prestate_tracer.js, it outputs the
+// accounts accessed by a transaction as they were before it.
+type prestateTracer struct {
+	prestate map[common.Address]*prestateAccount
+	db       vm.StateDB
+
+	create bool
+	to     common.Address
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+func newPrestateTracer() TxTracer {
+	return &prestateTracer{prestate: make(map[common.Address]*prestateAccount)}
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *prestateTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// lookupAccount injects the specified account into the prestate.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.prestate[addr]; ok {
+		return
+	}
+	account := &prestateAccount{
+		Balance: make(map[string]*hexutil.Big),
+		Code:    common.CopyBytes(t.db.GetCode(addr)),
+		Storage: make(map[common.Hash]common.Hash),
+		loaded:  make(map[common.Hash]bool),
+	}
+	for currency, balance := range t.db.Balances(addr) {
+		account.Balance[currency] = (*hexutil.Big)(new(big.Int).Set(balance))
+	}
+	t.prestate[addr] = account
+}
+
+// lookupStorage injects the specified storage entry of the given account into
+// the prestate.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	account := t.prestate[addr]
+	if account == nil {
+		t.lookupAccount(addr)
+		account = t.prestate[addr]
+	}
+	if account.loaded[key] {
+		return
+	}
+	account.loaded[key] = true
+	if value := t.db.GetState(addr, key); value != (common.Hash{}) {
+		account.Storage[key] = value
+	}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+// The value of the transaction is already transferred, it is moved back to the
+// sender so that the accounts are reported as they were before.
+func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, asset *assets.Asset) error {
+	t.db = env.StateDB
+	t.create, t.to = create, to
+	t.lookupAccount(to)
+
+	currency, value := assetValue(asset)
+	if value.Sign() > 0 {
+		moveBalance(t.prestate[to], currency, new(big.Int).Neg(value))
+		if t.db.IsContract(from) {
+			t.lookupAccount(from)
+			moveBalance(t.prestate[from], currency, value)
+		}
+	}
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		env.Cancel()
+		return nil
+	}
+	// Whenever new state is accessed, add it to the prestate
+	switch op {
+	case vm.EXTCODESIZE:
+		t.lookupAccount(contract.GetNonceAddress(env.StateDB, common.BigToContractAddress(stack.Back(0))))
+	case vm.EXTCODECOPY:
+		t.lookupAccount(common.BigToAddress(stack.Back(0)))
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(callAddress(env, contract, stack))
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.BigToHash(stack.Back(0)))
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the accounts accessed by the transaction.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.reason != nil && atomic.LoadUint32(&t.interrupt) > 0 {
+		return nil, t.reason
+	}
+	// A created contract has no prior state
+	if t.create {
+		delete(t.prestate, t.to)
+	}
+	result := make(map[string]*prestateAccount, len(t.prestate))
+	for addr, account := range t.prestate {
+		result[shortAddress(addr)] = account
+	}
+	return json.Marshal(result)
+}
+
+func moveBalance(account *prestateAccount, currency string, delta *big.Int) {
+	balance := new(big.Int)
+	if current, ok := account.Balance[currency]; ok {
+		balance.Set(current.ToInt())
+	}
+	balance.Add(balance, delta)
+	if balance.Sign() == 0 {
+		delete(account.Balance, currency)
+		return
+	}
+	account.Balance[currency] = (*hexutil.Big)(balance)
+}
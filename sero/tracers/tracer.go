@@ -366,11 +366,12 @@ func New(code string) (*Tracer, error) {
 		//copy(makeSlice(ctx.PushFixedBuffer(20), 20), contract[:])
 		return 1
 	})
-	//tracer.vm.PushGlobalGoFunction("isPrecompiled", func(ctx *duktape.Context) int {
-	//	_, ok := vm.PrecompiledContractsAutumnTwilight[common.BytesToAddress(popSlice(ctx))]
-	//	ctx.PushBoolean(ok)
-	//	return 1
-	//})
+	tracer.vm.PushGlobalGoFunction("isPrecompiled", func(ctx *duktape.Context) int {
+		// There are no pre-compiled contracts in SERO
+		ctx.Pop()
+		ctx.PushBoolean(false)
+		return 1
+	})
 	tracer.vm.PushGlobalGoFunction("slice", func(ctx *duktape.Context) int {
 		start, end := ctx.GetInt(-2), ctx.GetInt(-1)
 		ctx.Pop2()
@@ -491,7 +492,7 @@ func wrapError(context string, err error) error {
 }
 
 // CaptureStart implements the Tracer interface to initialize the tracing operation.
-func (jst *Tracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, asset *assets.Asset) error {
+func (jst *Tracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, asset *assets.Asset) error {
 	jst.ctx["type"] = "CALL"
 	if create {
 		jst.ctx["type"] = "CREATE"
@@ -500,9 +501,7 @@ func (jst *Tracer) CaptureStart(from common.Address, to common.Address, create b
 	jst.ctx["to"] = to
 	jst.ctx["input"] = input
 	jst.ctx["gas"] = gas
-	if asset.Tkt != nil {
-		jst.ctx["value"] = asset.Tkt.Value
-	}
+	_, jst.ctx["value"] = assetValue(asset)
 	return nil
 }
 
@@ -0,0 +1,236 @@
+// Copyright 2018 The go-sero Authors
+// This file is part of the go-sero library.
+//
+// The go-sero library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-sero library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-sero library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/common/math"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// callTracerTest defines a single test to check the call tracer against.
+type callTracerTest struct {
+	Genesis struct {
+		Alloc map[string]struct {
+			Balance *math.HexOrDecimal256       `json:"balance"`
+			Code    hexutil.Bytes               `json:"code"`
+			Storage map[common.Hash]common.Hash `json:"storage"`
+		} `json:"alloc"`
+	} `json:"genesis"`
+	Context struct {
+		Number     math.HexOrDecimal64   `json:"number"`
+		Difficulty *math.HexOrDecimal256 `json:"difficulty"`
+		Time       math.HexOrDecimal64   `json:"timestamp"`
+		GasLimit   math.HexOrDecimal64   `json:"gasLimit"`
+	} `json:"context"`
+	Input  hexutil.Bytes `json:"input"`
+	Result *callTrace    `json:"result"`
+}
+
+// callTrace is the result of the call tracer, without the time it took.
+type callTrace struct {
+	Type    string      `json:"type"`
+	From    string      `json:"from"`
+	To      string      `json:"to,omitempty"`
+	Input   string      `json:"input,omitempty"`
+	Output  string      `json:"output,omitempty"`
+	Gas     string      `json:"gas,omitempty"`
+	GasUsed string      `json:"gasUsed,omitempty"`
+	Value   string      `json:"value,omitempty"`
+	Error   string      `json:"error,omitempty"`
+	Calls   []callTrace `json:"calls,omitempty"`
+}
+
+// fixtureTx is the Ethereum transaction of a test fixture.
+type fixtureTx struct {
+	Nonce    uint64
+	GasPrice *big.Int
+	Gas      uint64
+	To       []byte
+	Value    *big.Int
+	Data     []byte
+	V, R, S  *big.Int
+}
+
+// fixtureAddress maps an address of a fixture to a SERO address.
+func fixtureAddress(hex string) common.Address {
+	return common.BytesToAddress(common.FromHex(hex))
+}
+
+// runFixture replays the transaction of a fixture with the given tracer.
+func runFixture(t *testing.T, test *callTracerTest, tracer TxTracer) json.RawMessage {
+	statedb, _ := state.New(state.NewDatabase(serodb.NewMemDatabase()), nil)
+	for hex, account := range test.Genesis.Alloc {
+		// Only the contracts have a state in SERO
+		if len(account.Code) == 0 {
+			continue
+		}
+		addr := fixtureAddress(hex)
+		statedb.CreateAccount(addr)
+		statedb.SetCode(addr, account.Code)
+		if account.Balance != nil {
+			statedb.AddBalance(addr, "SERO", (*big.Int)(account.Balance))
+		}
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+	for hex := range test.Genesis.Alloc {
+		statedb.AddNonceAddress(common.FromHex(hex), fixtureAddress(hex))
+	}
+
+	var tx fixtureTx
+	if err := rlp.DecodeBytes(test.Input, &tx); err != nil {
+		t.Fatalf("failed to decode the transaction: %v", err)
+	}
+	// The input of the transactions of SERO starts with the table of the
+	// addresses it uses, none here.
+	data := append(make([]byte, 18), tx.Data...)
+	from := fixtureAddress(test.Result.From)
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		Origin:      from,
+		GasPrice:    tx.GasPrice,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+	}
+	env := vm.NewEVM(context, statedb, params.AllEthashProtocolChanges, vm.Config{Debug: true, Tracer: tracer})
+
+	asset := &assets.Asset{Tkn: &assets.Token{
+		Currency: utils.CurrencyToUint256("SERO"),
+		Value:    utils.U256(*tx.Value),
+	}}
+	if len(tx.To) == 0 {
+		env.Create(vm.AccountRef(from), data, tx.Gas, asset)
+	} else {
+		env.Call(vm.AccountRef(from), fixtureAddress(hexutil.Encode(tx.To)), data, tx.Gas, asset)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+// Tests that the native call tracer reports the expected calls of the
+// transactions of the test fixtures.
+func TestNativeCallTracer(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(strings.TrimSuffix(file.Name(), ".json"), func(t *testing.T) {
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			res := runFixture(t, test, newCallTracer())
+			have := new(callTrace)
+			if err := json.Unmarshal(res, have); err != nil {
+				t.Fatalf("failed to unmarshal trace result: %v", err)
+			}
+			if !reflect.DeepEqual(have, test.Result) {
+				want, _ := json.Marshal(test.Result)
+				t.Fatalf("trace mismatch:\nhave %s\nwant %s", res, want)
+			}
+		})
+	}
+}
+
+// Tests that the prestate tracer reports the balance of the called contract
+// before the value of the transaction is transferred to it.
+func TestPrestateTracerValue(t *testing.T) {
+	statedb, _ := state.New(state.NewDatabase(serodb.NewMemDatabase()), nil)
+	from, to := fixtureAddress("0x01"), fixtureAddress("0x02")
+	statedb.CreateAccount(to)
+	statedb.SetCode(to, []byte{byte(vm.STOP)})
+	statedb.AddBalance(to, "SERO", big.NewInt(100))
+
+	tracer := newPrestateTracer()
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		Origin:      from,
+		GasPrice:    big.NewInt(1),
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(1),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    100000,
+	}
+	env := vm.NewEVM(context, statedb, params.AllEthashProtocolChanges, vm.Config{Debug: true, Tracer: tracer})
+	asset := &assets.Asset{Tkn: &assets.Token{
+		Currency: utils.CurrencyToUint256("SERO"),
+		Value:    utils.U256(*big.NewInt(10)),
+	}}
+	if _, _, err, _ := env.Call(vm.AccountRef(from), to, make([]byte, 18), 100000, asset); err != nil {
+		t.Fatalf("failed to call: %v", err)
+	}
+	if balance := statedb.GetBalance(to, "SERO"); balance.Cmp(big.NewInt(110)) != 0 {
+		t.Fatalf("value not transferred: have %v", balance)
+	}
+
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var prestate map[string]struct {
+		Balance map[string]*hexutil.Big `json:"balance"`
+	}
+	if err := json.Unmarshal(res, &prestate); err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+	account, ok := prestate[shortAddress(to)]
+	if !ok {
+		t.Fatalf("called contract missing from the prestate: %s", res)
+	}
+	if balance := account.Balance["SERO"]; balance == nil || balance.ToInt().Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("prestate balance mismatch: have %v, want 100", balance)
+	}
+	if _, ok := prestate[shortAddress(from)]; ok {
+		t.Fatalf("sender without state in the prestate: %s", res)
+	}
+}
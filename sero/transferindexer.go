@@ -0,0 +1,111 @@
+package sero
+
+import (
+	"time"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/state"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+const (
+	// tokenTransferSectionSize is the number of blocks indexed together by the
+	// token transfer indexer.
+	tokenTransferSectionSize = 64
+
+	// tokenTransferConfirms is the number of confirmation blocks before a section
+	// of transfers is indexed.
+	tokenTransferConfirms = 256
+
+	// tokenTransferThrottling is the time to wait between processing two
+	// consecutive index sections.
+	tokenTransferThrottling = 100 * time.Millisecond
+)
+
+// TokenTransferIndexer implements a core.ChainIndexer, indexing the currency
+// movements on the balances of contracts by contract and currency: issues,
+// sends, receipts of call assets and closed packages. Sends to private
+// addresses and call assets raise no log, so they can't be followed otherwise.
+// The transfers of the blocks imported before they were recorded are
+// regenerated by executing these blocks again.
+type TokenTransferIndexer struct {
+	db       serodb.Database // database instance to write index data into
+	chain    *core.BlockChain
+	replayer *blockReplayer // regenerates the transfers of the blocks imported before they were recorded
+
+	tail    *uint64      // first block whose transfers were recorded on import
+	batch   serodb.Batch // batch of the transfers of the current section
+	missing bool         // whether the transfers of a block could not be regenerated
+	head    common.Hash  // head is the hash of the last header processed
+}
+
+// NewTokenTransferIndexer returns a chain indexer that indexes the token
+// transfers done by the contracts of the canonical chain.
+func NewTokenTransferIndexer(db serodb.Database, chain *core.BlockChain) *core.ChainIndexer {
+	backend := &TokenTransferIndexer{
+		db:       db,
+		chain:    chain,
+		replayer: newBlockReplayer(chain),
+	}
+	table := serodb.NewTable(db, string(rawdb.TokenTransferIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, tokenTransferSectionSize, tokenTransferConfirms, tokenTransferThrottling, "tokentransfer")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new transfer section.
+func (t *TokenTransferIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	t.tail = rawdb.ReadTokenTransfersTail(t.db)
+	t.batch = t.db.NewBatch()
+	t.head = common.Hash{}
+	return nil
+}
+
+// transfers returns the token transfers of a block, regenerating them if the
+// block was imported before they were recorded.
+func (t *TokenTransferIndexer) transfers(header *types.Header) []*types.TokenTransfer {
+	number := header.Number.Uint64()
+	transfers := rawdb.ReadBlockTokenTransfers(t.db, t.head, number)
+	if transfers != nil || t.tail == nil || number >= *t.tail || t.replayer == nil {
+		return transfers
+	}
+	block := t.chain.GetBlock(t.head, number)
+	if block == nil {
+		return nil
+	}
+	err := t.replayer.replay(block, func(statedb *state.StateDB) {
+		transfers = statedb.TokenTransfers()
+	})
+	if err != nil {
+		if !t.missing {
+			log.Warn("Token transfer index misses the transfers of blocks without state", "number", number, "err", err)
+		}
+		t.missing = true
+		return nil
+	}
+	if len(transfers) > 0 {
+		rawdb.WriteBlockTokenTransfers(t.db, t.head, number, transfers)
+	}
+	return transfers
+}
+
+// Process implements core.ChainIndexerBackend, adding the token transfers of a
+// new header into the index, keyed by their position in the block.
+func (t *TokenTransferIndexer) Process(header *types.Header) {
+	number := header.Number.Uint64()
+	t.head = header.Hash()
+
+	for i, transfer := range t.transfers(header) {
+		transfer.Number = number
+		rawdb.WriteTokenTransfer(t.batch, transfer, uint32(i))
+	}
+}
+
+// Commit implements core.ChainIndexerBackend, writing the transfers of the
+// section into the database.
+func (t *TokenTransferIndexer) Commit() error {
+	return t.batch.Write()
+}
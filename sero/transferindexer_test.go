@@ -0,0 +1,260 @@
+package sero
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/pkg"
+	"github.com/sero-cash/go-sero/zero/utils"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+)
+
+func TestTokenTransferIndexer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transfers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := serodb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rawdb.WriteTokenTransfersTail(db, 0)
+	indexer := &TokenTransferIndexer{db: db}
+
+	contract := common.Address{1}
+	header := &types.Header{Number: big.NewInt(5)}
+	rawdb.WriteBlockTokenTransfers(db, header.Hash(), 5, []*types.TokenTransfer{
+		{Kind: types.TransferReceive, Contract: contract, Currency: "ABC", Amount: big.NewInt(1)},
+		{Kind: types.TransferSend, Contract: contract, Currency: "ABC", Amount: big.NewInt(2)},
+	})
+	if err := indexer.Reset(0, common.Hash{}); err != nil {
+		t.Fatal(err)
+	}
+	indexer.Process(&types.Header{Number: big.NewInt(4)})
+	indexer.Process(header)
+	if err := indexer.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	transfers := rawdb.ReadTokenTransfers(db, contract, "ABC", 0, 10)
+	if len(transfers) != 2 || transfers[0].Kind != types.TransferReceive || transfers[1].Number != 5 {
+		t.Fatalf("indexed transfers mismatch: %+v", transfers)
+	}
+}
+
+// Tests that the blocks imported before the token transfers were recorded are
+// executed again by the indexer, so older blocks are indexed as well.
+func TestTokenTransferReplay(t *testing.T) {
+	chain, blocks := newReplayChain(t, &core.CacheConfig{Disabled: true}, 4)
+	defer chain.Stop()
+
+	// Pretend the blocks were imported before the transfers were recorded
+	db := chain.GetDB()
+	rawdb.WriteTokenTransfersTail(db, uint64(len(blocks))+1)
+	indexer := &TokenTransferIndexer{db: db, chain: chain, replayer: newBlockReplayer(chain)}
+
+	if err := indexer.Reset(0, common.Hash{}); err != nil {
+		t.Fatal(err)
+	}
+	indexer.Process(chain.Genesis().Header())
+	for _, block := range blocks {
+		indexer.Process(block.Header())
+		if indexer.replayer.hash != block.Hash() {
+			t.Fatalf("block %d not replayed", block.NumberU64())
+		}
+	}
+	if indexer.missing {
+		t.Fatal("replay failed with the states available")
+	}
+}
+
+// transferTestCode is the creation code of a contract moving tokens on its
+// balance, by the first word of the call data:
+//
+//	1, total:   issues total TKN
+//	2, amount:  sends amount TKN to the caller
+//	3, amount:  sends amount TKN to the caller, then reverts
+//	4, id, key: closes the package id into the contract
+var transferTestCode = common.FromHex(
+	"6101b161000f6000396101b16000f36000358060011461002457806002146100" +
+		"8a57806003146100fe578060041461016b57005b604060005260203560205260" +
+		"036040527f544b4e000000000000000000000000000000000000000000000000" +
+		"00000000006060527f3be6bf24d822bcd6f6348f6f5a5c2d3108f04991ee63e8" +
+		"0cde49a8c4746a0ef360406000a16020516101af57600080fd5b3360005260a0" +
+		"60205260203560405260e0606052600360a0527f544b4e000000000000000000" +
+		"000000000000000000000000000000000000000060c052600060e0527f868bd6" +
+		"629e7c2e3d2ccf7b9968fad79b448e7a2bfb3ee20ed1acbc695c3c8b2360a060" +
+		"00a16080516101af57600080fd5b3360005260a060205260203560405260e060" +
+		"6052600360a0527f544b4e000000000000000000000000000000000000000000" +
+		"000000000000000060c052600060e0527f868bd6629e7c2e3d2ccf7b9968fad7" +
+		"9b448e7a2bfb3ee20ed1acbc695c3c8b2360a06000a1600080fd5b6020356000" +
+		"52604035602052600060e0527fbbf1aa2159b035802d0a4d44611849d5d4ada0" +
+		"329c81580477d5ec3e82f4f0a66101006000a16000516101af57600080fd5b00")
+
+// Tests that the token transfers of a contract run in a generated chain are
+// indexed with their kind, amount and peer, and that the transfers of a
+// reverted call are dropped.
+func TestTokenTransfersOfContract(t *testing.T) {
+	cpt.ZeroInit(cpt.NET_Alpha)
+	seroparam.Init_Dev(true)
+	zconfig.Init_SkipProof(true)
+	defer seroparam.Init_Dev(false)
+	defer zconfig.Init_SkipProof(false)
+
+	dir, err := ioutil.TempDir("", "transfers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := serodb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sero := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	amount := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), sero) }
+	asset := func(n int64) assets.Asset {
+		return assets.Asset{Tkn: &assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.U256(*amount(n))}}
+	}
+	call := func(op int64, args ...[]byte) []byte {
+		data := common.LeftPadBytes(big.NewInt(op).Bytes(), 32)
+		for _, arg := range args {
+			data = append(data, common.LeftPadBytes(arg, 32)...)
+		}
+		return data
+	}
+	var (
+		alice = core.NewTestAccount("alice")
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{alice.Address(): {Balance: amount(1000000)}},
+		}
+		pkgId = c_type.Uint256{1}
+	)
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFullFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	receipt := func(block *types.Block, tx *types.Transaction) *types.Receipt {
+		for _, r := range chain.GetReceiptsByHash(block.Hash()) {
+			if r.TxHash == tx.Hash() {
+				return r
+			}
+		}
+		t.Fatalf("block %d: missing receipt of %x", block.NumberU64(), tx.Hash())
+		return nil
+	}
+	var (
+		contract common.Address
+		sender   common.Address
+		txs      = make([]*types.Transaction, 6)
+	)
+	blocks, err := core.GeneratePrivateChain(chain, len(txs), func(i int, b *core.BlockGen) {
+		switch i {
+		case 0:
+			txs[i] = b.CallContract(alice, nil, asset(20), transferTestCode)
+		case 1:
+			contract = receipt(chain.CurrentBlock(), txs[0]).ContractAddress
+			txs[i] = b.CallContract(alice, &contract, assets.Asset{}, call(1, big.NewInt(1000).Bytes()))
+		case 2:
+			txs[i] = b.CallContract(alice, &contract, assets.Asset{}, call(2, big.NewInt(100).Bytes()))
+		case 3:
+			txs[i] = b.CallContract(alice, &contract, assets.Asset{}, call(3, big.NewInt(50).Bytes()))
+		case 4:
+			txs[i] = b.CreatePkg(alice, pkgId, contract, asset(10))
+		case 5:
+			statedb, err := chain.State()
+			if err != nil {
+				t.Fatalf("failed to open head state: %v", err)
+			}
+			zpkg := statedb.CurrentZState().Pkgs.GetPkgById(&pkgId)
+			if zpkg == nil {
+				t.Fatal("package not created")
+			}
+			sender = common.BytesToAddress(zpkg.From[:])
+			key := pkg.GetKey(&zpkg.From, &alice.Tk)
+			txs[i] = b.CallContract(alice, &contract, assets.Asset{}, call(4, pkgId[:], key[:]))
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to generate chain: %v", err)
+	}
+	for i, block := range blocks {
+		want := uint64(types.ReceiptStatusSuccessful)
+		if i == 3 {
+			want = types.ReceiptStatusFailed
+		}
+		if status := receipt(block, txs[i]).Status; status != want {
+			t.Errorf("block %d: receipt status mismatch: have %d, want %d", block.NumberU64(), status, want)
+		}
+	}
+	for _, i := range []int{3, 4} {
+		if transfers := rawdb.ReadBlockTokenTransfers(db, blocks[i].Hash(), blocks[i].NumberU64()); len(transfers) != 0 {
+			t.Errorf("block %d: unexpected transfers: %+v", blocks[i].NumberU64(), transfers)
+		}
+	}
+
+	indexer := &TokenTransferIndexer{db: db}
+	if err := indexer.Reset(0, common.Hash{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range blocks {
+		indexer.Process(block.Header())
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	transfer := func(i int, kind uint8, currency string, amount *big.Int, peer common.Address) *types.TokenTransfer {
+		return &types.TokenTransfer{
+			Kind:     kind,
+			Contract: contract,
+			Currency: currency,
+			Amount:   amount,
+			Peer:     peer,
+			TxHash:   txs[i].Hash(),
+			Number:   blocks[i].NumberU64(),
+		}
+	}
+	tests := []struct {
+		currency string
+		want     []*types.TokenTransfer
+	}{
+		{"SERO", []*types.TokenTransfer{
+			transfer(0, types.TransferReceive, "SERO", amount(20), txs[0].From()),
+			transfer(5, types.TransferPkgClose, "SERO", amount(10), sender),
+		}},
+		{"TKN", []*types.TokenTransfer{
+			transfer(1, types.TransferIssue, "TKN", big.NewInt(1000), common.Address{}),
+			transfer(2, types.TransferSend, "TKN", big.NewInt(100), txs[2].From()),
+		}},
+	}
+	for _, tt := range tests {
+		have := rawdb.ReadTokenTransfers(db, contract, tt.currency, 0, blocks[len(blocks)-1].NumberU64()+1)
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("%s transfers mismatch:\nhave %+v\nwant %+v", tt.currency, have, tt.want)
+		}
+	}
+}
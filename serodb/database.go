@@ -54,6 +54,8 @@ type LDBDatabase struct {
 	quitLock sync.Mutex      // Mutex protecting the quit channel access
 	quitChan chan chan error // Quit channel to stop the metrics collection before closing the database
 
+	ancients AncientStore // Store of the immutable data moved out of LevelDB
+
 	log log.Logger // Contextual logger tracking the database path
 }
 
@@ -102,18 +104,38 @@ func (db *LDBDatabase) Put(key []byte, value []byte) error {
 }
 
 func (db *LDBDatabase) Has(key []byte) (bool, error) {
-	return db.db.Has(key, nil)
+	has, err := db.db.Has(key, nil)
+	if err == nil && !has && db.ancients != nil {
+		_, has = db.ancients.Get(key)
+	}
+	return has, err
 }
 
-// Get returns the given key if it's present.
+// Get returns the given key if it's present, in LevelDB or in the ancient store.
 func (db *LDBDatabase) Get(key []byte) ([]byte, error) {
 	dat, err := db.db.Get(key, nil)
+	if err == leveldb.ErrNotFound && db.ancients != nil {
+		if dat, ok := db.ancients.Get(key); ok {
+			return dat, nil
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 	return dat, nil
 }
 
+// SetAncientStore sets the store looked up for the keys missing from LevelDB,
+// it must be set before the database is used.
+func (db *LDBDatabase) SetAncientStore(ancients AncientStore) {
+	db.ancients = ancients
+}
+
+// AncientStore returns the store of the immutable data, if any.
+func (db *LDBDatabase) AncientStore() AncientStore {
+	return db.ancients
+}
+
 // Delete deletes the key from the queue and database
 func (db *LDBDatabase) Delete(key []byte) error {
 	return db.db.Delete(key, nil)
@@ -141,6 +163,11 @@ func (db *LDBDatabase) Close() {
 		}
 		db.quitChan = nil
 	}
+	if db.ancients != nil {
+		if err := db.ancients.Close(); err != nil {
+			db.log.Error("Failed to close ancient store", "err", err)
+		}
+	}
 	err := db.db.Close()
 	if err == nil {
 		db.log.Info("Database closed")
@@ -55,6 +55,13 @@ type Batch interface {
 	Reset()
 }
 
+// AncientStore serves the immutable chain data moved out of the key-value
+// store, under the keys it was stored with.
+type AncientStore interface {
+	Get(key []byte) ([]byte, bool)
+	Close() error
+}
+
 type Tri interface {
 	TryGet(key []byte) ([]byte, error)
 	TryUpdate(key, value []byte) error
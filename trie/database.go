@@ -412,6 +412,15 @@ func (db *Database) Nodes() []common.Hash {
 	return hashes
 }
 
+// Dirty reports whether a node is held in memory, not flushed to disk yet.
+func (db *Database) Dirty(hash common.Hash) bool {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	_, ok := db.nodes[hash]
+	return ok
+}
+
 // Reference adds a new reference from a parent node to a child node.
 func (db *Database) Reference(child common.Hash, parent common.Hash) {
 	db.lock.RLock()
@@ -0,0 +1,402 @@
+package trie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// RangeTrie is a trie whose leaves can be served as flat ranges.
+type RangeTrie interface {
+	NodeIterator(start []byte) NodeIterator
+	Prove(key []byte, fromLevel uint, proofDb serodb.Putter) error
+}
+
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+// CollectRange gathers the leaves of a trie from origin up to and including
+// limit, stopping once maxBytes of keys and values were collected. The first
+// leaf after limit is returned as well if any, so that the proof closes the
+// range: the proof holds the merkle proofs of origin and of the last returned
+// key, which VerifyRangeProof checks the whole range against.
+func CollectRange(t RangeTrie, origin common.Hash, limit common.Hash, maxBytes uint64) (keys []common.Hash, values [][]byte, proof [][]byte, err error) {
+	it := NewIterator(t.NodeIterator(origin[:]))
+	size := uint64(0)
+	for size < maxBytes && it.Next() {
+		key := common.BytesToHash(it.Key)
+		keys = append(keys, key)
+		values = append(values, common.CopyBytes(it.Value))
+		size += uint64(common.HashLength + len(it.Value))
+
+		if bytes.Compare(key[:], limit[:]) >= 0 {
+			break
+		}
+	}
+	if it.Err != nil {
+		return nil, nil, nil, it.Err
+	}
+
+	var list proofList
+	if err = t.Prove(origin[:], 0, &list); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(keys) > 0 {
+		if err = t.Prove(keys[len(keys)-1][:], 0, &list); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return keys, values, list, nil
+}
+
+// VerifyRangeProof checks a range returned by CollectRange against the trie
+// root. The edge paths of origin and of the last key are rebuilt from the
+// proof, everything between them is dropped and filled again from the range,
+// so the root only matches if the range holds exactly the leaves of the trie
+// from origin to its last key. Without a proof, the range must be the whole
+// trie. It returns whether the trie holds leaves after the range.
+func VerifyRangeProof(root common.Hash, origin common.Hash, keys []common.Hash, values [][]byte, proof [][]byte) (bool, error) {
+	if len(keys) != len(values) {
+		return false, fmt.Errorf("key/value count mismatch: %d != %d", len(keys), len(values))
+	}
+	for i, key := range keys {
+		if bytes.Compare(key[:], origin[:]) < 0 {
+			return false, errors.New("range key before origin")
+		}
+		if i > 0 && bytes.Compare(keys[i-1][:], key[:]) >= 0 {
+			return false, errors.New("range keys not monotonic increasing")
+		}
+		if len(values[i]) == 0 {
+			return false, errors.New("range holds an empty value")
+		}
+	}
+	// Without proof the range is the whole trie
+	if len(proof) == 0 {
+		if origin != (common.Hash{}) {
+			return false, errors.New("missing proof of a partial range")
+		}
+		tr := &Trie{db: NewDatabase(serodb.NewMemDatabase())}
+		for i, key := range keys {
+			tr.Update(key[:], values[i])
+		}
+		if have := tr.Hash(); have != root {
+			return false, fmt.Errorf("range root mismatch: have %x, want %x", have, root)
+		}
+		return false, nil
+	}
+	db := serodb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	// An empty range proves there is nothing from origin on
+	if len(keys) == 0 {
+		rn, value, err := proofToPath(root, nil, origin[:], db, true)
+		if err != nil {
+			return false, err
+		}
+		if value != nil || hasRightElement(rn, origin[:]) {
+			return false, errors.New("more leaves available")
+		}
+		return false, nil
+	}
+	last := keys[len(keys)-1]
+	// A single leaf at origin has a single edge path
+	if len(keys) == 1 && last == origin {
+		rn, value, err := proofToPath(root, nil, origin[:], db, false)
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(value, values[0]) {
+			return false, errors.New("range value mismatch")
+		}
+		return hasRightElement(rn, origin[:]), nil
+	}
+	rn, _, err := proofToPath(root, nil, origin[:], db, true)
+	if err != nil {
+		return false, err
+	}
+	if rn, _, err = proofToPath(root, rn, last[:], db, true); err != nil {
+		return false, err
+	}
+	empty, err := unsetInternal(rn, origin[:], last[:])
+	if err != nil {
+		return false, err
+	}
+	tr := &Trie{root: rn, db: NewDatabase(serodb.NewMemDatabase())}
+	if empty {
+		tr.root = nil
+	}
+	for i, key := range keys {
+		if err := tr.TryUpdate(key[:], values[i]); err != nil {
+			return false, err
+		}
+	}
+	if have := tr.Hash(); have != root {
+		return false, fmt.Errorf("range root mismatch: have %x, want %x", have, root)
+	}
+	return hasRightElement(tr.root, last[:]), nil
+}
+
+// proofToPath resolves the nodes of the path of key from the proof, leaving
+// the nodes off the path as hash nodes. The path is merged into rn if given.
+// The returned value is the one of key, if the proof holds it.
+func proofToPath(root common.Hash, rn node, key []byte, proofDb DatabaseReader, allowNonExistent bool) (node, []byte, error) {
+	resolve := func(hash []byte) (node, error) {
+		buf, _ := proofDb.Get(hash)
+		if buf == nil {
+			return nil, fmt.Errorf("proof node %x missing", hash)
+		}
+		n, err := decodeNode(hash, buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("bad proof node: %v", err)
+		}
+		return n, nil
+	}
+	if rn == nil {
+		n, err := resolve(root[:])
+		if err != nil {
+			return nil, nil, err
+		}
+		rn = n
+	}
+	var (
+		parent  = rn
+		keyrest []byte
+		child   node
+		err     error
+	)
+	key = keybytesToHex(key)
+	for {
+		keyrest, child = step(parent, key)
+		switch cld := child.(type) {
+		case nil:
+			// The key is not in the trie, the resolved nodes prove it
+			if allowNonExistent {
+				return rn, nil, nil
+			}
+			return nil, nil, errors.New("key not in the trie")
+		case *shortNode, *fullNode:
+			key, parent = keyrest, child // Resolved by an earlier path
+			continue
+		case hashNode:
+			if child, err = resolve(cld); err != nil {
+				return nil, nil, err
+			}
+		case valueNode:
+			return rn, cld, nil
+		}
+		// Link the resolved child to its parent
+		switch pn := parent.(type) {
+		case *shortNode:
+			pn.Val = child
+		case *fullNode:
+			pn.Children[key[0]] = child
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", pn, pn))
+		}
+		key, parent = keyrest, child
+	}
+}
+
+// step returns the child of n on the path of key, along with the rest of key
+// below that child.
+func step(n node, key []byte) ([]byte, node) {
+	switch n := n.(type) {
+	case *shortNode:
+		if len(key) < len(n.Key) || !bytes.Equal(n.Key, key[:len(n.Key)]) {
+			return nil, nil
+		}
+		return key[len(n.Key):], n.Val
+	case *fullNode:
+		return key[1:], n.Children[key[0]]
+	case valueNode:
+		return nil, n
+	case nil:
+		return key, nil
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+	}
+}
+
+// unsetInternal drops every reference between the edge paths of left and right,
+// once they were resolved by proofToPath, so that the leaves in between can be
+// inserted again. The dropped part may be the whole trie, which is reported.
+func unsetInternal(n node, left []byte, right []byte) (bool, error) {
+	left, right = keybytesToHex(left), keybytesToHex(right)
+
+	// Step down to the fork point of the edge paths
+	var (
+		pos    = 0
+		parent node
+
+		shortForkLeft, shortForkRight int // Edge key against the short node key: -1 less, 0 on the path, 1 greater
+	)
+findFork:
+	for {
+		switch rn := n.(type) {
+		case *shortNode:
+			rn.flags = nodeFlag{dirty: true}
+
+			shortForkLeft = compareKeyPrefix(left[pos:], rn.Key)
+			shortForkRight = compareKeyPrefix(right[pos:], rn.Key)
+			if shortForkLeft != 0 || shortForkRight != 0 {
+				break findFork
+			}
+			parent = n
+			n, pos = rn.Val, pos+len(rn.Key)
+
+		case *fullNode:
+			rn.flags = nodeFlag{dirty: true}
+
+			leftnode, rightnode := rn.Children[left[pos]], rn.Children[right[pos]]
+			if leftnode == nil || rightnode == nil || leftnode != rightnode {
+				break findFork
+			}
+			parent = n
+			n, pos = leftnode, pos+1
+
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+		}
+	}
+	switch rn := n.(type) {
+	case *shortNode:
+		// Both edges on the same side of the short node leave nothing between
+		if shortForkLeft == shortForkRight && shortForkLeft != 0 {
+			return false, errors.New("empty range")
+		}
+		// The short node is entirely between the edges
+		if shortForkLeft != 0 && shortForkRight != 0 {
+			if parent == nil {
+				return true, nil
+			}
+			parent.(*fullNode).Children[left[pos-1]] = nil
+			return false, nil
+		}
+		// Only one edge is on the path of the short node
+		if shortForkRight != 0 {
+			if _, ok := rn.Val.(valueNode); ok {
+				if parent == nil {
+					return true, nil
+				}
+				parent.(*fullNode).Children[left[pos-1]] = nil
+				return false, nil
+			}
+			return false, unset(rn, rn.Val, left[pos:], len(rn.Key), false)
+		}
+		if _, ok := rn.Val.(valueNode); ok {
+			if parent == nil {
+				return true, nil
+			}
+			parent.(*fullNode).Children[right[pos-1]] = nil
+			return false, nil
+		}
+		return false, unset(rn, rn.Val, right[pos:], len(rn.Key), true)
+
+	case *fullNode:
+		for i := left[pos] + 1; i < right[pos]; i++ {
+			rn.Children[i] = nil
+		}
+		if err := unset(rn, rn.Children[left[pos]], left[pos:], 1, false); err != nil {
+			return false, err
+		}
+		if err := unset(rn, rn.Children[right[pos]], right[pos:], 1, true); err != nil {
+			return false, err
+		}
+		return false, nil
+
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+	}
+}
+
+// compareKeyPrefix compares the part of key the short node key spans with it.
+func compareKeyPrefix(key []byte, nodeKey []byte) int {
+	if len(key) < len(nodeKey) {
+		return bytes.Compare(key, nodeKey)
+	}
+	return bytes.Compare(key[:len(nodeKey)], nodeKey)
+}
+
+// unset drops the references on one side of the edge path of key below the
+// fork point: the right side of the left edge, or the left side of the right
+// edge if removeLeft is set.
+func unset(parent node, child node, key []byte, pos int, removeLeft bool) error {
+	switch cld := child.(type) {
+	case *fullNode:
+		if removeLeft {
+			for i := 0; i < int(key[pos]); i++ {
+				cld.Children[i] = nil
+			}
+		} else {
+			for i := key[pos] + 1; i < 16; i++ {
+				cld.Children[i] = nil
+			}
+		}
+		cld.flags = nodeFlag{dirty: true}
+		return unset(cld, cld.Children[key[pos]], key, pos+1, removeLeft)
+
+	case *shortNode:
+		if len(key[pos:]) < len(cld.Key) || !bytes.Equal(cld.Key, key[pos:pos+len(cld.Key)]) {
+			// The edge key is not in the trie, the short node is dropped if it
+			// falls between the edges
+			if removeLeft {
+				if bytes.Compare(cld.Key, key[pos:]) < 0 {
+					parent.(*fullNode).Children[key[pos-1]] = nil
+				}
+			} else {
+				if bytes.Compare(cld.Key, key[pos:]) > 0 {
+					parent.(*fullNode).Children[key[pos-1]] = nil
+				}
+			}
+			return nil
+		}
+		if _, ok := cld.Val.(valueNode); ok {
+			parent.(*fullNode).Children[key[pos-1]] = nil
+			return nil
+		}
+		cld.flags = nodeFlag{dirty: true}
+		return unset(cld, cld.Val, key, pos+len(cld.Key), removeLeft)
+
+	case nil:
+		// The edge key is not in the trie, nothing below the fork point
+		return nil
+
+	default:
+		panic(fmt.Sprintf("%T: invalid node: %v", child, child))
+	}
+}
+
+// hasRightElement returns whether the trie holds leaves after key.
+func hasRightElement(n node, key []byte) bool {
+	pos, key := 0, keybytesToHex(key)
+	for n != nil {
+		switch rn := n.(type) {
+		case *fullNode:
+			for i := key[pos] + 1; i < 16; i++ {
+				if rn.Children[i] != nil {
+					return true
+				}
+			}
+			n, pos = rn.Children[key[pos]], pos+1
+		case *shortNode:
+			if len(key)-pos < len(rn.Key) || !bytes.Equal(rn.Key, key[pos:pos+len(rn.Key)]) {
+				return bytes.Compare(rn.Key, key[pos:]) > 0
+			}
+			n, pos = rn.Val, pos+len(rn.Key)
+		case valueNode:
+			return false
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", n, n))
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package trie
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+func newRangeTrie(t *testing.T, n int) (*Trie, []common.Hash) {
+	tr, _ := New(common.Hash{}, NewDatabase(serodb.NewMemDatabase()))
+	keys := make([]common.Hash, n)
+	for i := range keys {
+		keys[i] = crypto.Keccak256Hash([]byte{byte(i), byte(i >> 8)})
+		tr.Update(keys[i][:], keys[i][:8])
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	return tr, keys
+}
+
+// Tests that consecutive ranges of a trie are proven and cover it exactly.
+func TestRangeProof(t *testing.T) {
+	tr, keys := newRangeTrie(t, 500)
+	root := tr.Hash()
+	limit := common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+
+	var (
+		origin common.Hash
+		all    []common.Hash
+	)
+	for {
+		hashes, values, proof, err := CollectRange(tr, origin, limit, uint64(1+rand.Intn(40))*40)
+		if err != nil {
+			t.Fatal(err)
+		}
+		more, err := VerifyRangeProof(root, origin, hashes, values, proof)
+		if err != nil {
+			t.Fatalf("range from %x rejected: %v", origin, err)
+		}
+		all = append(all, hashes...)
+		if !more {
+			break
+		}
+		origin, _ = incKey(hashes[len(hashes)-1])
+	}
+	if len(all) != len(keys) {
+		t.Fatalf("range leaves mismatch: have %d, want %d", len(all), len(keys))
+	}
+	for i := range keys {
+		if all[i] != keys[i] {
+			t.Fatalf("leaf %d mismatch: have %x, want %x", i, all[i], keys[i])
+		}
+	}
+}
+
+// Tests that a range missing, adding or changing a leaf is rejected.
+func TestRangeProofTampered(t *testing.T) {
+	tr, keys := newRangeTrie(t, 200)
+	root := tr.Hash()
+
+	origin := keys[10]
+	hashes, values, proof, err := CollectRange(tr, origin, keys[60], 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 51 {
+		t.Fatalf("range length mismatch: have %d, want 51", len(hashes))
+	}
+	if more, err := VerifyRangeProof(root, origin, hashes, values, proof); err != nil || !more {
+		t.Fatalf("valid range rejected: more %v, err %v", more, err)
+	}
+	// A leaf left out of the middle
+	dropped := append(append([]common.Hash{}, hashes[:20]...), hashes[21:]...)
+	droppedValues := append(append([][]byte{}, values[:20]...), values[21:]...)
+	if _, err := VerifyRangeProof(root, origin, dropped, droppedValues, proof); err == nil {
+		t.Fatal("range missing a leaf accepted")
+	}
+	// A changed value
+	changed := append([][]byte{}, values...)
+	changed[5] = []byte{1, 2, 3}
+	if _, err := VerifyRangeProof(root, origin, hashes, changed, proof); err == nil {
+		t.Fatal("range with a changed value accepted")
+	}
+	// A range starting after the proven origin
+	if _, err := VerifyRangeProof(root, keys[5], hashes, values, proof); err == nil {
+		t.Fatal("range with leaves before it left out accepted")
+	}
+	// A partial range without proof
+	if _, err := VerifyRangeProof(root, origin, hashes, values, nil); err == nil {
+		t.Fatal("partial range without proof accepted")
+	}
+}
+
+// Tests the edge ranges: the whole trie, nothing after the last leaf and a
+// single leaf.
+func TestRangeProofEdges(t *testing.T) {
+	tr, keys := newRangeTrie(t, 50)
+	root := tr.Hash()
+	limit := common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+
+	hashes, values, _, err := CollectRange(tr, common.Hash{}, limit, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if more, err := VerifyRangeProof(root, common.Hash{}, hashes, values, nil); err != nil || more {
+		t.Fatalf("whole trie rejected: more %v, err %v", more, err)
+	}
+	origin, _ := incKey(keys[len(keys)-1])
+	hashes, values, proof, err := CollectRange(tr, origin, limit, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if more, err := VerifyRangeProof(root, origin, hashes, values, proof); err != nil || more || len(hashes) != 0 {
+		t.Fatalf("empty tail rejected: more %v, err %v", more, err)
+	}
+	hashes, values, proof, err = CollectRange(tr, keys[7], keys[7], 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if more, err := VerifyRangeProof(root, keys[7], hashes, values, proof); err != nil || !more {
+		t.Fatalf("single leaf rejected: more %v, err %v", more, err)
+	}
+	// Leaves after the range can not be hidden
+	if _, err := VerifyRangeProof(root, keys[7], hashes[:0], values[:0], proof); err == nil {
+		t.Fatal("empty range hiding leaves accepted")
+	}
+}
+
+func incKey(h common.Hash) (common.Hash, bool) {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			return h, false
+		}
+	}
+	return h, true
+}
@@ -1,6 +1,8 @@
 package voter
 
 import (
+	"encoding/binary"
+	"errors"
 	"math/big"
 	"sync"
 	"time"
@@ -27,6 +29,7 @@ import (
 	"github.com/sero-cash/go-sero/core/state"
 	"github.com/sero-cash/go-sero/event"
 	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/rlp"
 )
 
 const (
@@ -36,8 +39,24 @@ const (
 
 	delayNum         = 1
 	lotteryQueueSize = 12
+
+	signedVotesLimit = 128 // Number of recent blocks to remember the own votes of
 )
 
+// ErrInvalidVoteSign is returned for the votes whose signature doesn't match
+// the vote key of their share.
+var ErrInvalidVoteSign = errors.New("invalid vote signature")
+
+// signedVotesPrefix + num % signedVotesLimit -> signedVotes, the slots are reused
+// by the later blocks.
+var signedVotesPrefix = []byte("VOTER$SIGNED$")
+
+// signedVotes are the votes the node signed for a block.
+type signedVotes struct {
+	Number uint64
+	Votes  []types.HeaderVote
+}
+
 type blockChain interface {
 	CurrentBlock() *types.Block
 	GetHeaderByHash(hash common.Hash) *types.Header
@@ -63,6 +82,9 @@ type Voter struct {
 
 	votes    map[common.Hash]time.Time
 	lotterys map[common.Hash]time.Time
+	parents  map[common.Hash]common.Hash // Parent of the block of the lotterys
+
+	signedMu sync.Mutex // Protects the signed votes in the database
 
 	lotteryQueue *PriorityQueue
 }
@@ -77,6 +99,7 @@ func NewVoter(chainconfig *params.ChainConfig, chain blockChain, sero Backend) *
 		lotteryCh:    make(chan *types.Lottery, chainLotterySize),
 		votes:        make(map[common.Hash]time.Time),
 		lotterys:     make(map[common.Hash]time.Time),
+		parents:      make(map[common.Hash]common.Hash),
 		lotteryQueue: &PriorityQueue{},
 	}
 	voter.lotteryQueue.Init(lotteryQueueSize)
@@ -108,6 +131,7 @@ func (self *Voter) loop() {
 			}
 			for _, h := range dropLotterys {
 				delete(self.lotterys, h)
+				delete(self.parents, h)
 			}
 			self.lotteryMu.Unlock()
 			self.voteMu.Lock()
@@ -348,6 +372,50 @@ func (self *Voter) sign(info voteInfo) {
 	vote := &types.Vote{info.index, info.parentNum, info.shareHash, info.poshash, info.isPool, sign}
 	//go self.voteWorkFeed.Send(core.NewVoteEvent{vote})
 	self.AddVote(vote)
+
+	self.signedMu.Lock()
+	defer self.signedMu.Unlock()
+
+	signed := self.signedVotes(info.parentNum + 1)
+	for _, v := range signed.Votes {
+		if v.Id == info.shareHash && v.IsPool == info.isPool {
+			return
+		}
+	}
+	signed.Votes = append(signed.Votes, types.HeaderVote{Id: info.shareHash, IsPool: info.isPool, Sign: sign})
+	if enc, err := rlp.EncodeToBytes(signed); err != nil {
+		log.Error("voter encode signed votes", "err", err)
+	} else if err := self.chain.GetDB().Put(signedVotesKey(signed.Number), enc); err != nil {
+		log.Error("voter store signed votes", "err", err)
+	}
+}
+
+func signedVotesKey(number uint64) []byte {
+	key := make([]byte, len(signedVotesPrefix)+8)
+	copy(key, signedVotesPrefix)
+	binary.BigEndian.PutUint64(key[len(signedVotesPrefix):], number%signedVotesLimit)
+	return key
+}
+
+// signedVotes reads the votes signed for a block, the lock must be held.
+func (self *Voter) signedVotes(number uint64) *signedVotes {
+	signed := &signedVotes{Number: number}
+	if enc, err := self.chain.GetDB().Get(signedVotesKey(number)); err == nil {
+		var stored signedVotes
+		if err := rlp.DecodeBytes(enc, &stored); err == nil && stored.Number == number {
+			signed.Votes = stored.Votes
+		}
+	}
+	return signed
+}
+
+// SignedVotes returns the votes the node signed for the block of the given
+// number, if it's one of the recent ones. They are kept across restarts.
+func (self *Voter) SignedVotes(number uint64) []types.HeaderVote {
+	self.signedMu.Lock()
+	defer self.signedMu.Unlock()
+
+	return self.signedVotes(number).Votes
 }
 
 // SubscribeNewTxsEvent registers a subscription of NewTxsEvent and
@@ -384,6 +452,7 @@ func (self *Voter) AddLottery(lottery *types.Lottery) {
 	if !exits {
 		log.Trace("AddLottery", "poshas", lottery.PosHash, "block", lottery.ParentNum+1)
 		self.lotterys[lottery.PosHash] = time.Now()
+		self.parents[lottery.PosHash] = lottery.ParentHash
 		self.lotteryCh <- lottery
 		self.SendLotteryEvent(lottery)
 	}
@@ -398,20 +467,79 @@ func (self *Voter) getStateByNumber(num uint64) (*state.StateDB, error) {
 
 }
 
-func (self *Voter) AddVote(vote *types.Vote) {
+// verifyVote checks the signature of a vote against the vote key of its share,
+// or of the pool of its share. It reports the vote unverified when its lottery
+// or parent is unknown, or when the pool of the share can't be voted for.
+func (self *Voter) verifyVote(vote *types.Vote) (bool, error) {
+	self.lotteryMu.RLock()
+	parentHash, ok := self.parents[vote.PosHash]
+	self.lotteryMu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	parent := self.chain.GetHeader(parentHash, vote.ParentNum)
+	if parent == nil {
+		return false, nil
+	}
+	state, err := self.chain.StateAt(parent)
+	if err != nil {
+		return false, nil
+	}
+	stakeState := stake.NewStakeState(state)
+	share := stakeState.GetShare(vote.ShareId)
+	if share == nil {
+		return false, ErrInvalidVoteSign
+	}
+	votePkr := &share.VotePKr
+	if vote.IsPool {
+		if share.PoolId == nil {
+			return false, ErrInvalidVoteSign
+		}
+		pool := stakeState.GetStakePool(*share.PoolId)
+		if pool == nil {
+			return false, ErrInvalidVoteSign
+		}
+		// The miners ignore the votes of such pools, honest voters sign them too
+		if !pool.CanBeVote() {
+			return false, nil
+		}
+		votePkr = &pool.VotePKr
+	}
+	parentPos := parent.HashPos()
+	stakeHash := types.StakeHash(&vote.PosHash, &parentPos, vote.IsPool)
+	if !superzk.VerifyPKr_ByHeight(vote.ParentNum+1, stakeHash.HashToUint256(), &vote.Sign, votePkr) {
+		return false, ErrInvalidVoteSign
+	}
+	return true, nil
+}
+
+// AddVote relays a vote received from a peer, it returns an error if its
+// signature is invalid. The votes that can't be verified yet are handed to the
+// miner but not relayed, since the peers hold the relays to their votes.
+func (self *Voter) AddVote(vote *types.Vote) error {
 	self.voteMu.Lock()
 	defer self.voteMu.Unlock()
 
 	current := self.chain.CurrentBlock().Number().Uint64()
 	if current > vote.ParentNum+delayNum {
 		log.Trace("AddVote droped", "current", current, "voteBlock", vote.ParentNum+1)
-		return
+		return nil
 	}
 	_, exits := self.votes[vote.Hash()]
 	if !exits {
-		log.Trace("AddVote", "hashpos", vote.PosHash, "block", vote.ParentNum+1)
+		verified, err := self.verifyVote(vote)
+		if err != nil {
+			return err
+		}
 		go self.voteWorkFeed.Send(core.NewVoteEvent{vote})
+		if !verified {
+			// Left unknown so that it is verified and relayed if it comes again
+			log.Trace("AddVote unverified", "hashpos", vote.PosHash, "block", vote.ParentNum+1)
+			return nil
+		}
+		log.Trace("AddVote", "hashpos", vote.PosHash, "block", vote.ParentNum+1)
 		self.SendVoteEvent(vote)
 		self.votes[vote.Hash()] = time.Now()
 	}
+	return nil
 }
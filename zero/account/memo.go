@@ -0,0 +1,210 @@
+package account
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+)
+
+// MemoVersion is the first byte of the typed memos.
+//
+// A typed memo is laid out as
+//
+//	version(1) | type(1) | length(1) | data(length) | zero padding
+//
+// The memos written as plain text are right aligned and start either with a
+// zero or with a printable byte, so they are told apart from the typed ones.
+const MemoVersion = 0x01
+
+// maxMemoData is the maximum length of the data of a typed memo.
+const maxMemoData = MaxMemoLength - 3
+
+// MemoType is the kind of the data of a memo.
+type MemoType uint8
+
+const (
+	MemoRaw       MemoType = iota // Bytes of an untyped memo that are not text
+	MemoText                      // Utf-8 text
+	MemoInvoice                   // Invoice id, opaque bytes
+	MemoReference                 // Reference number, uint64 big endian
+)
+
+// The prefixes of the string forms of the memos.
+const (
+	memoInvoicePrefix   = "inv:"
+	memoReferencePrefix = "ref:"
+	memoRawPrefix       = "0x"
+)
+
+// Memo is the decoded memo of an output.
+type Memo struct {
+	Type MemoType
+	Data []byte
+}
+
+// NewTextMemo returns a memo holding a text.
+func NewTextMemo(text string) Memo {
+	return Memo{Type: MemoText, Data: []byte(text)}
+}
+
+// NewInvoiceMemo returns a memo holding an invoice id.
+func NewInvoiceMemo(id []byte) Memo {
+	return Memo{Type: MemoInvoice, Data: id}
+}
+
+// NewReferenceMemo returns a memo holding a reference number.
+func NewReferenceMemo(ref uint64) Memo {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, ref)
+	return Memo{Type: MemoReference, Data: data}
+}
+
+// DecodeMemo decodes the memo of an output, typed or written as plain text.
+func DecodeMemo(memo *c_type.Uint512) (ret Memo) {
+	if memo[0] == MemoVersion {
+		typ, length := MemoType(memo[1]), int(memo[2])
+		if typ > MemoRaw && typ <= MemoReference && length <= maxMemoData &&
+			(typ != MemoReference || length == 8) && isZero(memo[3+length:]) {
+			ret.Type = typ
+			ret.Data = append([]byte{}, memo[3:3+length]...)
+			return
+		}
+	}
+	data := bytes.TrimLeft(memo[:], "\x00")
+	if len(data) == 0 {
+		return
+	}
+	if utf8.Valid(data) {
+		ret.Type = MemoText
+	}
+	ret.Data = append([]byte{}, data...)
+	return
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Encode returns the typed encoding of the memo.
+func (self *Memo) Encode() (ret c_type.Uint512, e error) {
+	switch self.Type {
+	case MemoText, MemoInvoice:
+	case MemoReference:
+		if len(self.Data) != 8 {
+			e = errors.New("the reference of a memo must be 8 bytes")
+			return
+		}
+	default:
+		e = errors.Errorf("unsupported memo type %d", self.Type)
+		return
+	}
+	if len(self.Data) > maxMemoData {
+		e = errors.Errorf("the memo data is longer than %d bytes", maxMemoData)
+		return
+	}
+	ret[0] = MemoVersion
+	ret[1] = byte(self.Type)
+	ret[2] = byte(len(self.Data))
+	copy(ret[3:], self.Data)
+	return
+}
+
+// EncodeMemo encodes the string form of a memo for an output. The memos that
+// don't fit a typed encoding are written as plain text, right aligned.
+func EncodeMemo(str string) (ret c_type.Uint512, e error) {
+	if str == "" {
+		return
+	}
+	memo, err := ParseMemo(str)
+	if err != nil {
+		memo = NewTextMemo(str)
+	}
+	if ret, e = memo.Encode(); e == nil {
+		return
+	}
+	if memo.Type == MemoInvoice || memo.Type == MemoReference {
+		return
+	}
+	if len(memo.Data) > MaxMemoLength {
+		e = errors.Errorf("the memo is longer than %d bytes", MaxMemoLength)
+		return
+	}
+	ret, e = c_type.Uint512{}, nil
+	copy(ret[len(ret)-len(memo.Data):], memo.Data)
+	return
+}
+
+// IsEmpty tells whether the output has no memo.
+func (self *Memo) IsEmpty() bool {
+	return len(self.Data) == 0
+}
+
+// Reference returns the reference number of a reference memo.
+func (self *Memo) Reference() (uint64, bool) {
+	if self.Type != MemoReference || len(self.Data) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(self.Data), true
+}
+
+// Tag returns the key the outputs are attributed by, the same for a text
+// written typed or as plain text.
+func (self *Memo) Tag() []byte {
+	if self.IsEmpty() {
+		return nil
+	}
+	return append([]byte{byte(self.Type)}, self.Data...)
+}
+
+// String returns the text of a text memo, "inv:<hex>" for an invoice id,
+// "ref:<number>" for a reference and "0x<hex>" for raw bytes.
+func (self Memo) String() string {
+	switch self.Type {
+	case MemoText:
+		return string(self.Data)
+	case MemoInvoice:
+		return memoInvoicePrefix + hex.EncodeToString(self.Data)
+	case MemoReference:
+		ref, _ := self.Reference()
+		return memoReferencePrefix + strconv.FormatUint(ref, 10)
+	default:
+		if self.IsEmpty() {
+			return ""
+		}
+		return memoRawPrefix + hex.EncodeToString(self.Data)
+	}
+}
+
+// ParseMemo is the inverse of String.
+func ParseMemo(str string) (ret Memo, e error) {
+	switch {
+	case strings.HasPrefix(str, memoInvoicePrefix):
+		ret.Type = MemoInvoice
+		ret.Data, e = hex.DecodeString(str[len(memoInvoicePrefix):])
+	case strings.HasPrefix(str, memoReferencePrefix):
+		var ref uint64
+		if ref, e = strconv.ParseUint(str[len(memoReferencePrefix):], 10, 64); e == nil {
+			ret = NewReferenceMemo(ref)
+		}
+	case strings.HasPrefix(str, memoRawPrefix):
+		ret.Data, e = hex.DecodeString(str[len(memoRawPrefix):])
+	default:
+		ret = NewTextMemo(str)
+	}
+	if e == nil && len(ret.Data) > MaxMemoLength {
+		e = errors.Errorf("the memo is longer than %d bytes", MaxMemoLength)
+	}
+	return
+}
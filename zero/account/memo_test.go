@@ -0,0 +1,103 @@
+package account
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+)
+
+func TestMemoEncoding(t *testing.T) {
+	memos := []Memo{
+		NewTextMemo("deposit user 42"),
+		NewInvoiceMemo([]byte{0xde, 0xad, 0xbe, 0xef}),
+		NewReferenceMemo(1234567890),
+	}
+	for _, memo := range memos {
+		encoded, err := memo.Encode()
+		if err != nil {
+			t.Fatalf("failed to encode %v: %v", memo, err)
+		}
+		decoded := DecodeMemo(&encoded)
+		if decoded.Type != memo.Type || !bytes.Equal(decoded.Data, memo.Data) {
+			t.Fatalf("memo mismatch: have %v, want %v", decoded, memo)
+		}
+		parsed, err := ParseMemo(memo.String())
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", memo.String(), err)
+		}
+		if !bytes.Equal(parsed.Tag(), memo.Tag()) {
+			t.Fatalf("tag mismatch for %s: have %x, want %x", memo.String(), parsed.Tag(), memo.Tag())
+		}
+	}
+	if ref, ok := memos[2].Reference(); !ok || ref != 1234567890 {
+		t.Fatalf("reference mismatch: have %d", ref)
+	}
+
+	long := NewTextMemo(string(bytes.Repeat([]byte{'a'}, maxMemoData+1)))
+	if _, err := long.Encode(); err == nil {
+		t.Fatalf("encoded a memo longer than %d bytes", maxMemoData)
+	}
+	if _, err := (&Memo{Type: MemoReference, Data: []byte{1}}).Encode(); err == nil {
+		t.Fatalf("encoded a short reference")
+	}
+}
+
+func TestMemoPlainText(t *testing.T) {
+	var plain c_type.Uint512
+	copy(plain[len(plain)-len("user42"):], "user42")
+	memo := DecodeMemo(&plain)
+	if memo.Type != MemoText || memo.String() != "user42" {
+		t.Fatalf("plain text memo mismatch: have %v", memo)
+	}
+	// The typed and the plain text forms of a text share their tag
+	typed := NewTextMemo("user42")
+	if !bytes.Equal(memo.Tag(), typed.Tag()) {
+		t.Fatalf("tag mismatch: have %x, want %x", memo.Tag(), typed.Tag())
+	}
+
+	var empty c_type.Uint512
+	if memo := DecodeMemo(&empty); !memo.IsEmpty() || memo.Tag() != nil || memo.String() != "" {
+		t.Fatalf("empty memo mismatch: have %v", memo)
+	}
+
+	var raw c_type.Uint512
+	raw[60], raw[63] = 0xff, 0xfe
+	if memo := DecodeMemo(&raw); memo.Type != MemoRaw || memo.String() != "0xff0000fe" {
+		t.Fatalf("raw memo mismatch: have %v", memo)
+	}
+
+	// A typed memo with garbage after its data is not typed
+	encoded, _ := typed.Encode()
+	encoded[63] = 'x'
+	if memo := DecodeMemo(&encoded); memo.Type == MemoText && memo.String() == "user42" {
+		t.Fatalf("decoded a corrupted typed memo")
+	}
+}
+
+func TestEncodeMemo(t *testing.T) {
+	for _, str := range []string{"user42", "inv:deadbeef", "ref:42", "0x01ff"} {
+		encoded, err := EncodeMemo(str)
+		if err != nil {
+			t.Fatalf("failed to encode %s: %v", str, err)
+		}
+		if memo := DecodeMemo(&encoded); memo.String() != str {
+			t.Fatalf("memo mismatch: have %s, want %s", memo.String(), str)
+		}
+	}
+	// The texts too long for a typed memo are written as plain text
+	long := string(bytes.Repeat([]byte{'a'}, MaxMemoLength))
+	encoded, err := EncodeMemo(long)
+	if err != nil || string(encoded[:]) != long {
+		t.Fatalf("plain text memo mismatch: %x, %v", encoded, err)
+	}
+	if encoded, err := EncodeMemo(""); err != nil || encoded != (c_type.Uint512{}) {
+		t.Fatalf("empty memo mismatch: %x, %v", encoded, err)
+	}
+	for _, str := range []string{long + "a", "inv:" + strings.Repeat("00", maxMemoData+1)} {
+		if _, err := EncodeMemo(str); err == nil {
+			t.Fatalf("encoded a memo too long: %s", str)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package account
+
+import (
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PaymentURIScheme is the scheme of the payment URIs.
+const PaymentURIScheme = "sero"
+
+// MaxMemoLength is the maximum length in bytes of the memo of an output.
+const MaxMemoLength = 64
+
+// The query parameters of a payment URI.
+const (
+	uriCurrency = "currency"
+	uriAmount   = "amount"
+	uriMemo     = "memo"
+	uriExpiry   = "exp"
+	uriLabel    = "label"
+)
+
+// PaymentURI is a request for a payment to a PK or a PKr, encoded as
+//
+//	sero:<address>?currency=<currency>&amount=<amount>&memo=<memo>&exp=<expiry>&label=<label>
+//
+// The address is in base58, or in the checksummed code form when parsed. The
+// amount is a decimal number in the units of the currency, the expiry is a
+// unix time. All the query parameters are optional, the unknown ones are
+// ignored unless they start with "req-".
+type PaymentURI struct {
+	Address  Address
+	Currency string
+	Amount   string
+	Memo     string
+	Expiry   uint64
+	Label    string
+}
+
+// ParsePaymentURI decodes and checks a payment URI.
+func ParsePaymentURI(uri string) (ret PaymentURI, e error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		e = err
+		return
+	}
+	if u.Scheme != PaymentURIScheme {
+		e = errors.Errorf("invalid payment uri scheme %q", u.Scheme)
+		return
+	}
+	addr := u.Opaque
+	if addr == "" {
+		addr = strings.TrimPrefix(u.Host+u.Path, "/")
+	}
+	if ret.Address, e = NewAddressByString(addr); e != nil {
+		return
+	}
+	if len(ret.Address.Bytes) != 64 && len(ret.Address.Bytes) != 96 {
+		e = errors.New("the payment uri address is neither a PK nor a PKr")
+		return
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		e = err
+		return
+	}
+	for key := range query {
+		switch key {
+		case uriCurrency, uriAmount, uriMemo, uriExpiry, uriLabel:
+		default:
+			if strings.HasPrefix(key, "req-") {
+				e = errors.Errorf("unsupported required payment uri parameter %q", key)
+				return
+			}
+		}
+	}
+	ret.Currency = strings.ToUpper(query.Get(uriCurrency))
+	if ret.Amount = query.Get(uriAmount); ret.Amount != "" {
+		if _, err := ParseAmount(ret.Amount, 255); err != nil {
+			e = err
+			return
+		}
+	}
+	if ret.Memo = query.Get(uriMemo); len(ret.Memo) > MaxMemoLength {
+		e = errors.Errorf("the payment uri memo is longer than %d bytes", MaxMemoLength)
+		return
+	}
+	if exp := query.Get(uriExpiry); exp != "" {
+		if ret.Expiry, e = strconv.ParseUint(exp, 10, 64); e != nil {
+			return
+		}
+	}
+	ret.Label = query.Get(uriLabel)
+	return
+}
+
+// String encodes the payment URI.
+func (self *PaymentURI) String() string {
+	query := url.Values{}
+	if self.Currency != "" {
+		query.Set(uriCurrency, self.Currency)
+	}
+	if self.Amount != "" {
+		query.Set(uriAmount, self.Amount)
+	}
+	if self.Memo != "" {
+		query.Set(uriMemo, self.Memo)
+	}
+	if self.Expiry != 0 {
+		query.Set(uriExpiry, strconv.FormatUint(self.Expiry, 10))
+	}
+	if self.Label != "" {
+		query.Set(uriLabel, self.Label)
+	}
+	uri := PaymentURIScheme + ":" + self.Address.ToBase58()
+	if len(query) > 0 {
+		uri += "?" + strings.Replace(query.Encode(), "+", "%20", -1)
+	}
+	return uri
+}
+
+// Expired tells whether the payment request has expired at the given time.
+func (self *PaymentURI) Expired(now time.Time) bool {
+	return self.Expiry != 0 && uint64(now.Unix()) >= self.Expiry
+}
+
+// Value returns the amount in the smallest unit of a currency of the given
+// decimals, or nil if the URI has no amount.
+func (self *PaymentURI) Value(decimals uint8) (*big.Int, error) {
+	if self.Amount == "" {
+		return nil, nil
+	}
+	return ParseAmount(self.Amount, decimals)
+}
+
+// SetValue sets the amount from a value in the smallest unit of a currency of
+// the given decimals.
+func (self *PaymentURI) SetValue(value *big.Int, decimals uint8) {
+	self.Amount = FormatAmount(value, decimals)
+}
+
+// ParseAmount converts a decimal amount into the smallest unit of a currency
+// of the given decimals.
+func ParseAmount(amount string, decimals uint8) (*big.Int, error) {
+	parts := strings.Split(amount, ".")
+	if len(parts) > 2 || parts[0] == "" && (len(parts) == 1 || parts[1] == "") {
+		return nil, errors.Errorf("invalid amount %q", amount)
+	}
+	for _, part := range parts {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return nil, errors.Errorf("invalid amount %q", amount)
+			}
+		}
+	}
+	fraction := ""
+	if len(parts) == 2 {
+		fraction = strings.TrimRight(parts[1], "0")
+	}
+	if len(fraction) > int(decimals) {
+		return nil, errors.Errorf("the amount %q has more than %d decimals", amount, decimals)
+	}
+	digits := parts[0] + fraction + strings.Repeat("0", int(decimals)-len(fraction))
+	value, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, errors.Errorf("invalid amount %q", amount)
+	}
+	return value, nil
+}
+
+// FormatAmount converts a value in the smallest unit of a currency of the
+// given decimals into a decimal amount.
+func FormatAmount(value *big.Int, decimals uint8) string {
+	digits := value.String()
+	if decimals == 0 {
+		return digits
+	}
+	if len(digits) <= int(decimals) {
+		digits = strings.Repeat("0", int(decimals)-len(digits)+1) + digits
+	}
+	point := len(digits) - int(decimals)
+	fraction := strings.TrimRight(digits[point:], "0")
+	if fraction == "" {
+		return digits[:point]
+	}
+	return digits[:point] + "." + fraction
+}
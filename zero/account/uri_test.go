@@ -0,0 +1,89 @@
+package account
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestPaymentURI(t *testing.T) {
+	addr := NewAddressByBytes(bytes.Repeat([]byte{7}, 96))
+	uri := PaymentURI{
+		Address:  addr,
+		Currency: "SERO",
+		Memo:     "order 42&co",
+		Expiry:   1700000000,
+		Label:    "Shop",
+	}
+	uri.SetValue(big.NewInt(1500000000000000000), 18)
+	if uri.Amount != "1.5" {
+		t.Fatalf("amount mismatch: have %s, want %s", uri.Amount, "1.5")
+	}
+	decoded, err := ParsePaymentURI(uri.String())
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", uri.String(), err)
+	}
+	if !bytes.Equal(decoded.Address.Bytes, addr.Bytes) || decoded.Currency != uri.Currency || decoded.Amount != uri.Amount ||
+		decoded.Memo != uri.Memo || decoded.Expiry != uri.Expiry || decoded.Label != uri.Label {
+		t.Fatalf("uri mismatch: have %+v, want %+v", decoded, uri)
+	}
+	if value, _ := decoded.Value(18); value.Cmp(big.NewInt(1500000000000000000)) != 0 {
+		t.Fatalf("value mismatch: have %v", value)
+	}
+	if !decoded.Expired(time.Unix(1700000000, 0)) || decoded.Expired(time.Unix(1699999999, 0)) {
+		t.Fatalf("wrong expiry")
+	}
+
+	// The checksummed form of the address is accepted
+	if _, err := ParsePaymentURI("sero:" + addr.ToCode() + "?amount=1"); err != nil {
+		t.Fatalf("failed to parse the code address: %v", err)
+	}
+	short := NewAddressByBytes([]byte("short"))
+	for _, invalid := range []string{
+		"bitcoin:" + addr.ToBase58(),
+		"sero:" + short.ToBase58(),
+		"sero:" + addr.ToBase58() + "?amount=1.2.3",
+		"sero:" + addr.ToBase58() + "?amount=-1",
+		"sero:" + addr.ToBase58() + "?exp=soon",
+		"sero:" + addr.ToBase58() + "?req-split=1",
+	} {
+		if _, err := ParsePaymentURI(invalid); err == nil {
+			t.Errorf("parsed invalid uri %s", invalid)
+		}
+	}
+}
+
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint8
+		value    string
+		format   string
+	}{
+		{"1", 0, "1", "1"},
+		{"1.5", 2, "150", "1.5"},
+		{".5", 1, "5", "0.5"},
+		{"0.000001", 6, "1", "0.000001"},
+		{"12.3400", 4, "123400", "12.34"},
+		{"10", 18, "10000000000000000000", "10"},
+	}
+	for _, tt := range tests {
+		value, err := ParseAmount(tt.amount, tt.decimals)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", tt.amount, err)
+		}
+		if value.String() != tt.value {
+			t.Errorf("%s: value mismatch: have %v, want %s", tt.amount, value, tt.value)
+		}
+		if format := FormatAmount(value, tt.decimals); format != tt.format {
+			t.Errorf("%s: format mismatch: have %s, want %s", tt.amount, format, tt.format)
+		}
+	}
+	if _, err := ParseAmount("0.001", 2); err == nil {
+		t.Errorf("parsed an amount with too many decimals")
+	}
+	if _, err := ParseAmount(".", 2); err == nil {
+		t.Errorf("parsed an empty amount")
+	}
+}
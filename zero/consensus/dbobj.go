@@ -33,6 +33,11 @@ func (self DBObj) setBlockRecords(batch serodb.Putter, num uint64, hash *common.
 	}
 }
 
+// PutBlockRecords stores the records of a block retrieved from a peer.
+func (self DBObj) PutBlockRecords(batch serodb.Putter, num uint64, hash *common.Hash, records []*Record) {
+	self.setBlockRecords(batch, num, hash, records)
+}
+
 func (self DBObj) GetBlockRecords(getter serodb.Getter, num uint64, hash *common.Hash) (records []*Record) {
 	if b, err := getter.Get(makeBlockName(self.Pre, num, hash)); err != nil {
 		return
@@ -65,3 +70,14 @@ func (self DBObj) GetObject(getter serodb.Getter, hash []byte, item CItem) (ret
 		return item
 	}
 }
+
+// PutObject stores an object under its state hash, as the block records of the
+// block that changed it do.
+func (self DBObj) PutObject(putter serodb.Putter, hash []byte, item CItem) error {
+	k := key{self.Pre, hash}
+	b, err := rlp.EncodeToBytes(item)
+	if err != nil {
+		return err
+	}
+	return putter.Put([]byte(k.k()), b)
+}
@@ -27,6 +27,15 @@ func (self *ObjPoint) AddObj(item PItem) {
 	return
 }
 
+// GetState returns the state hash of the object of the given id, or nil if
+// there is none.
+func (self *ObjPoint) GetState(id []byte) []byte {
+	if v := self.cons.getObj(&key{self.objPre, id}, &Bytes{}, true, false); v != nil && v.item != nil {
+		return *v.item.(*Bytes)
+	}
+	return nil
+}
+
 func (self *ObjPoint) GetObj(id []byte, item PItem) (ret CItem) {
 
 	if v := self.cons.getObj(&key{self.objPre, id}, &Bytes{}, true, false); v != nil && v.item != nil {
@@ -71,3 +71,18 @@ func genRootCM(self *OutState) (cm c_type.Uint256, e error) {
 		return
 	}
 }
+
+// CheckRootCM returns whether the commitments of an out received from a peer
+// are the ones of its content.
+func (self *OutState) CheckRootCM() bool {
+	if self.RootCM == nil {
+		return false
+	}
+	if self.Out_O != nil {
+		if cm, err := genOutCM(self); err != nil || self.OutCM == nil || cm != *self.OutCM {
+			return false
+		}
+	}
+	cm, err := genRootCM(self)
+	return err == nil && cm == *self.RootCM
+}
@@ -0,0 +1,75 @@
+package localdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+var (
+	prunedKey       = []byte("$SERO_LOCALDB_PRUNED$")
+	pruneAttemptKey = []byte("$SERO_LOCALDB_PRUNE_ATTEMPT$")
+)
+
+// PutPruned records the first block whose zstate records were kept by the
+// last pruning.
+func PutPruned(db serodb.Putter, num uint64) {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], num)
+	db.Put(prunedKey, enc[:])
+}
+
+// GetPruned returns the first block whose zstate records are available, the
+// records of the blocks below were pruned.
+func GetPruned(db serodb.Getter) uint64 {
+	if enc, err := db.Get(prunedKey); err != nil || len(enc) != 8 {
+		return 0
+	} else {
+		return binary.BigEndian.Uint64(enc)
+	}
+}
+
+// PutPruneAttempt records the head block the last pruning was started at.
+func PutPruneAttempt(db serodb.Putter, num uint64) {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], num)
+	db.Put(pruneAttemptKey, enc[:])
+}
+
+// GetPruneAttempt returns the head block the last pruning was started at,
+// whether it succeeded or not.
+func GetPruneAttempt(db serodb.Getter) uint64 {
+	if enc, err := db.Get(pruneAttemptKey); err != nil || len(enc) != 8 {
+		return 0
+	} else {
+		return binary.BigEndian.Uint64(enc)
+	}
+}
+
+// ParseBlockKey returns the block number of a key made by BlockKey.
+func ParseBlockKey(key []byte) (num uint64, ok bool) {
+	if !bytes.HasPrefix(key, BlockKeyPrefix) {
+		return
+	}
+	body := key[len(BlockKeyPrefix):]
+	if len(body) < 1+len(c_type.Uint256{}) {
+		return
+	}
+	num = new(big.Int).SetBytes(body[:len(body)-1-len(c_type.Uint256{})]).Uint64()
+	ok = true
+	return
+}
+
+func DeleteBlock(db serodb.Deleter, num uint64, hash *c_type.Uint256) {
+	db.Delete(BlockKey(num, hash))
+}
+
+func DeleteRoot(db serodb.Deleter, root *c_type.Uint256, rs *RootState) {
+	db.Delete(Root2TxHashKey(root))
+	if rs.OS.RootCM != nil {
+		db.Delete(RootCM2RootKey(rs.OS.RootCM))
+	}
+}
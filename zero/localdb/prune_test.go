@@ -0,0 +1,31 @@
+package localdb
+
+import (
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+func TestParseBlockKey(t *testing.T) {
+	hash := c_type.Uint256{1, 2, 3}
+	for _, num := range []uint64{0, 1, 255, 256, 1234567} {
+		if got, ok := ParseBlockKey(BlockKey(num, &hash)); !ok || got != num {
+			t.Errorf("block %d: parsed %d, ok %v", num, got, ok)
+		}
+	}
+	if _, ok := ParseBlockKey(PkgKey(&hash)); ok {
+		t.Errorf("parsed a pkg key as a block key")
+	}
+}
+
+func TestPruned(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	if num := GetPruned(db); num != 0 {
+		t.Fatalf("empty database pruned below %d", num)
+	}
+	PutPruned(db, 90000)
+	if num := GetPruned(db); num != 90000 {
+		t.Fatalf("pruned below %d, want 90000", num)
+	}
+}
@@ -471,6 +471,12 @@ func (self *StakeState) GetShare(key common.Hash) *Share {
 	return item.(*Share)
 }
 
+// ShareState returns the state hash of the share of the given id, or nil if
+// there is none.
+func (self *StakeState) ShareState(id []byte) []byte {
+	return self.shareObj.GetState(id)
+}
+
 func GetStakePoolByBlockNumber(getter serodb.Getter, id common.Hash, blockHash common.Hash, blockNumber uint64) *StakePool {
 	records := state.StakeDB.GetBlockRecords(getter, blockNumber, &blockHash)
 	for _, record := range records {
@@ -496,6 +502,12 @@ func (self *StakeState) GetStakePool(poolId common.Hash) *StakePool {
 	return item.(*StakePool)
 }
 
+// StakePoolState returns the state hash of the stake pool of the given id, or
+// nil if there is none.
+func (self *StakeState) StakePoolState(id []byte) []byte {
+	return self.stakePoolObj.GetState(id)
+}
+
 func GetBlockRecords(getter serodb.Getter, blockHash common.Hash, blockNumber uint64) (shares []*Share, pools []*StakePool) {
 	records := state.StakeDB.GetBlockRecords(getter, blockNumber, &blockHash)
 	for _, record := range records {
@@ -1,6 +1,7 @@
 package stake
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
 	"testing"
@@ -40,6 +41,27 @@ func TestAddShare(t *testing.T) {
 	//fmt.Println(state.ShareSize())
 }
 
+func TestStakeStates(t *testing.T) {
+	state, _ := newState()
+	share := &Share{PKr: c_type.PKr{1}, Value: big.NewInt(10000), InitNum: 10, Num: 10}
+	state.AddPendingShare(share)
+	pool := &StakePool{PKr: c_type.PKr{2}, Amount: big.NewInt(10000)}
+	state.AddStakePool(pool)
+
+	if hash := state.ShareState(share.Id()); !bytes.Equal(hash, share.State()) {
+		t.Fatalf("share state mismatch: have %x, want %x", hash, share.State())
+	}
+	if hash := state.StakePoolState(pool.Id()); !bytes.Equal(hash, pool.State()) {
+		t.Fatalf("pool state mismatch: have %x, want %x", hash, pool.State())
+	}
+	if hash := state.ShareState(pool.Id()); hash != nil {
+		t.Fatalf("unknown share state: %x", hash)
+	}
+	if hash := state.StakePoolState(share.Id()); hash != nil {
+		t.Fatalf("unknown pool state: %x", hash)
+	}
+}
+
 func TestCaleAvePrice(t *testing.T) {
 	state, _ := newState()
 	//var pkr c_type.PKr
@@ -97,3 +97,15 @@ func getPayPeriod() uint64 {
 	}
 	return payWindow
 }
+
+// GetStatisticsMissWindow returns the number of blocks the miss rate of the
+// votes is computed over.
+func GetStatisticsMissWindow() uint64 {
+	return getStatisticsMissWindow()
+}
+
+// MinMissRate returns the miss rate of the votes above which the blocks no
+// longer need two votes.
+func MinMissRate() float64 {
+	return minMissRate
+}
@@ -49,6 +49,12 @@ func (self *Param) CalcRoot(value *c_type.Uint256, pos uint64, paths *[DEPTH]c_t
 	return
 }
 
+// AnchorKey returns the state object and key under which the anchor of a tree
+// is stored.
+func (self *Param) AnchorKey(treeIndex uint64) (obj c_type.PKr, key c_type.Uint256) {
+	return self.obj, indexPathKey(1, treeIndex)
+}
+
 func (self *Param) createEmpty() (ret [c_type.DEPTH + 1]c_type.Uint256) {
 	ret[0] = c_type.Empty_Uint256
 	for i := 1; i <= c_type.DEPTH; i++ {
@@ -161,6 +167,51 @@ func (self *MerkleTree) GetPaths(value c_type.Uint256) (pos uint64, paths [DEPTH
 	return
 }
 
+// HasLeaf returns whether value is a leaf of the tree, GetPaths panics otherwise.
+func (self *MerkleTree) HasLeaf(value c_type.Uint256) bool {
+	leafIndex := c_type.Uint256_To_Uint64(self.db.GetState(&self.param.obj, leafKey(value).NewRef()).NewRef())
+	return leafIndex != 0
+}
+
+// AnchorOf returns the root the tree had right after value was appended, which
+// is the root of the out value is the commitment of. The left brothers on the
+// path of value were complete by then, so they still hold the same values.
+func (self *MerkleTree) AnchorOf(value c_type.Uint256) (anchor c_type.Uint256, ok bool) {
+	leafIndex := c_type.Uint256_To_Uint64(self.db.GetState(&self.param.obj, leafKey(value).NewRef()).NewRef())
+	if leafIndex == 0 {
+		return
+	}
+	treeIndex := self.GetTreeIndex(value)
+
+	anchor = value
+	depth := toDepth(leafIndex)
+	for leafIndex != 1 {
+		brotherIndex := brother(leafIndex)
+		var brotherValue c_type.Uint256
+		if brotherIndex > leafIndex {
+			brotherValue = self.param.EmptyRoots()[depth]
+		} else {
+			brotherValue = self.db.GetState(&self.param.obj, indexPathKey(brotherIndex, treeIndex).NewRef())
+			if brotherValue == c_type.Empty_Uint256 {
+				return
+			}
+		}
+		if leafIndex%2 == 0 {
+			anchor = self.param.combine(&anchor, &brotherValue)
+		} else {
+			anchor = self.param.combine(&brotherValue, &anchor)
+		}
+		leafIndex = parent(leafIndex)
+		depth++
+	}
+	return anchor, true
+}
+
+// GetTreeIndex returns the index of the tree holding value.
+func (self *MerkleTree) GetTreeIndex(value c_type.Uint256) uint64 {
+	return c_type.Uint256_To_Uint64(self.db.GetState(&self.param.obj, treeKey(value).NewRef()).NewRef())
+}
+
 func (self *MerkleTree) nextLeafIndex() uint64 {
 	leafIndex := self.getCurrentLeafIndex()
 	if leafIndex == self.param.cap {
@@ -42,8 +42,10 @@ func (self *Data) GetOut(tr tri.Tri, root *c_type.Uint256) (src *localdb.OutStat
 	if self.Roots.Has(tr, root) {
 		var rt *localdb.RootState
 		if r, ok := self.Root2Out[*root]; !ok {
-			rt = localdb.GetRoot(tr.GlobalGetter(), root)
-			self.Root2Out[*root] = *rt
+			// The record of a spent root may have been pruned
+			if rt = localdb.GetRoot(tr.GlobalGetter(), root); rt != nil {
+				self.Root2Out[*root] = *rt
+			}
 		} else {
 			rt = &r
 		}
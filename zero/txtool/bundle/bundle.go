@@ -0,0 +1,262 @@
+// Package bundle defines the files carrying a private transaction to an
+// air-gapped signer and its signature back. An unsigned bundle holds all the
+// signer needs, the transaction parameters with the witnesses of the inputs,
+// together with a summary for the owner to review. Both kinds are versioned
+// and checksummed against corruption while being copied around.
+package bundle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// Version is the bundle format written by this package.
+const Version = 1
+
+var (
+	ErrVersion   = errors.New("unsupported bundle version")
+	ErrChecksum  = errors.New("bundle checksum mismatch")
+	ErrSummary   = errors.New("bundle summary doesn't match the transaction")
+	ErrNoWitness = errors.New("transaction input without witness")
+	ErrUnsigned  = errors.New("bundle transaction not signed")
+)
+
+// Output is a transfer of the transaction as shown to the signer.
+type Output struct {
+	To       string
+	Change   bool   `json:",omitempty"`
+	Currency string `json:",omitempty"`
+	Value    string `json:",omitempty"`
+	Category string `json:",omitempty"`
+	Ticket   string `json:",omitempty"`
+}
+
+// Summary is the human-readable content of a transaction.
+type Summary struct {
+	From     string
+	Inputs   int
+	Outputs  []Output
+	Commands []string `json:",omitempty"`
+	Fee      string
+	Gas      uint64
+	GasPrice string
+}
+
+func newOutput(to *c_type.PKr, asset *assets.Asset, from *c_type.PKr) Output {
+	out := Output{To: base58.Encode(to[:]), Change: *to == *from}
+	if asset.Tkn != nil {
+		out.Currency = utils.Uint256ToCurrency(&asset.Tkn.Currency)
+		out.Value = asset.Tkn.Value.ToInt().String()
+	}
+	if asset.Tkt != nil {
+		out.Category = utils.Uint256ToCurrency(&asset.Tkt.Category)
+		out.Ticket = hexutil.Encode(asset.Tkt.Value[:])
+	}
+	return out
+}
+
+// Summarize describes the transfers and commands of a transaction.
+func Summarize(param *txtool.GTxParam) Summary {
+	from := &param.From.PKr
+	summary := Summary{
+		From:   base58.Encode(from[:]),
+		Inputs: len(param.Ins),
+		Fee:    param.Fee.Value.ToInt().String() + " " + utils.Uint256ToCurrency(&param.Fee.Currency),
+		Gas:    param.Gas,
+	}
+	if param.GasPrice != nil {
+		summary.GasPrice = param.GasPrice.String()
+	}
+	for i := range param.Outs {
+		summary.Outputs = append(summary.Outputs, newOutput(&param.Outs[i].PKr, &param.Outs[i].Asset, from))
+	}
+	cmds := &param.Cmds
+	if cmds.BuyShare != nil {
+		summary.Commands = append(summary.Commands, fmt.Sprintf("BuyShare %v vote %s", cmds.BuyShare.Value.ToInt(), base58.Encode(cmds.BuyShare.Vote[:])))
+	}
+	if cmds.RegistPool != nil {
+		summary.Commands = append(summary.Commands, fmt.Sprintf("RegistPool %v vote %s fee rate %d", cmds.RegistPool.Value.ToInt(), base58.Encode(cmds.RegistPool.Vote[:]), cmds.RegistPool.FeeRate))
+	}
+	if cmds.ClosePool != nil {
+		summary.Commands = append(summary.Commands, "ClosePool")
+	}
+	if cmds.Contract != nil {
+		to := "create"
+		if cmds.Contract.To != nil {
+			to = base58.Encode(cmds.Contract.To[:])
+			summary.Outputs = append(summary.Outputs, newOutput(cmds.Contract.To, &cmds.Contract.Asset, from))
+		}
+		summary.Commands = append(summary.Commands, fmt.Sprintf("Contract %s with %d bytes of data", to, len(cmds.Contract.Data)))
+	}
+	if cmds.PkgCreate != nil {
+		summary.Commands = append(summary.Commands, fmt.Sprintf("PkgCreate %s", hexutil.Encode(cmds.PkgCreate.Id[:])))
+		summary.Outputs = append(summary.Outputs, newOutput(&cmds.PkgCreate.PKr, &cmds.PkgCreate.Asset, from))
+	}
+	if cmds.PkgTransfer != nil {
+		summary.Commands = append(summary.Commands, fmt.Sprintf("PkgTransfer %s to %s", hexutil.Encode(cmds.PkgTransfer.Id[:]), base58.Encode(cmds.PkgTransfer.PKr[:])))
+	}
+	if cmds.PkgClose != nil {
+		summary.Commands = append(summary.Commands, fmt.Sprintf("PkgClose %s", hexutil.Encode(cmds.PkgClose.Id[:])))
+	}
+	return summary
+}
+
+// String formats the summary for a terminal.
+func (s *Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From:    %s\n", s.From)
+	fmt.Fprintf(&b, "Inputs:  %d\n", s.Inputs)
+	for _, out := range s.Outputs {
+		kind := "To:     "
+		if out.Change {
+			kind = "Change: "
+		}
+		if out.Currency != "" {
+			fmt.Fprintf(&b, "%s %s %s %s\n", kind, out.To, out.Value, out.Currency)
+		}
+		if out.Category != "" {
+			fmt.Fprintf(&b, "%s %s ticket %s %s\n", kind, out.To, out.Category, out.Ticket)
+		}
+	}
+	for _, cmd := range s.Commands {
+		fmt.Fprintf(&b, "Command: %s\n", cmd)
+	}
+	fmt.Fprintf(&b, "Fee:     %s (gas %d at %s)\n", s.Fee, s.Gas, s.GasPrice)
+	return b.String()
+}
+
+// checksum hashes the JSON encoding of the bundle content.
+func checksum(content interface{}) (common.Hash, error) {
+	blob, err := json.Marshal(content)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(blob), nil
+}
+
+type unsignedContent struct {
+	Version int
+	ChainId *hexutil.Big
+	Summary Summary
+	Param   txtool.GTxParam
+}
+
+// Unsigned is the bundle taken to the signer.
+type Unsigned struct {
+	unsignedContent
+	Checksum common.Hash
+}
+
+// NewUnsigned bundles the parameters of a transaction, whose inputs must
+// carry their witnesses already, for the signer.
+func NewUnsigned(chainId *big.Int, param *txtool.GTxParam) (*Unsigned, error) {
+	for _, in := range param.Ins {
+		if in.Witness.Anchor == (c_type.Uint256{}) {
+			return nil, ErrNoWitness
+		}
+	}
+	u := &Unsigned{unsignedContent: unsignedContent{
+		Version: Version,
+		ChainId: (*hexutil.Big)(chainId),
+		Summary: Summarize(param),
+		Param:   *param,
+	}}
+	var err error
+	if u.Checksum, err = checksum(&u.unsignedContent); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Verify checks the version and the checksum of the bundle and that its
+// summary describes the transaction.
+func (u *Unsigned) Verify() error {
+	if u.Version != Version {
+		return ErrVersion
+	}
+	if sum, err := checksum(&u.unsignedContent); err != nil {
+		return err
+	} else if sum != u.Checksum {
+		return ErrChecksum
+	}
+	want, err := json.Marshal(Summarize(&u.Param))
+	if err != nil {
+		return err
+	}
+	if have, _ := json.Marshal(&u.Summary); string(have) != string(want) {
+		return ErrSummary
+	}
+	return nil
+}
+
+// DecodeUnsigned parses and verifies an unsigned bundle.
+func DecodeUnsigned(blob []byte) (*Unsigned, error) {
+	u := new(Unsigned)
+	if err := json.Unmarshal(blob, u); err != nil {
+		return nil, err
+	}
+	if err := u.Verify(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+type signedContent struct {
+	Version int
+	ChainId *hexutil.Big
+	Summary Summary
+	Tx      txtool.GTx
+}
+
+// Signed is the bundle brought back from the signer to be committed.
+type Signed struct {
+	signedContent
+	Checksum common.Hash
+}
+
+// NewSigned bundles the signed transaction of an unsigned bundle.
+func NewSigned(u *Unsigned, tx *txtool.GTx) (*Signed, error) {
+	s := &Signed{signedContent: signedContent{
+		Version: Version,
+		ChainId: u.ChainId,
+		Summary: u.Summary,
+		Tx:      *tx,
+	}}
+	var err error
+	if s.Checksum, err = checksum(&s.signedContent); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Verify checks the version and the checksum of the bundle and that it holds
+// a signed transaction of the given chain.
+func (s *Signed) Verify(chainId *big.Int) error {
+	if s.Version != Version {
+		return ErrVersion
+	}
+	if sum, err := checksum(&s.signedContent); err != nil {
+		return err
+	} else if sum != s.Checksum {
+		return ErrChecksum
+	}
+	if s.ChainId == nil || s.ChainId.ToInt().Cmp(chainId) != 0 {
+		return fmt.Errorf("bundle for chain %v, expected %v", s.ChainId, chainId)
+	}
+	if s.Tx.Hash == (c_type.Uint256{}) {
+		return ErrUnsigned
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package bundle
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func testParam() *txtool.GTxParam {
+	param := &txtool.GTxParam{
+		Gas:      25000,
+		GasPrice: big.NewInt(1000000000),
+		Fee:      assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.U256(*big.NewInt(25000000000000))},
+	}
+	param.From.PKr[0] = 1
+	param.Ins = []txtool.GIn{{}}
+	param.Ins[0].Out.Root[0] = 2
+	param.Ins[0].Witness.Anchor[0] = 3
+
+	to := c_type.PKr{4}
+	param.Outs = []txtool.GOut{
+		{PKr: to, Asset: assets.Asset{Tkn: &assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.U256(*big.NewInt(1000))}}},
+		{PKr: param.From.PKr, Asset: assets.Asset{Tkn: &assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.U256(*big.NewInt(5))}}},
+	}
+	return param
+}
+
+func TestUnsignedRoundtrip(t *testing.T) {
+	unsigned, err := NewUnsigned(big.NewInt(2019), testParam())
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	if n := len(unsigned.Summary.Outputs); n != 2 {
+		t.Fatalf("summary outputs mismatch: have %d, want 2", n)
+	}
+	if out := unsigned.Summary.Outputs[0]; out.Value != "1000" || out.Currency != "SERO" || out.Change {
+		t.Errorf("transfer summary mismatch: %+v", out)
+	}
+	if !unsigned.Summary.Outputs[1].Change {
+		t.Errorf("change not detected")
+	}
+	blob, err := json.Marshal(unsigned)
+	if err != nil {
+		t.Fatalf("failed to encode bundle: %v", err)
+	}
+	decoded, err := DecodeUnsigned(blob)
+	if err != nil {
+		t.Fatalf("failed to decode bundle: %v", err)
+	}
+	if decoded.Checksum != unsigned.Checksum || decoded.ChainId.ToInt().Int64() != 2019 {
+		t.Errorf("decoded bundle mismatch")
+	}
+}
+
+func TestUnsignedTampering(t *testing.T) {
+	unsigned, _ := NewUnsigned(big.NewInt(2019), testParam())
+
+	// Changing the transaction breaks the checksum
+	changed := *unsigned
+	changed.Param.Outs = append([]txtool.GOut{}, unsigned.Param.Outs...)
+	changed.Param.Outs[0].PKr[1] = 9
+	if err := changed.Verify(); err != ErrChecksum {
+		t.Errorf("changed transaction error mismatch: have %v, want %v", err, ErrChecksum)
+	}
+	// Changing the summary along with the checksum breaks their match
+	lying := *unsigned
+	lying.Summary.Outputs = append([]Output{}, unsigned.Summary.Outputs...)
+	lying.Summary.Outputs[0].Value = "1"
+	lying.Checksum, _ = checksum(&lying.unsignedContent)
+	if err := lying.Verify(); err != ErrSummary {
+		t.Errorf("changed summary error mismatch: have %v, want %v", err, ErrSummary)
+	}
+	// Bundles of other versions are rejected
+	future := *unsigned
+	future.Version = Version + 1
+	if err := future.Verify(); err != ErrVersion {
+		t.Errorf("version error mismatch: have %v, want %v", err, ErrVersion)
+	}
+}
+
+func TestUnsignedWitness(t *testing.T) {
+	param := testParam()
+	param.Ins[0].Witness = txtool.Witness{}
+	if _, err := NewUnsigned(big.NewInt(2019), param); err != ErrNoWitness {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrNoWitness)
+	}
+}
+
+func TestSignedVerify(t *testing.T) {
+	unsigned, _ := NewUnsigned(big.NewInt(2019), testParam())
+	tx := &txtool.GTx{}
+	tx.Hash[0] = 7
+	signed, err := NewSigned(unsigned, tx)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	blob, _ := json.Marshal(signed)
+	decoded := new(Signed)
+	if err := json.Unmarshal(blob, decoded); err != nil {
+		t.Fatalf("failed to decode bundle: %v", err)
+	}
+	if err := decoded.Verify(big.NewInt(2019)); err != nil {
+		t.Errorf("valid bundle rejected: %v", err)
+	}
+	if err := decoded.Verify(big.NewInt(1)); err == nil {
+		t.Errorf("bundle of another chain accepted")
+	}
+	decoded.Tx.Gas++
+	if err := decoded.Verify(big.NewInt(2019)); err != ErrChecksum {
+		t.Errorf("changed transaction error mismatch: have %v, want %v", err, ErrChecksum)
+	}
+}
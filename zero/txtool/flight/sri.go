@@ -60,7 +60,7 @@ func GetOut(root *c_type.Uint256, num uint64) (out *localdb.RootState) {
 
 func GetBlock(num uint64, hash *common.Hash) (ret *localdb.Block) {
 	ret = localdb.GetBlock(txtool.Ref_inst.Bc.GetDB(), num, hash.HashToUint256())
-	if ret == nil {
+	if ret == nil && num >= localdb.GetPruned(txtool.Ref_inst.Bc.GetDB()) {
 		temp_state := txtool.Ref_inst.Bc.CurrentState(hash)
 		if temp_state == nil {
 			panic(fmt.Sprintf("new zstate error: %v:%v !", num, hash))
@@ -77,6 +77,10 @@ func GetBlock(num uint64, hash *common.Hash) (ret *localdb.Block) {
 
 func (self *SRI) GetBlocksInfoByDelay(start uint64, count uint64, delay uint64) (blocks []txtool.Block, e error) {
 	stable_num := txtool.Ref_inst.GetDelayedNum(delay)
+	if pruned := localdb.GetPruned(txtool.Ref_inst.Bc.GetDB()); start < pruned {
+		e = fmt.Errorf("GetBlocksInfo blocks below %v were pruned, start: %v", pruned, start)
+		return
+	}
 	if start <= stable_num {
 		if stable_num-start+1 < count {
 			count = stable_num - start + 1
@@ -4,6 +4,7 @@ import (
 	"github.com/sero-cash/go-czero-import/c_superzk"
 	"github.com/sero-cash/go-sero/zero/txs/stx"
 	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/zconfig"
 )
 
 type prove_ctx struct {
@@ -152,6 +153,9 @@ func ProveTx(tx *stx.T, param *txtool.GTxParam) (ctx prove_ctx, e error) {
 	ctx.tx = *tx
 	ctx.prepare()
 
+	if zconfig.IsSkipProof() {
+		return
+	}
 	if e = ctx.prove(); e != nil {
 		return
 	}
@@ -140,7 +140,7 @@ func BuildTxParam(
 			pkr = CreatePkr(&pk, 0)
 		}
 		ck.AddOut(&reception.Asset)
-		Outs = append(Outs, txtool.GOut{PKr: pkr, Asset: reception.Asset})
+		Outs = append(Outs, txtool.GOut{PKr: pkr, Asset: reception.Asset, Memo: reception.Memo})
 	}
 
 	if cmdsAsset := param.Cmds.OutAsset(); cmdsAsset != nil {
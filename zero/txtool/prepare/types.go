@@ -19,6 +19,7 @@ import (
 type Reception struct {
 	Addr  c_type.PKr
 	Asset assets.Asset
+	Memo  c_type.Uint512
 }
 
 type PkgCloseCmd struct {
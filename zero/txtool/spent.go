@@ -0,0 +1,36 @@
+package txtool
+
+import (
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/core/types"
+)
+
+// SpentBy maps the nils and the roots spent by the txs of a block to the hash
+// of the tx spending them. The outs of the Z ins are only found by their nil.
+func SpentBy(block *types.Block) map[c_type.Uint256]c_type.Uint256 {
+	spentBy := map[c_type.Uint256]c_type.Uint256{}
+	if block == nil {
+		return spentBy
+	}
+	for _, tx := range block.Transactions() {
+		var hash c_type.Uint256
+		copy(hash[:], tx.Hash().Bytes())
+		ztx := tx.GetZZSTX()
+		for _, in := range ztx.Desc_O.Ins {
+			spentBy[in.Root] = hash
+		}
+		for _, in := range ztx.Desc_Z.Ins {
+			spentBy[in.Nil] = hash
+		}
+		for _, in := range ztx.Tx1.Ins_P0 {
+			spentBy[in.Root], spentBy[in.Nil] = hash, hash
+		}
+		for _, in := range ztx.Tx1.Ins_P {
+			spentBy[in.Root], spentBy[in.Nil] = hash, hash
+		}
+		for _, in := range ztx.Tx1.Ins_C {
+			spentBy[in.Nil] = hash
+		}
+	}
+	return spentBy
+}
@@ -1,8 +1,14 @@
 package verify_1
 
-import "github.com/sero-cash/go-czero-import/seroparam"
+import (
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+)
 
 func (self *verifyWithoutStateCtx) ProcessVerifyProof() {
+	if zconfig.IsSkipProof() {
+		return
+	}
 	for _, in := range self.tx.Tx1.Ins_C {
 		g := verify_input_desc{}
 		g.anchor = in.Anchor
@@ -2,6 +2,7 @@ package exchange
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
@@ -48,6 +49,7 @@ type Account struct {
 	isChanged     bool
 	nextMergeTime time.Time
 	version       int
+	label         string // Label of the watch-only accounts
 }
 
 type PkrAccount struct {
@@ -104,12 +106,16 @@ type Exchange struct {
 	db             *serodb.LDBDatabase
 	txPool         *core.TxPool
 	accountManager *accounts.Manager
+	auditKey       *ecdsa.PrivateKey // Signs the audit bundles
 
 	accounts    sync.Map
 	pkrAccounts sync.Map
 
 	usedFlag sync.Map
 	numbers  sync.Map
+	rescans  sync.Map // Accounts being rescanned, see Rescan
+
+	indexLock sync.Mutex // Serializes the indexing and the rescans
 
 	feed    event.Feed
 	updater event.Subscription        // Wallet update subscriptions for all backends
@@ -124,7 +130,7 @@ func CurrentExchange() *Exchange {
 	return current_exchange
 }
 
-func NewExchange(dbpath string, txPool *core.TxPool, accountManager *accounts.Manager, autoMerge bool) (exchange *Exchange) {
+func NewExchange(dbpath string, txPool *core.TxPool, accountManager *accounts.Manager, auditKey *ecdsa.PrivateKey, autoMerge bool) (exchange *Exchange) {
 
 	update := make(chan accounts.WalletEvent, 1)
 	updater := accountManager.Subscribe(update)
@@ -132,6 +138,7 @@ func NewExchange(dbpath string, txPool *core.TxPool, accountManager *accounts.Ma
 	exchange = &Exchange{
 		txPool:         txPool,
 		accountManager: accountManager,
+		auditKey:       auditKey,
 		update:         update,
 		updater:        updater,
 	}
@@ -148,10 +155,18 @@ func NewExchange(dbpath string, txPool *core.TxPool, accountManager *accounts.Ma
 	for _, w := range accountManager.Wallets() {
 		exchange.initWallet(w)
 	}
+	exchange.loadWatchAccounts()
 
 	exchange.pkrAccounts = sync.Map{}
 	exchange.usedFlag = sync.Map{}
 
+	exchange.loadRescans()
+
+	go func() {
+		exchange.rebuildTicketIndex()
+		exchange.rebuildTicketEvents()
+	}()
+
 	AddJob("0/10 * * * * ?", exchange.fetchBlockInfo)
 
 	if autoMerge {
@@ -165,7 +180,13 @@ func NewExchange(dbpath string, txPool *core.TxPool, accountManager *accounts.Ma
 
 func (self *Exchange) initWallet(w accounts.Wallet) {
 
-	if _, ok := self.accounts.Load(w.Accounts()[0].GetPk()); !ok {
+	if value, ok := self.accounts.Load(w.Accounts()[0].GetPk()); ok {
+		// A watch-only account can spend once its wallet is loaded
+		if account := value.(*Account); account.wallet == nil {
+			account.wallet = w
+			account.version = w.Accounts()[0].Version
+		}
+	} else {
 		account := Account{}
 		account.wallet = w
 		account.pk = w.Accounts()[0].GetPk().NewRef()
@@ -263,7 +284,13 @@ func (self *Exchange) updateAccount() {
 				self.initWallet(event.Wallet)
 			case accounts.WalletDropped:
 				address := event.Wallet.Accounts()[0].Address
-				self.numbers.Delete(address.ToUint512())
+				if _, err := self.getWatch(address.ToUint512()); err == nil {
+					if account := self.getAccountByPk(address.ToUint512()); account != nil {
+						account.wallet = nil
+					}
+				} else {
+					self.numbers.Delete(address.ToUint512())
+				}
 			}
 			self.lock.Unlock()
 
@@ -314,6 +341,9 @@ func (self *Exchange) GetPkr(pk *c_type.Uint512, index *c_type.Uint256) (pkr c_t
 		return pkr, errors.New("not found Pk")
 	} else {
 		acc := value.(*Account)
+		if acc.wallet == nil {
+			return superzk.Pk2PKr(acc.pk, index), nil
+		}
 		return acc.wallet.Accounts()[0].GetPkr(index), nil
 
 	}
@@ -517,6 +547,27 @@ type BlockInfo struct {
 	Outs []Utxo
 }
 
+// merge adds the records of another index of the block which are missing.
+func (self *BlockInfo) merge(other *BlockInfo) {
+	outs, ins := map[c_type.Uint256]bool{}, map[c_type.Uint256]bool{}
+	for _, out := range self.Outs {
+		outs[out.Root] = true
+	}
+	for _, root := range self.Ins {
+		ins[root] = true
+	}
+	for _, out := range other.Outs {
+		if !outs[out.Root] {
+			self.Outs = append(self.Outs, out)
+		}
+	}
+	for _, root := range other.Ins {
+		if !ins[root] {
+			self.Ins = append(self.Ins, root)
+		}
+	}
+}
+
 func (self *Exchange) GetBlocksInfo(start, end uint64) (blocks []BlockInfo, err error) {
 	iterator := self.db.NewIteratorWithPrefix(blockPrefix)
 	for ok := iterator.Seek(blockKey(start)); ok; ok = iterator.Next() {
@@ -652,6 +703,11 @@ func (self *Exchange) genTx(account *Account, param *prepare.BeforeTxParam) (txP
 		return
 	}
 
+	if account.wallet == nil {
+		self.ClearTxParam(txParam)
+		e = ErrWatchOnly
+		return
+	}
 	var seed *address.Seed
 	if seed, e = account.wallet.GetSeed(); e != nil {
 		self.ClearTxParam(txParam)
@@ -868,6 +924,20 @@ func (self *Exchange) fetchBlockInfo() {
 }
 
 func (self *Exchange) fetchAndIndexUtxo(start, countBlock uint64, pks []c_type.Uint512) (count int) {
+	self.indexLock.Lock()
+	defer self.indexLock.Unlock()
+
+	// Skip the accounts rewound by a rescan since the cursors were read
+	indexed := []c_type.Uint512{}
+	for _, pk := range pks {
+		if value, ok := self.numbers.Load(pk); ok && value.(uint64) == start {
+			indexed = append(indexed, pk)
+		}
+	}
+	if len(indexed) == 0 {
+		return
+	}
+	pks = indexed
 
 	blocks, err := flight.SRI_Inst.GetBlocksInfo(start, countBlock)
 	if err != nil {
@@ -906,7 +976,7 @@ func (self *Exchange) fetchAndIndexUtxo(start, countBlock uint64, pks []c_type.U
 				continue
 			}
 
-			utxo := Utxo{Pkr: *pkr, Root: out.Root, Nil: dout.Nil, TxHash: out.State.TxHash, Num: out.State.Num, Asset: dout.Asset, IsZ: out.State.OS.IsZero()}
+			utxo := Utxo{Pkr: *pkr, Root: out.Root, Nil: dout.Nil, TxHash: out.State.TxHash, Num: out.State.Num, Asset: dout.Asset, IsZ: out.State.OS.IsZero(), Memo: decodeMemo(&dout.Memo)}
 			nilsMap[utxo.Root] = utxo
 			nilsMap[utxo.Nil] = utxo
 
@@ -961,27 +1031,49 @@ func (self *Exchange) fetchAndIndexUtxo(start, countBlock uint64, pks []c_type.U
 
 	self.indexPkgs(pks, batch, blocks)
 
+	if err = self.writeIndex(batch, pks, uint64(blocks[len(blocks)-1].Num)+1, utxosMap, blockMap, nils); err != nil {
+		return
+	}
+	count = len(blocks)
+	return
+}
+
+// writeIndex writes the outputs and the spendings of the fetched blocks with
+// the next height to index for the accounts.
+func (self *Exchange) writeIndex(batch serodb.Batch, pks []c_type.Uint512, num uint64, utxosMap map[PkKey][]Utxo, blockMap map[uint64]*BlockInfo, nils []c_type.Uint256) (err error) {
 	var roots []c_type.Uint256
 	if len(utxosMap) > 0 || len(nils) > 0 {
 		if roots, err = self.indexBlocks(batch, utxosMap, blockMap, nils); err != nil {
 			log.Error("indexBlocks ", "error", err)
 			return
 		}
+		if err = self.indexTickets(batch, utxosMap, blockMap); err != nil {
+			log.Error("indexTickets ", "error", err)
+			return
+		}
+		self.indexMemos(batch, utxosMap)
 	}
 
-	count = len(blocks)
-	num := uint64(blocks[count-1].Num) + 1
 	// "NUM"+PK  => Num
 	data := utils.EncodeNumber(num)
+	rescanned := []c_type.Uint512{}
 	for _, pk := range pks {
 		batch.Put(numKey(pk), data)
+		if status, ok := self.GetRescan(pk); ok && num >= status.Target {
+			batch.Delete(rescanKey(pk))
+			rescanned = append(rescanned, pk)
+		}
 	}
 
-	err = batch.Write()
-	if err == nil {
-		for _, pk := range pks {
-			self.numbers.Store(pk, num)
-		}
+	if err = batch.Write(); err != nil {
+		log.Error("Exchange index write", "error", err)
+		return
+	}
+	for _, pk := range pks {
+		self.numbers.Store(pk, num)
+	}
+	for _, pk := range rescanned {
+		self.rescans.Delete(pk)
 	}
 
 	for _, root := range roots {
@@ -995,6 +1087,14 @@ func (self *Exchange) indexBlocks(batch serodb.Batch, utxosMap map[PkKey][]Utxo,
 	ops := map[string]string{}
 
 	for num, blockInfo := range blockMap {
+		// Keep the records of the accounts indexed apart, by another pass or
+		// after a rescan
+		if data, _ := self.db.Get(blockKey(num)); data != nil {
+			var stored BlockInfo
+			if e := rlp.DecodeBytes(data, &stored); e == nil {
+				blockInfo.merge(&stored)
+			}
+		}
 		data, e := rlp.EncodeToBytes(&blockInfo)
 		if e != nil {
 			err = e
@@ -1168,7 +1268,21 @@ type MergeUtxos struct {
 	//tickets map[c_type.Uint256]c_type.Uint256
 }
 
-var default_fee_value = new(big.Int).Mul(big.NewInt(25000), big.NewInt(1000000000))
+var (
+	default_gas       = big.NewInt(25000)
+	default_gas_price = big.NewInt(1000000000)
+	default_fee_value = new(big.Int).Mul(default_gas, default_gas_price)
+)
+
+// DefaultGasPrice returns the gas price of the txs made by the wallet.
+func DefaultGasPrice() *big.Int {
+	return new(big.Int).Set(default_gas_price)
+}
+
+// DefaultFee returns the fee of the txs made by the wallet.
+func DefaultFee() *big.Int {
+	return new(big.Int).Set(default_fee_value)
+}
 
 func (self *Exchange) getMergeUtxos(from *c_type.Uint512, currency string, zcount int, left int, icount int) (mu MergeUtxos, e error) {
 	if zcount > 400 {
@@ -1250,7 +1364,7 @@ func (self *Exchange) GenMergeTx(mp *MergeParam) (txParam *txtool.GTxParam, e er
 		return
 	}
 	if mp.To == nil {
-		mp.To = account.wallet.Accounts()[0].GetDefaultPkr(1).NewRef()
+		mp.To = account.mainPkr.NewRef()
 	}
 	var mu MergeUtxos
 	if mu, e = self.getMergeUtxos(account.pk, mp.Currency, int(mp.Zcount), int(mp.Left), int(mp.Icount)); e != nil {
@@ -1298,7 +1412,7 @@ func (self *Exchange) GenMergeTx(mp *MergeParam) (txParam *txtool.GTxParam, e er
 			utils.CurrencyToUint256("SERO"),
 			utils.U256(*default_fee_value),
 		},
-		*big.NewInt(1000000000),
+		*default_gas_price,
 		mu.list.Roots(),
 		*mp.To,
 		receptions,
@@ -1319,6 +1433,10 @@ func (self *Exchange) Merge(pk *c_type.Uint512, currency string, force bool) (co
 		return
 	}
 
+	if account.wallet == nil {
+		e = ErrWatchOnly
+		return
+	}
 	seed, err := account.wallet.GetSeed()
 	if err != nil || seed == nil {
 		e = errors.New("account is locked")
@@ -1374,7 +1492,7 @@ func (self *Exchange) Merge(pk *c_type.Uint512, currency string, force bool) (co
 				utils.CurrencyToUint256("SERO"),
 				utils.U256(*default_fee_value),
 			},
-			*big.NewInt(1000000000),
+			*default_gas_price,
 			mu.list.Roots(),
 			account.mainPkr,
 			receptions,
@@ -1410,6 +1528,9 @@ func (self *Exchange) merge() {
 	}
 	self.accounts.Range(func(key, value interface{}) bool {
 		account := value.(*Account)
+		if account.wallet == nil {
+			return true
+		}
 		if count, txhash, err := self.Merge(account.pk, "SERO", false); err != nil {
 			log.Error("autoMerge fail", "accountKey", *utils.Base58Encode(account.pk[:]), "count", count, "error", err)
 		} else {
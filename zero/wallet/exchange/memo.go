@@ -0,0 +1,72 @@
+package exchange
+
+import (
+	"errors"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/account"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+var memoPrefix = []byte("MEMO")
+
+// "MEMO" + hash(tag) + num + root => PK
+func memoKey(tag []byte, num uint64, root *c_type.Uint256) []byte {
+	key := append(memoPrefix, crypto.Keccak256(tag)...)
+	key = append(key, utils.EncodeNumber(num)...)
+	if root != nil {
+		key = append(key, root[:]...)
+	}
+	return key
+}
+
+// decodeMemo decodes the memo of a received output.
+func decodeMemo(memo *c_type.Uint512) account.Memo {
+	return account.DecodeMemo(memo)
+}
+
+// indexMemos indexes the received outputs by the tag of their memo, the index
+// is kept when the outputs are spent so that the deposits stay attributed.
+func (self *Exchange) indexMemos(batch serodb.Batch, utxosMap map[PkKey][]Utxo) {
+	for key, list := range utxosMap {
+		for _, utxo := range list {
+			if tag := utxo.Memo.Tag(); tag != nil {
+				batch.Put(memoKey(tag, utxo.Num, &utxo.Root), key.key[:])
+			}
+		}
+	}
+}
+
+// GetRecordsByMemo returns the outputs received from begin to end whose memo
+// has the tag of the given one.
+func (self *Exchange) GetRecordsByMemo(memo account.Memo, begin, end uint64) (records []Utxo, err error) {
+	tag := memo.Tag()
+	if tag == nil {
+		err = errors.New("the memo is empty")
+		return
+	}
+	prefix := memoKey(tag, 0, nil)
+	prefix = prefix[:len(prefix)-8]
+	iterator := self.db.NewIteratorWithPrefix(prefix)
+	defer iterator.Release()
+	for ok := iterator.Seek(memoKey(tag, begin, nil)); ok; ok = iterator.Next() {
+		key := iterator.Key()
+		if utils.DecodeNumber(key[len(prefix):len(prefix)+8]) >= end {
+			break
+		}
+		var root c_type.Uint256
+		copy(root[:], key[len(prefix)+8:])
+		utxo, e := self.getUtxo(root)
+		if e != nil {
+			err = e
+			return
+		}
+		if utxo.Root != root {
+			continue
+		}
+		records = append(records, utxo)
+	}
+	return
+}
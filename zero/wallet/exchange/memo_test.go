@@ -0,0 +1,77 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/zero/account"
+)
+
+// memoUtxo returns an output received with the memo written the given way.
+func memoUtxo(root byte, num uint64, memo c_type.Uint512) Utxo {
+	utxo := tokenUtxo(root, 10, num)
+	utxo.Memo = decodeMemo(&memo)
+	return utxo
+}
+
+func TestGetRecordsByMemo(t *testing.T) {
+	pk := c_type.Uint512{1}
+	ex, done := newRescanTestExchange(t, pk)
+	defer done()
+
+	typed, err := account.EncodeMemo("user42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plain c_type.Uint512
+	copy(plain[len(plain)-len("user42"):], "user42")
+	ref, err := account.EncodeMemo("ref:42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := []rescanTestBlock{
+		{num: 5, outs: []Utxo{memoUtxo(1, 5, typed), memoUtxo(2, 5, ref)}},
+		{num: 7, outs: []Utxo{memoUtxo(3, 7, plain), memoUtxo(4, 7, c_type.Uint512{})}},
+	}
+	for _, block := range blocks {
+		indexTestBlock(t, ex, pk, block)
+	}
+	ex.numbers.Store(pk, uint64(8))
+
+	check := func(memo string, begin, end uint64, roots ...byte) {
+		parsed, err := account.ParseMemo(memo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := ex.GetRecordsByMemo(parsed, begin, end)
+		if err != nil {
+			t.Fatalf("failed to get the records of %s: %v", memo, err)
+		}
+		if len(records) != len(roots) {
+			t.Fatalf("%s records mismatch: have %d, want %d", memo, len(records), len(roots))
+		}
+		for i, record := range records {
+			if record.Root != (c_type.Uint256{roots[i]}) {
+				t.Fatalf("%s record %d mismatch: have %x, want %x", memo, i, record.Root, roots[i])
+			}
+		}
+	}
+	// The typed and the plain text memos are attributed together
+	check("user42", 0, 10, 1, 3)
+	check("user42", 6, 10, 3)
+	check("user42", 0, 7, 1)
+	check("ref:42", 0, 10, 2)
+	check("ref:43", 0, 10)
+
+	// The rescanned outputs are attributed again once indexed
+	if err := ex.Rescan(pk, 6); err != nil {
+		t.Fatalf("failed to rescan: %v", err)
+	}
+	check("user42", 0, 10, 1)
+	indexTestBlock(t, ex, pk, blocks[1])
+	check("user42", 0, 10, 1, 3)
+
+	if _, err := ex.GetRecordsByMemo(account.Memo{}, 0, 10); err == nil {
+		t.Fatal("got the records of an empty memo")
+	}
+}
@@ -0,0 +1,238 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/sero-cash/go-czero-import/c_superzk"
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-czero-import/superzk"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/txtool/flight"
+	"github.com/sero-cash/go-sero/zero/txtool/generate/generate_1"
+)
+
+// maxPendingBlocks is the maximum number of blocks above the indexed ones
+// scanned for the unconfirmed outputs of an account.
+const maxPendingBlocks = 256
+
+// PendingBalance is the unconfirmed part of the balance of a currency.
+type PendingBalance struct {
+	In            *big.Int // Received in unconfirmed blocks or pending txs
+	Out           *big.Int // Spent from the confirmed balance
+	Confirmations uint64   // Blocks before all the incoming amount is confirmed
+}
+
+// PendingRecord is an output received or spent by an account that is not
+// confirmed yet. The outputs of the pending txs have neither root nor nil.
+type PendingRecord struct {
+	Utxo
+	Spent         bool
+	InPool        bool
+	Confirmations uint64
+}
+
+// pendingScan collects the unconfirmed records of an account.
+type pendingScan struct {
+	exchange *Exchange
+	account  *Account
+
+	records  []PendingRecord
+	received map[c_type.Uint256]int  // Unconfirmed outputs by root and nil
+	consumed map[int]bool            // Unconfirmed outputs spent again
+	spent    map[c_type.Uint256]bool // Confirmed outputs already spent
+}
+
+func newPendingScan(exchange *Exchange, account *Account) *pendingScan {
+	return &pendingScan{
+		exchange: exchange,
+		account:  account,
+		received: make(map[c_type.Uint256]int),
+		consumed: make(map[int]bool),
+		spent:    make(map[c_type.Uint256]bool),
+	}
+}
+
+// spend records the spending of the output with the given nil or root.
+func (self *pendingScan) spend(Nil c_type.Uint256, txHash c_type.Uint256, num uint64, inPool bool, confirmations uint64) {
+	if index, ok := self.received[Nil]; ok {
+		self.consumed[index] = true
+		return
+	}
+	value, _ := self.exchange.db.Get(nilKey(Nil))
+	if len(value) < 130 {
+		return
+	}
+	var pk c_type.Uint512
+	var root c_type.Uint256
+	copy(pk[:], value[2:66])
+	copy(root[:], value[98:130])
+	if pk != *self.account.pk || self.spent[root] {
+		return
+	}
+	utxo, err := self.exchange.getUtxo(root)
+	if err != nil || utxo.Root != root {
+		return
+	}
+	self.spent[root] = true
+	utxo.TxHash, utxo.Num = txHash, num
+	self.records = append(self.records, PendingRecord{Utxo: utxo, Spent: true, InPool: inPool, Confirmations: confirmations})
+}
+
+// receive records an unconfirmed output of the account.
+func (self *pendingScan) receive(utxo Utxo, inPool bool, confirmations uint64) {
+	index := len(self.records)
+	if !inPool {
+		self.received[utxo.Root] = index
+		self.received[utxo.Nil] = index
+	}
+	self.records = append(self.records, PendingRecord{Utxo: utxo, InPool: inPool, Confirmations: confirmations})
+}
+
+// scanBlocks collects the records of the blocks above the indexed ones. It
+// fails if there are more than maxPendingBlocks of them, the records would be
+// incomplete.
+func (self *pendingScan) scanBlocks(start, head, delay uint64) error {
+	if start > head {
+		return nil
+	}
+	if head+1-start > maxPendingBlocks {
+		return fmt.Errorf("account indexed up to block %d, %d blocks behind the head, more than %d", start, head+1-start, maxPendingBlocks)
+	}
+	blocks, err := flight.SRI_Inst.GetBlocksInfoByDelay(start, head+1-start, 0)
+	if err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		num := uint64(block.Num)
+		var confirmations uint64
+		if num+delay > head {
+			confirmations = num + delay - head
+		}
+		for _, out := range block.Outs {
+			pkr := out.State.OS.ToPKr()
+			if pkr == nil || !superzk.IsMyPKr(self.account.tk, pkr) {
+				continue
+			}
+			dout := DecOuts([]txtool.Out{out}, &self.account.skr)[0]
+			if dout.Nil == c_type.Empty_Uint256 {
+				continue
+			}
+			utxo := Utxo{Pkr: *pkr, Root: out.Root, Nil: dout.Nil, TxHash: out.State.TxHash, Num: num, Asset: dout.Asset, IsZ: out.State.OS.IsZero()}
+			self.receive(utxo, false, confirmations)
+		}
+		for _, Nil := range block.Nils {
+			self.spend(Nil, c_type.Uint256{}, num, false, confirmations)
+		}
+	}
+	return nil
+}
+
+// scanTx collects the records of a tx of the pool.
+func (self *pendingScan) scanTx(tx *types.Transaction, delay uint64) {
+	var hash c_type.Uint256
+	copy(hash[:], tx.Hash().Bytes())
+	ztx := tx.GetZZSTX()
+
+	for _, in := range ztx.Desc_O.Ins {
+		self.spend(in.Root, hash, 0, true, delay+1)
+	}
+	for _, in := range ztx.Desc_Z.Ins {
+		self.spend(in.Nil, hash, 0, true, delay+1)
+	}
+	for _, in := range ztx.Tx1.Ins_P0 {
+		self.spend(in.Root, hash, 0, true, delay+1)
+	}
+	for _, in := range ztx.Tx1.Ins_P {
+		self.spend(in.Root, hash, 0, true, delay+1)
+	}
+	for _, in := range ztx.Tx1.Ins_C {
+		self.spend(in.Nil, hash, 0, true, delay+1)
+	}
+
+	// The outputs of the version 0 txs are not decoded, such txs are not
+	// accepted since SIP5.
+	for _, out := range ztx.Tx1.Outs_P {
+		if superzk.IsMyPKr(self.account.tk, &out.PKr) {
+			utxo := Utxo{Pkr: out.PKr, TxHash: hash, Asset: out.Asset}
+			self.receive(utxo, true, delay+1)
+		}
+	}
+	for i := range ztx.Tx1.Outs_C {
+		out := &ztx.Tx1.Outs_C[i]
+		if !superzk.IsMyPKr(self.account.tk, &out.PKr) {
+			continue
+		}
+		if key, _, err := c_superzk.FetchKey(&out.PKr, self.account.tk, &out.RPK); err == nil {
+			if dout, _ := generate_1.ConfirmOutC(&key, out); dout != nil {
+				utxo := Utxo{Pkr: out.PKr, TxHash: hash, Asset: dout.Asset, IsZ: true}
+				self.receive(utxo, true, delay+1)
+			}
+		}
+	}
+}
+
+// GetPending returns the unconfirmed balances of an account by currency and
+// the records they are made of. They are built from the blocks not indexed
+// yet and from the txs of the pool.
+func (self *Exchange) GetPending(pk c_type.Uint512) (balances map[string]*PendingBalance, records []PendingRecord, e error) {
+	account := self.getAccountByPk(pk)
+	if account == nil {
+		e = errors.New("not found Pk")
+		return
+	}
+	if txtool.Ref_inst.Bc == nil || !txtool.Ref_inst.Bc.IsValid() {
+		e = errors.New("chain is not ready")
+		return
+	}
+	scan := newPendingScan(self, account)
+	head := txtool.Ref_inst.Bc.GetCurrenHeader().Number.Uint64()
+	delay := seroparam.DefaultConfirmedBlock()
+	if value, ok := self.numbers.Load(pk); ok {
+		if e = scan.scanBlocks(value.(uint64), head, delay); e != nil {
+			return
+		}
+	}
+	if self.txPool != nil {
+		pending, queued := self.txPool.Content()
+		for _, tx := range append(pending, queued...) {
+			scan.scanTx(tx, delay)
+		}
+	}
+
+	balances, records = scan.result()
+	return
+}
+
+// result sums the records not spent again by currency.
+func (self *pendingScan) result() (balances map[string]*PendingBalance, records []PendingRecord) {
+	balances = make(map[string]*PendingBalance)
+	for i, record := range self.records {
+		if self.consumed[i] {
+			continue
+		}
+		records = append(records, record)
+		if record.Asset.Tkn == nil {
+			continue
+		}
+		currency := common.BytesToString(record.Asset.Tkn.Currency[:])
+		balance, ok := balances[currency]
+		if !ok {
+			balance = &PendingBalance{In: new(big.Int), Out: new(big.Int)}
+			balances[currency] = balance
+		}
+		if record.Spent {
+			balance.Out.Add(balance.Out, record.Asset.Tkn.Value.ToIntRef())
+		} else {
+			balance.In.Add(balance.In, record.Asset.Tkn.Value.ToIntRef())
+			if record.Confirmations > balance.Confirmations {
+				balance.Confirmations = record.Confirmations
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,76 @@
+package exchange
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func seroUtxo(root byte, value int64, num uint64) Utxo {
+	return Utxo{
+		Pkr:    c_type.PKr{root},
+		Root:   c_type.Uint256{root},
+		Nil:    c_type.Uint256{root, 1},
+		TxHash: c_type.Uint256{root, 2},
+		Num:    num,
+		Asset:  assets.Asset{Tkn: &assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.U256(*big.NewInt(value))}},
+	}
+}
+
+func TestPendingBalances(t *testing.T) {
+	ex, done := newTestExchange(t)
+	defer done()
+
+	pk := c_type.Uint512{1}
+	scan := newPendingScan(ex, &Account{pk: &pk})
+
+	// A confirmed output of the account, indexed like the exchange does
+	confirmed := seroUtxo(1, 100, 5)
+	data, err := rlp.EncodeToBytes(&confirmed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex.db.Put(rootKey(confirmed.Root), data)
+	currency := utils.CurrencyToUint256("SERO")
+	ex.db.Put(nilKey(confirmed.Nil), utxoPkKey(pk, currency[:], &confirmed.Root))
+
+	// Received in an unconfirmed block, then spent again in the pool
+	scan.receive(seroUtxo(2, 30, 20), false, 3)
+	scan.spend(c_type.Uint256{2, 1}, c_type.Uint256{9}, 0, true, 13)
+	// Received in an unconfirmed block and in the pool
+	scan.receive(seroUtxo(3, 7, 21), false, 4)
+	scan.receive(Utxo{Pkr: c_type.PKr{4}, TxHash: c_type.Uint256{4}, Asset: seroUtxo(4, 5, 0).Asset}, true, 13)
+	// The confirmed output spent in an unconfirmed block, twice over
+	scan.spend(confirmed.Nil, c_type.Uint256{}, 22, false, 5)
+	scan.spend(confirmed.Nil, c_type.Uint256{8}, 0, true, 13)
+	// Outputs of other accounts are ignored
+	scan.spend(c_type.Uint256{5, 1}, c_type.Uint256{}, 22, false, 5)
+
+	balances, records := scan.result()
+	if len(records) != 3 {
+		t.Fatalf("record count mismatch: have %d, want 3", len(records))
+	}
+	balance := balances["SERO"]
+	if balance == nil {
+		t.Fatal("missing SERO balance")
+	}
+	if balance.In.Cmp(big.NewInt(12)) != 0 || balance.Out.Cmp(big.NewInt(100)) != 0 || balance.Confirmations != 13 {
+		t.Fatalf("balance mismatch: in %v out %v confirmations %d", balance.In, balance.Out, balance.Confirmations)
+	}
+}
+
+// Tests that the pending amounts aren't reported from a partial scan of the
+// blocks above the indexed ones.
+func TestPendingScanTooFar(t *testing.T) {
+	scan := newPendingScan(nil, &Account{})
+	if err := scan.scanBlocks(100, 100+maxPendingBlocks, 12); err == nil {
+		t.Fatal("scan of too many blocks succeeded")
+	}
+	if err := scan.scanBlocks(101, 100, 12); err != nil {
+		t.Fatalf("scan without blocks failed: %v", err)
+	}
+}
@@ -0,0 +1,307 @@
+package exchange
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+var rescanPrefix = []byte("RESCAN")
+
+// "RESCAN" + PK => RescanStatus
+func rescanKey(pk c_type.Uint512) []byte {
+	return append(rescanPrefix, pk[:]...)
+}
+
+// RescanStatus is the progress of the rescan of an account.
+type RescanStatus struct {
+	From   uint64 // Height the index of the account was rewound to
+	Target uint64 // Height the account was indexed to before the rescan
+}
+
+// Rescan rewinds the index of an account to the given height and lets the
+// indexer fetch the following blocks again for it, the other accounts keep
+// indexing meanwhile. The outputs received from the height on are removed,
+// the ones spent from the height on are restored as unspent so that their
+// spending is indexed again. The package index is not rewound.
+func (self *Exchange) Rescan(pk c_type.Uint512, from uint64) (e error) {
+	account := self.getAccountByPk(pk)
+	if account == nil {
+		return errors.New("not found Pk")
+	}
+	self.indexLock.Lock()
+	defer self.indexLock.Unlock()
+
+	if has, _ := self.db.Has(tktEventsKey); !has {
+		return errors.New("ticket history is being indexed")
+	}
+	value, ok := self.numbers.Load(pk)
+	if !ok {
+		return errors.New("not found Pk")
+	}
+	target := value.(uint64)
+	if from >= target {
+		return errors.New("rescan height is above the indexed ones")
+	}
+
+	batch := self.db.NewBatch()
+	removed := map[c_type.Uint256]bool{}
+	iterator := self.db.NewIteratorWithPrefix(utxoPrefix)
+	defer iterator.Release()
+	for ok := iterator.Seek(utxoKey(from, c_type.Uint512{})); ok; ok = iterator.Next() {
+		key := iterator.Key()
+		if !bytes.Equal(key[12:76], pk[:]) {
+			continue
+		}
+		roots := []c_type.Uint256{}
+		if e = rlp.DecodeBytes(iterator.Value(), &roots); e != nil {
+			return
+		}
+		for _, root := range roots {
+			utxo, err := self.getUtxo(root)
+			if err != nil || utxo.Root != root {
+				continue
+			}
+			for _, key := range self.utxoIndexKeys(pk, &utxo) {
+				batch.Delete(key)
+			}
+			if tag := utxo.Memo.Tag(); tag != nil {
+				batch.Delete(memoKey(tag, utxo.Num, &root))
+			}
+			batch.Delete(rootKey(root))
+			batch.Delete(nilToRootKey(utxo.Nil))
+			removed[root] = true
+		}
+		batch.Delete(common.CopyBytes(key))
+	}
+
+	if e = self.rewindBlocks(batch, account, pk, from, removed); e != nil {
+		return
+	}
+	if e = self.rewindTxs(batch, removed); e != nil {
+		return
+	}
+	if e = self.rewindTicketHistory(batch, pk, from); e != nil {
+		return
+	}
+
+	status := RescanStatus{From: from, Target: target}
+	data, e := rlp.EncodeToBytes(&status)
+	if e != nil {
+		return
+	}
+	batch.Put(rescanKey(pk), data)
+	batch.Put(numKey(pk), utils.EncodeNumber(from))
+	if e = batch.Write(); e != nil {
+		return
+	}
+	for root := range removed {
+		self.usedFlag.Delete(root)
+	}
+	self.numbers.Store(pk, from)
+	self.rescans.Store(pk, status)
+	account.isChanged = true
+
+	log.Info("Exchange rescan", "pk", *utils.Base58Encode(pk[:]), "from", from, "to", target, "removed", len(removed))
+	return
+}
+
+// GetRescan returns the progress of the rescan of an account, if it is not
+// finished.
+func (self *Exchange) GetRescan(pk c_type.Uint512) (status RescanStatus, ok bool) {
+	if value, ok := self.rescans.Load(pk); ok {
+		return value.(RescanStatus), true
+	}
+	return
+}
+
+// loadRescans resumes the rescans which were not finished when the node
+// stopped.
+func (self *Exchange) loadRescans() {
+	iterator := self.db.NewIteratorWithPrefix(rescanPrefix)
+	defer iterator.Release()
+	for iterator.Next() {
+		key := iterator.Key()
+		if len(key) != len(rescanPrefix)+64 {
+			continue
+		}
+		var status RescanStatus
+		if err := rlp.DecodeBytes(iterator.Value(), &status); err != nil {
+			log.Error("Exchange invalid rescan status RLP", "err", err)
+			continue
+		}
+		var pk c_type.Uint512
+		copy(pk[:], key[len(rescanPrefix):])
+		self.rescans.Store(pk, status)
+	}
+}
+
+// utxoIndexKeys returns the keys indexing an unspent output of an account by
+// currency, ticket and nil.
+func (self *Exchange) utxoIndexKeys(pk c_type.Uint512, utxo *Utxo) (keys [][]byte) {
+	if utxo.Asset.Tkn != nil {
+		keys = append(keys, utxoPkKey(pk, utxo.Asset.Tkn.Currency[:], &utxo.Root))
+	}
+	if utxo.Asset.Tkt != nil {
+		keys = append(keys, utxoPkKey(pk, utxo.Asset.Tkt.Value[:], &utxo.Root))
+		keys = append(keys, tktKey(pk, &utxo.Asset.Tkt.Category, &utxo.Asset.Tkt.Value))
+		keys = append(keys, tktRootKey(utxo.Root))
+	}
+	keys = append(keys, nilKey(utxo.Nil), nilKey(utxo.Root))
+	return
+}
+
+// restoreUtxo indexes again as unspent an output of an account received
+// before the rescan height.
+func (self *Exchange) restoreUtxo(batch serodb.Batch, pk c_type.Uint512, utxo *Utxo) error {
+	var pkKeys []byte
+	if utxo.Asset.Tkn != nil {
+		key := utxoPkKey(pk, utxo.Asset.Tkn.Currency[:], &utxo.Root)
+		batch.Put(key, []byte{0})
+		pkKeys = append(pkKeys, key...)
+	}
+	if utxo.Asset.Tkt != nil {
+		key := utxoPkKey(pk, utxo.Asset.Tkt.Value[:], &utxo.Root)
+		batch.Put(key, []byte{0})
+		pkKeys = append(pkKeys, key...)
+
+		record := TicketRecord{
+			Category: utxo.Asset.Tkt.Category,
+			Value:    utxo.Asset.Tkt.Value,
+			Root:     utxo.Root,
+			Pkr:      utxo.Pkr,
+			TxHash:   utxo.TxHash,
+			Num:      utxo.Num,
+		}
+		data, err := rlp.EncodeToBytes(&record)
+		if err != nil {
+			return err
+		}
+		tkey := tktKey(pk, &record.Category, &record.Value)
+		batch.Put(tkey, data)
+		batch.Put(tktRootKey(record.Root), tkey)
+	}
+	batch.Put(nilKey(utxo.Nil), pkKeys)
+	batch.Put(nilKey(utxo.Root), pkKeys)
+	return nil
+}
+
+// rewindBlocks removes the outputs and the spendings of an account from the
+// block records above the rescan height.
+func (self *Exchange) rewindBlocks(batch serodb.Batch, account *Account, pk c_type.Uint512, from uint64, removed map[c_type.Uint256]bool) error {
+	iterator := self.db.NewIteratorWithPrefix(blockPrefix)
+	defer iterator.Release()
+	for ok := iterator.Seek(blockKey(from)); ok; ok = iterator.Next() {
+		var block BlockInfo
+		if err := rlp.DecodeBytes(iterator.Value(), &block); err != nil {
+			return err
+		}
+		changed := false
+		outs := []Utxo{}
+		for _, out := range block.Outs {
+			if removed[out.Root] {
+				changed = true
+				continue
+			}
+			outs = append(outs, out)
+		}
+		ins := []c_type.Uint256{}
+		for _, root := range block.Ins {
+			if removed[root] {
+				changed = true
+				continue
+			}
+			if utxo, err := self.getUtxo(root); err == nil && utxo.Root == root && self.ownsUtxo(account, &utxo) {
+				if err := self.restoreUtxo(batch, pk, &utxo); err != nil {
+					return err
+				}
+				changed = true
+				continue
+			}
+			ins = append(ins, root)
+		}
+		if !changed {
+			continue
+		}
+		key := common.CopyBytes(iterator.Key())
+		if len(outs) == 0 && len(ins) == 0 {
+			batch.Delete(key)
+			continue
+		}
+		block.Outs, block.Ins = outs, ins
+		data, err := rlp.EncodeToBytes(&block)
+		if err != nil {
+			return err
+		}
+		batch.Put(key, data)
+	}
+	return nil
+}
+
+// ownsUtxo tells whether an output was indexed for the account.
+func (self *Exchange) ownsUtxo(account *Account, utxo *Utxo) bool {
+	if account.balancePkr != nil && utxo.Pkr == *account.balancePkr {
+		return true
+	}
+	_, ok := self.ownPkr([]c_type.Uint512{*account.pk}, utxo.Pkr)
+	return ok
+}
+
+// rewindTxs removes the removed outputs from the tx records.
+func (self *Exchange) rewindTxs(batch serodb.Batch, removed map[c_type.Uint256]bool) error {
+	txs := map[c_type.Uint256]bool{}
+	for root := range removed {
+		if utxo, err := self.getUtxo(root); err == nil {
+			txs[utxo.TxHash] = true
+		}
+	}
+	for txHash := range txs {
+		records, err := self.GetRecordsByTxHash(txHash)
+		if err != nil {
+			continue
+		}
+		kept := []Utxo{}
+		for _, record := range records {
+			if !removed[record.Root] {
+				kept = append(kept, record)
+			}
+		}
+		if len(kept) == 0 {
+			batch.Delete(txKey(txHash))
+			continue
+		}
+		data, err := rlp.EncodeToBytes(&kept)
+		if err != nil {
+			return err
+		}
+		batch.Put(txKey(txHash), data)
+	}
+	return nil
+}
+
+// rewindTicketHistory removes the ticket events of an account above the
+// rescan height.
+func (self *Exchange) rewindTicketHistory(batch serodb.Batch, pk c_type.Uint512, from uint64) error {
+	prefix := tktEventKey(pk, 0, nil, nil)[:len(tktEventPrefix)+64]
+	iterator := self.db.NewIteratorWithPrefix(prefix)
+	defer iterator.Release()
+	for ok := iterator.Seek(tktEventKey(pk, from, nil, nil)); ok; ok = iterator.Next() {
+		key := iterator.Key()
+		if len(key) != len(prefix)+8+64 {
+			continue
+		}
+		num := utils.DecodeNumber(key[len(prefix) : len(prefix)+8])
+		var value, root c_type.Uint256
+		copy(value[:], key[len(prefix)+8:len(prefix)+40])
+		copy(root[:], key[len(prefix)+40:])
+		batch.Delete(tktHistoryKey(value, num, &root))
+		batch.Delete(common.CopyBytes(key))
+	}
+	return nil
+}
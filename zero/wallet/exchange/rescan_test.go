@@ -0,0 +1,147 @@
+package exchange
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+type rescanTestBlock struct {
+	num   uint64
+	outs  []Utxo
+	spent []Utxo
+}
+
+func tokenUtxo(root byte, value uint64, num uint64) Utxo {
+	return Utxo{
+		Pkr:    c_type.PKr{9},
+		Root:   c_type.Uint256{root},
+		Nil:    c_type.Uint256{root, 1},
+		TxHash: c_type.Uint256{root, 2},
+		Num:    num,
+		Asset:  assets.Asset{Tkn: &assets.Token{Currency: utils.CurrencyToUint256("SERO"), Value: utils.NewU256(value)}},
+	}
+}
+
+// newRescanTestExchange returns an exchange with an account owning the outputs
+// paid to the test pkr.
+func newRescanTestExchange(t *testing.T, pk c_type.Uint512) (*Exchange, func()) {
+	ex, done := newTestExchange(t)
+	pkr := c_type.PKr{9}
+	ex.accounts.Store(pk, &Account{pk: pk.NewRef(), balancePkr: &pkr})
+	ex.db.Put(tktEventsKey, []byte{1})
+	return ex, done
+}
+
+// indexTestBlock indexes a block the way fetchAndIndexUtxo does.
+func indexTestBlock(t *testing.T, ex *Exchange, pk c_type.Uint512, block rescanTestBlock) {
+	utxosMap := map[PkKey][]Utxo{}
+	if len(block.outs) > 0 {
+		utxosMap[PkKey{key: pk, Num: block.num}] = block.outs
+	}
+	info := &BlockInfo{Num: block.num, Outs: block.outs}
+	nils := []c_type.Uint256{}
+	for _, utxo := range block.spent {
+		info.Ins = append(info.Ins, utxo.Root)
+		nils = append(nils, utxo.Nil)
+	}
+	blockMap := map[uint64]*BlockInfo{block.num: info}
+	if err := ex.writeIndex(ex.db.NewBatch(), []c_type.Uint512{pk}, block.num+1, utxosMap, blockMap, nils); err != nil {
+		t.Fatalf("failed to index block %d: %v", block.num, err)
+	}
+}
+
+// dumpTestExchange returns the index of an exchange but the cursors.
+func dumpTestExchange(ex *Exchange) map[string]string {
+	dump := map[string]string{}
+	iterator := ex.db.NewIterator()
+	defer iterator.Release()
+	for iterator.Next() {
+		if bytes.HasPrefix(iterator.Key(), numPrefix) {
+			continue
+		}
+		dump[string(iterator.Key())] = common.Bytes2Hex(iterator.Value())
+	}
+	return dump
+}
+
+func TestRescan(t *testing.T) {
+	pk := c_type.Uint512{1}
+
+	received := tokenUtxo(1, 100, 5)
+	ticket := tokenUtxo(2, 0, 5)
+	ticket.Asset = assets.Asset{Tkt: &assets.Ticket{Category: utils.CurrencyToUint256("TKT"), Value: c_type.Uint256{20}}}
+	change := tokenUtxo(3, 50, 7)
+	blocks := []rescanTestBlock{
+		{num: 5, outs: []Utxo{received, ticket}},
+		{num: 7, outs: []Utxo{change}, spent: []Utxo{received}},
+		{num: 9, spent: []Utxo{ticket}},
+	}
+
+	fresh, done := newRescanTestExchange(t, pk)
+	defer done()
+	for _, block := range blocks {
+		indexTestBlock(t, fresh, pk, block)
+	}
+
+	ex, done := newRescanTestExchange(t, pk)
+	defer done()
+	for _, block := range blocks {
+		indexTestBlock(t, ex, pk, block)
+	}
+	ex.numbers.Store(pk, uint64(10))
+
+	if err := ex.Rescan(pk, 6); err != nil {
+		t.Fatalf("failed to rescan: %v", err)
+	}
+	if utils.DecodeNumber(mustGet(t, ex, numKey(pk))) != 6 {
+		t.Fatal("cursor not rewound")
+	}
+	if status, ok := ex.GetRescan(pk); !ok || status.From != 6 || status.Target != 10 {
+		t.Fatalf("rescan status mismatch: %+v", status)
+	}
+	if events, _ := ex.GetTicketHistory(ticket.Asset.Tkt.Value); len(events) != 1 || events[0].Spent {
+		t.Fatalf("ticket history not rewound: %+v", events)
+	}
+
+	// The rescan resumes after a restart
+	restarted := &Exchange{db: ex.db}
+	restarted.loadRescans()
+	if status, ok := restarted.GetRescan(pk); !ok || status.Target != 10 {
+		t.Fatalf("rescan status not persisted: %+v", status)
+	}
+
+	for _, block := range blocks[1:] {
+		indexTestBlock(t, ex, pk, block)
+	}
+	if _, ok := ex.GetRescan(pk); ok {
+		t.Fatal("rescan not finished")
+	}
+	if has, _ := ex.db.Has(rescanKey(pk)); has {
+		t.Fatal("rescan status not deleted")
+	}
+
+	have, want := dumpTestExchange(ex), dumpTestExchange(fresh)
+	for key, value := range want {
+		if have[key] != value {
+			t.Errorf("key %x mismatch: have %s, want %s", key, have[key], value)
+		}
+	}
+	for key, value := range have {
+		if _, ok := want[key]; !ok {
+			t.Errorf("extra key %x: %s", key, value)
+		}
+	}
+}
+
+func mustGet(t *testing.T, ex *Exchange, key []byte) []byte {
+	value, err := ex.db.Get(key)
+	if err != nil {
+		t.Fatalf("missing key %x: %v", key, err)
+	}
+	return value
+}
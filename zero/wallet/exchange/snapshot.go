@@ -0,0 +1,39 @@
+package exchange
+
+import (
+	"errors"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/utils"
+	"github.com/sero-cash/go-sero/zero/wallet/snapshot"
+)
+
+// SnapshotManifest returns the manifest of a snapshot of an exchange database,
+// the accounts it indexes and the last block indexed for them. The accounts
+// indexed to a lower block resume from their own one after the import.
+func SnapshotManifest(db *serodb.LDBDatabase, chainDb serodb.Database) (*snapshot.Manifest, error) {
+	manifest := &snapshot.Manifest{Kind: snapshot.KindExchange}
+	next := uint64(0)
+	iterator := db.NewIteratorWithPrefix(numPrefix)
+	for iterator.Next() {
+		key := iterator.Key()
+		if len(key) != len(numPrefix)+64 {
+			continue
+		}
+		var pk c_type.Uint512
+		copy(pk[:], key[len(numPrefix):])
+		manifest.Pks = append(manifest.Pks, pk)
+		if num := utils.DecodeNumber(iterator.Value()); num > next {
+			next = num
+		}
+	}
+	iterator.Release()
+	if next == 0 {
+		return nil, errors.New("no block indexed in the exchange database")
+	}
+	manifest.Number = next - 1
+	manifest.Hash = rawdb.ReadCanonicalHash(chainDb, manifest.Number)
+	return manifest, nil
+}
@@ -0,0 +1,370 @@
+package exchange
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/txtool/prepare"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+var (
+	tktPrefix        = []byte("TKTPK")
+	tktRootPrefix    = []byte("TKTROOT")
+	tktHistoryPrefix = []byte("TKTHIS")
+	tktIndexedKey    = []byte("TKTINDEXED")
+	tktEventPrefix   = []byte("TKTEV")
+	tktEventsKey     = []byte("INDEXEDTKTEV")
+)
+
+// "TKTPK" + PK + category + value => TicketRecord
+func tktKey(pk c_type.Uint512, category *c_type.Uint256, value *c_type.Uint256) []byte {
+	key := append(tktPrefix, pk[:]...)
+	if category != nil {
+		key = append(key, category[:]...)
+	}
+	if value != nil {
+		key = append(key, value[:]...)
+	}
+	return key
+}
+
+// "TKTROOT" + root => tktKey
+func tktRootKey(root c_type.Uint256) []byte {
+	return append(tktRootPrefix, root[:]...)
+}
+
+// "TKTHIS" + value + num + root => TicketEvent
+func tktHistoryKey(value c_type.Uint256, num uint64, root *c_type.Uint256) []byte {
+	key := append(tktHistoryPrefix, value[:]...)
+	key = append(key, utils.EncodeNumber(num)...)
+	if root != nil {
+		key = append(key, root[:]...)
+	}
+	return key
+}
+
+// "TKTEV" + PK + num + value + root => nil
+func tktEventKey(pk c_type.Uint512, num uint64, value *c_type.Uint256, root *c_type.Uint256) []byte {
+	key := append(tktEventPrefix, pk[:]...)
+	key = append(key, utils.EncodeNumber(num)...)
+	if value != nil {
+		key = append(key, value[:]...)
+		key = append(key, root[:]...)
+	}
+	return key
+}
+
+type TicketRecord struct {
+	Category c_type.Uint256
+	Value    c_type.Uint256
+	Root     c_type.Uint256
+	Pkr      c_type.PKr
+	TxHash   c_type.Uint256
+	Num      uint64
+	Locked   bool `rlp:"-"`
+}
+
+type TicketEvent struct {
+	Pk     c_type.Uint512
+	Pkr    c_type.PKr
+	Root   c_type.Uint256
+	TxHash c_type.Uint256
+	Num    uint64
+	Spent  bool
+}
+
+type ticketOut struct {
+	pk   c_type.Uint512
+	utxo Utxo
+}
+
+func (self *Exchange) indexTickets(batch serodb.Batch, utxosMap map[PkKey][]Utxo, blockMap map[uint64]*BlockInfo) (err error) {
+	outs := map[uint64][]ticketOut{}
+	roots := map[c_type.Uint256][]byte{}
+	for key, list := range utxosMap {
+		for _, utxo := range list {
+			if utxo.Asset.Tkt != nil {
+				outs[key.Num] = append(outs[key.Num], ticketOut{key.key, utxo})
+			}
+		}
+	}
+
+	nums := uint64Slice{}
+	for num := range blockMap {
+		nums = append(nums, num)
+	}
+	sort.Sort(nums)
+
+	for _, num := range nums {
+		var spentBy map[c_type.Uint256]c_type.Uint256
+		received := map[string]c_type.Uint256{} // Ticket keys written in this block => root
+		for _, out := range outs[num] {
+			record := TicketRecord{
+				Category: out.utxo.Asset.Tkt.Category,
+				Value:    out.utxo.Asset.Tkt.Value,
+				Root:     out.utxo.Root,
+				Pkr:      out.utxo.Pkr,
+				TxHash:   out.utxo.TxHash,
+				Num:      out.utxo.Num,
+			}
+			data, e := rlp.EncodeToBytes(&record)
+			if e != nil {
+				err = e
+				return
+			}
+			key := tktKey(out.pk, &record.Category, &record.Value)
+			batch.Put(key, data)
+			batch.Put(tktRootKey(record.Root), key)
+			roots[record.Root] = key
+			received[string(key)] = record.Root
+
+			event := TicketEvent{Pk: out.pk, Pkr: record.Pkr, Root: record.Root, TxHash: record.TxHash, Num: num}
+			if data, err = rlp.EncodeToBytes(&event); err != nil {
+				return
+			}
+			batch.Put(tktHistoryKey(record.Value, num, &record.Root), data)
+			batch.Put(tktEventKey(out.pk, num, &record.Value, &record.Root), []byte{0})
+		}
+
+		for _, root := range blockMap[num].Ins {
+			key, ok := roots[root]
+			if !ok {
+				if key, _ = self.db.Get(tktRootKey(root)); key == nil {
+					continue
+				}
+			}
+			delete(roots, root)
+
+			var pk c_type.Uint512
+			copy(pk[:], key[len(tktPrefix):len(tktPrefix)+64])
+			var value c_type.Uint256
+			copy(value[:], key[len(key)-32:])
+
+			if spentBy == nil {
+				spentBy = self.spentBy(blockMap[num])
+			}
+			event := TicketEvent{Pk: pk, Root: root, TxHash: spentBy[root], Num: num, Spent: true}
+			if utxo, e := self.getUtxo(root); e == nil {
+				event.Pkr = utxo.Pkr
+				if event.TxHash == c_type.Empty_Uint256 {
+					event.TxHash = spentBy[utxo.Nil]
+				}
+			}
+			data, e := rlp.EncodeToBytes(&event)
+			if e != nil {
+				err = e
+				return
+			}
+			batch.Put(tktHistoryKey(value, num, &root), data)
+			batch.Put(tktEventKey(pk, num, &value, &root), []byte{0})
+			// The ticket may come back to the account in the same block
+			if other, ok := received[string(key)]; !ok || other == root {
+				batch.Delete(key)
+			}
+			batch.Delete(tktRootKey(root))
+		}
+	}
+	return
+}
+
+// spentBy maps the nils and roots spent in a block to their spending tx.
+func (self *Exchange) spentBy(info *BlockInfo) map[c_type.Uint256]c_type.Uint256 {
+	if txtool.Ref_inst.Bc == nil {
+		return map[c_type.Uint256]c_type.Uint256{}
+	}
+	block := txtool.Ref_inst.Bc.GetBlockByNumber(info.Num)
+	if block == nil || block.Hash() != common.BytesToHash(info.Hash[:]) {
+		return map[c_type.Uint256]c_type.Uint256{}
+	}
+	return txtool.SpentBy(block)
+}
+
+// rebuildTicketIndex fills the ticket inventory of databases which were indexed
+// before the ticket keys existed. It holds the index lock, so the blocks are
+// indexed once it is done.
+func (self *Exchange) rebuildTicketIndex() {
+	self.indexLock.Lock()
+	defer self.indexLock.Unlock()
+
+	if has, _ := self.db.Has(tktIndexedKey); has {
+		return
+	}
+	batch := self.db.NewBatch()
+	count := 0
+	iterator := self.db.NewIteratorWithPrefix(pkPrefix)
+	defer iterator.Release()
+	for iterator.Next() {
+		key := iterator.Key()
+		if len(key) != 130 {
+			continue
+		}
+		var pk c_type.Uint512
+		copy(pk[:], key[2:66])
+		var root c_type.Uint256
+		copy(root[:], key[98:130])
+		if utxo, err := self.getUtxo(root); err == nil && utxo.Asset.Tkt != nil {
+			if common.BytesToHash(key[66:98]) != common.BytesToHash(utxo.Asset.Tkt.Value[:]) {
+				continue
+			}
+			record := TicketRecord{
+				Category: utxo.Asset.Tkt.Category,
+				Value:    utxo.Asset.Tkt.Value,
+				Root:     utxo.Root,
+				Pkr:      utxo.Pkr,
+				TxHash:   utxo.TxHash,
+				Num:      utxo.Num,
+			}
+			if data, err := rlp.EncodeToBytes(&record); err == nil {
+				tkey := tktKey(pk, &record.Category, &record.Value)
+				batch.Put(tkey, data)
+				batch.Put(tktRootKey(record.Root), tkey)
+				count++
+			}
+		}
+	}
+	batch.Put(tktIndexedKey, []byte{1})
+	if err := batch.Write(); err != nil {
+		log.Error("Exchange rebuild ticket index", "error", err)
+		return
+	}
+	log.Info("Exchange rebuild ticket index", "count", count)
+}
+
+// rebuildTicketEvents indexes by account the ticket history of databases which
+// were indexed before the account keys existed, the rescans need them.
+func (self *Exchange) rebuildTicketEvents() {
+	self.indexLock.Lock()
+	defer self.indexLock.Unlock()
+
+	if has, _ := self.db.Has(tktEventsKey); has {
+		return
+	}
+	batch := self.db.NewBatch()
+	count := 0
+	iterator := self.db.NewIteratorWithPrefix(tktHistoryPrefix)
+	defer iterator.Release()
+	for iterator.Next() {
+		var event TicketEvent
+		if err := rlp.DecodeBytes(iterator.Value(), &event); err != nil {
+			continue
+		}
+		key := iterator.Key()
+		var value c_type.Uint256
+		copy(value[:], key[len(tktHistoryPrefix):len(tktHistoryPrefix)+32])
+		batch.Put(tktEventKey(event.Pk, event.Num, &value, &event.Root), []byte{0})
+		count++
+	}
+	batch.Put(tktEventsKey, []byte{1})
+	if err := batch.Write(); err != nil {
+		log.Error("Exchange rebuild ticket events", "error", err)
+		return
+	}
+	log.Info("Exchange rebuild ticket events", "count", count)
+}
+
+func (self *Exchange) GetTicketCategories(pk c_type.Uint512) (categories map[c_type.Uint256]uint64) {
+	categories = map[c_type.Uint256]uint64{}
+	iterator := self.db.NewIteratorWithPrefix(tktKey(pk, nil, nil))
+	defer iterator.Release()
+	for iterator.Next() {
+		key := iterator.Key()
+		var category c_type.Uint256
+		copy(category[:], key[len(key)-64:len(key)-32])
+		categories[category]++
+	}
+	return
+}
+
+func (self *Exchange) GetTickets(pk c_type.Uint512, category *c_type.Uint256, offset uint64, limit uint64) (records []TicketRecord, total uint64, e error) {
+	iterator := self.db.NewIteratorWithPrefix(tktKey(pk, category, nil))
+	defer iterator.Release()
+	for iterator.Next() {
+		total++
+		if total <= offset || uint64(len(records)) >= limit {
+			continue
+		}
+		var record TicketRecord
+		if e = rlp.DecodeBytes(iterator.Value(), &record); e != nil {
+			return
+		}
+		if _, flag := self.usedFlag.Load(record.Root); flag {
+			record.Locked = true
+		}
+		records = append(records, record)
+	}
+	return
+}
+
+func (self *Exchange) GetTicketHistory(value c_type.Uint256) (events []TicketEvent, e error) {
+	iterator := self.db.NewIteratorWithPrefix(append(tktHistoryPrefix, value[:]...))
+	defer iterator.Release()
+	for iterator.Next() {
+		var event TicketEvent
+		if e = rlp.DecodeBytes(iterator.Value(), &event); e != nil {
+			return
+		}
+		events = append(events, event)
+	}
+	return
+}
+
+type TransferTicketsParam struct {
+	From     c_type.Uint512
+	To       c_type.PKr
+	RefundTo *c_type.PKr
+	Tickets  []assets.Ticket
+	GasPrice *big.Int
+}
+
+func (self *Exchange) TransferTickets(param *TransferTicketsParam) (txhash c_type.Uint256, e error) {
+	if len(param.Tickets) == 0 {
+		e = errors.New("no tickets to transfer")
+		return
+	}
+	gasPrice, fee := default_gas_price, default_fee_value
+	if param.GasPrice != nil && param.GasPrice.Sign() > 0 {
+		gasPrice = param.GasPrice
+		fee = new(big.Int).Mul(default_gas, gasPrice)
+	}
+
+	receptions := []prepare.Reception{}
+	for _, ticket := range param.Tickets {
+		t := ticket
+		receptions = append(receptions, prepare.Reception{
+			Addr:  param.To,
+			Asset: assets.Asset{Tkt: &t},
+		})
+	}
+
+	preTx := prepare.PreTxParam{
+		From:       param.From,
+		RefundTo:   param.RefundTo,
+		Receptions: receptions,
+		Fee: assets.Token{
+			Currency: utils.CurrencyToUint256("SERO"),
+			Value:    utils.U256(*fee),
+		},
+		GasPrice: gasPrice,
+	}
+
+	pretx, gtx, err := self.GenTxWithSign(preTx)
+	if err != nil {
+		e = err
+		return
+	}
+	if e = self.commitTx(gtx); e != nil {
+		self.ClearTxParam(pretx)
+		return
+	}
+	txhash = gtx.Hash
+	return
+}
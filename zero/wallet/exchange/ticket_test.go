@@ -0,0 +1,168 @@
+package exchange
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+func newTestExchange(t *testing.T) (*Exchange, func()) {
+	dir, err := ioutil.TempDir("", "exchange")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := serodb.NewLDBDatabase(dir, 16, 16)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return &Exchange{db: db}, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func ticketUtxo(t *testing.T, ex *Exchange, root byte, value byte, num uint64) Utxo {
+	utxo := Utxo{
+		Pkr:    c_type.PKr{root},
+		Root:   c_type.Uint256{root},
+		Nil:    c_type.Uint256{root, 1},
+		TxHash: c_type.Uint256{root, 2},
+		Num:    num,
+		Asset:  assets.Asset{Tkt: &assets.Ticket{Category: utils.CurrencyToUint256("TKT"), Value: c_type.Uint256{value}}},
+	}
+	data, err := rlp.EncodeToBytes(&utxo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex.db.Put(rootKey(utxo.Root), data)
+	return utxo
+}
+
+func TestIndexTickets(t *testing.T) {
+	ex, done := newTestExchange(t)
+	defer done()
+
+	pk := c_type.Uint512{1}
+	utxo := ticketUtxo(t, ex, 1, 10, 5)
+
+	batch := ex.db.NewBatch()
+	utxos := map[PkKey][]Utxo{{key: pk, Num: 5}: {utxo}}
+	blocks := map[uint64]*BlockInfo{5: {Num: 5, Outs: []Utxo{utxo}}}
+	if err := ex.indexTickets(batch, utxos, blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	categories := ex.GetTicketCategories(pk)
+	if len(categories) != 1 || categories[utils.CurrencyToUint256("TKT")] != 1 {
+		t.Fatalf("categories mismatch: %v", categories)
+	}
+	records, total, err := ex.GetTickets(pk, nil, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(records) != 1 || records[0].Root != utxo.Root || records[0].Num != 5 {
+		t.Fatalf("tickets mismatch: total %d, %+v", total, records)
+	}
+
+	// Spend the ticket in a later block
+	batch = ex.db.NewBatch()
+	blocks = map[uint64]*BlockInfo{7: {Num: 7, Ins: []c_type.Uint256{utxo.Root}}}
+	if err := ex.indexTickets(batch, map[PkKey][]Utxo{}, blocks); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := ex.GetTickets(pk, nil, 0, 10); total != 0 {
+		t.Fatalf("spent ticket still listed: %d", total)
+	}
+	events, err := ex.GetTicketHistory(c_type.Uint256{10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("history length mismatch: have %d, want 2", len(events))
+	}
+	if events[0].Spent || events[0].Num != 5 || events[0].TxHash != utxo.TxHash {
+		t.Fatalf("receive event mismatch: %+v", events[0])
+	}
+	if !events[1].Spent || events[1].Num != 7 || events[1].Pk != pk || events[1].Pkr != utxo.Pkr {
+		t.Fatalf("spend event mismatch: %+v", events[1])
+	}
+
+	// The ticket comes back, then is spent and received again in one block
+	index := func(num uint64, ins []c_type.Uint256, outs ...Utxo) {
+		batch := ex.db.NewBatch()
+		blocks := map[uint64]*BlockInfo{num: {Num: num, Ins: ins, Outs: outs}}
+		if err := ex.indexTickets(batch, map[PkKey][]Utxo{{key: pk, Num: num}: outs}, blocks); err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.Write(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	back := ticketUtxo(t, ex, 2, 10, 8)
+	index(8, nil, back)
+	again := ticketUtxo(t, ex, 3, 10, 9)
+	index(9, []c_type.Uint256{back.Root}, again)
+	records, total, err = ex.GetTickets(pk, nil, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(records) != 1 || records[0].Root != again.Root {
+		t.Fatalf("ticket received again mismatch: total %d, %+v", total, records)
+	}
+}
+
+func TestRebuildTicketIndex(t *testing.T) {
+	ex, done := newTestExchange(t)
+	defer done()
+
+	pk := c_type.Uint512{2}
+	utxo := ticketUtxo(t, ex, 3, 20, 9)
+	ex.db.Put(utxoPkKey(pk, utxo.Asset.Tkt.Value[:], &utxo.Root), []byte{0})
+
+	ex.rebuildTicketIndex()
+	records, total, err := ex.GetTickets(pk, nil, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || records[0].Value != utxo.Asset.Tkt.Value {
+		t.Fatalf("rebuilt tickets mismatch: total %d, %+v", total, records)
+	}
+	if has, _ := ex.db.Has(tktIndexedKey); !has {
+		t.Fatal("rebuild marker not written")
+	}
+}
+
+func TestRebuildTicketEvents(t *testing.T) {
+	ex, done := newTestExchange(t)
+	defer done()
+
+	pk := c_type.Uint512{4}
+	utxo := ticketUtxo(t, ex, 5, 30, 11)
+	event := TicketEvent{Pk: pk, Pkr: utxo.Pkr, Root: utxo.Root, TxHash: utxo.TxHash, Num: 11}
+	data, err := rlp.EncodeToBytes(&event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ex.db.Put(tktHistoryKey(utxo.Asset.Tkt.Value, 11, &utxo.Root), data)
+
+	ex.rebuildTicketEvents()
+	if has, _ := ex.db.Has(tktEventKey(pk, 11, &utxo.Asset.Tkt.Value, &utxo.Root)); !has {
+		t.Fatal("ticket event not indexed by account")
+	}
+	if has, _ := ex.db.Has(tktEventsKey); !has {
+		t.Fatal("rebuild marker not written")
+	}
+}
@@ -3,6 +3,7 @@ package exchange
 import (
 	"github.com/sero-cash/go-czero-import/c_superzk"
 	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/zero/account"
 	"github.com/sero-cash/go-sero/zero/txs/assets"
 )
 
@@ -15,6 +16,7 @@ type Utxo struct {
 	Asset  assets.Asset
 	IsZ    bool
 	Ignore bool
+	Memo   account.Memo
 	flag   int
 }
 
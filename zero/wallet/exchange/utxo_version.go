@@ -6,6 +6,7 @@ import (
 	"github.com/sero-cash/go-czero-import/c_type"
 	"github.com/sero-cash/go-sero/core/types/vserial"
 	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/account"
 	"github.com/sero-cash/go-sero/zero/txs/assets"
 )
 
@@ -23,13 +24,27 @@ type Utxo_Version1 struct {
 	Ignore bool
 }
 
+type Utxo_Version2 struct {
+	Pkr    c_type.PKr
+	Root   c_type.Uint256
+	TxHash c_type.Uint256
+	Nil    c_type.Uint256
+	Num    uint64
+	Asset  assets.Asset
+	IsZ    bool
+	Ignore bool
+	Memo   account.Memo
+}
+
 func (b *Utxo) DecodeRLP(s *rlp.Stream) error {
 	vs := vserial.NewVSerial()
 	v0 := Utxo_Version0{}
 	v1 := Utxo_Version1{}
+	v2 := Utxo_Version2{}
 
 	vs.Add(&v0, vserial.VERSION_0)
 	vs.Add(&v1, vserial.VERSION_1)
+	vs.Add(&v2, vserial.VERSION_2)
 	if e := s.Decode(&vs); e != nil {
 		return e
 	}
@@ -39,6 +54,8 @@ func (b *Utxo) DecodeRLP(s *rlp.Stream) error {
 		if vs.V() >= vserial.VERSION_1 {
 			SetUtxoForVersion1(b, &v1)
 		}
+	} else if vs.V() >= vserial.VERSION_2 {
+		SetUtxoForVersion2(b, &v2)
 	}
 	return nil
 }
@@ -71,9 +88,40 @@ func SetUtxoForVersion1(b *Utxo, v1 *Utxo_Version1) {
 	b.Ignore = v1.Ignore
 }
 
+func SetVersion2ForUtxo(v2 *Utxo_Version2, b *Utxo) {
+	v2.Pkr = b.Pkr
+	v2.Root = b.Root
+	v2.TxHash = b.TxHash
+	v2.Nil = b.Nil
+	v2.Num = b.Num
+	v2.Asset = b.Asset
+	v2.IsZ = b.IsZ
+	v2.Ignore = b.Ignore
+	v2.Memo = b.Memo
+}
+
+func SetUtxoForVersion2(b *Utxo, v2 *Utxo_Version2) {
+	b.Pkr = v2.Pkr
+	b.Root = v2.Root
+	b.TxHash = v2.TxHash
+	b.Nil = v2.Nil
+	b.Num = v2.Num
+	b.Asset = v2.Asset
+	b.IsZ = v2.IsZ
+	b.Ignore = v2.Ignore
+	b.Memo = v2.Memo
+}
+
 func (b *Utxo) EncodeRLP(w io.Writer) error {
 	vs := vserial.NewVSerial()
 
+	if !b.Memo.IsEmpty() {
+		v2 := Utxo_Version2{}
+		SetVersion2ForUtxo(&v2, b)
+		vs.Add(&v2, vserial.VERSION_2)
+		return rlp.Encode(w, &vs)
+	}
+
 	v0 := Utxo_Version0{}
 	SetVersion0ForUtxo(&v0, b)
 	vs.Add(&v0, vserial.VERSION_0)
@@ -0,0 +1,300 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_superzk"
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/superzk"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/p2p/discover"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+var (
+	watchPrefix = []byte("WATCH")
+
+	ErrWatchExists   = errors.New("the account is already tracked")
+	ErrNotWatched    = errors.New("not found watch-only account")
+	ErrWatchOnly     = errors.New("the account is watch-only")
+	ErrAuditChecksum = errors.New("audit bundle checksum mismatch")
+	ErrAuditSigner   = errors.New("audit bundle not signed by the expected node")
+	ErrAuditNoKey    = errors.New("no key to sign the audit bundle")
+)
+
+// auditBundleVersion is the version of the audit bundles made by this node,
+// the bundles of version 1 were not signed.
+const auditBundleVersion = 2
+
+// "WATCH" + PK => WatchAccount
+func watchKey(pk c_type.Uint512) []byte {
+	return append(watchPrefix, pk[:]...)
+}
+
+// WatchAccount is an account tracked by the exchange from its TK only, without
+// a wallet of the account manager. Its outputs are indexed but it can not
+// spend them.
+type WatchAccount struct {
+	Pk    c_type.Uint512
+	Tk    c_type.Tk
+	Label string
+	At    uint64
+}
+
+// AuditBundle carries the watch-only accounts of an exchange to an audit node,
+// which rebuilds their balances from the chain. It is signed with the node key
+// of the exporting exchange, the audit node checks it against the node ID it
+// got apart.
+type AuditBundle struct {
+	Version   uint
+	Accounts  []WatchAccount
+	Checksum  common.Hash
+	Signature []byte
+}
+
+func (self *AuditBundle) checksum() (ret common.Hash, e error) {
+	data, err := rlp.EncodeToBytes(&self.Accounts)
+	if err != nil {
+		e = err
+		return
+	}
+	ret = common.BytesToHash(crypto.Keccak256([]byte{byte(self.Version)}, data))
+	return
+}
+
+// Signer returns the node ID of the key which signed the bundle.
+func (self *AuditBundle) Signer() (signer discover.NodeID, e error) {
+	sum, err := self.checksum()
+	if err != nil {
+		e = err
+		return
+	}
+	if sum != self.Checksum {
+		e = ErrAuditChecksum
+		return
+	}
+	pub, err := crypto.SigToPub(sum[:], self.Signature)
+	if err != nil {
+		e = err
+		return
+	}
+	signer = discover.PubkeyID(pub)
+	return
+}
+
+// Verify checks that the bundle was signed by the given node and was not
+// altered since it was exported.
+func (self *AuditBundle) Verify(signer discover.NodeID) error {
+	if self.Version != auditBundleVersion {
+		return fmt.Errorf("unsupported audit bundle version %d", self.Version)
+	}
+	if have, err := self.Signer(); err != nil {
+		return err
+	} else if have != signer {
+		return ErrAuditSigner
+	}
+	return nil
+}
+
+// loadWatchAccounts tracks the watch-only accounts stored in the database.
+func (self *Exchange) loadWatchAccounts() {
+	iterator := self.db.NewIteratorWithPrefix(watchPrefix)
+	for iterator.Next() {
+		var watch WatchAccount
+		if err := rlp.DecodeBytes(iterator.Value(), &watch); err != nil {
+			log.Error("Exchange invalid watch-only account RLP", "err", err)
+			continue
+		}
+		self.initWatch(&watch)
+	}
+}
+
+func (self *Exchange) initWatch(watch *WatchAccount) {
+	if _, ok := self.accounts.Load(watch.Pk); ok {
+		return
+	}
+	account := Account{}
+	account.pk = watch.Pk.NewRef()
+	account.tk = watch.Tk.NewRef()
+	copy(account.skr[:], account.tk[:])
+	account.mainPkr = defaultPkr(account.pk, 1)
+	account.isChanged = true
+	account.nextMergeTime = time.Now()
+	account.version = 1
+	if c_superzk.IsSzkTk(account.tk) {
+		account.version = 2
+	}
+	account.label = watch.Label
+	self.accounts.Store(*account.pk, &account)
+
+	if num := self.starNum(account.pk); num > watch.At {
+		self.numbers.Store(*account.pk, num)
+	} else {
+		self.numbers.Store(*account.pk, watch.At)
+	}
+	log.Info("Add watch-only PK", "pk", *utils.Base58Encode(account.pk[:]), "label", watch.Label, "At", self.GetCurrencyNumber(*account.pk))
+}
+
+// defaultPkr derives the PKr of the given index like the wallets of the
+// account manager do.
+func defaultPkr(pk *c_type.Uint512, index uint64) c_type.PKr {
+	r := c_type.Uint256{}
+	copy(r[:], common.LeftPadBytes(utils.EncodeNumber(index), 32))
+	return superzk.Pk2PKr(pk, &r)
+}
+
+// AddWatchAccount starts to index the outputs of the account of the given TK
+// from the given height, without the account being in the keystore.
+func (self *Exchange) AddWatchAccount(tk c_type.Tk, label string, at uint64) (pk c_type.Uint512, e error) {
+	if pk, e = superzk.Tk2Pk(&tk); e != nil {
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if _, ok := self.accounts.Load(pk); ok {
+		e = ErrWatchExists
+		return
+	}
+	watch := WatchAccount{Pk: pk, Tk: tk, Label: label, At: at}
+	if e = self.putWatch(&watch); e != nil {
+		return
+	}
+	self.initWatch(&watch)
+	return
+}
+
+// RemoveWatchAccount stops indexing a watch-only account. Its indexed outputs
+// are kept and are used again if the account is added back.
+func (self *Exchange) RemoveWatchAccount(pk c_type.Uint512) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if _, err := self.getWatch(pk); err != nil {
+		return err
+	}
+	if err := self.db.Delete(watchKey(pk)); err != nil {
+		return err
+	}
+	if account := self.getAccountByPk(pk); account != nil && account.wallet == nil {
+		self.accounts.Delete(pk)
+		self.numbers.Delete(pk)
+	}
+	return nil
+}
+
+// SetWatchLabel changes the label of a watch-only account.
+func (self *Exchange) SetWatchLabel(pk c_type.Uint512, label string) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	watch, err := self.getWatch(pk)
+	if err != nil {
+		return err
+	}
+	watch.Label = label
+	if err := self.putWatch(watch); err != nil {
+		return err
+	}
+	if account := self.getAccountByPk(pk); account != nil {
+		account.label = label
+	}
+	return nil
+}
+
+// GetWatchAccounts returns the watch-only accounts of the exchange.
+func (self *Exchange) GetWatchAccounts() (watches []WatchAccount, e error) {
+	iterator := self.db.NewIteratorWithPrefix(watchPrefix)
+	for iterator.Next() {
+		var watch WatchAccount
+		if e = rlp.DecodeBytes(iterator.Value(), &watch); e != nil {
+			return
+		}
+		watches = append(watches, watch)
+	}
+	return
+}
+
+// ExportAuditBundle returns the signed bundle of the given watch-only
+// accounts, or of all of them if none is given.
+func (self *Exchange) ExportAuditBundle(pks []c_type.Uint512) (bundle *AuditBundle, e error) {
+	if self.auditKey == nil {
+		e = ErrAuditNoKey
+		return
+	}
+	bundle = &AuditBundle{Version: auditBundleVersion}
+	if len(pks) == 0 {
+		if bundle.Accounts, e = self.GetWatchAccounts(); e != nil {
+			return
+		}
+	}
+	for _, pk := range pks {
+		watch, err := self.getWatch(pk)
+		if err != nil {
+			e = err
+			return
+		}
+		bundle.Accounts = append(bundle.Accounts, *watch)
+	}
+	if bundle.Checksum, e = bundle.checksum(); e != nil {
+		return
+	}
+	bundle.Signature, e = crypto.Sign(bundle.Checksum[:], self.auditKey)
+	return
+}
+
+// AuditSigner returns the node ID of the key signing the audit bundles of the
+// exchange.
+func (self *Exchange) AuditSigner() (signer discover.NodeID, e error) {
+	if self.auditKey == nil {
+		e = ErrAuditNoKey
+		return
+	}
+	signer = discover.PubkeyID(&self.auditKey.PublicKey)
+	return
+}
+
+// ImportAuditBundle adds the accounts of an audit bundle signed by the given
+// node as watch-only ones, skipping the accounts already tracked.
+func (self *Exchange) ImportAuditBundle(bundle *AuditBundle, signer discover.NodeID) (pks []c_type.Uint512, e error) {
+	if e = bundle.Verify(signer); e != nil {
+		return
+	}
+	for _, watch := range bundle.Accounts {
+		pk, err := self.AddWatchAccount(watch.Tk, watch.Label, watch.At)
+		if err == ErrWatchExists {
+			continue
+		}
+		if err != nil {
+			e = err
+			return
+		}
+		pks = append(pks, pk)
+	}
+	return
+}
+
+func (self *Exchange) getWatch(pk c_type.Uint512) (*WatchAccount, error) {
+	data, err := self.db.Get(watchKey(pk))
+	if err != nil {
+		return nil, ErrNotWatched
+	}
+	watch := new(WatchAccount)
+	if err := rlp.DecodeBytes(data, watch); err != nil {
+		return nil, err
+	}
+	return watch, nil
+}
+
+func (self *Exchange) putWatch(watch *WatchAccount) error {
+	data, err := rlp.EncodeToBytes(watch)
+	if err != nil {
+		return err
+	}
+	return self.db.Put(watchKey(watch.Pk), data)
+}
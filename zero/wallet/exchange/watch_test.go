@@ -0,0 +1,112 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/p2p/discover"
+)
+
+func TestAuditBundle(t *testing.T) {
+	ex, done := newTestExchange(t)
+	defer done()
+
+	key, _ := crypto.GenerateKey()
+	ex.auditKey = key
+	watches := []WatchAccount{
+		{Pk: c_type.Uint512{1}, Tk: c_type.Tk{1}, Label: "hot", At: 10},
+		{Pk: c_type.Uint512{2}, Tk: c_type.Tk{2}, Label: "cold", At: 20},
+	}
+	for i := range watches {
+		if err := ex.putWatch(&watches[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	bundle, err := ex.ExportAuditBundle(nil)
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(bundle.Accounts) != 2 {
+		t.Fatalf("bundle accounts mismatch: have %d, want 2", len(bundle.Accounts))
+	}
+	signer, err := ex.AuditSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer != discover.PubkeyID(&key.PublicKey) {
+		t.Fatalf("signer mismatch: have %x", signer)
+	}
+	if err := bundle.Verify(signer); err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+
+	// Only the given accounts are exported
+	bundle, err = ex.ExportAuditBundle([]c_type.Uint512{{2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Accounts) != 1 || bundle.Accounts[0].Label != "cold" {
+		t.Fatalf("bundle accounts mismatch: %+v", bundle.Accounts)
+	}
+	if _, err := ex.ExportAuditBundle([]c_type.Uint512{{3}}); err != ErrNotWatched {
+		t.Fatalf("unknown account error mismatch: have %v, want %v", err, ErrNotWatched)
+	}
+
+	// A bundle altered after the export is rejected
+	altered := *bundle
+	altered.Accounts = []WatchAccount{bundle.Accounts[0]}
+	altered.Accounts[0].Tk = c_type.Tk{3}
+	if err := altered.Verify(signer); err != ErrAuditChecksum {
+		t.Fatalf("altered bundle error mismatch: have %v, want %v", err, ErrAuditChecksum)
+	}
+
+	// A bundle resigned by another node is rejected
+	other, _ := crypto.GenerateKey()
+	altered.Checksum, _ = altered.checksum()
+	altered.Signature, _ = crypto.Sign(altered.Checksum[:], other)
+	if err := altered.Verify(signer); err != ErrAuditSigner {
+		t.Fatalf("resigned bundle error mismatch: have %v, want %v", err, ErrAuditSigner)
+	}
+
+	// An unsigned bundle of the first version is rejected
+	altered.Version, altered.Signature = 1, nil
+	if err := altered.Verify(signer); err == nil {
+		t.Fatal("unsigned bundle verified")
+	}
+
+	ex.auditKey = nil
+	if _, err := ex.ExportAuditBundle(nil); err != ErrAuditNoKey {
+		t.Fatalf("export without key error mismatch: have %v, want %v", err, ErrAuditNoKey)
+	}
+}
+
+func TestWatchAccounts(t *testing.T) {
+	ex, done := newTestExchange(t)
+	defer done()
+
+	watch := WatchAccount{Pk: c_type.Uint512{1}, Tk: c_type.Tk{1}, Label: "hot", At: 10}
+	if err := ex.putWatch(&watch); err != nil {
+		t.Fatal(err)
+	}
+	if err := ex.SetWatchLabel(watch.Pk, "cold"); err != nil {
+		t.Fatalf("failed to set label: %v", err)
+	}
+	watches, err := ex.GetWatchAccounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(watches) != 1 || watches[0].Label != "cold" || watches[0].At != 10 {
+		t.Fatalf("watch accounts mismatch: %+v", watches)
+	}
+	if err := ex.RemoveWatchAccount(watch.Pk); err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+	if err := ex.RemoveWatchAccount(watch.Pk); err != ErrNotWatched {
+		t.Fatalf("remove error mismatch: have %v, want %v", err, ErrNotWatched)
+	}
+	if watches, _ := ex.GetWatchAccounts(); len(watches) != 0 {
+		t.Fatalf("removed account still listed: %+v", watches)
+	}
+}
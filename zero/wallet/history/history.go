@@ -0,0 +1,483 @@
+// Package history indexes the history of the accounts of the wallets of a
+// node: the outs received, the outs spent with their spending tx, the fees and
+// the contract calls of the txs sent. Unlike the exchange, it keeps no balances
+// and does not build txs.
+package history
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/robfig/cron"
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/superzk"
+	"github.com/sero-cash/go-sero/accounts"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/event"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/txtool/flight"
+	"github.com/sero-cash/go-sero/zero/utils"
+)
+
+// Kinds of the history records.
+const (
+	RecordIn       uint8 = iota // Out received by the account
+	RecordOut                   // Out of the account spent
+	RecordFee                   // Fee of a tx sent by the account
+	RecordContract              // Asset sent to a contract by a tx of the account
+)
+
+var (
+	numPrefix     = []byte("NUM")
+	nilPrefix     = []byte("NIL")
+	historyPrefix = []byte("HISTORY")
+
+	ErrNotIndexed = errors.New("the account is not indexed")
+)
+
+// fetchCount is the number of blocks indexed at once.
+var fetchCount = uint64(1000)
+
+// Cursor is the position of a record in the history of an account.
+type Cursor struct {
+	Num   uint64
+	Index uint32
+}
+
+// Record is an entry of the history of an account.
+type Record struct {
+	Kind   uint8
+	Num    uint64
+	TxHash c_type.Uint256
+	Pkr    c_type.PKr     // PKr of the out received or spent, or contract called
+	Root   c_type.Uint256 // Out received or spent
+	Asset  assets.Asset
+}
+
+// spent is the out received by an account, found by its nil or its root once
+// it gets spent.
+type spent struct {
+	Pk     c_type.Uint512
+	Nil    c_type.Uint256
+	Record Record
+}
+
+type account struct {
+	pk *c_type.Uint512
+	tk *c_type.Tk
+}
+
+type History struct {
+	db *serodb.LDBDatabase
+	bc *core.BlockChain
+
+	accounts  sync.Map // PK => *account
+	numbers   sync.Map // PK => next block to index
+	indexLock sync.Mutex
+	closed    bool // whether the database is closed, guarded by indexLock
+	cron      *cron.Cron
+
+	updater event.Subscription        // Wallet update subscriptions for all backends
+	update  chan accounts.WalletEvent // Subscription sink for backend wallet changes
+	quit    chan chan error
+	lock    sync.RWMutex
+}
+
+var current_history *History
+
+func CurrentHistory() *History {
+	return current_history
+}
+
+func NewHistory(dbpath string, bc *core.BlockChain, accountManager *accounts.Manager) *History {
+	update := make(chan accounts.WalletEvent, 1)
+	updater := accountManager.Subscribe(update)
+
+	history := &History{
+		bc:      bc,
+		update:  update,
+		updater: updater,
+		quit:    make(chan chan error),
+	}
+	current_history = history
+
+	db, err := serodb.NewLDBDatabase(dbpath, 256, 256)
+	if err != nil {
+		panic(err)
+	}
+	history.db = db
+
+	for _, w := range accountManager.Wallets() {
+		history.initWallet(w)
+	}
+
+	history.cron = AddJob("0/10 * * * * ?", history.fetchBlockInfo)
+
+	go history.updateAccount()
+	log.Info("Init NewHistory success")
+	return history
+}
+
+func (self *History) initWallet(w accounts.Wallet) {
+	a := w.Accounts()[0]
+	pk := a.GetPk()
+	if _, ok := self.accounts.Load(pk); ok {
+		return
+	}
+	self.accounts.Store(pk, &account{pk: pk.NewRef(), tk: a.Tk.ToTk().NewRef()})
+	if num := self.startNum(&pk); num > a.At {
+		self.numbers.Store(pk, num)
+	} else {
+		self.numbers.Store(pk, a.At)
+	}
+}
+
+func (self *History) updateAccount() {
+	// Close all subscriptions when the manager terminates
+	defer func() {
+		self.lock.Lock()
+		self.updater.Unsubscribe()
+		self.updater = nil
+		self.lock.Unlock()
+	}()
+
+	for {
+		select {
+		case event := <-self.update:
+			self.lock.Lock()
+			switch event.Kind {
+			case accounts.WalletArrived:
+				self.initWallet(event.Wallet)
+			case accounts.WalletDropped:
+				pk := event.Wallet.Accounts()[0].Address.ToUint512()
+				self.accounts.Delete(pk)
+				self.numbers.Delete(pk)
+			}
+			self.lock.Unlock()
+
+		case errc := <-self.quit:
+			errc <- nil
+			return
+		}
+	}
+}
+
+// Stop stops indexing and closes the database, once the indexing in progress
+// is done.
+func (self *History) Stop() {
+	self.cron.Stop()
+	errc := make(chan error)
+	self.quit <- errc
+	<-errc
+
+	self.indexLock.Lock()
+	defer self.indexLock.Unlock()
+	self.closed = true
+	self.db.Close()
+}
+
+func (self *History) startNum(pk *c_type.Uint512) uint64 {
+	value, err := self.db.Get(numKey(*pk))
+	if err != nil {
+		return 0
+	}
+	return utils.DecodeNumber(value)
+}
+
+// IndexedNumber returns the next block to index for an account.
+func (self *History) IndexedNumber(pk c_type.Uint512) (uint64, bool) {
+	if value, ok := self.numbers.Load(pk); ok {
+		return value.(uint64), true
+	}
+	return 0, false
+}
+
+func (self *History) fetchBlockInfo() {
+	if txtool.Ref_inst.Bc == nil || !txtool.Ref_inst.Bc.IsValid() {
+		return
+	}
+	for {
+		// Index together the accounts at the lowest block, up to the next one
+		indexs := map[uint64][]c_type.Uint512{}
+		orders := []uint64{}
+		self.numbers.Range(func(key, value interface{}) bool {
+			num := value.(uint64)
+			if _, ok := indexs[num]; !ok {
+				orders = append(orders, num)
+			}
+			indexs[num] = append(indexs[num], key.(c_type.Uint512))
+			return true
+		})
+		if len(orders) == 0 {
+			return
+		}
+		sort.Slice(orders, func(i, j int) bool { return orders[i] < orders[j] })
+
+		start, count := orders[0], fetchCount
+		if len(orders) > 1 && orders[1]-start < count {
+			count = orders[1] - start
+		}
+		if self.index(start, count, indexs[start]) < int(count) {
+			return
+		}
+	}
+}
+
+func (self *History) ownPkr(pks []c_type.Uint512, pkr *c_type.PKr) *account {
+	for _, pk := range pks {
+		if value, ok := self.accounts.Load(pk); ok {
+			if a := value.(*account); superzk.IsMyPKr(a.tk, pkr) {
+				return a
+			}
+		}
+	}
+	return nil
+}
+
+func (self *History) getSpent(key c_type.Uint256) (s spent, ok bool) {
+	data, err := self.db.Get(nilKey(key))
+	if err != nil {
+		return
+	}
+	if err := rlp.DecodeBytes(data, &s); err != nil {
+		log.Error("Invalid history spent RLP", "key", common.Bytes2Hex(key[:]), "err", err)
+		return
+	}
+	return s, true
+}
+
+func (self *History) index(start, count uint64, pks []c_type.Uint512) int {
+	self.indexLock.Lock()
+	defer self.indexLock.Unlock()
+	if self.closed {
+		return 0
+	}
+
+	// Skip the accounts dropped since the cursors were read
+	indexed := []c_type.Uint512{}
+	for _, pk := range pks {
+		if value, ok := self.numbers.Load(pk); ok && value.(uint64) == start {
+			indexed = append(indexed, pk)
+		}
+	}
+	if len(indexed) == 0 {
+		return 0
+	}
+	pks = indexed
+	indexing := map[c_type.Uint512]bool{}
+	for _, pk := range pks {
+		indexing[pk] = true
+	}
+
+	blocks, err := flight.SRI_Inst.GetBlocksInfo(start, count)
+	if err != nil {
+		log.Info("History GetBlocksInfo", "error", err)
+		return 0
+	}
+	if len(blocks) == 0 {
+		return 0
+	}
+
+	batch := self.db.NewBatch()
+	spents := map[c_type.Uint256]spent{} // Outs received in this pass by nil and by root
+	for _, block := range blocks {
+		num := uint64(block.Num)
+		records := map[c_type.Uint512][]Record{}
+
+		for _, out := range block.Outs {
+			pkr := out.State.OS.ToPKr()
+			if pkr == nil {
+				continue
+			}
+			a := self.ownPkr(pks, pkr)
+			if a == nil {
+				continue
+			}
+			douts := flight.DecOut(a.tk, []txtool.Out{out})
+			if len(douts) == 0 || len(douts[0].Nils) == 0 {
+				continue
+			}
+			record := Record{Kind: RecordIn, Num: num, TxHash: out.State.TxHash, Pkr: *pkr, Root: out.Root, Asset: douts[0].Asset}
+			records[*a.pk] = append(records[*a.pk], record)
+
+			s := spent{Pk: *a.pk, Nil: douts[0].Nils[0], Record: record}
+			data, err := rlp.EncodeToBytes(&s)
+			if err != nil {
+				log.Error("History encode spent", "error", err)
+				return 0
+			}
+			spents[s.Nil], spents[out.Root] = s, s
+			batch.Put(nilKey(s.Nil), data)
+			batch.Put(nilKey(out.Root), data)
+		}
+
+		chainBlock := self.bc.GetBlock(common.BytesToHash(block.Hash[:]), num)
+		spentBy := txtool.SpentBy(chainBlock) // Nils and roots spent => spending tx
+		if chainBlock != nil {
+			for _, tx := range chainBlock.Transactions() {
+				var hash c_type.Uint256
+				copy(hash[:], tx.Hash().Bytes())
+				ztx := tx.GetZZSTX()
+				a := self.ownPkr(pks, &ztx.From)
+				if a == nil {
+					continue
+				}
+				fee := ztx.Fee.Clone()
+				records[*a.pk] = append(records[*a.pk], Record{Kind: RecordFee, Num: num, TxHash: hash, Pkr: ztx.From, Asset: assets.Asset{Tkn: &fee}})
+				if ztx.IsOpContract() {
+					record := Record{Kind: RecordContract, Num: num, TxHash: hash}
+					if to := ztx.ContractAddress(); to != nil {
+						record.Pkr = *to
+					}
+					if asset := ztx.ContractAsset(); asset != nil {
+						record.Asset = asset.Clone()
+					}
+					records[*a.pk] = append(records[*a.pk], record)
+				}
+			}
+		}
+
+		done := map[c_type.Uint256]bool{}
+		for _, key := range block.Nils {
+			s, ok := spents[key]
+			if !ok {
+				if s, ok = self.getSpent(key); !ok || !indexing[s.Pk] {
+					continue
+				}
+			}
+			if done[s.Record.Root] {
+				continue
+			}
+			done[s.Record.Root] = true
+
+			record := s.Record
+			record.Kind = RecordOut
+			record.Num = num
+			record.TxHash = spentBy[key]
+			records[s.Pk] = append(records[s.Pk], record)
+
+			batch.Delete(nilKey(s.Nil))
+			batch.Delete(nilKey(s.Record.Root))
+			delete(spents, s.Nil)
+			delete(spents, s.Record.Root)
+		}
+
+		for pk, list := range records {
+			for i := range list {
+				data, err := rlp.EncodeToBytes(&list[i])
+				if err != nil {
+					log.Error("History encode record", "error", err)
+					return 0
+				}
+				batch.Put(historyKey(pk, num, uint32(i)), data)
+			}
+		}
+	}
+
+	count = uint64(len(blocks))
+	num := uint64(blocks[count-1].Num) + 1
+	data := utils.EncodeNumber(num)
+	for _, pk := range pks {
+		batch.Put(numKey(pk), data)
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("History write", "error", err)
+		return 0
+	}
+	for _, pk := range pks {
+		self.numbers.Store(pk, num)
+	}
+	log.Info("History indexed", "blockNumber", num-1)
+	return int(count)
+}
+
+// GetRecords returns at most limit records of the history of an account, from
+// the cursor to the block end excluded. The cursor of the next record is
+// returned if the limit was reached before the end.
+func (self *History) GetRecords(pk c_type.Uint512, from Cursor, end uint64, limit int) (records []Record, next *Cursor, err error) {
+	if _, ok := self.accounts.Load(pk); !ok {
+		return nil, nil, ErrNotIndexed
+	}
+	return readRecords(self.db, pk, from, end, limit)
+}
+
+func readRecords(db *serodb.LDBDatabase, pk c_type.Uint512, from Cursor, end uint64, limit int) (records []Record, next *Cursor, err error) {
+	prefix := append(append([]byte{}, historyPrefix...), pk[:]...)
+	iterator := db.NewIteratorWithPrefix(prefix)
+	defer iterator.Release()
+
+	for ok := iterator.Seek(historyKey(pk, from.Num, from.Index)); ok; ok = iterator.Next() {
+		key := iterator.Key()
+		num := utils.DecodeNumber(key[len(prefix) : len(prefix)+8])
+		if num >= end {
+			break
+		}
+		if len(records) == limit {
+			next = &Cursor{Num: num, Index: binary.BigEndian.Uint32(key[len(prefix)+8:])}
+			break
+		}
+		var record Record
+		if err = rlp.DecodeBytes(iterator.Value(), &record); err != nil {
+			return
+		}
+		records = append(records, record)
+	}
+	return
+}
+
+// "NUM" + PK => next block to index
+func numKey(pk c_type.Uint512) []byte {
+	return append(append([]byte{}, numPrefix...), pk[:]...)
+}
+
+// "NIL" + nil/root => spent
+func nilKey(key c_type.Uint256) []byte {
+	return append(append([]byte{}, nilPrefix...), key[:]...)
+}
+
+// "HISTORY" + PK + num + index => Record
+func historyKey(pk c_type.Uint512, num uint64, index uint32) []byte {
+	key := append(append([]byte{}, historyPrefix...), pk[:]...)
+	key = append(key, utils.EncodeNumber(num)...)
+	var enc [4]byte
+	binary.BigEndian.PutUint32(enc[:], index)
+	return append(key, enc[:]...)
+}
+
+func AddJob(spec string, run RunFunc) *cron.Cron {
+	c := cron.New()
+	c.AddJob(spec, &RunJob{run: run})
+	c.Start()
+	return c
+}
+
+type (
+	RunFunc func()
+)
+
+type RunJob struct {
+	runing int32
+	run    RunFunc
+}
+
+func (r *RunJob) Run() {
+	x := atomic.LoadInt32(&r.runing)
+	if x == 1 {
+		return
+	}
+
+	atomic.StoreInt32(&r.runing, 1)
+	defer func() {
+		atomic.StoreInt32(&r.runing, 0)
+	}()
+
+	r.run()
+}
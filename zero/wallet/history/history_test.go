@@ -0,0 +1,174 @@
+package history
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/zconfig"
+)
+
+// maxTestRecords is the limit of the history pages read by the tests.
+const maxTestRecords = 100
+
+func TestReadRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := serodb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	pk, other := c_type.Uint512{1}, c_type.Uint512{2}
+	put := func(pk c_type.Uint512, kind uint8, num uint64, index uint32) {
+		data, err := rlp.EncodeToBytes(&Record{Kind: kind, Num: num, Root: c_type.Uint256{byte(num), byte(index)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Put(historyKey(pk, num, index), data)
+	}
+	put(pk, RecordIn, 5, 0)
+	put(pk, RecordFee, 7, 0)
+	put(pk, RecordOut, 7, 1)
+	put(other, RecordIn, 7, 0)
+	put(pk, RecordIn, 300, 0)
+	db.Put(numKey(pk), []byte{1})
+
+	check := func(begin, end uint64, kinds ...uint8) {
+		records, _, err := readRecords(db, pk, Cursor{Num: begin}, end, len(kinds)+1)
+		if err != nil {
+			t.Fatalf("range %d-%d: %v", begin, end, err)
+		}
+		if len(records) != len(kinds) {
+			t.Fatalf("range %d-%d: record count mismatch: have %d, want %d", begin, end, len(records), len(kinds))
+		}
+		for i, record := range records {
+			if record.Kind != kinds[i] {
+				t.Errorf("range %d-%d: record %d kind mismatch: have %d, want %d", begin, end, i, record.Kind, kinds[i])
+			}
+		}
+	}
+	check(0, 1000, RecordIn, RecordFee, RecordOut, RecordIn)
+	check(6, 300, RecordFee, RecordOut)
+	check(300, 301, RecordIn)
+	check(8, 300)
+
+	// The pages end at the limit, the next one starts at the cursor returned
+	records, next, err := readRecords(db, pk, Cursor{}, 1000, 2)
+	if err != nil || len(records) != 2 || next == nil || *next != (Cursor{Num: 7, Index: 1}) {
+		t.Fatalf("first page mismatch: %d records, next %+v, %v", len(records), next, err)
+	}
+	records, next, err = readRecords(db, pk, *next, 1000, 2)
+	if err != nil || len(records) != 2 || records[0].Kind != RecordOut || next != nil {
+		t.Fatalf("last page mismatch: %d records, next %+v, %v", len(records), next, err)
+	}
+	if _, next, _ = readRecords(db, pk, Cursor{}, 300, 3); next != nil {
+		t.Fatalf("cursor past the end: %+v", next)
+	}
+}
+
+// Tests that the outs received and spent and the fees of an account are
+// indexed from the blocks of a private chain.
+func TestHistoryIndex(t *testing.T) {
+	cpt.ZeroInit(cpt.NET_Alpha)
+	seroparam.Init_Dev(true)
+	zconfig.Init_SkipProof(true)
+	defer seroparam.Init_Dev(false)
+	defer zconfig.Init_SkipProof(false)
+
+	sero := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	var (
+		alice = core.NewTestAccount("alice")
+		bob   = core.NewTestAccount("bob")
+		carol = core.NewTestAccount("carol")
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{alice.Address(): {Balance: new(big.Int).Mul(big.NewInt(1000000), sero)}},
+		}
+		chainDb = serodb.NewMemDatabase()
+	)
+	gspec.MustCommit(chainDb)
+	chain, err := core.NewBlockChain(chainDb, nil, params.TestChainConfig, ethash.NewFullFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	txtool.Ref_inst.SetBC(&core.State1BlockChain{Bc: chain})
+
+	var received, sent *types.Transaction
+	_, err = core.GeneratePrivateChain(chain, 2+int(seroparam.DefaultConfirmedBlock()), func(i int, b *core.BlockGen) {
+		switch i {
+		case 0:
+			received = b.Transfer(alice, bob.Address(), "SERO", new(big.Int).Mul(big.NewInt(1000), sero))
+		case 1:
+			sent = b.Transfer(bob, carol.Address(), "SERO", sero)
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to generate chain: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := serodb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	history := &History{db: db, bc: chain}
+	history.accounts.Store(bob.Pk, &account{pk: &bob.Pk, tk: &bob.Tk})
+	history.numbers.Store(bob.Pk, uint64(0))
+	if count := history.index(0, fetchCount, []c_type.Uint512{bob.Pk}); count != 3 {
+		t.Fatalf("indexed block count mismatch: have %d, want 3", count)
+	}
+	if num, _ := history.IndexedNumber(bob.Pk); num != 3 {
+		t.Fatalf("next block mismatch: have %d, want 3", num)
+	}
+
+	records, _, err := history.GetRecords(bob.Pk, Cursor{}, 3, maxTestRecords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := func(tx *types.Transaction) (hash c_type.Uint256) {
+		copy(hash[:], tx.Hash().Bytes())
+		return
+	}
+	kinds := map[uint8][]Record{}
+	for _, record := range records {
+		kinds[record.Kind] = append(kinds[record.Kind], record)
+	}
+	in := kinds[RecordIn]
+	if len(in) != 2 || in[0].Num != 1 || in[0].TxHash != hash(received) || in[0].Asset.Tkn.Value.ToIntRef().Cmp(new(big.Int).Mul(big.NewInt(1000), sero)) != 0 {
+		t.Fatalf("received outs mismatch: %+v", in)
+	}
+	if in[1].Num != 2 || in[1].TxHash != hash(sent) {
+		t.Fatalf("change out mismatch: %+v", in[1])
+	}
+	if out := kinds[RecordOut]; len(out) != 1 || out[0].Num != 2 || out[0].Root != in[0].Root || out[0].TxHash != hash(sent) {
+		t.Fatalf("spent outs mismatch: %+v", out)
+	}
+	if fee := kinds[RecordFee]; len(fee) != 1 || fee[0].Num != 2 || fee[0].TxHash != hash(sent) {
+		t.Fatalf("fees mismatch: %+v", fee)
+	}
+}
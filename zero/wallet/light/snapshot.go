@@ -0,0 +1,21 @@
+package light
+
+import (
+	"errors"
+
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/wallet/snapshot"
+)
+
+// SnapshotManifest returns the manifest of a snapshot of a light node
+// database with the last block indexed.
+func SnapshotManifest(db *serodb.LDBDatabase, chainDb serodb.Database) (*snapshot.Manifest, error) {
+	value, err := db.Get(numKey())
+	if err != nil || len(value) != 8 {
+		return nil, errors.New("no block indexed in the light node database")
+	}
+	manifest := &snapshot.Manifest{Kind: snapshot.KindLight, Number: bytesToUint64(value)}
+	manifest.Hash = rawdb.ReadCanonicalHash(chainDb, manifest.Number)
+	return manifest, nil
+}
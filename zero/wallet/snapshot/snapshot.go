@@ -0,0 +1,275 @@
+// Package snapshot exports and imports the index databases of the exchange and
+// of the light node, so that a new node does not index the chain again.
+//
+// A snapshot file is the RLP encoding of its manifest followed by the entries
+// of the database in the key order.
+package snapshot
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/crypto/sha3"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
+// Version is the version of the snapshot format.
+const Version = 1
+
+// The kinds of the snapshots.
+const (
+	KindExchange = "exchange"
+	KindLight    = "light"
+)
+
+var (
+	ErrVersion      = errors.New("unsupported snapshot version")
+	ErrContentHash  = errors.New("snapshot content hash mismatch")
+	ErrNotEmpty     = errors.New("the database is not empty")
+	ErrForeignChain = errors.New("the snapshot is from a foreign chain")
+)
+
+// Manifest describes the content of a snapshot.
+type Manifest struct {
+	Version     uint
+	Kind        string
+	Number      uint64           // Last block indexed in the snapshot
+	Hash        common.Hash      // Hash of the last block indexed
+	Pks         []c_type.Uint512 // Accounts indexed, for the exchange
+	Entries     uint64
+	ContentHash common.Hash
+}
+
+// CheckChain checks that the last block indexed in the snapshot is in the
+// canonical chain of the given database.
+func (self *Manifest) CheckChain(chainDb serodb.Database) error {
+	hash := rawdb.ReadCanonicalHash(chainDb, self.Number)
+	if hash == (common.Hash{}) {
+		return fmt.Errorf("the block %d of the snapshot is not synced yet", self.Number)
+	}
+	if hash != self.Hash {
+		return fmt.Errorf("%v: block %d is %x, want %x", ErrForeignChain, self.Number, hash, self.Hash)
+	}
+	return nil
+}
+
+type entry struct {
+	Key   []byte
+	Value []byte
+}
+
+// contentHash hashes and counts the entries of an iterator.
+func contentHash(it iterator.Iterator) (hash common.Hash, count uint64, err error) {
+	hasher := sha3.NewKeccak256()
+	for it.Next() {
+		if err = rlp.Encode(hasher, &entry{it.Key(), it.Value()}); err != nil {
+			return
+		}
+		count++
+	}
+	if err = it.Error(); err != nil {
+		return
+	}
+	hasher.Sum(hash[:0])
+	return
+}
+
+// Export writes a snapshot of the database with the given manifest, whose
+// entry count and content hash are filled. The database is read at a single
+// point in time so that it can keep being written.
+func Export(db *serodb.LDBDatabase, path string, manifest Manifest) (*Manifest, error) {
+	snap, err := db.LDB().GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Release()
+
+	it := snap.NewIterator(nil, nil)
+	manifest.Version = Version
+	manifest.ContentHash, manifest.Entries, err = contentHash(it)
+	it.Release()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := rlp.Encode(w, &manifest); err != nil {
+		return nil, err
+	}
+	it = snap.NewIterator(nil, nil)
+	defer it.Release()
+	for it.Next() {
+		if err := rlp.Encode(w, &entry{it.Key(), it.Value()}); err != nil {
+			return nil, err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return &manifest, f.Sync()
+}
+
+// open opens a snapshot file and reads its manifest.
+func open(path string) (*os.File, *rlp.Stream, *Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	stream := rlp.NewStream(bufio.NewReader(f), 0)
+	manifest := new(Manifest)
+	if err := stream.Decode(manifest); err != nil {
+		f.Close()
+		return nil, nil, nil, err
+	}
+	if manifest.Version != Version {
+		f.Close()
+		return nil, nil, nil, fmt.Errorf("%v: %d", ErrVersion, manifest.Version)
+	}
+	return f, stream, manifest, nil
+}
+
+// iterate calls fn for every entry of a snapshot file.
+func iterate(path string, fn func(e *entry) error) (*Manifest, error) {
+	f, stream, manifest, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	for {
+		var e entry
+		if err := stream.Decode(&e); err == io.EOF {
+			return manifest, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if err := fn(&e); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ReadManifest returns the manifest of a snapshot file.
+func ReadManifest(path string) (*Manifest, error) {
+	f, _, manifest, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return manifest, nil
+}
+
+// Verify checks the entries of a snapshot file against its manifest.
+func Verify(path string) (*Manifest, error) {
+	hasher := sha3.NewKeccak256()
+	count := uint64(0)
+	manifest, err := iterate(path, func(e *entry) error {
+		count++
+		return rlp.Encode(hasher, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var hash common.Hash
+	hasher.Sum(hash[:0])
+	if hash != manifest.ContentHash || count != manifest.Entries {
+		return nil, ErrContentHash
+	}
+	return manifest, nil
+}
+
+// Import writes the entries of a snapshot file of the given chain into an
+// empty database. The entries are checked against the manifest while written,
+// and the database is emptied again if they don't match or the import fails.
+func Import(db *serodb.LDBDatabase, chainDb serodb.Database, path string) (manifest *Manifest, err error) {
+	it := db.NewIterator()
+	empty := !it.Next()
+	it.Release()
+	if !empty {
+		return nil, ErrNotEmpty
+	}
+	if manifest, err = ReadManifest(path); err != nil {
+		return nil, err
+	}
+	if err = manifest.CheckChain(chainDb); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if cerr := clear(db); cerr != nil {
+			err = fmt.Errorf("%v, failed to empty the database: %v", err, cerr)
+		}
+		manifest = nil
+	}()
+
+	hasher := sha3.NewKeccak256()
+	count := uint64(0)
+	batch := db.NewBatch()
+	manifest, err = iterate(path, func(e *entry) error {
+		count++
+		if err := rlp.Encode(hasher, e); err != nil {
+			return err
+		}
+		if err := batch.Put(e.Key, e.Value); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= serodb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	if err = batch.Write(); err != nil {
+		return
+	}
+	var hash common.Hash
+	hasher.Sum(hash[:0])
+	if hash != manifest.ContentHash || count != manifest.Entries {
+		err = ErrContentHash
+	}
+	return
+}
+
+// clear deletes every entry of the database.
+func clear(db *serodb.LDBDatabase) error {
+	it := db.NewIterator()
+	defer it.Release()
+	batch := db.NewBatch()
+	for it.Next() {
+		if err := batch.Delete(common.CopyBytes(it.Key())); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= serodb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
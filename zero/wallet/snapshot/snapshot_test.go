@@ -0,0 +1,173 @@
+package snapshot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/serodb"
+)
+
+// newTestChain returns a chain database whose canonical block 7 is the one of
+// the test snapshots.
+func newTestChain() (serodb.Database, common.Hash) {
+	db := serodb.NewMemDatabase()
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(7)})
+	rawdb.WriteCanonicalHash(db, block.Hash(), 7)
+	return db, block.Hash()
+}
+
+// exportTestSnapshot exports a database of 1000 entries to a snapshot file
+// indexed up to the given block.
+func exportTestSnapshot(t *testing.T, dir string, hash common.Hash) (string, *Manifest) {
+	src, err := serodb.NewLDBDatabase(filepath.Join(dir, "src"), 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for i := 0; i < 1000; i++ {
+		src.Put([]byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprintf("value%d", i)))
+	}
+	path := filepath.Join(dir, "snapshot")
+	manifest, err := Export(src, path, Manifest{Kind: KindExchange, Number: 7, Hash: hash, Pks: []c_type.Uint512{{1}}})
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if manifest.Entries != 1000 || manifest.Version != Version {
+		t.Fatalf("manifest mismatch: %+v", manifest)
+	}
+	return path, manifest
+}
+
+// isEmpty tells whether a database has no entries.
+func isEmpty(db *serodb.LDBDatabase) bool {
+	it := db.NewIterator()
+	defer it.Release()
+	return !it.Next()
+}
+
+func TestExportImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chainDb, hash := newTestChain()
+	path, manifest := exportTestSnapshot(t, dir, hash)
+
+	dst, err := serodb.NewLDBDatabase(filepath.Join(dir, "dst"), 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	imported, err := Import(dst, chainDb, path)
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if imported.ContentHash != manifest.ContentHash || imported.Kind != KindExchange || len(imported.Pks) != 1 || imported.Pks[0] != manifest.Pks[0] {
+		t.Fatalf("imported manifest mismatch: have %+v, want %+v", imported, manifest)
+	}
+	for i := 0; i < 1000; i++ {
+		value, err := dst.Get([]byte(fmt.Sprintf("key%04d", i)))
+		if err != nil || string(value) != fmt.Sprintf("value%d", i) {
+			t.Fatalf("entry %d mismatch: %s, %v", i, value, err)
+		}
+	}
+	if _, err := Import(dst, chainDb, path); err != ErrNotEmpty {
+		t.Fatalf("imported into a non empty database: %v", err)
+	}
+
+	// Corrupt the last entry
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1]++
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(path); err != ErrContentHash {
+		t.Fatalf("verified a corrupted snapshot: %v", err)
+	}
+}
+
+func TestCheckChain(t *testing.T) {
+	db := serodb.NewMemDatabase()
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(5)})
+	rawdb.WriteCanonicalHash(db, block.Hash(), 5)
+
+	manifest := Manifest{Number: 5, Hash: block.Hash()}
+	if err := manifest.CheckChain(db); err != nil {
+		t.Fatalf("rejected the local chain: %v", err)
+	}
+	manifest.Hash[0]++
+	if err := manifest.CheckChain(db); err == nil {
+		t.Fatalf("accepted a foreign chain")
+	}
+	manifest.Number = 6
+	if err := manifest.CheckChain(db); err == nil {
+		t.Fatalf("accepted a block not synced")
+	}
+}
+
+func TestImportRejects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chainDb, hash := newTestChain()
+	path, _ := exportTestSnapshot(t, dir, hash)
+	dst, err := serodb.NewLDBDatabase(filepath.Join(dir, "dst"), 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	// A snapshot of another chain is rejected before anything is written
+	foreignDb := serodb.NewMemDatabase()
+	rawdb.WriteCanonicalHash(foreignDb, common.Hash{1}, 7)
+	if _, err := Import(dst, foreignDb, path); err == nil || !strings.Contains(err.Error(), ErrForeignChain.Error()) {
+		t.Fatalf("imported a snapshot of a foreign chain: %v", err)
+	}
+	if !isEmpty(dst) {
+		t.Fatal("foreign snapshot written")
+	}
+
+	// A tampered entry is detected once written, and what was written removed
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1]++
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if manifest, err := Import(dst, chainDb, path); err != ErrContentHash || manifest != nil {
+		t.Fatalf("imported a tampered snapshot: %v", err)
+	}
+	if !isEmpty(dst) {
+		t.Fatal("tampered snapshot left in the database")
+	}
+
+	// A truncated file fails in the middle of the import
+	if err := ioutil.WriteFile(path, data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Import(dst, chainDb, path); err == nil {
+		t.Fatal("imported a truncated snapshot")
+	}
+	if !isEmpty(dst) {
+		t.Fatal("truncated snapshot left in the database")
+	}
+}
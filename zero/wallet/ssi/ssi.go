@@ -1,11 +1,13 @@
 package ssi
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sero-cash/go-czero-import/c_superzk"
 
@@ -18,6 +20,7 @@ import (
 	"github.com/sero-cash/go-sero/zero/txtool"
 
 	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/serodb"
 	"github.com/sero-cash/go-sero/zero/txs/assets"
 	"github.com/sero-cash/go-sero/zero/utils"
 
@@ -25,10 +28,74 @@ import (
 )
 
 type SSI struct {
+	store *TxStore
+	lock  sync.RWMutex // guards store, held while the store is used
+	quit  chan struct{}
+	wg    sync.WaitGroup
 }
 
+var errStoreNotStarted = errors.New("SSI tx store not started")
+
 var SSI_Inst = SSI{}
 
+// Start opens the store of the generated transactions and starts tracking
+// their state.
+func (self *SSI) Start(dbpath string, chain serodb.Database, pool txPool) (e error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.store, e = NewTxStore(dbpath, chain, pool, DefaultTxTTL); e != nil {
+		return
+	}
+	self.quit = make(chan struct{})
+	self.wg.Add(1)
+	go self.update()
+	return
+}
+
+// Stop stops tracking the transactions and closes their store.
+func (self *SSI) Stop() {
+	self.lock.RLock()
+	started := self.store != nil
+	self.lock.RUnlock()
+	if !started {
+		return
+	}
+	close(self.quit)
+	self.wg.Wait()
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.store.Close()
+	self.store = nil
+}
+
+// withStore calls fn with the store, which is not closed until fn returns.
+func (self *SSI) withStore(fn func(store *TxStore) error) error {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+	if self.store == nil {
+		return errStoreNotStarted
+	}
+	return fn(self.store)
+}
+
+func (self *SSI) update() {
+	defer self.wg.Done()
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			self.withStore(func(store *TxStore) error {
+				store.Update(time.Now())
+				return nil
+			})
+		case <-self.quit:
+			return
+		}
+	}
+}
+
 func (self *SSI) CreateKr(flag bool) (kr txtool.Kr) {
 	rnd := c_superzk.RandomFr()
 	zsk := c_superzk.RandomFr()
@@ -99,8 +166,6 @@ func (self *SSI) Detail(roots []c_type.Uint256, skr *c_type.PKr) (douts []txtool
 	return
 }
 
-var txMap sync.Map
-
 func (self *SSI) GenTxParam(param *PreTxParam) (p txtool.GTxParam, e error) {
 	log.Printf("genTx start")
 	p.Gas = param.Gas
@@ -228,7 +293,11 @@ func (self *SSI) GenTx(param *PreTxParam) (hash c_type.Uint256, e error) {
 			return
 		} else {
 			hash = gtx.Tx.ToHash()
-			txMap.Store(hash, &gtx)
+			if e = self.withStore(func(store *TxStore) error {
+				return store.Put(hash, &gtx, time.Now())
+			}); e != nil {
+				return
+			}
 			log.Printf("genTx success hash: %s", common.Bytes2Hex(hash[:]))
 			return
 		}
@@ -236,14 +305,32 @@ func (self *SSI) GenTx(param *PreTxParam) (hash c_type.Uint256, e error) {
 }
 
 func (self *SSI) GetTx(txhash c_type.Uint256) (tx *txtool.GTx, e error) {
-	if ld, ok := txMap.Load(txhash); !ok {
-		e = fmt.Errorf("SSI GetTx Failed : %v", txhash)
-	} else {
-		if ld == nil {
-			e = fmt.Errorf("SSI GetTx Nil : %v", txhash)
-		} else {
-			tx = ld.(*txtool.GTx)
-		}
-	}
+	e = self.withStore(func(store *TxStore) (err error) {
+		tx, err = store.Get(txhash, time.Now())
+		return
+	})
+	return
+}
+
+// Committed records that a transaction was handed to the pool.
+func (self *SSI) Committed(txhash c_type.Uint256) (e error) {
+	return self.withStore(func(store *TxStore) error {
+		return store.Committed(txhash, time.Now())
+	})
+}
+
+func (self *SSI) GetTxStatus(txhash c_type.Uint256) (status *TxStatus, e error) {
+	e = self.withStore(func(store *TxStore) (err error) {
+		status, err = store.Status(txhash)
+		return
+	})
+	return
+}
+
+func (self *SSI) ListTxs(state TxState) (txs []TxStatus, e error) {
+	e = self.withStore(func(store *TxStore) (err error) {
+		txs, err = store.List(state)
+		return
+	})
 	return
 }
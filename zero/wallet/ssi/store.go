@@ -0,0 +1,268 @@
+package ssi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+const (
+	// DefaultTxTTL is how long a generated transaction can be committed.
+	DefaultTxTTL = time.Hour
+
+	// txRetention is how long the status of a mined or dropped transaction is
+	// kept around.
+	txRetention = 7 * 24 * time.Hour
+
+	// txConfirmations is the depth a transaction is checked for reorgs until.
+	txConfirmations = 12
+)
+
+// TxState is the stage of a generated transaction.
+type TxState string
+
+const (
+	TxGenerated TxState = "generated"
+	TxCommitted TxState = "committed"
+	TxMined     TxState = "mined"
+	TxDropped   TxState = "dropped"
+)
+
+// TxStatus tells what became of a generated transaction.
+type TxStatus struct {
+	Hash    c_type.Uint256
+	State   TxState
+	Block   *hexutil.Uint64 `json:",omitempty"` // Block the transaction was mined in
+	Created hexutil.Uint64  // Unix time the transaction was generated
+	Updated hexutil.Uint64  // Unix time of the last state change
+	Expires hexutil.Uint64  `json:",omitempty"` // Unix time a generated transaction can't be committed after
+}
+
+type txRecord struct {
+	TxStatus
+	Tx *txtool.GTx `json:",omitempty"`
+}
+
+var txPrefix = []byte("SSITX")
+
+func txKey(hash *c_type.Uint256) []byte {
+	return append(append([]byte{}, txPrefix...), hash[:]...)
+}
+
+// txPool is the part of the transaction pool telling dropped transactions.
+type txPool interface {
+	Get(hash common.Hash) *types.Transaction
+}
+
+// TxStore keeps the generated transactions until they are committed or they
+// expire, and tracks their state afterwards.
+type TxStore struct {
+	db    *serodb.LDBDatabase
+	chain serodb.Database
+	pool  txPool
+	ttl   time.Duration
+	lock  sync.Mutex
+}
+
+// NewTxStore opens the store of the generated transactions in dbpath.
+func NewTxStore(dbpath string, chain serodb.Database, pool txPool, ttl time.Duration) (*TxStore, error) {
+	db, err := serodb.NewLDBDatabase(dbpath, 16, 16)
+	if err != nil {
+		return nil, err
+	}
+	return &TxStore{db: db, chain: chain, pool: pool, ttl: ttl}, nil
+}
+
+func (self *TxStore) read(hash *c_type.Uint256) (*txRecord, error) {
+	data, err := self.db.Get(txKey(hash))
+	if err != nil {
+		return nil, fmt.Errorf("SSI tx not found : %v", hexutil.Encode(hash[:]))
+	}
+	record := new(txRecord)
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (self *TxStore) write(batch serodb.Putter, record *txRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return batch.Put(txKey(&record.Hash), data)
+}
+
+// Put stores a generated transaction.
+func (self *TxStore) Put(hash c_type.Uint256, tx *txtool.GTx, now time.Time) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	record := &txRecord{
+		TxStatus: TxStatus{
+			Hash:    hash,
+			State:   TxGenerated,
+			Created: hexutil.Uint64(now.Unix()),
+			Updated: hexutil.Uint64(now.Unix()),
+			Expires: hexutil.Uint64(now.Add(self.ttl).Unix()),
+		},
+		Tx: tx,
+	}
+	return self.write(self.db, record)
+}
+
+// Get returns a transaction to commit, unless it expired or got mined.
+func (self *TxStore) Get(hash c_type.Uint256, now time.Time) (*txtool.GTx, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	record, err := self.read(&hash)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case record.Tx == nil || record.State == TxMined:
+		return nil, fmt.Errorf("SSI tx already %s : %v", record.State, hexutil.Encode(hash[:]))
+	case record.State == TxGenerated && now.Unix() >= int64(record.Expires):
+		return nil, fmt.Errorf("SSI tx expired : %v", hexutil.Encode(hash[:]))
+	}
+	return record.Tx, nil
+}
+
+// Committed marks a transaction as handed to the pool.
+func (self *TxStore) Committed(hash c_type.Uint256, now time.Time) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	record, err := self.read(&hash)
+	if err != nil {
+		return err
+	}
+	if record.State == TxMined {
+		return nil
+	}
+	record.State, record.Expires, record.Updated = TxCommitted, 0, hexutil.Uint64(now.Unix())
+	return self.write(self.db, record)
+}
+
+// Status returns the state of a transaction.
+func (self *TxStore) Status(hash c_type.Uint256) (*TxStatus, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	record, err := self.read(&hash)
+	if err != nil {
+		return nil, err
+	}
+	return &record.TxStatus, nil
+}
+
+// List returns the states of all transactions, or only of the ones in the
+// given state if it's not empty.
+func (self *TxStore) List(state TxState) (txs []TxStatus, e error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	iterator := self.db.NewIteratorWithPrefix(txPrefix)
+	defer iterator.Release()
+	for iterator.Next() {
+		record := new(txRecord)
+		if e = json.Unmarshal(iterator.Value(), record); e != nil {
+			return
+		}
+		if state == "" || record.State == state {
+			txs = append(txs, record.TxStatus)
+		}
+	}
+	e = iterator.Error()
+	return
+}
+
+// Update moves the transactions to their current state, and deletes the
+// expired generated ones and the old finished ones.
+func (self *TxStore) Update(now time.Time) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	var head uint64
+	if number := rawdb.ReadHeaderNumber(self.chain, rawdb.ReadHeadBlockHash(self.chain)); number != nil {
+		head = *number
+	}
+	batch := self.db.NewBatch()
+	var expired, mined, dropped int
+
+	iterator := self.db.NewIteratorWithPrefix(txPrefix)
+	for iterator.Next() {
+		record := new(txRecord)
+		if err := json.Unmarshal(iterator.Value(), record); err != nil {
+			log.Error("SSI tx store corrupted", "key", hexutil.Encode(iterator.Key()), "err", err)
+			continue
+		}
+		state, changed := record.State, false
+		switch state {
+		case TxGenerated:
+			if now.Unix() >= int64(record.Expires) {
+				batch.Delete(common.CopyBytes(iterator.Key()))
+				expired++
+			}
+			continue
+		case TxMined, TxDropped:
+			if now.Sub(time.Unix(int64(record.Updated), 0)) > txRetention {
+				batch.Delete(common.CopyBytes(iterator.Key()))
+				continue
+			}
+		}
+		if state == TxMined && head >= uint64(*record.Block)+txConfirmations {
+			// Deep enough to stay, the transaction won't be committed again
+			if record.Tx != nil {
+				record.Tx, changed = nil, true
+			}
+		} else {
+			hash := common.BytesToHash(record.Hash[:])
+			if blockHash, number, _ := rawdb.ReadTxLookupEntry(self.chain, hash); blockHash != (common.Hash{}) && rawdb.ReadCanonicalHash(self.chain, number) == blockHash {
+				if state != TxMined || uint64(*record.Block) != number {
+					block := hexutil.Uint64(number)
+					record.State, record.Block, changed = TxMined, &block, true
+					mined++
+				}
+			} else if state == TxMined {
+				// Reorged out, it's back in the pool or gone
+				record.State, record.Block, changed = TxCommitted, nil, true
+			} else if state == TxCommitted && self.pool.Get(hash) == nil {
+				record.State, changed = TxDropped, true
+				dropped++
+			}
+			if changed {
+				record.Updated = hexutil.Uint64(now.Unix())
+			}
+		}
+		if changed {
+			if err := self.write(batch, record); err != nil {
+				log.Error("Failed to update SSI tx", "hash", hexutil.Encode(record.Hash[:]), "err", err)
+			}
+		}
+	}
+	iterator.Release()
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to update SSI txs", "err", err)
+		return
+	}
+	if expired > 0 || mined > 0 || dropped > 0 {
+		log.Info("Updated SSI txs", "expired", expired, "mined", mined, "dropped", dropped)
+	}
+}
+
+// Close closes the database of the store.
+func (self *TxStore) Close() {
+	self.db.Close()
+}
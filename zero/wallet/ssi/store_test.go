@@ -0,0 +1,133 @@
+package ssi
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/txtool"
+)
+
+type testPool map[common.Hash]*types.Transaction
+
+func (pool testPool) Get(hash common.Hash) *types.Transaction {
+	return pool[hash]
+}
+
+func newTestStore(t *testing.T, chain serodb.Database, pool testPool) (*TxStore, func()) {
+	dir, err := ioutil.TempDir("", "ssi-store-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewTxStore(dir, chain, pool, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func setHead(chain serodb.Database, number uint64) {
+	header := &types.Header{Number: new(big.Int).SetUint64(number)}
+	rawdb.WriteHeader(chain, header)
+	rawdb.WriteHeadBlockHash(chain, header.Hash())
+}
+
+func TestTxStoreExpiry(t *testing.T) {
+	chain := serodb.NewMemDatabase()
+	store, done := newTestStore(t, chain, testPool{})
+	defer done()
+
+	start := time.Unix(1000000, 0)
+	hash := c_type.Uint256{1}
+	if err := store.Put(hash, &txtool.GTx{Hash: hash}, start); err != nil {
+		t.Fatalf("failed to store tx: %v", err)
+	}
+	if tx, err := store.Get(hash, start.Add(time.Minute)); err != nil || tx.Hash != hash {
+		t.Fatalf("failed to get tx: %v", err)
+	}
+	if _, err := store.Get(hash, start.Add(time.Hour)); err == nil {
+		t.Fatalf("expired tx returned")
+	}
+	store.Update(start.Add(time.Hour))
+	if _, err := store.Status(hash); err == nil {
+		t.Fatalf("expired tx not deleted")
+	}
+}
+
+func TestTxStoreLifecycle(t *testing.T) {
+	chain := serodb.NewMemDatabase()
+	pool := testPool{}
+	store, done := newTestStore(t, chain, pool)
+	defer done()
+
+	start := time.Unix(1000000, 0)
+	committed, dropped := c_type.Uint256{1}, c_type.Uint256{2}
+	for _, hash := range []c_type.Uint256{committed, dropped} {
+		store.Put(hash, &txtool.GTx{Hash: hash}, start)
+		if err := store.Committed(hash, start); err != nil {
+			t.Fatalf("failed to commit tx: %v", err)
+		}
+	}
+	pool[common.BytesToHash(committed[:])] = new(types.Transaction)
+	setHead(chain, 10)
+
+	store.Update(start.Add(2 * time.Hour))
+	if status, _ := store.Status(committed); status.State != TxCommitted {
+		t.Errorf("pooled tx state mismatch: have %s, want %s", status.State, TxCommitted)
+	}
+	if status, _ := store.Status(dropped); status.State != TxDropped {
+		t.Errorf("lost tx state mismatch: have %s, want %s", status.State, TxDropped)
+	}
+	// Dropped transactions can be committed again
+	if _, err := store.Get(dropped, start.Add(2*time.Hour)); err != nil {
+		t.Errorf("dropped tx not returned: %v", err)
+	}
+	if txs, _ := store.List(TxDropped); len(txs) != 1 || txs[0].Hash != dropped {
+		t.Errorf("dropped tx list mismatch: %v", txs)
+	}
+	if txs, _ := store.List(""); len(txs) != 2 {
+		t.Errorf("tx list length mismatch: have %d, want 2", len(txs))
+	}
+	// Finished transactions are forgotten after a while
+	store.Update(start.Add(txRetention + 3*time.Hour))
+	if _, err := store.Status(dropped); err == nil {
+		t.Errorf("old dropped tx not deleted")
+	}
+	if _, err := store.Status(committed); err != nil {
+		t.Errorf("pending tx deleted: %v", err)
+	}
+}
+
+func TestSSIStartStop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ssi-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var ssi SSI
+	if _, err := ssi.ListTxs(""); err != errStoreNotStarted {
+		t.Fatalf("list before start error mismatch: have %v, want %v", err, errStoreNotStarted)
+	}
+	if err := ssi.Start(dir, serodb.NewMemDatabase(), testPool{}); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	if _, err := ssi.ListTxs(""); err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	ssi.Stop()
+	if _, err := ssi.GetTxStatus(c_type.Uint256{1}); err != errStoreNotStarted {
+		t.Fatalf("status after stop error mismatch: have %v, want %v", err, errStoreNotStarted)
+	}
+	ssi.Stop()
+}
@@ -36,4 +36,6 @@ type ISSI interface {
 	Detail(root []c_type.Uint256, skr *c_type.PKr) ([]txtool.DOut, error)
 	GenTx(param *PreTxParam) (c_type.Uint256, error)
 	CommitTx(txhash *c_type.Uint256) error
+	GetTxStatus(txhash c_type.Uint256) (*TxStatus, error)
+	ListTxs(state TxState) ([]TxStatus, error)
 }
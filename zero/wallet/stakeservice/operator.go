@@ -0,0 +1,570 @@
+package stakeservice
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/event"
+	"github.com/sero-cash/go-sero/log"
+	"github.com/sero-cash/go-sero/rlp"
+	"github.com/sero-cash/go-sero/zero/stake"
+	"github.com/sero-cash/go-sero/zero/txs/assets"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/txtool"
+	"github.com/sero-cash/go-sero/zero/txtool/prepare"
+	"github.com/sero-cash/go-sero/zero/utils"
+	"github.com/sero-cash/go-sero/zero/wallet/exchange"
+)
+
+const (
+	// defaultAlertRate is the part of the minimum miss rate, per ten
+	// thousand, from which the miss rate of a pool is alerted.
+	defaultAlertRate = 8000
+
+	// sweepDropDelay is the number of blocks a sweep tx stays unknown to the
+	// node, neither pending nor mined, before it is considered dropped.
+	sweepDropDelay = 12
+)
+
+var (
+	operatorPrefix = []byte("OPERATOR")
+
+	ErrOperatorExists   = errors.New("the stake pool is already operated")
+	ErrNotOperated      = errors.New("the stake pool is not operated")
+	ErrPoolNotExists    = errors.New("stake pool not exists")
+	ErrPoolClosed       = errors.New("stake pool has closed")
+	ErrOperatorNotOwner = errors.New("the stake pool is not owned by the account")
+	ErrNoExchange       = errors.New("exchange mode no start")
+)
+
+// Kinds of the alerts of the pool operators.
+const (
+	AlertMissRate     = "missRate"     // The miss rate of the pool nears the minimum one
+	AlertMissRateOk   = "missRateOk"   // The miss rate of the pool is back to normal
+	AlertExpired      = "expired"      // Shares of the pool expired without voting
+	AlertSwept        = "swept"        // The income of the pool was swept
+	AlertSweepFailed  = "sweepFailed"  // The sweep of the income failed
+	AlertScheduled    = "scheduled"    // A scheduled change of the pool was sent
+	AlertScheduleFail = "scheduleFail" // A scheduled change of the pool failed
+)
+
+// "OPERATOR" + poolId => PoolOperator
+func operatorKey(poolId common.Hash) []byte {
+	return append(operatorPrefix, poolId[:]...)
+}
+
+// PoolSchedule is a change of the fee rate or of the vote PKr of a pool sent
+// once the chain reaches the given height. A zero fee or vote is unchanged.
+type PoolSchedule struct {
+	At   uint64
+	Fee  uint32
+	Vote c_type.PKr
+}
+
+// PoolOperator is the configuration of a stake pool operated by an account of
+// the node.
+type PoolOperator struct {
+	PoolId common.Hash
+	Pk     c_type.Uint512 // Account owning the pool
+	PKr    c_type.PKr     // PKr the pool was registered with
+
+	SweepTo     c_type.PKr // Receiver of the income of the pool, none if zero
+	SweepMin    *big.Int   // Minimum income swept at once
+	SweepPeriod uint64     // Blocks between two sweeps
+	Swept       *big.Int   // Income of the pool swept by the mined sweep txs
+	LastSweep   uint64
+	SweepTx     common.Hash // Sweep tx sent and not mined yet, none if zero
+	Sweeping    *big.Int    // Income swept by SweepTx
+
+	AlertRate uint32 // Part of the minimum miss rate alerted, per ten thousand
+	Schedules []PoolSchedule
+}
+
+// PoolHealth is the vote health of an operated pool over the statistics
+// window of the miss rate.
+type PoolHealth struct {
+	PoolId          common.Hash `json:"poolId"`
+	Number          uint64      `json:"number"`
+	MissedVoteNum   uint32      `json:"missedNum"`
+	ExpireNum       uint32      `json:"expireNum"`
+	ChoicedShareNum uint32      `json:"choicedNum"`
+	Choiced         uint32      `json:"choiced"`  // Shares of the pool selected in the window
+	Missed          uint32      `json:"missed"`   // Votes of the pool missed in the window
+	Expired         uint32      `json:"expired"`  // Shares of the pool expired in the window
+	MissRate        float64     `json:"missRate"` // Missed votes by selected shares in the window
+	MinMissRate     float64     `json:"minMissRate"`
+	Alerting        bool        `json:"alerting"`
+}
+
+// PoolAlert is sent to the subscribers when the health of an operated pool
+// degrades or when the operator acts on the pool.
+type PoolAlert struct {
+	PoolId   common.Hash `json:"poolId"`
+	Number   uint64      `json:"number"`
+	Kind     string      `json:"kind"`
+	MissRate float64     `json:"missRate"`
+	TxHash   common.Hash `json:"txHash"`
+	Message  string      `json:"message"`
+}
+
+type poolSample struct {
+	num     uint64
+	missed  uint32
+	choiced uint32
+	expire  uint32
+}
+
+// poolOperator is the running state of an operated pool.
+type poolOperator struct {
+	config   PoolOperator
+	samples  []poolSample
+	alerting bool
+}
+
+func (self *poolOperator) health() (health PoolHealth) {
+	health.PoolId = self.config.PoolId
+	health.MinMissRate = stake.MinMissRate()
+	health.Alerting = self.alerting
+	if len(self.samples) == 0 {
+		return
+	}
+	first, last := self.samples[0], self.samples[len(self.samples)-1]
+	health.Number = last.num
+	health.MissedVoteNum = last.missed
+	health.ExpireNum = last.expire
+	health.ChoicedShareNum = last.choiced
+	health.Choiced = last.choiced - first.choiced
+	if last.missed > first.missed {
+		health.Missed = last.missed - first.missed
+	}
+	health.Expired = last.expire - first.expire
+	if health.Choiced > 0 {
+		health.MissRate = float64(health.Missed) / float64(health.Choiced)
+	}
+	return
+}
+
+// sample records the state of the pool at the given height and drops the
+// samples out of the statistics window.
+func (self *poolOperator) sample(num uint64, pool *stake.StakePool) (expired uint32) {
+	if n := len(self.samples); n > 0 {
+		last := self.samples[n-1]
+		if last.num >= num {
+			return
+		}
+		if pool.ExpireNum > last.expire {
+			expired = pool.ExpireNum - last.expire
+		}
+	}
+	self.samples = append(self.samples, poolSample{num, pool.MissedVoteNum, pool.ChoicedShareNum, pool.ExpireNum})
+	window := stake.GetStatisticsMissWindow()
+	i := 0
+	for i < len(self.samples)-1 && self.samples[i].num+window < num {
+		i++
+	}
+	self.samples = self.samples[i:]
+	return
+}
+
+// AddPoolOperator starts to operate the pool registered by the given account
+// with the given PKr.
+func (self *StakeService) AddPoolOperator(config PoolOperator) (e error) {
+	if exchange.CurrentExchange() == nil {
+		return ErrNoExchange
+	}
+	if _, ok := self.accounts.Load(config.Pk); !ok {
+		return errors.New("not found Pk")
+	}
+	config.PoolId = crypto.Keccak256Hash(config.PKr[:])
+	pool, err := self.currentPool(config.PoolId)
+	if err != nil {
+		return err
+	}
+	if pool.PKr != config.PKr {
+		return ErrOperatorNotOwner
+	}
+	if config.SweepMin == nil {
+		config.SweepMin = new(big.Int)
+	}
+	if config.Swept == nil {
+		// The income paid before the operator started is not swept.
+		config.Swept = paidIncome(pool)
+	}
+	if config.AlertRate == 0 {
+		config.AlertRate = defaultAlertRate
+	}
+	sort.Slice(config.Schedules, func(i, j int) bool {
+		return config.Schedules[i].At < config.Schedules[j].At
+	})
+
+	self.operatorLock.Lock()
+	defer self.operatorLock.Unlock()
+	if _, ok := self.operators.Load(config.PoolId); ok {
+		return ErrOperatorExists
+	}
+	if e = self.putOperator(&config); e != nil {
+		return
+	}
+	self.operators.Store(config.PoolId, &poolOperator{config: config})
+	log.Info("Add stake pool operator", "poolId", config.PoolId.String(), "pk", *utils.Base58Encode(config.Pk[:]))
+	return
+}
+
+// RemovePoolOperator stops operating a pool.
+func (self *StakeService) RemovePoolOperator(poolId common.Hash) error {
+	self.operatorLock.Lock()
+	defer self.operatorLock.Unlock()
+	if _, ok := self.operators.Load(poolId); !ok {
+		return ErrNotOperated
+	}
+	if err := self.db.Delete(operatorKey(poolId)); err != nil {
+		return err
+	}
+	self.operators.Delete(poolId)
+	return nil
+}
+
+// SchedulePoolChange schedules a change of the fee rate or of the vote PKr of
+// an operated pool.
+func (self *StakeService) SchedulePoolChange(poolId common.Hash, schedule PoolSchedule) error {
+	if schedule.Fee == 0 && schedule.Vote == (c_type.PKr{}) {
+		return errors.New("nothing to change")
+	}
+	if schedule.Fee != 0 && (schedule.Fee < seroparam.LOWEST_STAKING_NODE_FEE_RATE || schedule.Fee > seroparam.HIGHEST_STAKING_NODE_FEE_RATE) {
+		return fmt.Errorf("fee rate must be between %v and %v", seroparam.LOWEST_STAKING_NODE_FEE_RATE, seroparam.HIGHEST_STAKING_NODE_FEE_RATE)
+	}
+	self.operatorLock.Lock()
+	defer self.operatorLock.Unlock()
+	op := self.getOperator(poolId)
+	if op == nil {
+		return ErrNotOperated
+	}
+	config := op.config
+	config.Schedules = append(append([]PoolSchedule{}, config.Schedules...), schedule)
+	sort.SliceStable(config.Schedules, func(i, j int) bool {
+		return config.Schedules[i].At < config.Schedules[j].At
+	})
+	if err := self.putOperator(&config); err != nil {
+		return err
+	}
+	op.config = config
+	return nil
+}
+
+// PoolOperators returns the configurations of the operated pools.
+func (self *StakeService) PoolOperators() (configs []PoolOperator) {
+	self.operatorLock.Lock()
+	defer self.operatorLock.Unlock()
+	self.operators.Range(func(key, value interface{}) bool {
+		configs = append(configs, value.(*poolOperator).config)
+		return true
+	})
+	return
+}
+
+// PoolHealth returns the vote health of an operated pool.
+func (self *StakeService) PoolHealth(poolId common.Hash) (health PoolHealth, e error) {
+	self.operatorLock.Lock()
+	defer self.operatorLock.Unlock()
+	op := self.getOperator(poolId)
+	if op == nil {
+		e = ErrNotOperated
+		return
+	}
+	return op.health(), nil
+}
+
+// SubscribePoolAlerts registers a subscription of the alerts of the pool
+// operators.
+func (self *StakeService) SubscribePoolAlerts(ch chan<- PoolAlert) event.Subscription {
+	return self.feed.Subscribe(ch)
+}
+
+// loadOperators starts the operators stored in the database.
+func (self *StakeService) loadOperators() {
+	iterator := self.db.NewIteratorWithPrefix(operatorPrefix)
+	for iterator.Next() {
+		var config PoolOperator
+		if err := rlp.DecodeBytes(iterator.Value(), &config); err != nil {
+			log.Error("StakeService invalid pool operator RLP", "err", err)
+			continue
+		}
+		self.operators.Store(config.PoolId, &poolOperator{config: config})
+	}
+}
+
+func (self *StakeService) getOperator(poolId common.Hash) *poolOperator {
+	if value, ok := self.operators.Load(poolId); ok {
+		return value.(*poolOperator)
+	}
+	return nil
+}
+
+func (self *StakeService) putOperator(config *PoolOperator) error {
+	data, err := rlp.EncodeToBytes(config)
+	if err != nil {
+		return err
+	}
+	return self.db.Put(operatorKey(config.PoolId), data)
+}
+
+func (self *StakeService) currentPool(poolId common.Hash) (*stake.StakePool, error) {
+	state, err := self.bc.State()
+	if err != nil {
+		return nil, err
+	}
+	pool := stake.NewStakeState(state).GetStakePool(poolId)
+	if pool == nil {
+		return nil, ErrPoolNotExists
+	}
+	return pool, nil
+}
+
+// paidIncome returns the income of the pool already paid to its PKr.
+func paidIncome(pool *stake.StakePool) *big.Int {
+	paid := new(big.Int)
+	if pool.Profit != nil {
+		paid.Set(pool.Profit)
+	}
+	if pool.Income != nil {
+		paid.Sub(paid, pool.Income)
+	}
+	if paid.Sign() < 0 {
+		paid.SetUint64(0)
+	}
+	return paid
+}
+
+func (self *StakeService) alert(alert PoolAlert) {
+	log.Warn("Stake pool operator", "poolId", alert.PoolId.String(), "kind", alert.Kind, "number", alert.Number, "msg", alert.Message)
+	self.feed.Send(alert)
+}
+
+// operate checks the health of the operated pools, sends their scheduled
+// changes and sweeps their income.
+func (self *StakeService) operate() {
+	header := self.bc.CurrentHeader()
+	state, err := self.bc.StateAt(header)
+	if err != nil {
+		return
+	}
+	stakeState := stake.NewStakeState(state)
+	num := header.Number.Uint64()
+
+	self.operatorLock.Lock()
+	defer self.operatorLock.Unlock()
+	self.operators.Range(func(key, value interface{}) bool {
+		op := value.(*poolOperator)
+		pool := stakeState.GetStakePool(op.config.PoolId)
+		if pool == nil {
+			return true
+		}
+		self.checkHealth(op, num, pool)
+		config := op.config
+		changed := self.runSchedules(&config, num, pool)
+		if self.sweep(&config, num, pool) {
+			changed = true
+		}
+		if changed {
+			if err := self.putOperator(&config); err != nil {
+				log.Error("StakeService put pool operator", "poolId", config.PoolId.String(), "err", err)
+			}
+			op.config = config
+		}
+		return true
+	})
+}
+
+func (self *StakeService) checkHealth(op *poolOperator, num uint64, pool *stake.StakePool) {
+	if len(op.samples) > 0 && op.samples[len(op.samples)-1].num >= num {
+		return
+	}
+	expired := op.sample(num, pool)
+	health := op.health()
+	if expired > 0 {
+		self.alert(PoolAlert{
+			PoolId:   op.config.PoolId,
+			Number:   num,
+			Kind:     AlertExpired,
+			MissRate: health.MissRate,
+			Message:  fmt.Sprintf("%v shares expired", expired),
+		})
+	}
+	threshold := stake.MinMissRate() * float64(op.config.AlertRate) / 10000
+	if !op.alerting && health.MissRate >= threshold {
+		op.alerting = true
+		self.alert(PoolAlert{
+			PoolId:   op.config.PoolId,
+			Number:   num,
+			Kind:     AlertMissRate,
+			MissRate: health.MissRate,
+			Message:  fmt.Sprintf("missed %v of %v votes", health.Missed, health.Choiced),
+		})
+	} else if op.alerting && health.MissRate < threshold {
+		op.alerting = false
+		self.alert(PoolAlert{
+			PoolId:   op.config.PoolId,
+			Number:   num,
+			Kind:     AlertMissRateOk,
+			MissRate: health.MissRate,
+		})
+	}
+}
+
+// runSchedules sends the changes scheduled up to the given height, at most one
+// by block since every change overrides the pool registration.
+func (self *StakeService) runSchedules(config *PoolOperator, num uint64, pool *stake.StakePool) bool {
+	if len(config.Schedules) == 0 || config.Schedules[0].At > num {
+		return false
+	}
+	schedule := config.Schedules[0]
+	config.Schedules = config.Schedules[1:]
+	if pool.Closed {
+		self.alert(PoolAlert{PoolId: config.PoolId, Number: num, Kind: AlertScheduleFail, Message: ErrPoolClosed.Error()})
+		return true
+	}
+	cmd := stx.RegistPoolCmd{
+		Vote:    pool.VotePKr,
+		FeeRate: uint32(pool.Fee),
+	}
+	if schedule.Vote != (c_type.PKr{}) {
+		cmd.Vote = schedule.Vote
+	}
+	if schedule.Fee != 0 {
+		cmd.FeeRate = schedule.Fee
+	}
+	param := self.operatorTxParam(config)
+	param.Cmds.RegistPool = &cmd
+	hash, err := self.sendTx(param)
+	if err != nil {
+		self.alert(PoolAlert{PoolId: config.PoolId, Number: num, Kind: AlertScheduleFail, Message: err.Error()})
+		return true
+	}
+	self.alert(PoolAlert{
+		PoolId:  config.PoolId,
+		Number:  num,
+		Kind:    AlertScheduled,
+		TxHash:  hash,
+		Message: fmt.Sprintf("fee=%v vote=%v", cmd.FeeRate, common.BytesToAddress(cmd.Vote[:]).String()),
+	})
+	return true
+}
+
+// sweep sends the income paid to the pool since the last sweep to the
+// configured PKr, the fee of the tx is taken from it. The income is counted as
+// swept once the tx is mined, no other sweep is sent meanwhile.
+func (self *StakeService) sweep(config *PoolOperator, num uint64, pool *stake.StakePool) bool {
+	if config.SweepTx != (common.Hash{}) {
+		return self.checkSweep(config, num)
+	}
+	if config.SweepTo == (c_type.PKr{}) || num < config.LastSweep+config.SweepPeriod {
+		return false
+	}
+	amount := new(big.Int).Sub(paidIncome(pool), config.Swept)
+	if amount.Sign() <= 0 || amount.Cmp(config.SweepMin) < 0 {
+		return false
+	}
+	param := self.operatorTxParam(config)
+	value := new(big.Int).Sub(amount, param.Fee.Value.ToIntRef())
+	if value.Sign() <= 0 {
+		return false
+	}
+	param.Receptions = []prepare.Reception{{
+		Addr: config.SweepTo,
+		Asset: assets.Asset{Tkn: &assets.Token{
+			Currency: utils.CurrencyToUint256("SERO"),
+			Value:    utils.U256(*value),
+		}},
+	}}
+	config.LastSweep = num
+	hash, err := self.sendTx(param)
+	if err != nil {
+		self.alert(PoolAlert{PoolId: config.PoolId, Number: num, Kind: AlertSweepFailed, Message: err.Error()})
+		return true
+	}
+	config.SweepTx, config.Sweeping = hash, amount
+	return true
+}
+
+// checkSweep counts the income of the pending sweep tx as swept once the tx is
+// mined, or forgets the tx if it failed or was dropped.
+func (self *StakeService) checkSweep(config *PoolOperator, num uint64) bool {
+	// The pool is checked first, the receipts are written before the mined
+	// txs leave it
+	if self.txPool != nil && self.txPool.Get(config.SweepTx) != nil {
+		return false
+	}
+	receipt, _, _, _ := rawdb.ReadReceipt(self.bc.GetDB(), config.SweepTx)
+	switch {
+	case receipt != nil && receipt.Status == types.ReceiptStatusSuccessful:
+		config.Swept = new(big.Int).Add(config.Swept, config.Sweeping)
+		self.alert(PoolAlert{
+			PoolId:  config.PoolId,
+			Number:  num,
+			Kind:    AlertSwept,
+			TxHash:  config.SweepTx,
+			Message: fmt.Sprintf("swept %v", config.Sweeping),
+		})
+	case receipt != nil:
+		self.alert(PoolAlert{PoolId: config.PoolId, Number: num, Kind: AlertSweepFailed, TxHash: config.SweepTx, Message: "sweep tx failed"})
+	case num < config.LastSweep+sweepDropDelay:
+		return false
+	default:
+		self.alert(PoolAlert{PoolId: config.PoolId, Number: num, Kind: AlertSweepFailed, TxHash: config.SweepTx, Message: "sweep tx dropped"})
+	}
+	config.SweepTx, config.Sweeping = common.Hash{}, nil
+	return true
+}
+
+func (self *StakeService) operatorTxParam(config *PoolOperator) prepare.PreTxParam {
+	refundTo := config.PKr
+	return prepare.PreTxParam{
+		From:     config.Pk,
+		RefundTo: &refundTo,
+		Fee: assets.Token{
+			Currency: utils.CurrencyToUint256("SERO"),
+			Value:    utils.U256(*exchange.DefaultFee()),
+		},
+		GasPrice: exchange.DefaultGasPrice(),
+	}
+}
+
+// sendTx signs a tx of an operator with the exchange and adds it to the pool.
+func (self *StakeService) sendTx(param prepare.PreTxParam) (hash common.Hash, e error) {
+	if self.txPool == nil {
+		e = errors.New("tx pool is not ready")
+		return
+	}
+	ex := exchange.CurrentExchange()
+	if ex == nil {
+		e = ErrNoExchange
+		return
+	}
+	pretx, gtx, err := ex.GenTxWithSign(param)
+	if err != nil {
+		e = err
+		return
+	}
+	if e = self.commitTx(gtx); e != nil {
+		ex.ClearTxParam(pretx)
+		return
+	}
+	hash = common.BytesToHash(gtx.Hash[:])
+	return
+}
+
+func (self *StakeService) commitTx(tx *txtool.GTx) error {
+	gasPrice := big.Int(tx.GasPrice)
+	gas := uint64(tx.Gas)
+	signedTx := types.NewTxWithGTx(gas, &gasPrice, &tx.Tx)
+	log.Info("StakeService commitTx", "txhash", signedTx.Hash().String())
+	return self.txPool.AddLocal(signedTx)
+}
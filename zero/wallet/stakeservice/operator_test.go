@@ -0,0 +1,234 @@
+package stakeservice
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-czero-import/cpt"
+	"github.com/sero-cash/go-czero-import/seroparam"
+	"github.com/sero-cash/go-sero/common"
+	"github.com/sero-cash/go-sero/consensus/ethash"
+	"github.com/sero-cash/go-sero/core"
+	"github.com/sero-cash/go-sero/core/rawdb"
+	"github.com/sero-cash/go-sero/core/types"
+	"github.com/sero-cash/go-sero/core/vm"
+	"github.com/sero-cash/go-sero/params"
+	"github.com/sero-cash/go-sero/serodb"
+	"github.com/sero-cash/go-sero/zero/stake"
+	"github.com/sero-cash/go-sero/zero/txs/stx"
+	"github.com/sero-cash/go-sero/zero/wallet/exchange"
+)
+
+// newTestService returns a stake service over an empty chain, without tx pool
+// nor exchange, and the channel of its alerts.
+func newTestService(t *testing.T) (*StakeService, chan PoolAlert, func()) {
+	cpt.ZeroInit(cpt.NET_Alpha)
+	dir, err := ioutil.TempDir("", "stakeservice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := serodb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainDb := serodb.NewMemDatabase()
+	(&core.Genesis{Config: params.TestChainConfig}).MustCommit(chainDb)
+	chain, err := core.NewBlockChain(chainDb, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := &StakeService{bc: chain, db: db}
+	alerts := make(chan PoolAlert, 16)
+	sub := service.SubscribePoolAlerts(alerts)
+	return service, alerts, func() {
+		sub.Unsubscribe()
+		chain.Stop()
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// nextAlert returns the pending alert of the service, if any.
+func nextAlert(alerts chan PoolAlert) *PoolAlert {
+	select {
+	case alert := <-alerts:
+		return &alert
+	default:
+		return nil
+	}
+}
+
+// mineTestTx writes the lookup entry and the receipt of a tx as if it was
+// mined in the given block.
+func mineTestTx(service *StakeService, tx *types.Transaction, num uint64, status uint64) {
+	db := service.bc.GetDB()
+	block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(num)}, []*types.Transaction{tx}, nil)
+	rawdb.WriteTxLookupEntries(db, block)
+	rawdb.WriteReceipts(db, block.Hash(), num, types.Receipts{{Status: status, TxHash: tx.Hash()}})
+}
+
+func testTx(id byte) *types.Transaction {
+	return types.NewTxWithGTx(25000, big.NewInt(1), &stx.T{Ehash: c_type.Uint256{id}})
+}
+
+func TestPoolOperatorHealth(t *testing.T) {
+	op := &poolOperator{config: PoolOperator{PoolId: common.Hash{1}}}
+	if health := op.health(); health.Number != 0 || health.MissRate != 0 || health.MinMissRate != stake.MinMissRate() {
+		t.Fatalf("empty health mismatch: %+v", health)
+	}
+
+	if expired := op.sample(10, &stake.StakePool{ChoicedShareNum: 100, MissedVoteNum: 10, ExpireNum: 1}); expired != 0 {
+		t.Fatalf("first sample expired mismatch: have %d, want 0", expired)
+	}
+	if expired := op.sample(20, &stake.StakePool{ChoicedShareNum: 140, MissedVoteNum: 20, ExpireNum: 4}); expired != 3 {
+		t.Fatalf("expired mismatch: have %d, want 3", expired)
+	}
+	// A sample of a height already sampled is ignored
+	if expired := op.sample(20, &stake.StakePool{ChoicedShareNum: 200, ExpireNum: 9}); expired != 0 || len(op.samples) != 2 {
+		t.Fatalf("resampled height: expired %d, %d samples", expired, len(op.samples))
+	}
+	health := op.health()
+	if health.Number != 20 || health.Choiced != 40 || health.Missed != 10 || health.Expired != 3 || health.MissRate != 0.25 {
+		t.Fatalf("health mismatch: %+v", health)
+	}
+	if health.ChoicedShareNum != 140 || health.MissedVoteNum != 20 || health.ExpireNum != 4 {
+		t.Fatalf("pool counters mismatch: %+v", health)
+	}
+
+	// The samples out of the window are dropped, the last one is kept
+	far := 20 + stake.GetStatisticsMissWindow() + 1
+	op.sample(far, &stake.StakePool{ChoicedShareNum: 150, MissedVoteNum: 20, ExpireNum: 4})
+	if len(op.samples) != 1 || op.samples[0].num != far {
+		t.Fatalf("window mismatch: %+v", op.samples)
+	}
+	if health := op.health(); health.Choiced != 0 || health.MissRate != 0 {
+		t.Fatalf("health over one sample mismatch: %+v", health)
+	}
+}
+
+func TestPoolOperatorSweep(t *testing.T) {
+	service, alerts, done := newTestService(t)
+	defer done()
+
+	sent, failed := testTx(1), testTx(2)
+	pool := &stake.StakePool{Profit: new(big.Int).Mul(exchange.DefaultFee(), big.NewInt(10)), Income: big.NewInt(0)}
+	config := &PoolOperator{
+		PoolId:      common.Hash{1},
+		SweepTo:     c_type.PKr{1},
+		SweepMin:    new(big.Int),
+		SweepPeriod: 20,
+		Swept:       new(big.Int),
+		LastSweep:   5,
+		SweepTx:     sent.Hash(),
+		Sweeping:    big.NewInt(100),
+	}
+
+	// The income isn't swept and no other sweep is sent before the tx is mined
+	if service.sweep(config, 10, pool) || config.Swept.Sign() != 0 || config.SweepTx != sent.Hash() {
+		t.Fatalf("pending sweep counted: %+v", config)
+	}
+	if alert := nextAlert(alerts); alert != nil {
+		t.Fatalf("alert before the sweep is mined: %+v", alert)
+	}
+	mineTestTx(service, sent, 11, types.ReceiptStatusSuccessful)
+	if !service.sweep(config, 11, pool) || config.Swept.Cmp(big.NewInt(100)) != 0 || config.SweepTx != (common.Hash{}) {
+		t.Fatalf("mined sweep not counted: %+v", config)
+	}
+	if alert := nextAlert(alerts); alert == nil || alert.Kind != AlertSwept || alert.TxHash != sent.Hash() {
+		t.Fatalf("swept alert mismatch: %+v", alert)
+	}
+
+	// A failed sweep tx leaves the income to sweep
+	config.SweepTx, config.Sweeping = failed.Hash(), big.NewInt(200)
+	mineTestTx(service, failed, 32, types.ReceiptStatusFailed)
+	if !service.sweep(config, 32, pool) || config.Swept.Cmp(big.NewInt(100)) != 0 || config.SweepTx != (common.Hash{}) {
+		t.Fatalf("failed sweep counted: %+v", config)
+	}
+	if alert := nextAlert(alerts); alert == nil || alert.Kind != AlertSweepFailed || alert.TxHash != failed.Hash() {
+		t.Fatalf("failed sweep alert mismatch: %+v", alert)
+	}
+
+	// An unknown sweep tx is dropped after a delay
+	config.SweepTx, config.Sweeping, config.LastSweep = testTx(3).Hash(), big.NewInt(200), 40
+	if service.sweep(config, 40+sweepDropDelay-1, pool) || config.SweepTx == (common.Hash{}) {
+		t.Fatalf("sweep dropped before the delay: %+v", config)
+	}
+	if !service.sweep(config, 40+sweepDropDelay, pool) || config.SweepTx != (common.Hash{}) || config.Swept.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("sweep not dropped: %+v", config)
+	}
+	if alert := nextAlert(alerts); alert == nil || alert.Kind != AlertSweepFailed {
+		t.Fatalf("dropped sweep alert mismatch: %+v", alert)
+	}
+
+	// The next sweep waits for its period and counts nothing if not sent
+	if service.sweep(config, 59, pool) {
+		t.Fatal("sweep sent before its period")
+	}
+	if !service.sweep(config, 60, pool) || config.LastSweep != 60 || config.SweepTx != (common.Hash{}) || config.Swept.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("unsent sweep mismatch: %+v", config)
+	}
+	if alert := nextAlert(alerts); alert == nil || alert.Kind != AlertSweepFailed {
+		t.Fatalf("unsent sweep alert mismatch: %+v", alert)
+	}
+}
+
+func TestPoolOperatorSchedules(t *testing.T) {
+	service, alerts, done := newTestService(t)
+	defer done()
+
+	poolId := common.Hash{1}
+	service.operators.Store(poolId, &poolOperator{config: PoolOperator{PoolId: poolId, Swept: new(big.Int), SweepMin: new(big.Int)}})
+	fee := uint32(seroparam.LOWEST_STAKING_NODE_FEE_RATE)
+	for _, schedule := range []PoolSchedule{
+		{At: 20, Fee: fee},
+		{At: 10, Vote: c_type.PKr{2}},
+		{At: 10, Fee: fee + 1},
+	} {
+		if err := service.SchedulePoolChange(poolId, schedule); err != nil {
+			t.Fatalf("failed to schedule %+v: %v", schedule, err)
+		}
+	}
+	for _, schedule := range []PoolSchedule{
+		{At: 30},
+		{At: 30, Fee: seroparam.LOWEST_STAKING_NODE_FEE_RATE - 1},
+		{At: 30, Fee: seroparam.HIGHEST_STAKING_NODE_FEE_RATE + 1},
+	} {
+		if err := service.SchedulePoolChange(poolId, schedule); err == nil {
+			t.Fatalf("scheduled an invalid change: %+v", schedule)
+		}
+	}
+	if err := service.SchedulePoolChange(common.Hash{2}, PoolSchedule{At: 30, Fee: fee}); err != ErrNotOperated {
+		t.Fatalf("scheduled a change of a pool not operated: %v", err)
+	}
+	config := service.getOperator(poolId).config
+	if len(config.Schedules) != 3 || config.Schedules[0].Vote != (c_type.PKr{2}) || config.Schedules[1].Fee != fee+1 || config.Schedules[2].At != 20 {
+		t.Fatalf("schedules mismatch: %+v", config.Schedules)
+	}
+
+	pool := &stake.StakePool{Fee: 3000}
+	if service.runSchedules(&config, 9, pool) || len(config.Schedules) != 3 {
+		t.Fatal("schedule run before its height")
+	}
+	// One change is sent by block, the failures are alerted
+	for i := 2; i >= 1; i-- {
+		if !service.runSchedules(&config, 15, pool) || len(config.Schedules) != i {
+			t.Fatalf("schedule not run: %+v", config.Schedules)
+		}
+		if alert := nextAlert(alerts); alert == nil || alert.Kind != AlertScheduleFail {
+			t.Fatalf("schedule alert mismatch: %+v", alert)
+		}
+	}
+	if service.runSchedules(&config, 15, pool) {
+		t.Fatal("schedule run before its height")
+	}
+	pool.Closed = true
+	if !service.runSchedules(&config, 20, pool) || len(config.Schedules) != 0 {
+		t.Fatalf("schedule of a closed pool kept: %+v", config.Schedules)
+	}
+	if alert := nextAlert(alerts); alert == nil || alert.Kind != AlertScheduleFail || alert.Message != ErrPoolClosed.Error() {
+		t.Fatalf("closed pool alert mismatch: %+v", alert)
+	}
+}
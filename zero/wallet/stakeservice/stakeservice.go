@@ -28,12 +28,16 @@ type Account struct {
 
 type StakeService struct {
 	bc             *core.BlockChain
+	txPool         *core.TxPool
 	accountManager *accounts.Manager
 	db             *serodb.LDBDatabase
 
 	accounts sync.Map
 	numbers  sync.Map
 
+	operators    sync.Map
+	operatorLock sync.Mutex
+
 	feed    event.Feed
 	updater event.Subscription        // Wallet update subscriptions for all backends
 	update  chan accounts.WalletEvent // Subscription sink for backend wallet changes
@@ -47,12 +51,13 @@ func CurrentStakeService() *StakeService {
 	return current_StakeService
 }
 
-func NewStakeService(dbpath string, bc *core.BlockChain, accountManager *accounts.Manager) *StakeService {
+func NewStakeService(dbpath string, bc *core.BlockChain, txPool *core.TxPool, accountManager *accounts.Manager) *StakeService {
 	update := make(chan accounts.WalletEvent, 1)
 	updater := accountManager.Subscribe(update)
 
 	stakeService := &StakeService{
 		bc:             bc,
+		txPool:         txPool,
 		accountManager: accountManager,
 		update:         update,
 		updater:        updater,
@@ -71,7 +76,10 @@ func NewStakeService(dbpath string, bc *core.BlockChain, accountManager *account
 		stakeService.initWallet(w)
 	}
 
+	stakeService.loadOperators()
+
 	AddJob("0/10 * * * * ?", stakeService.stakeIndex)
+	AddJob("5/10 * * * * ?", stakeService.operate)
 	go stakeService.updateAccount()
 	return stakeService
 }
@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/sero-cash/go-czero-import/c_type"
 	"github.com/sero-cash/go-czero-import/seroparam"
@@ -291,29 +293,51 @@ var checkpoints_json = `[
 /*
  */
 
-type checkPoint struct {
+// CheckPoint is the state root expected at a block number.
+type CheckPoint struct {
 	Num  uint64
 	Root c_type.Uint256
 }
 
+// checkPointInterval is the distance between two checkpoints.
+const checkPointInterval = 10000
+
 type checkPoints struct {
-	maxNum uint64
-	points map[uint64][]byte
+	mu         sync.RWMutex
+	maxNum     uint64
+	trustedNum uint64
+	points     map[uint64][]byte
 }
 
 func (self *checkPoints) MaxNum() (ret uint64) {
 	if seroparam.Is_Dev() {
 		return 0
 	} else {
+		self.mu.RLock()
+		defer self.mu.RUnlock()
 		return self.maxNum
 	}
 }
 
+// TrustedNum returns the last built-in checkpoint. The headers of the blocks up
+// to it are not verified, the points loaded from files only check the roots.
+func (self *checkPoints) TrustedNum() uint64 {
+	if seroparam.Is_Dev() {
+		return 0
+	} else {
+		self.mu.RLock()
+		defer self.mu.RUnlock()
+		return self.trustedNum
+	}
+}
+
 func (self *checkPoints) Check(num uint64, root []byte) (e error) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
 	if num > self.maxNum {
-		panic(fmt.Errorf("check points error: the num > maxNum %d-%s", num, hex.EncodeToString(root)))
+		return fmt.Errorf("check points error: the num > maxNum %d-%s", num, hex.EncodeToString(root))
 	}
-	if (num > 0) && (num%10000 == 0) {
+	if (num > 0) && (num%checkPointInterval == 0) {
 		if rt, ok := self.points[num]; !ok {
 			return fmt.Errorf("check points error: can not find the point %d-%s", num, hex.EncodeToString(root))
 		} else {
@@ -328,9 +352,59 @@ func (self *checkPoints) Check(num uint64, root []byte) (e error) {
 	}
 }
 
-func newCheckPoints() (ret checkPoints) {
-	ret.points = make(map[uint64][]byte)
-	var cps []*checkPoint
+// Add extends the checkpoints with the given ones. The points must be on the
+// checkpoint interval, agree with the known ones and leave no gap after the
+// current last point.
+func (self *checkPoints) Add(cps []CheckPoint) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	sorted := make([]CheckPoint, len(cps))
+	copy(sorted, cps)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Num < sorted[j].Num })
+
+	next := self.maxNum + checkPointInterval
+	for _, cp := range sorted {
+		if cp.Num == 0 || cp.Num%checkPointInterval != 0 {
+			return fmt.Errorf("check points error: the point %d is not on the interval", cp.Num)
+		}
+		if rt, ok := self.points[cp.Num]; ok {
+			if bytes.Compare(rt, cp.Root[:]) != 0 {
+				return fmt.Errorf("check points error: the point are not match %d-%s (%s)", cp.Num, hex.EncodeToString(cp.Root[:]), hex.EncodeToString(rt))
+			}
+			continue
+		}
+		if cp.Num > next {
+			return fmt.Errorf("check points error: missing the point %d", next)
+		}
+		next = cp.Num + checkPointInterval
+	}
+	for _, cp := range sorted {
+		root := cp.Root
+		self.points[cp.Num] = root[:]
+		if cp.Num > self.maxNum {
+			self.maxNum = cp.Num
+		}
+	}
+	return nil
+}
+
+// Points returns the checkpoints ordered by number.
+func (self *checkPoints) Points() (ret []CheckPoint) {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+	for num, root := range self.points {
+		cp := CheckPoint{Num: num}
+		copy(cp.Root[:], root)
+		ret = append(ret, cp)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Num < ret[j].Num })
+	return
+}
+
+func newCheckPoints() (ret *checkPoints) {
+	ret = &checkPoints{points: make(map[uint64][]byte)}
+	var cps []*CheckPoint
 	json.Unmarshal([]byte(checkpoints_json), &cps)
 
 	for _, cp := range cps {
@@ -339,6 +413,7 @@ func newCheckPoints() (ret checkPoints) {
 			ret.maxNum = cp.Num
 		}
 	}
+	ret.trustedNum = ret.maxNum
 	return
 }
 
@@ -0,0 +1,107 @@
+package zconfig
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/sero-cash/go-sero/common/hexutil"
+	"github.com/sero-cash/go-sero/crypto"
+	"github.com/sero-cash/go-sero/rlp"
+)
+
+var ErrCheckPointSigner = errors.New("check points error: the file is not signed by a trusted signer")
+
+// CheckPointFile is a set of checkpoints generated from a synced node and
+// signed by its operator.
+type CheckPointFile struct {
+	Points    []CheckPoint  `json:"points"`
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// SigHash returns the hash signed by the operator.
+func (self *CheckPointFile) SigHash() ([]byte, error) {
+	data, err := rlp.EncodeToBytes(self.Points)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(data), nil
+}
+
+// Sign signs the checkpoints of the file with the given key.
+func (self *CheckPointFile) Sign(key *ecdsa.PrivateKey) error {
+	hash, err := self.SigHash()
+	if err != nil {
+		return err
+	}
+	self.Signature, err = crypto.Sign(hash, key)
+	return err
+}
+
+// Signer returns the compressed public key which signed the file.
+func (self *CheckPointFile) Signer() (hexutil.Bytes, error) {
+	hash, err := self.SigHash()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := crypto.SigToPub(hash, self.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.CompressPubkey(pub), nil
+}
+
+// ReadCheckPointFile reads a checkpoint file and checks its signature.
+func ReadCheckPointFile(path string) (*CheckPointFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := new(CheckPointFile)
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("invalid check point file %s: %v", path, err)
+	}
+	if _, err := file.Signer(); err != nil {
+		return nil, fmt.Errorf("invalid check point file %s: %v", path, err)
+	}
+	return file, nil
+}
+
+// WriteCheckPointFile writes a signed checkpoint file.
+func WriteCheckPointFile(path string, file *CheckPointFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// LoadCheckPointFile adds the checkpoints of a file to the ones checked by the
+// node. If signers are given the file must be signed by one of them.
+func LoadCheckPointFile(path string, signers []hexutil.Bytes) (signer hexutil.Bytes, e error) {
+	file, err := ReadCheckPointFile(path)
+	if err != nil {
+		e = err
+		return
+	}
+	if signer, e = file.Signer(); e != nil {
+		return
+	}
+	if len(signers) > 0 {
+		trusted := false
+		for _, s := range signers {
+			if string(s) == string(signer) {
+				trusted = true
+				break
+			}
+		}
+		if !trusted {
+			e = ErrCheckPointSigner
+			return
+		}
+	}
+	e = CheckPoints.Add(file.Points)
+	return
+}
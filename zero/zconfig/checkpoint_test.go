@@ -1,12 +1,71 @@
 package zconfig
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"testing"
+
+	"github.com/sero-cash/go-czero-import/c_type"
+	"github.com/sero-cash/go-sero/crypto"
 )
 
 func TestCheckPoint(t *testing.T) {
 	fmt.Println("checkpoint: ", hex.EncodeToString(CheckPoints.points[10000]))
 	fmt.Println("maxNum: ", CheckPoints.MaxNum())
 }
+
+func TestCheckPointAdd(t *testing.T) {
+	cps := &checkPoints{points: make(map[uint64][]byte)}
+	cps.points[10000] = make([]byte, 32)
+	cps.maxNum = 10000
+	cps.trustedNum = 10000
+
+	if err := cps.Check(20000, make([]byte, 32)); err == nil {
+		t.Fatalf("expected an error beyond the last point")
+	}
+	if err := cps.Add([]CheckPoint{{Num: 30000}}); err == nil {
+		t.Fatalf("expected an error for a gap")
+	}
+	if err := cps.Add([]CheckPoint{{Num: 25000}}); err == nil {
+		t.Fatalf("expected an error off the interval")
+	}
+	if err := cps.Add([]CheckPoint{{Num: 10000, Root: c_type.Uint256{1}}}); err == nil {
+		t.Fatalf("expected an error for a conflicting point")
+	}
+	if err := cps.Add([]CheckPoint{{Num: 30000, Root: c_type.Uint256{3}}, {Num: 20000, Root: c_type.Uint256{2}}}); err != nil {
+		t.Fatalf("failed to add the points: %v", err)
+	}
+	if cps.maxNum != 30000 {
+		t.Fatalf("maxNum mismatch: have %d, want %d", cps.maxNum, 30000)
+	}
+	if num := cps.TrustedNum(); num != 10000 {
+		t.Fatalf("added points extend the trusted ones: have %d, want %d", num, 10000)
+	}
+	root := c_type.Uint256{2}
+	if err := cps.Check(20000, root[:]); err != nil {
+		t.Fatalf("failed to check the point: %v", err)
+	}
+	if err := cps.Check(30000, root[:]); err == nil {
+		t.Fatalf("expected a mismatch")
+	}
+}
+
+func TestCheckPointFileSignature(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	file := &CheckPointFile{Points: []CheckPoint{{Num: 10000, Root: c_type.Uint256{1}}}}
+	if err := file.Sign(key); err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	signer, err := file.Signer()
+	if err != nil {
+		t.Fatalf("failed to recover the signer: %v", err)
+	}
+	if !bytes.Equal(signer, crypto.CompressPubkey(&key.PublicKey)) {
+		t.Fatalf("signer mismatch: have %x, want %x", signer, crypto.CompressPubkey(&key.PublicKey))
+	}
+	file.Points[0].Root[0] = 2
+	if signer, _ := file.Signer(); bytes.Equal(signer, crypto.CompressPubkey(&key.PublicKey)) {
+		t.Fatalf("altered file still signed by the key")
+	}
+}
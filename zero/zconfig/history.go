@@ -0,0 +1,7 @@
+package zconfig
+
+import "path/filepath"
+
+func History_dir() string {
+	return filepath.Join(dir, "history")
+}
@@ -0,0 +1,16 @@
+package zconfig
+
+import "github.com/sero-cash/go-czero-import/seroparam"
+
+var skipProof bool
+
+// Init_SkipProof sets whether the node neither generates nor verifies the zero
+// knowledge proofs of the transactions. It only takes effect on the dev
+// network, where it keeps the test chains fast to build.
+func Init_SkipProof(b bool) {
+	skipProof = b
+}
+
+func IsSkipProof() bool {
+	return skipProof && seroparam.Is_Dev()
+}